@@ -3,60 +3,293 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/enzo-gbd/GBA/configs"
 	"github.com/enzo-gbd/GBA/internal/controllers/auth"
+	"github.com/enzo-gbd/GBA/internal/controllers/code"
+	"github.com/enzo-gbd/GBA/internal/controllers/consent"
+	"github.com/enzo-gbd/GBA/internal/controllers/conversation"
+	"github.com/enzo-gbd/GBA/internal/controllers/emailDomain"
+	"github.com/enzo-gbd/GBA/internal/controllers/health"
+	"github.com/enzo-gbd/GBA/internal/controllers/memory"
+	"github.com/enzo-gbd/GBA/internal/controllers/moderation"
+	"github.com/enzo-gbd/GBA/internal/controllers/organization"
+	"github.com/enzo-gbd/GBA/internal/controllers/persona"
+	"github.com/enzo-gbd/GBA/internal/controllers/privacy"
+	"github.com/enzo-gbd/GBA/internal/controllers/profile"
+	"github.com/enzo-gbd/GBA/internal/controllers/prompt"
+	"github.com/enzo-gbd/GBA/internal/controllers/share"
+	"github.com/enzo-gbd/GBA/internal/controllers/socket"
+	"github.com/enzo-gbd/GBA/internal/controllers/usage"
 	"github.com/enzo-gbd/GBA/internal/controllers/user"
+	"github.com/enzo-gbd/GBA/internal/controllers/vocabulary"
 	"github.com/enzo-gbd/GBA/internal/db"
 	"github.com/enzo-gbd/GBA/internal/middlewares"
 	"github.com/enzo-gbd/GBA/internal/routes/admin"
 	"github.com/enzo-gbd/GBA/internal/routes/api"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/services/captcha"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/enzo-gbd/GBA/internal/services/emailpolicy"
+	"github.com/enzo-gbd/GBA/internal/services/guard"
+	"github.com/enzo-gbd/GBA/internal/services/letters"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/mailer"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	moderationService "github.com/enzo-gbd/GBA/internal/services/moderation"
+	"github.com/enzo-gbd/GBA/internal/services/realtime"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
+	"gorm.io/gorm"
 )
 
 var (
 	// AuthRouteController handles the authentication-related routes.
 	AuthRouteController api.AuthRouteController
 
+	// ConsentRouteController handles the public parental consent routes.
+	ConsentRouteController api.ConsentRouteController
+
 	// UserAPIRouteController handles user management within the API scope.
 	UserAPIRouteController api.UserAPIRouteController
 
 	// UserAdminRouteController handles user management within the admin scope.
 	UserAdminRouteController admin.UserAdminRouteController
+
+	// CodeAdminRouteController handles subscription code management within the admin scope.
+	CodeAdminRouteController admin.CodeAdminRouteController
+
+	// EmailDomainAdminRouteController handles the email policy management within the admin scope.
+	EmailDomainAdminRouteController admin.EmailDomainAdminRouteController
+
+	// OrganizationRouteController handles organizations, their members and invitations within the API scope.
+	OrganizationRouteController api.OrganizationRouteController
+
+	// ConversationRouteController handles the conversations of the current user and their messages.
+	ConversationRouteController api.ConversationRouteController
+
+	// PersonaRouteController handles the public catalogue of the pen pals.
+	PersonaRouteController api.PersonaRouteController
+
+	// PersonaAdminRouteController handles the pen pals within the admin scope.
+	PersonaAdminRouteController admin.PersonaAdminRouteController
+
+	// PromptAdminRouteController handles the prompt templates within the admin scope.
+	PromptAdminRouteController admin.PromptAdminRouteController
+
+	// SocketRouteController handles the WebSocket connections of the chat.
+	SocketRouteController api.SocketRouteController
+
+	// UsageRouteController handles the consumption of the current user.
+	UsageRouteController api.UsageRouteController
+
+	// UsageAdminRouteController handles the usage reports within the admin scope.
+	UsageAdminRouteController admin.UsageAdminRouteController
+
+	// MemoryRouteController handles the facts remembered about the current user.
+	MemoryRouteController api.MemoryRouteController
+
+	// VocabularyRouteController handles the vocabulary of the current user and its reviews.
+	VocabularyRouteController api.VocabularyRouteController
+
+	// ProfileRouteController handles the learner profile of the current user and their placement tests.
+	ProfileRouteController api.ProfileRouteController
+
+	// ModerationAdminRouteController handles the review queue of the moderation within the admin scope.
+	ModerationAdminRouteController admin.ModerationAdminRouteController
+
+	// ShareRouteController handles the public links to the conversations and the public route opening them.
+	ShareRouteController api.ShareRouteController
+
+	// ShareAdminRouteController handles the takedown of the public links within the admin scope.
+	ShareAdminRouteController admin.ShareAdminRouteController
+
+	// PrivacyAdminRouteController handles the monitoring of the personal data redaction within the admin scope.
+	PrivacyAdminRouteController admin.PrivacyAdminRouteController
+
+	// HealthRouteController handles the public health check.
+	HealthRouteController api.HealthRouteController
+
+	// HealthAdminRouteController handles the monitoring of the language models within the admin scope.
+	HealthAdminRouteController admin.HealthAdminRouteController
 )
 
 // init initializes the controllers for the API and administration routes.
 func init() {
-	authController := auth.NewAuthController()
-	AuthRouteController = api.NewAuthRouteController(authController)
+	consentController := consent.NewConsentController()
+	ConsentRouteController = api.NewConsentRouteController(consentController)
 
 	userController := user.NewUserController()
 	UserAPIRouteController = api.NewAPIRouteUserController(userController)
 	UserAdminRouteController = admin.NewAdminRouteUserController(userController)
+
+	codeController := code.NewCodeController()
+	CodeAdminRouteController = admin.NewAdminRouteCodeController(codeController)
+
+	personaController := persona.NewPersonaController()
+	PersonaRouteController = api.NewPersonaRouteController(personaController)
+	PersonaAdminRouteController = admin.NewAdminRoutePersonaController(personaController)
+
+	promptController := prompt.NewPromptController()
+	PromptAdminRouteController = admin.NewAdminRoutePromptController(promptController)
+
+	moderationController := moderation.NewModerationController()
+	ModerationAdminRouteController = admin.NewAdminRouteModerationController(moderationController)
+}
+
+// initServices initializes the controllers depending on services built from the configuration, and starts the
+// background workers.
+func initServices(config *configs.Config, database *gorm.DB) {
+	if err := agegate.CheckConfig(config); err != nil {
+		log.Fatal("Invalid parental consent configuration: ", err)
+	}
+	mailService := mailer.NewMailer(config)
+
+	emailPolicy, err := emailpolicy.NewPolicyFromConfig(config)
+	if err != nil {
+		log.Fatal("Could not load the email blocklist: ", err)
+	}
+	go emailPolicy.Blocklist().Watch(context.Background(), config.EmailBlocklistReloadInterval)
+
+	emailDomainController := emailDomain.NewEmailDomainController(emailPolicy)
+	EmailDomainAdminRouteController = admin.NewAdminRouteEmailDomainController(emailDomainController)
+
+	authController := auth.NewAuthController(mailService, emailPolicy)
+	AuthRouteController = api.NewAuthRouteController(
+		authController,
+		captcha.NewVerifier(config, "register"),
+		captcha.NewVerifier(config, "login"),
+		captcha.NewAttemptTracker(config.CaptchaLoginThreshold, config.CaptchaLoginWindow),
+	)
+
+	organizationController := organization.NewOrganizationController(mailService)
+	OrganizationRouteController = api.NewOrganizationRouteController(organizationController)
+
+	provider, err := llm.NewProvider(config)
+	if err != nil {
+		log.Fatal("Could not create the language model provider: ", err)
+	}
+	resilientProvider := llm.NewResilientProviderFromConfig(provider, config)
+	provider = resilientProvider
+	healthController := health.NewHealthController(resilientProvider)
+	HealthRouteController = api.NewHealthRouteController(healthController)
+	HealthAdminRouteController = admin.NewAdminRouteHealthController(healthController)
+
+	redactor, err := redaction.NewRedactorFromConfig(config)
+	if err != nil {
+		log.Fatal("Could not create the personal data redaction: ", err)
+	}
+	provider = redaction.NewProvider(provider, redactor)
+	privacyController := privacy.NewPrivacyController(redactor)
+	PrivacyAdminRouteController = admin.NewAdminRoutePrivacyController(privacyController)
+
+	meter := metering.NewMeter(config)
+	usageController := usage.NewUsageController(meter)
+	UsageRouteController = api.NewUsageRouteController(usageController)
+	UsageAdminRouteController = admin.NewAdminRouteUsageController(usageController)
+
+	moderator, err := moderationService.NewModeratorFromConfig(config)
+	if err != nil {
+		log.Fatal("Could not create the moderation: ", err)
+	}
+	injectionGuard, err := guard.NewGuardFromConfig(config)
+	if err != nil {
+		log.Fatal("Could not create the injection guard: ", err)
+	}
+	chatService := chat.NewService(provider, meter, chat.Options{
+		Window: chat.Window{
+			Budget:        config.ContextTokenBudget,
+			SummaryTokens: config.ContextSummaryMaxTokens,
+		},
+		Memory:      chat.Memory{Recall: config.MemoryRecalledFacts},
+		Corrections: config.CorrectionsEnabled,
+		Moderator:   moderator,
+		Guard:       injectionGuard,
+		Letters: chat.Letters{
+			MinDelay: config.LettersMinDelay,
+			MaxDelay: config.LettersMaxDelay,
+		},
+	})
+	conversationController := conversation.NewConversationController(chatService)
+	ConversationRouteController = api.NewConversationRouteController(conversationController)
+
+	shareController, err := share.NewShareController(agegate.NewPolicy(config))
+	if err != nil {
+		log.Fatal("Could not create the share scrubbing: ", err)
+	}
+	ShareRouteController = api.NewShareRouteController(shareController)
+	ShareAdminRouteController = admin.NewAdminRouteShareController(shareController)
+
+	memoryController := memory.NewMemoryController()
+	MemoryRouteController = api.NewMemoryRouteController(memoryController)
+
+	vocabularyController := vocabulary.NewVocabularyController(provider, meter)
+	VocabularyRouteController = api.NewVocabularyRouteController(vocabularyController)
+
+	profileController := profile.NewProfileController(provider, meter)
+	ProfileRouteController = api.NewProfileRouteController(profileController)
+
+	hub := realtime.NewHub(config)
+	socketController := socket.NewSocketController(chatService, hub)
+	SocketRouteController = api.NewSocketRouteController(socketController)
+
+	notifiers := []letters.Notifier{letters.HubNotifier{Hub: hub}}
+	if config.LettersEmailNotifications {
+		notifiers = append(notifiers, letters.MailNotifier{Mailer: mailService, BaseURL: config.AppBaseURL})
+	}
+	letterWorker := letters.NewWorker(chatService, letters.Options{
+		PollInterval: config.LettersPollInterval,
+		BatchSize:    config.LettersBatchSize,
+		Lease:        config.LettersLease,
+		MaxAttempts:  config.LettersMaxAttempts,
+		RetryDelay:   config.LettersRetryDelay,
+	}, notifiers...)
+	go letterWorker.Run(context.Background(), database)
 }
 
 // apiRoutes configures the API and admin routes with the appropriate controllers and middleware.
 func apiRoutes(router *gin.Engine) {
 	apiRouter := router.Group("/api")
 	{
+		HealthRouteController.HealthRoute(apiRouter)
 		AuthRouteController.AuthRoutes(apiRouter)
+		ConsentRouteController.ConsentRoute(apiRouter)
+		SocketRouteController.SocketRoute(apiRouter)
+		PersonaRouteController.PersonaRoute(apiRouter)
+		ShareRouteController.SharedRoute(apiRouter)
 		UserAPIRouteController.UserRoute(apiRouter)
+		OrganizationRouteController.OrganizationRoute(apiRouter)
+		ConversationRouteController.ConversationRoute(apiRouter)
+		ShareRouteController.ShareRoute(apiRouter)
+		UsageRouteController.UsageRoute(apiRouter)
+		MemoryRouteController.MemoryRoute(apiRouter)
+		VocabularyRouteController.VocabularyRoute(apiRouter)
+		ProfileRouteController.ProfileRoute(apiRouter)
 	}
 	adminRouter := router.Group("/admin")
 	adminRouter.Use(middlewares.DeserializeUser())
 	adminRouter.Use(middlewares.CheckUserRole("admin"))
 	{
 		UserAdminRouteController.UserRoute(adminRouter)
+		CodeAdminRouteController.CodeRoute(adminRouter)
+		EmailDomainAdminRouteController.EmailDomainRoute(adminRouter)
+		PersonaAdminRouteController.PersonaRoute(adminRouter)
+		PromptAdminRouteController.PromptRoute(adminRouter)
+		UsageAdminRouteController.UsageRoute(adminRouter)
+		ModerationAdminRouteController.ModerationRoute(adminRouter)
+		PrivacyAdminRouteController.PrivacyRoute(adminRouter)
+		ShareAdminRouteController.ShareRoute(adminRouter)
+		HealthAdminRouteController.HealthRoute(adminRouter)
 	}
 }
 
 // setupRouter initializes the Gin engine with middleware, database, and rate limiting.
 // It returns the configured router.
-func setupRouter(config *configs.Config) *gin.Engine {
+func setupRouter(database *gorm.DB) *gin.Engine {
 	router := gin.Default()
-	database := db.InitDB(config)
 
 	limiter := rate.NewLimiter(1, 5)
 
@@ -75,7 +308,9 @@ func main() {
 	if err != nil {
 		log.Fatal("Could not load environment variables: ", err)
 	}
-	router := setupRouter(&config)
+	database := db.InitDB(&config)
+	initServices(&config, database)
+	router := setupRouter(database)
 
 	apiRoutes(router)
 
@@ -6,6 +6,7 @@ import (
 	"github.com/enzo-gbd/GBA/configs"
 	"github.com/enzo-gbd/GBA/internal/db"
 	"github.com/enzo-gbd/GBA/internal/models"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -15,8 +16,49 @@ func main() {
 	}
 
 	database := db.InitDB(&config)
-	err = database.AutoMigrate(&models.User{})
+	err = database.AutoMigrate(
+		&models.User{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.SubscriptionCode{},
+		&models.CodeRedemption{},
+		&models.ParentalConsent{},
+		&models.EmailDomainRule{},
+		&models.PromptTemplate{},
+		&models.Persona{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.UsageRecord{},
+		&models.Memory{},
+		&models.VocabularyItem{},
+		&models.LearnerLanguage{},
+		&models.PlacementTest{},
+		&models.ModerationRecord{},
+		&models.Share{},
+		&models.Letter{},
+	)
 	if err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
+	if err := linkMessages(database); err != nil {
+		log.Fatalf("failed to link the messages: %v", err)
+	}
+}
+
+// linkMessages turns the messages of the conversations predating the branches into a single branch, each message
+// answering the previous one, and makes their last message the end of the active branch.
+func linkMessages(database *gorm.DB) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE messages SET parent_id = ordered.previous_id
+			FROM (SELECT id, LAG(id) OVER (PARTITION BY conversation_id ORDER BY created_at, id) AS previous_id FROM messages) AS ordered
+			WHERE messages.id = ordered.id AND ordered.previous_id IS NOT NULL AND messages.parent_id IS NULL
+			AND messages.conversation_id IN (SELECT id FROM conversations WHERE active_leaf_id IS NULL)`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE conversations SET active_leaf_id = (SELECT id FROM messages
+			WHERE messages.conversation_id = conversations.id ORDER BY created_at DESC, id DESC LIMIT 1)
+			WHERE active_leaf_id IS NULL`).Error
+	})
 }
@@ -9,6 +9,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// RegistrationModeInviteOnly is the registration mode requiring a subscription code to sign up.
+const RegistrationModeInviteOnly = "invite_only"
+
 // env defaults to "local", indicating the environment settings to load. This can be overridden by setting a different value in the environment variables.
 var env = "local"
 
@@ -31,6 +34,95 @@ type Config struct {
 	RefreshTokenExpiresIn  time.Duration `mapstructure:"REFRESH_TOKEN_EXPIRED_IN"`  // RefreshTokenExpiresIn specifies the duration after which refresh tokens expire.
 	AccessTokenMaxAge      int           `mapstructure:"ACCESS_TOKEN_MAXAGE"`       // AccessTokenMaxAge specifies the maximum age in seconds for access tokens.
 	RefreshTokenMaxAge     int           `mapstructure:"REFRESH_TOKEN_MAXAGE"`      // RefreshTokenMaxAge specifies the maximum age in seconds for refresh tokens.
+
+	AppBaseURL string `mapstructure:"APP_BASE_URL"` // AppBaseURL is the public URL of the client application, used to build links sent by email.
+
+	SMTPHost     string `mapstructure:"SMTP_HOST"`     // SMTPHost represents the mail server address. Emails are only logged when empty.
+	SMTPPort     string `mapstructure:"SMTP_PORT"`     // SMTPPort represents the port on which the mail server is listening.
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"` // SMTPUsername represents the user name used to authenticate against the mail server.
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"` // SMTPPassword represents the password used to authenticate against the mail server.
+	SMTPFrom     string `mapstructure:"SMTP_FROM"`     // SMTPFrom represents the sender address of outgoing emails.
+
+	InvitationExpiresIn time.Duration `mapstructure:"INVITATION_EXPIRED_IN"` // InvitationExpiresIn specifies the duration after which organization invitations expire.
+
+	RegistrationMode string `mapstructure:"REGISTRATION_MODE"` // RegistrationMode is either "open" or "invite_only", which requires a subscription code to sign up.
+
+	MinimumAge               int           `mapstructure:"MINIMUM_AGE"`                 // MinimumAge is the age under which users cannot register.
+	ParentalConsentAge       int           `mapstructure:"PARENTAL_CONSENT_AGE"`        // ParentalConsentAge is the age under which users need the consent of a parent.
+	ParentalConsentSecret    string        `mapstructure:"PARENTAL_CONSENT_SECRET"`     // ParentalConsentSecret is the key used to sign the parental consent links.
+	ParentalConsentExpiresIn time.Duration `mapstructure:"PARENTAL_CONSENT_EXPIRED_IN"` // ParentalConsentExpiresIn specifies the duration after which parental consent links expire.
+
+	CaptchaProvider       string        `mapstructure:"CAPTCHA_PROVIDER"`        // CaptchaProvider is either "hcaptcha" or "turnstile". Human verification is disabled when empty.
+	CaptchaSecret         string        `mapstructure:"CAPTCHA_SECRET"`          // CaptchaSecret is the secret key used to verify the tokens with the provider.
+	CaptchaVerifyURL      string        `mapstructure:"CAPTCHA_VERIFY_URL"`      // CaptchaVerifyURL overrides the verification endpoint of the provider.
+	CaptchaRoutes         string        `mapstructure:"CAPTCHA_ROUTES"`          // CaptchaRoutes is the comma separated list of routes protected by human verification.
+	CaptchaLoginThreshold int           `mapstructure:"CAPTCHA_LOGIN_THRESHOLD"` // CaptchaLoginThreshold is the number of failed logins after which human verification is required.
+	CaptchaLoginWindow    time.Duration `mapstructure:"CAPTCHA_LOGIN_WINDOW"`    // CaptchaLoginWindow specifies how long failed logins are remembered.
+
+	EmailPolicyMode              string        `mapstructure:"EMAIL_POLICY_MODE"`               // EmailPolicyMode is either "blocklist" or "allowlist", which only lets allowed domains register.
+	EmailBlocklistFile           string        `mapstructure:"EMAIL_BLOCKLIST_FILE"`            // EmailBlocklistFile is the path of the file listing the disposable email domains.
+	EmailBlocklistReloadInterval time.Duration `mapstructure:"EMAIL_BLOCKLIST_RELOAD_INTERVAL"` // EmailBlocklistReloadInterval specifies how often the blocklist file is checked for changes.
+
+	LLMProvider    string        `mapstructure:"LLM_PROVIDER"`    // LLMProvider is either "openai" or "fake", which answers without any network call.
+	LLMBaseURL     string        `mapstructure:"LLM_BASE_URL"`    // LLMBaseURL is the root of the OpenAI-compatible API.
+	LLMAPIKey      string        `mapstructure:"LLM_API_KEY"`     // LLMAPIKey is the key used to authenticate against the API.
+	LLMModel       string        `mapstructure:"LLM_MODEL"`       // LLMModel is the model used when none is requested.
+	LLMTemperature float64       `mapstructure:"LLM_TEMPERATURE"` // LLMTemperature is the sampling temperature used when none is requested.
+	LLMMaxTokens   int           `mapstructure:"LLM_MAX_TOKENS"`  // LLMMaxTokens is the maximum length of a reply used when none is requested.
+	LLMTimeout     time.Duration `mapstructure:"LLM_TIMEOUT"`     // LLMTimeout specifies how long each call to the API can take.
+
+	LLMFallbackModels   string        `mapstructure:"LLM_FALLBACK_MODELS"`   // LLMFallbackModels is the comma separated list of the models tried in order when the requested one fails.
+	LLMMaxRetries       int           `mapstructure:"LLM_MAX_RETRIES"`       // LLMMaxRetries is the number of times a failed call is retried before falling back.
+	LLMRetryBaseDelay   time.Duration `mapstructure:"LLM_RETRY_BASE_DELAY"`  // LLMRetryBaseDelay specifies the wait before the first retry, doubled at each retry.
+	LLMRetryMaxDelay    time.Duration `mapstructure:"LLM_RETRY_MAX_DELAY"`   // LLMRetryMaxDelay specifies the longest wait between two retries.
+	LLMBreakerThreshold int           `mapstructure:"LLM_BREAKER_THRESHOLD"` // LLMBreakerThreshold is the number of consecutive failures after which a model is no longer called.
+	LLMBreakerCooldown  time.Duration `mapstructure:"LLM_BREAKER_COOLDOWN"`  // LLMBreakerCooldown specifies how long a failing model is no longer called.
+
+	WSTicketExpiresIn       time.Duration `mapstructure:"WS_TICKET_EXPIRED_IN"`        // WSTicketExpiresIn specifies the duration after which unused WebSocket tickets expire.
+	WSMaxConnectionsPerUser int           `mapstructure:"WS_MAX_CONNECTIONS_PER_USER"` // WSMaxConnectionsPerUser is the number of WebSocket connections a user can keep open.
+	WSMaxMessageSize        int64         `mapstructure:"WS_MAX_MESSAGE_SIZE"`         // WSMaxMessageSize is the size of the largest WebSocket event accepted, in bytes.
+	WSPingInterval          time.Duration `mapstructure:"WS_PING_INTERVAL"`            // WSPingInterval specifies the time between two WebSocket heartbeats.
+	WSSendBuffer            int           `mapstructure:"WS_SEND_BUFFER"`              // WSSendBuffer is the number of events waiting to be sent before a client is considered too slow.
+	WSMessagesPerMinute     int           `mapstructure:"WS_MESSAGES_PER_MINUTE"`      // WSMessagesPerMinute is the number of events a WebSocket connection can send per minute.
+
+	UsageFreeDailyTokens        int64   `mapstructure:"USAGE_FREE_DAILY_TOKENS"`        // UsageFreeDailyTokens is the number of tokens a user of the free plan can consume per day, 0 is unlimited.
+	UsageFreeMonthlyTokens      int64   `mapstructure:"USAGE_FREE_MONTHLY_TOKENS"`      // UsageFreeMonthlyTokens is the number of tokens a user of the free plan can consume per month, 0 is unlimited.
+	UsagePremiumDailyTokens     int64   `mapstructure:"USAGE_PREMIUM_DAILY_TOKENS"`     // UsagePremiumDailyTokens is the number of tokens a user of the premium plan can consume per day, 0 is unlimited.
+	UsagePremiumMonthlyTokens   int64   `mapstructure:"USAGE_PREMIUM_MONTHLY_TOKENS"`   // UsagePremiumMonthlyTokens is the number of tokens a user of the premium plan can consume per month, 0 is unlimited.
+	UsageClassroomDailyTokens   int64   `mapstructure:"USAGE_CLASSROOM_DAILY_TOKENS"`   // UsageClassroomDailyTokens is the number of tokens a user of the classroom plan can consume per day, 0 is unlimited.
+	UsageClassroomMonthlyTokens int64   `mapstructure:"USAGE_CLASSROOM_MONTHLY_TOKENS"` // UsageClassroomMonthlyTokens is the number of tokens a user of the classroom plan can consume per month, 0 is unlimited.
+	UsagePromptPrice            float64 `mapstructure:"USAGE_PROMPT_PRICE"`             // UsagePromptPrice is the price of a million prompt tokens, in US dollars.
+	UsageCompletionPrice        float64 `mapstructure:"USAGE_COMPLETION_PRICE"`         // UsageCompletionPrice is the price of a million completion tokens, in US dollars.
+
+	ContextTokenBudget      int `mapstructure:"CONTEXT_TOKEN_BUDGET"`       // ContextTokenBudget is the number of tokens of the history sent to the language model, 0 is unlimited.
+	ContextSummaryMaxTokens int `mapstructure:"CONTEXT_SUMMARY_MAX_TOKENS"` // ContextSummaryMaxTokens is the maximum length of the summary of a conversation, in tokens.
+
+	MemoryRecalledFacts int `mapstructure:"MEMORY_RECALLED_FACTS"` // MemoryRecalledFacts is the number of facts about the learner added to the prompts, 0 disables the memory.
+
+	CorrectionsEnabled bool `mapstructure:"CORRECTIONS_ENABLED"` // CorrectionsEnabled enables the correction of the messages of the learners.
+
+	ModerationEnabled       bool   `mapstructure:"MODERATION_ENABLED"`        // ModerationEnabled enables the screening of the messages of the learners and the replies of the pen pals.
+	ModerationClassifier    string `mapstructure:"MODERATION_CLASSIFIER"`     // ModerationClassifier is either "rules" or "openai", which also calls the moderation API of the language model.
+	ModerationRulesFile     string `mapstructure:"MODERATION_RULES_FILE"`     // ModerationRulesFile is the path of the file of moderation rules added to the built-in ones.
+	ModerationActions       string `mapstructure:"MODERATION_ACTIONS"`        // ModerationActions lists the action taken for each category, as category:action pairs.
+	ModerationStrictActions string `mapstructure:"MODERATION_STRICT_ACTIONS"` // ModerationStrictActions lists the action taken for each category on under-age accounts.
+
+	PIIRedactedCategories string `mapstructure:"PII_REDACTED_CATEGORIES"` // PIIRedactedCategories lists the categories of personal data replaced with placeholders before any call to the language model.
+	PIIRestoredCategories string `mapstructure:"PII_RESTORED_CATEGORIES"` // PIIRestoredCategories lists the categories of personal data restored in the completions, the others are masked.
+
+	GuardEnabled       bool    `mapstructure:"GUARD_ENABLED"`        // GuardEnabled enables the detection of the attempts to override the instructions of the pen pals.
+	GuardThreshold     float64 `mapstructure:"GUARD_THRESHOLD"`      // GuardThreshold is the lowest score, between 0 and 1, of a message considered an attempt.
+	GuardResponse      string  `mapstructure:"GUARD_RESPONSE"`       // GuardResponse is either "refuse", "sanitize" or "flag", applied to the attempts.
+	GuardClassifierURL string  `mapstructure:"GUARD_CLASSIFIER_URL"` // GuardClassifierURL is the optional endpoint of an external classifier scoring the messages.
+
+	LettersMinDelay           time.Duration `mapstructure:"LETTERS_MIN_DELAY"`           // LettersMinDelay is the shortest delay of a reply in letters mode, for the pen pals without a delay window.
+	LettersMaxDelay           time.Duration `mapstructure:"LETTERS_MAX_DELAY"`           // LettersMaxDelay is the longest delay of a reply in letters mode, for the pen pals without a delay window.
+	LettersPollInterval       time.Duration `mapstructure:"LETTERS_POLL_INTERVAL"`       // LettersPollInterval specifies how often the due letters are looked up, 0 disables their delivery.
+	LettersBatchSize          int           `mapstructure:"LETTERS_BATCH_SIZE"`          // LettersBatchSize is the largest number of letters delivered at each lookup.
+	LettersLease              time.Duration `mapstructure:"LETTERS_LEASE"`               // LettersLease bounds the delivery of a letter, after which it is tried again.
+	LettersMaxAttempts        int           `mapstructure:"LETTERS_MAX_ATTEMPTS"`        // LettersMaxAttempts is the number of times the delivery of a letter is tried before it fails.
+	LettersRetryDelay         time.Duration `mapstructure:"LETTERS_RETRY_DELAY"`         // LettersRetryDelay is multiplied by the number of attempts to postpone a failed delivery.
+	LettersEmailNotifications bool          `mapstructure:"LETTERS_EMAIL_NOTIFICATIONS"` // LettersEmailNotifications enables the emails telling the learners that their letter was answered.
 }
 
 // getAbsoluteRootPath computes and returns the absolute path to the root directory of the project by examining the caller's location in the filesystem.
@@ -1,31 +1,46 @@
 package auth
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/enzo-gbd/GBA/configs"
 	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/services/codes"
+	"github.com/enzo-gbd/GBA/internal/services/emailpolicy"
+	"github.com/enzo-gbd/GBA/internal/services/mailer"
 	"github.com/enzo-gbd/GBA/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-type AuthController struct{}
+type AuthController struct {
+	mailer      mailer.Mailer
+	emailPolicy *emailpolicy.Policy
+}
 
-func NewAuthController() AuthController {
-	return AuthController{}
+func NewAuthController(mailer mailer.Mailer, emailPolicy *emailpolicy.Policy) AuthController {
+	return AuthController{mailer: mailer, emailPolicy: emailPolicy}
 }
 
 // SignUpUser handles user registration.
 // @Summary Register a new user
 // @Description Registers a new user with the necessary details provided in the request body.
+// @Description An optional subscription code is redeemed atomically with the registration, and is mandatory in invite-only mode.
+// @Description Users under the minimum age are rejected, and minors must provide a parent email to which a signed consent link is sent.
+// @Description The email must be accepted by the email policy, and must not be a spelling of the address of an existing account.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param payload body models.SignUpInput true "User Registration Data"
 // @Success 201 {object} map[string]interface{} "Returns status success on successful registration"
 // @Failure 400 {object} map[string]interface{} "Returns error message for bad request"
+// @Failure 403 {object} map[string]interface{} "Returns error message when a subscription code is required or the user is too young"
 // @Failure 409 {object} map[string]interface{} "Returns error message for email already exists"
 // @Failure 500 {object} map[string]interface{} "Returns error message for internal server error"
 // @Router /register [post]
@@ -48,6 +63,41 @@ func (ac *AuthController) SignUpUser(context *gin.Context) {
 		return
 	}
 
+	config, err := configs.LoadConfig()
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, "Configuration error")
+		return
+	}
+	if config.RegistrationMode == configs.RegistrationModeInviteOnly && payload.Code == "" {
+		utils.AbortWithError(context, http.StatusForbidden, "A subscription code is required to register")
+		return
+	}
+
+	now := time.Now()
+	policy := agegate.NewPolicy(&config)
+	if policy.IsTooYoung(payload.Birthday, now) {
+		utils.AbortWithError(context, http.StatusForbidden, fmt.Sprintf("You must be at least %d years old to register", policy.MinimumAge))
+		return
+	}
+	minor := policy.IsMinor(payload.Birthday, now)
+	if minor && payload.ParentEmail == "" {
+		utils.AbortWithError(context, http.StatusBadRequest, "A parent email is required to register a minor")
+		return
+	}
+	if minor && strings.EqualFold(payload.ParentEmail, payload.Email) {
+		utils.AbortWithError(context, http.StatusBadRequest, "The parent email must differ from the user email")
+		return
+	}
+
+	if err := ac.emailPolicy.Check(database, payload.Email); err != nil {
+		if emailpolicy.IsPolicyError(err) {
+			utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
 	hashedPassword, err := utils.HashPassword(payload.Password)
 	if err != nil {
 		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
@@ -55,14 +105,21 @@ func (ac *AuthController) SignUpUser(context *gin.Context) {
 	}
 
 	newUser := models.User{
-		FirstName: payload.FirstName,
-		Name:      payload.Name,
-		Email:     strings.ToLower(payload.Email),
-		Password:  hashedPassword,
-		Birthday:  payload.Birthday,
-		Gender:    payload.Gender,
-		Role:      "user",
-		Verified:  false,
+		FirstName:      payload.FirstName,
+		Name:           payload.Name,
+		Email:          strings.ToLower(payload.Email),
+		Password:       hashedPassword,
+		Birthday:       payload.Birthday,
+		Gender:         payload.Gender,
+		NativeLanguage: payload.NativeLanguage,
+		Role:           "user",
+		Verified:       false,
+	}
+	if newUser.NativeLanguage == "" {
+		newUser.NativeLanguage = models.DefaultNativeLanguage
+	}
+	if payload.Code != "" {
+		newUser.SubscriptionCode = sql.NullString{String: codes.Normalize(payload.Code), Valid: true}
 	}
 	err = newUser.Validate()
 	if err != nil {
@@ -71,19 +128,55 @@ func (ac *AuthController) SignUpUser(context *gin.Context) {
 	}
 
 	var exists models.User
-	if err := database.Where("email = ?", newUser.Email).First(&exists).Error; err == nil {
+	if err := database.Where("email = ? OR normalized_email = ?", newUser.Email, utils.NormalizeEmail(newUser.Email)).First(&exists).Error; err == nil {
 		utils.AbortWithError(context, http.StatusConflict, "User with that email already exists")
 		return
 	}
 
-	result := database.Create(&newUser)
-
-	if result.Error != nil {
-		utils.AbortWithError(context, http.StatusBadGateway, "Something bad happened")
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		if payload.Code != "" {
+			if _, err := codes.Redeem(tx, payload.Code, newUser.ID, now); err != nil {
+				return err
+			}
+		}
+		if minor {
+			return ac.requestParentalConsent(tx, &config, newUser, strings.ToLower(payload.ParentEmail), now)
+		}
+		return nil
+	})
+	if err != nil {
+		if codes.IsRedemptionError(err) {
+			utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		} else {
+			utils.AbortWithError(context, http.StatusBadGateway, "Something bad happened")
+		}
 		return
 	}
 
-	utils.SendSuccess(context, http.StatusCreated, gin.H{"status": "success"})
+	utils.SendSuccess(context, http.StatusCreated, gin.H{"status": "success", "parental_consent_required": minor})
+}
+
+// requestParentalConsent records a pending consent request for a minor and emails the signed consent link to the parent.
+// It runs inside the registration transaction, so the account is not created if the email cannot be sent.
+func (ac *AuthController) requestParentalConsent(tx *gorm.DB, config *configs.Config, user models.User, parentEmail string, now time.Time) error {
+	consent := models.ParentalConsent{
+		UserID:      user.ID,
+		ParentEmail: parentEmail,
+		Status:      models.ConsentStatusPending,
+		ExpiresAt:   now.Add(agegate.LinkLifespan(config)),
+	}
+	if err := tx.Create(&consent).Error; err != nil {
+		return err
+	}
+
+	link := agegate.ConsentLink(config.AppBaseURL, config.ParentalConsentSecret, consent.ID, consent.ExpiresAt)
+	subject := fmt.Sprintf("Your consent is needed for %s's account", user.FirstName)
+	body := fmt.Sprintf("%s has registered and needs your consent to use the application.\n\nReview the request: %s\n\nThis link expires on %s.",
+		user.FirstName, link, consent.ExpiresAt.Format(time.RFC1123))
+	return ac.mailer.Send(parentEmail, subject, body)
 }
 
 // SignInUser handles user login.
@@ -96,6 +189,7 @@ func (ac *AuthController) SignUpUser(context *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Returns the access token and refresh token on successful login"
 // @Failure 400 {object} map[string]interface{} "Returns error message for bad request"
 // @Failure 401 {object} map[string]interface{} "Returns error message for invalid email or password"
+// @Failure 403 {object} map[string]interface{} "Returns error message when a minor's parental consent has not been granted"
 // @Failure 500 {object} map[string]interface{} "Returns error message for internal server error"
 // @Router /login [post]
 func (ac *AuthController) SignInUser(context *gin.Context) {
@@ -135,6 +229,18 @@ func (ac *AuthController) SignInUser(context *gin.Context) {
 		return
 	}
 
+	if agegate.NewPolicy(&config).IsMinor(user.Birthday, time.Now()) {
+		var consent models.ParentalConsent
+		err := database.Where("user_id = ? AND status = ?", user.ID, models.ConsentStatusGranted).First(&consent).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusForbidden, "Parental consent has not been granted yet")
+			return
+		} else if err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
 	accessToken, err := utils.GenerateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
 	if err != nil {
 		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
@@ -2,8 +2,11 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 	"time"
@@ -14,14 +17,29 @@ import (
 	"github.com/enzo-gbd/GBA/internal/middlewares"
 	"github.com/enzo-gbd/GBA/internal/models"
 	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/emailpolicy"
 	"github.com/enzo-gbd/GBA/internal/utils"
 	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 )
 
-var authController = NewAuthController()
+type recordingMailer struct {
+	to      []string
+	bodies  []string
+	failure error
+}
+
+func (m *recordingMailer) Send(to string, subject string, body string) error {
+	m.to = append(m.to, to)
+	m.bodies = append(m.bodies, body)
+	return m.failure
+}
+
+var mailService *recordingMailer
+var authController AuthController
 var router *gin.Engine
 var database *gorm.DB
 var sqlDB *sql.DB
@@ -30,6 +48,8 @@ var mock sqlmock.Sqlmock
 func setupRouter() {
 	router = gin.Default()
 	database, sqlDB, mock = db.InitMockDB()
+	mailService = &recordingMailer{}
+	authController = NewAuthController(mailService, emailpolicy.NewPolicy("", nil))
 
 	router.Use(middlewares.InjectDB(database))
 }
@@ -38,6 +58,12 @@ func TestMain(m *testing.M) {
 	m.Run()
 }
 
+// expectEmailPolicy expects the lookup of the email domain rules, finding none.
+func expectEmailPolicy() {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "email_domain_rules" WHERE domain IN`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
 func TestSignUpInput(t *testing.T) {
 	method, url := "POST", "/register"
 	queryCreate := `INSERT INTO "users"`
@@ -124,6 +150,7 @@ func TestSignUpInput(t *testing.T) {
 			defer sqlDB.Close()
 
 			if tt.expectedCode >= 200 && tt.expectedCode < 300 {
+				expectEmailPolicy()
 				mock.ExpectBegin()
 				mock.ExpectExec(regexp.QuoteMeta(queryCreate)).
 					WithArgs(
@@ -136,6 +163,8 @@ func TestSignUpInput(t *testing.T) {
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
+						models.DefaultNativeLanguage,
+						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
@@ -164,6 +193,303 @@ func TestSignUpInput(t *testing.T) {
 	}
 }
 
+func TestSignUpWithCode(t *testing.T) {
+	method, url := "POST", "/register"
+	queryCreate := `INSERT INTO "users"`
+	queryCode := `SELECT * FROM "subscription_codes" WHERE code = $1 ORDER BY "subscription_codes"."id" LIMIT $2`
+	queryRedeem := `UPDATE "subscription_codes" SET "redemption_count"=redemption_count + 1`
+	queryRedemption := `INSERT INTO "code_redemptions"`
+
+	code := models.SubscriptionCode{ID: uuid.New(), Code: "ABCD-EFGH-JKMN", Plan: models.PlanPremium, MaxRedemptions: 1}
+
+	tests := []struct {
+		name         string
+		mode         string
+		code         string
+		knownCode    bool
+		exhausted    bool
+		expectedCode int
+	}{
+		{
+			name:         "open mode without code",
+			mode:         "open",
+			code:         "",
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "invite only mode without code",
+			mode:         configs.RegistrationModeInviteOnly,
+			code:         "",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "invite only mode with valid code",
+			mode:         configs.RegistrationModeInviteOnly,
+			code:         "abcd-efgh-jkmn",
+			knownCode:    true,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "unknown code",
+			mode:         "open",
+			code:         "abcd-efgh-jkmn",
+			knownCode:    false,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "exhausted code",
+			mode:         "open",
+			code:         "abcd-efgh-jkmn",
+			knownCode:    true,
+			exhausted:    true,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REGISTRATION_MODE", tt.mode)
+			setupRouter()
+			router.POST(url, authController.SignUpUser)
+			defer sqlDB.Close()
+
+			if tt.expectedCode != http.StatusForbidden {
+				expectEmailPolicy()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryCreate)).WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.code != "" {
+					if tt.knownCode {
+						mock.ExpectQuery(regexp.QuoteMeta(queryCode)).
+							WithArgs(code.Code, 1).
+							WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.SubscriptionCode{code}))
+						rowsAffected := int64(1)
+						if tt.exhausted {
+							rowsAffected = 0
+						}
+						mock.ExpectExec(regexp.QuoteMeta(queryRedeem)).WillReturnResult(sqlmock.NewResult(0, rowsAffected))
+						if !tt.exhausted {
+							mock.ExpectExec(regexp.QuoteMeta(queryRedemption)).WillReturnResult(sqlmock.NewResult(0, 1))
+						}
+					} else {
+						mock.ExpectQuery(regexp.QuoteMeta(queryCode)).WillReturnError(gorm.ErrRecordNotFound)
+					}
+				}
+				if tt.expectedCode == http.StatusCreated {
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectRollback()
+				}
+			}
+
+			input := builders.NewUserBuilder().
+				WhereSubscriptionCode(sql.NullString{String: tt.code, Valid: tt.code != ""}).
+				BuildSignUpInput()
+			w, err := utils.HttpTestRequest(router, method, url, input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("code = %v, expected code %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSignUpMinor(t *testing.T) {
+	method, url := "POST", "/register"
+	queryCreate := `INSERT INTO "users"`
+	queryConsent := `INSERT INTO "parental_consents"`
+
+	now := time.Now()
+
+	tests := []struct {
+		name         string
+		birthday     time.Time
+		parentEmail  string
+		mailFailure  error
+		expectedCode int
+	}{
+		{
+			name:         "minor with parent email",
+			birthday:     now.AddDate(-12, 0, 0),
+			parentEmail:  "Parent.Doe@mail.pe",
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "minor without parent email",
+			birthday:     now.AddDate(-12, 0, 0),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "minor using their own email as parent email",
+			birthday:     now.AddDate(-12, 0, 0),
+			parentEmail:  "John.Doe@mail.pe",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "under the minimum age",
+			birthday:     now.AddDate(-6, 0, 0),
+			parentEmail:  "parent.doe@mail.pe",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "birthday in the future",
+			birthday:     now.AddDate(0, 1, 0),
+			parentEmail:  "parent.doe@mail.pe",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "implausibly old",
+			birthday:     now.AddDate(-130, 0, 0),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "consent email failure",
+			birthday:     now.AddDate(-12, 0, 0),
+			parentEmail:  "parent.doe@mail.pe",
+			mailFailure:  errors.New("smtp unavailable"),
+			expectedCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MINIMUM_AGE", "8")
+			t.Setenv("PARENTAL_CONSENT_AGE", "16")
+			setupRouter()
+			mailService.failure = tt.mailFailure
+			router.POST(url, authController.SignUpUser)
+			defer sqlDB.Close()
+
+			if tt.expectedCode == http.StatusCreated || tt.expectedCode == http.StatusBadGateway {
+				expectEmailPolicy()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryCreate)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryConsent)).WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.expectedCode == http.StatusCreated {
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectRollback()
+				}
+			}
+
+			input := builders.NewUserBuilder().WhereBirthday(tt.birthday).BuildSignUpInput()
+			input.ParentEmail = tt.parentEmail
+			w, err := utils.HttpTestRequest(router, method, url, input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("code = %v, expected code %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.expectedCode == http.StatusCreated {
+				assert.Equal(t, []string{"parent.doe@mail.pe"}, mailService.to)
+				assert.Contains(t, mailService.bodies[0], "/consents/")
+				assert.Contains(t, mailService.bodies[0], "signature=")
+			}
+		})
+	}
+}
+
+func TestSignUpEmailPolicy(t *testing.T) {
+	method, url := "POST", "/register"
+	queryRules := `SELECT * FROM "email_domain_rules" WHERE domain IN`
+	queryExists := `SELECT * FROM "users" WHERE email = $1 OR normalized_email = $2 ORDER BY "users"."id" LIMIT $3`
+	queryCreate := `INSERT INTO "users"`
+
+	blocklistFile := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklistFile, []byte("mailinator.com\n"), 0o600); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	blocklist, err := emailpolicy.LoadBlocklist(blocklistFile)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	existing := builders.NewUserBuilder().WhereEmail("johndoe@gmail.com").Build()
+
+	tests := []struct {
+		name         string
+		mode         string
+		email        string
+		rules        []models.EmailDomainRule
+		duplicate    bool
+		expectedCode int
+	}{
+		{
+			name:         "allowed domain",
+			email:        "john.doe@class.school.edu",
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "disposable domain",
+			email:        "john.doe@eu.mailinator.com",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "domain blocked by an administrator",
+			email:        "john.doe@class.school.edu",
+			rules:        []models.EmailDomainRule{{Domain: "school.edu", Kind: models.EmailDomainBlock}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "allowlist mode without rule",
+			mode:         emailpolicy.ModeAllowlist,
+			email:        "john.doe@class.school.edu",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "allowlist mode with allowed parent domain",
+			mode:         emailpolicy.ModeAllowlist,
+			email:        "john.doe@class.school.edu",
+			rules:        []models.EmailDomainRule{{Domain: "school.edu", Kind: models.EmailDomainAllow}},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "spelling of an existing address",
+			email:        "John.Doe+second@gmail.com",
+			duplicate:    true,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			authController = NewAuthController(mailService, emailpolicy.NewPolicy(tt.mode, blocklist))
+			router.POST(url, authController.SignUpUser)
+			defer sqlDB.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(queryRules)).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.rules))
+			if tt.duplicate {
+				mock.ExpectQuery(regexp.QuoteMeta(queryExists)).
+					WithArgs("john.doe+second@gmail.com", "johndoe@gmail.com", 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{existing}))
+			}
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectQuery(regexp.QuoteMeta(queryExists)).WillReturnError(gorm.ErrRecordNotFound)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryCreate)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			input := builders.NewUserBuilder().WhereEmail(tt.email).BuildSignUpInput()
+			w, err := utils.HttpTestRequest(router, method, url, input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("code = %v, expected code %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestSignInInput(t *testing.T) {
 	method, url := "POST", "/login"
 	queryFirst := `SELECT * FROM "users" WHERE email = $1 ORDER BY "users"."id" LIMIT $2`
@@ -275,6 +601,68 @@ func TestSignInInput(t *testing.T) {
 	}
 }
 
+func TestSignInMinor(t *testing.T) {
+	method, url := "POST", "/login"
+	queryFirst := `SELECT * FROM "users" WHERE email = $1 ORDER BY "users"."id" LIMIT $2`
+	queryConsent := `SELECT * FROM "parental_consents" WHERE user_id = $1 AND status = $2 ORDER BY "parental_consents"."id" LIMIT $3`
+
+	hashedPassword, err := utils.HashPassword("Password123.")
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	lucas := builders.NewUserBuilder().WherePassword(hashedPassword).WhereBirthday(time.Now().AddDate(-12, 0, 0)).Build()
+
+	tests := []struct {
+		name         string
+		granted      bool
+		expectedCode int
+	}{
+		{
+			name:         "consent granted",
+			granted:      true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "consent not granted",
+			granted:      false,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PARENTAL_CONSENT_AGE", "16")
+			setupRouter()
+			router.POST(url, authController.SignInUser)
+			defer sqlDB.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(queryFirst)).
+				WithArgs(lucas.Email, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{lucas}))
+			if tt.granted {
+				consent := models.ParentalConsent{ID: uuid.New(), UserID: lucas.ID, Status: models.ConsentStatusGranted}
+				mock.ExpectQuery(regexp.QuoteMeta(queryConsent)).
+					WithArgs(lucas.ID, models.ConsentStatusGranted, 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.ParentalConsent{consent}))
+			} else {
+				mock.ExpectQuery(regexp.QuoteMeta(queryConsent)).
+					WithArgs(lucas.ID, models.ConsentStatusGranted, 1).
+					WillReturnError(gorm.ErrRecordNotFound)
+			}
+
+			input := builders.NewUserBuilder().WhereBirthday(lucas.Birthday).BuildSignInInput()
+			w, err := utils.HttpTestRequest(router, method, url, &input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("code = %v, expected code %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestLogout(t *testing.T) {
 	method, url := "POST", "/logout"
 
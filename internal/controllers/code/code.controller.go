@@ -0,0 +1,147 @@
+package code
+
+import (
+	"net/http"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/codes"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CodeController struct{}
+
+func NewCodeController() CodeController {
+	return CodeController{}
+}
+
+// GenerateCodes generates a batch of subscription codes.
+// @Summary Generate subscription codes
+// @Description Generates a batch of single- or multi-use codes granting a plan, with an optional expiry.
+// @Tags codes
+// @Accept json
+// @Produce json
+// @Param payload body models.CodeBatchInput true "Batch Data"
+// @Success 201 {array} models.SubscriptionCodeResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /codes [post]
+func (cc *CodeController) GenerateCodes(context *gin.Context) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return
+	}
+	currentUser := obj.(*models.User)
+
+	var payload models.CodeBatchInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	generated, err := codes.GenerateBatch(database, payload, currentUser.ID)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.SubscriptionCodeResponse, 0, len(generated))
+	for _, code := range generated {
+		responses = append(responses, code.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusCreated, responses)
+}
+
+// GetCodes retrieves the subscription codes.
+// @Summary Get subscription codes
+// @Description Fetches a page of subscription codes, optionally filtered by batch and plan.
+// @Tags codes
+// @Produce json
+// @Param batch_id query string false "Batch ID"
+// @Param plan query string false "Plan"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.SubscriptionCodeResponse
+// @Failure 400 {object} object
+// @Failure 500 {object} object
+// @Router /codes [get]
+func (cc *CodeController) GetCodes(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("created_at DESC")
+	if batchID := context.Query("batch_id"); batchID != "" {
+		if _, err := uuid.Parse(batchID); err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+			return
+		}
+		query = query.Where("batch_id = ?", batchID)
+	}
+	if plan := context.Query("plan"); plan != "" {
+		query = query.Where("plan = ?", plan)
+	}
+
+	var subscriptionCodes []models.SubscriptionCode
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&subscriptionCodes).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.SubscriptionCodeResponse, 0, len(subscriptionCodes))
+	for _, code := range subscriptionCodes {
+		responses = append(responses, code.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetRedemptions retrieves the redemption history of the subscription codes.
+// @Summary Get code redemptions
+// @Description Fetches a page of the redemption history, most recent first, optionally filtered by code.
+// @Tags codes
+// @Produce json
+// @Param code query string false "Code"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.CodeRedemptionResponse
+// @Failure 500 {object} object
+// @Router /codes/redemptions [get]
+func (cc *CodeController) GetRedemptions(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Table("code_redemptions").
+		Select("code_redemptions.id, code_redemptions.code_id, subscription_codes.code, subscription_codes.plan, " +
+			"code_redemptions.user_id, users.email, code_redemptions.redeemed_at").
+		Joins("JOIN subscription_codes ON subscription_codes.id = code_redemptions.code_id").
+		Joins("LEFT JOIN users ON users.id = code_redemptions.user_id").
+		Order("code_redemptions.redeemed_at DESC")
+	if code := context.Query("code"); code != "" {
+		query = query.Where("subscription_codes.code = ?", codes.Normalize(code))
+	}
+
+	responses := []models.CodeRedemptionResponse{}
+	if err := query.Scopes(utils.GetPagination(context).Scope).Scan(&responses).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
@@ -0,0 +1,169 @@
+package code
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var codeController = NewCodeController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGenerateCodes(t *testing.T) {
+	method, url := "POST", "/codes"
+	admin := builders.NewUserBuilder().WhereRole("admin").Build()
+
+	tests := []struct {
+		name         string
+		input        models.CodeBatchInput
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.CodeBatchInput{Count: 2, Plan: models.PlanPremium, MaxRedemptions: 1},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "invalid plan",
+			input:        models.CodeBatchInput{Count: 2, Plan: "gold", MaxRedemptions: 1},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No body",
+			input:        models.CodeBatchInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST(url, func(context *gin.Context) {
+				context.Set("currentUser", &admin)
+			}, codeController.GenerateCodes)
+
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO "subscription_codes"`).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+
+			if tt.expectedCode == http.StatusCreated {
+				var response []models.SubscriptionCodeResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Len(t, response, 2)
+				assert.Equal(t, admin.ID, response[0].CreatedByID)
+			}
+		})
+	}
+}
+
+func TestGetCodes(t *testing.T) {
+	method, url := "GET", "/codes"
+	batchID := uuid.New()
+
+	tests := []struct {
+		name         string
+		query        string
+		sql          string
+		expectedCode int
+	}{
+		{
+			name:         "all codes",
+			query:        "",
+			sql:          `SELECT \* FROM "subscription_codes" ORDER BY created_at DESC LIMIT \$1$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "codes of a batch",
+			query:        "?batch_id=" + batchID.String() + "&plan=premium&page=2",
+			sql:          `SELECT \* FROM "subscription_codes" WHERE batch_id = \$1 AND plan = \$2 ORDER BY created_at DESC LIMIT \$3 OFFSET \$4$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid batch",
+			query:        "?batch_id=1234",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET(url, codeController.GetCodes)
+
+			if tt.sql != "" {
+				mock.ExpectQuery(tt.sql).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url+tt.query, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetRedemptions(t *testing.T) {
+	method, url := "GET", "/codes/redemptions"
+
+	setupRouter()
+	defer sqlDB.Close()
+	router.GET(url, codeController.GetRedemptions)
+
+	mock.ExpectQuery(`SELECT (.+) FROM "code_redemptions" JOIN subscription_codes (.+) WHERE subscription_codes.code = \$1`).
+		WithArgs("ABCD-EFGH-JKMN", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "email"}).AddRow(uuid.New(), "ABCD-EFGH-JKMN", "john.doe@mail.pe"))
+
+	w, err := utils.HttpTestRequest(router, method, url+"?code=abcd-efgh-jkmn", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []models.CodeRedemptionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, "john.doe@mail.pe", response[0].Email)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
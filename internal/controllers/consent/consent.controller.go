@@ -0,0 +1,174 @@
+package consent
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConsentController struct{}
+
+func NewConsentController() ConsentController {
+	return ConsentController{}
+}
+
+// GetConsent retrieves a parental consent request from its signed link.
+// @Summary Get a parental consent request
+// @Description Fetches the consent request a parent received by email. The link signature and expiry are checked.
+// @Tags consents
+// @Produce json
+// @Param id path string true "Consent ID"
+// @Param expires query string true "Link expiry"
+// @Param signature query string true "Link signature"
+// @Success 200 {object} models.ParentalConsentResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 410 {object} object
+// @Failure 500 {object} object
+// @Router /consents/{id} [get]
+func (cc *ConsentController) GetConsent(context *gin.Context) {
+	consent, child, ok := findConsent(context)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, consent.ToResponse(child.FirstName))
+}
+
+// GrantConsent records the parent's consent, which lets the minor sign in.
+// @Summary Grant a parental consent request
+// @Description Records the parent's consent from the signed link. A request can only be answered once.
+// @Tags consents
+// @Produce json
+// @Param id path string true "Consent ID"
+// @Param expires query string true "Link expiry"
+// @Param signature query string true "Link signature"
+// @Success 200 {object} models.ParentalConsentResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 410 {object} object
+// @Failure 500 {object} object
+// @Router /consents/{id}/grant [post]
+func (cc *ConsentController) GrantConsent(context *gin.Context) {
+	decide(context, models.ConsentStatusGranted)
+}
+
+// DenyConsent records the parent's refusal, which keeps the minor from signing in.
+// @Summary Deny a parental consent request
+// @Description Records the parent's refusal from the signed link. A request can only be answered once.
+// @Tags consents
+// @Produce json
+// @Param id path string true "Consent ID"
+// @Param expires query string true "Link expiry"
+// @Param signature query string true "Link signature"
+// @Success 200 {object} models.ParentalConsentResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 410 {object} object
+// @Failure 500 {object} object
+// @Router /consents/{id}/deny [post]
+func (cc *ConsentController) DenyConsent(context *gin.Context) {
+	decide(context, models.ConsentStatusDenied)
+}
+
+// decide records the parent's answer to the consent request targeted by the signed link.
+func decide(context *gin.Context, status string) {
+	consent, child, ok := findConsent(context)
+	if !ok {
+		return
+	}
+
+	if consent.Status != models.ConsentStatusPending {
+		utils.AbortWithError(context, http.StatusConflict, "This consent request has already been answered")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// The request is only answered if it is still pending, so that concurrent answers can't both succeed.
+	decidedAt, decisionIP := time.Now(), context.ClientIP()
+	result := database.Model(&consent).Where("status = ?", models.ConsentStatusPending).Updates(map[string]interface{}{
+		"status":      status,
+		"decided_at":  decidedAt,
+		"decision_ip": decisionIP,
+	})
+	if result.Error != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.AbortWithError(context, http.StatusConflict, "This consent request has already been answered")
+		return
+	}
+	consent.Status = status
+	consent.DecidedAt = sql.NullTime{Time: decidedAt, Valid: true}
+	consent.DecisionIP = sql.NullString{String: decisionIP, Valid: true}
+	utils.SendSuccess(context, http.StatusOK, consent.ToResponse(child.FirstName))
+}
+
+// findConsent verifies the signed link of the request and loads the consent request with the minor it is for.
+// It aborts the request and returns false when the link is invalid or the consent can't be found.
+func findConsent(context *gin.Context) (models.ParentalConsent, models.User, bool) {
+	var consent models.ParentalConsent
+	var child models.User
+
+	consentID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return consent, child, false
+	}
+
+	config, err := configs.LoadConfig()
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, "Configuration error")
+		return consent, child, false
+	}
+	err = agegate.Verify(config.ParentalConsentSecret, consentID, context.Query("expires"), context.Query("signature"), time.Now())
+	if errors.Is(err, agegate.ErrLinkExpired) {
+		utils.AbortWithError(context, http.StatusGone, err.Error())
+		return consent, child, false
+	} else if err != nil {
+		utils.AbortWithError(context, http.StatusForbidden, err.Error())
+		return consent, child, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return consent, child, false
+	}
+	if err := database.Where("id = ?", consentID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found consent")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return consent, child, false
+	}
+	if err := database.Where("id = ?", consent.UserID).First(&child).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found user")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return consent, child, false
+	}
+	return consent, child, true
+}
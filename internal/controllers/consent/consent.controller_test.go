@@ -0,0 +1,217 @@
+package consent
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var consentController = NewConsentController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+// signedURL returns the API path of the consent request with a valid signature for the given expiry.
+func signedURL(path string, consentID uuid.UUID, expiresAt time.Time) string {
+	config, _ := configs.LoadConfig()
+	return fmt.Sprintf("%s?expires=%d&signature=%s", path, expiresAt.Unix(), agegate.Sign(config.ParentalConsentSecret, consentID, expiresAt))
+}
+
+func TestGetConsent(t *testing.T) {
+	queryConsent := `SELECT * FROM "parental_consents" WHERE id = $1 ORDER BY "parental_consents"."id" LIMIT $2`
+	queryUser := `SELECT * FROM "users" WHERE id = $1 ORDER BY "users"."id" LIMIT $2`
+
+	lucas := builders.NewUserBuilder().WhereFirstName("Lucas").WhereBirthday(time.Now().AddDate(-12, 0, 0)).Build()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	consent := models.ParentalConsent{ID: uuid.New(), UserID: lucas.ID, ParentEmail: "parent.doe@mail.pe", Status: models.ConsentStatusPending, ExpiresAt: expiresAt}
+
+	tests := []struct {
+		name         string
+		url          string
+		known        bool
+		expectedCode int
+	}{
+		{
+			name:         "valid link",
+			url:          signedURL("/consents/"+consent.ID.String(), consent.ID, expiresAt),
+			known:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown consent",
+			url:          signedURL("/consents/"+consent.ID.String(), consent.ID, expiresAt),
+			known:        false,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "tampered signature",
+			url:          fmt.Sprintf("/consents/%s?expires=%d&signature=forged", consent.ID, expiresAt.Unix()),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "expired link",
+			url:          signedURL("/consents/"+consent.ID.String(), consent.ID, time.Now().Add(-time.Hour)),
+			expectedCode: http.StatusGone,
+		},
+		{
+			name:         "invalid UUID",
+			url:          "/consents/invalid",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			router.GET("/consents/:id", consentController.GetConsent)
+			defer sqlDB.Close()
+
+			if tt.expectedCode == http.StatusOK || tt.expectedCode == http.StatusNotFound {
+				if tt.known {
+					mock.ExpectQuery(regexp.QuoteMeta(queryConsent)).
+						WithArgs(consent.ID, 1).
+						WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.ParentalConsent{consent}))
+					mock.ExpectQuery(regexp.QuoteMeta(queryUser)).
+						WithArgs(lucas.ID, 1).
+						WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{lucas}))
+				} else {
+					mock.ExpectQuery(regexp.QuoteMeta(queryConsent)).WillReturnError(gorm.ErrRecordNotFound)
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", tt.url, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.ParentalConsentResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "Lucas", response.ChildFirstName)
+				assert.Equal(t, models.ConsentStatusPending, response.Status)
+			}
+		})
+	}
+}
+
+func TestDecideConsent(t *testing.T) {
+	queryConsent := `SELECT * FROM "parental_consents" WHERE id = $1 ORDER BY "parental_consents"."id" LIMIT $2`
+	queryUser := `SELECT * FROM "users" WHERE id = $1 ORDER BY "users"."id" LIMIT $2`
+	queryUpdate := `UPDATE "parental_consents" SET "decided_at"=$1,"decision_ip"=$2,"status"=$3,"updated_at"=$4 WHERE status = $5 AND "id" = $6`
+
+	lucas := builders.NewUserBuilder().WhereFirstName("Lucas").WhereBirthday(time.Now().AddDate(-12, 0, 0)).Build()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		action         string
+		status         string
+		expectedStatus string
+		answered       bool
+		expectedCode   int
+	}{
+		{
+			name:           "grant pending consent",
+			action:         "grant",
+			status:         models.ConsentStatusPending,
+			expectedStatus: models.ConsentStatusGranted,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "deny pending consent",
+			action:         "deny",
+			status:         models.ConsentStatusPending,
+			expectedStatus: models.ConsentStatusDenied,
+			expectedCode:   http.StatusOK,
+		},
+		{
+			name:           "grant consent denied meanwhile",
+			action:         "grant",
+			status:         models.ConsentStatusPending,
+			expectedStatus: models.ConsentStatusGranted,
+			answered:       true,
+			expectedCode:   http.StatusConflict,
+		},
+		{
+			name:         "grant denied consent",
+			action:       "grant",
+			status:       models.ConsentStatusDenied,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			router.POST("/consents/:id/grant", consentController.GrantConsent)
+			router.POST("/consents/:id/deny", consentController.DenyConsent)
+			defer sqlDB.Close()
+
+			consent := models.ParentalConsent{ID: uuid.New(), UserID: lucas.ID, ParentEmail: "parent.doe@mail.pe", Status: tt.status, ExpiresAt: expiresAt}
+			mock.ExpectQuery(regexp.QuoteMeta(queryConsent)).
+				WithArgs(consent.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.ParentalConsent{consent}))
+			mock.ExpectQuery(regexp.QuoteMeta(queryUser)).
+				WithArgs(lucas.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{lucas}))
+			if tt.status == models.ConsentStatusPending {
+				affected := int64(1)
+				if tt.answered {
+					affected = 0
+				}
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryUpdate)).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tt.expectedStatus, sqlmock.AnyArg(), models.ConsentStatusPending, consent.ID).
+					WillReturnResult(sqlmock.NewResult(0, affected))
+				mock.ExpectCommit()
+			}
+
+			url := signedURL(fmt.Sprintf("/consents/%s/%s", consent.ID, tt.action), consent.ID, expiresAt)
+			w, err := utils.HttpTestRequest(router, "POST", url, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.ParentalConsentResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedStatus, response.Status)
+				assert.NotNil(t, response.DecidedAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,1043 @@
+package conversation
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/enzo-gbd/GBA/internal/services/export"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/placement"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConversationController struct {
+	chat *chat.Service
+}
+
+func NewConversationController(chatService *chat.Service) ConversationController {
+	return ConversationController{chatService}
+}
+
+// CreateConversation starts a new conversation for the current user.
+// @Summary Create a conversation
+// @Description Starts a new conversation in the given language, optionally with a pen pal.
+// @Description In letters mode, the replies of the pen pal are delivered after a delay, like letters.
+// @Description A pen pal restricted to some CEFR levels can't be picked by learners of another level in the language.
+// @Tags conversations
+// @Accept json
+// @Produce json
+// @Param payload body models.ConversationInput true "Conversation Data"
+// @Success 201 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /conversations [post]
+func (cc *ConversationController) CreateConversation(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	var payload models.ConversationInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	conversation := models.Conversation{
+		UserID:   currentUser.ID,
+		Language: payload.Language,
+		Title:    payload.Title,
+		Mode:     payload.Mode,
+	}
+	if conversation.Mode == "" {
+		conversation.Mode = models.ConversationModeChat
+	}
+	if payload.PersonaID != nil {
+		var persona models.Persona
+		if err := database.Where("id = ? AND enabled = ?", *payload.PersonaID, true).First(&persona).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.AbortWithError(context, http.StatusNotFound, "Can't found persona")
+			} else {
+				utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		if len(persona.AllowedLevels) > 0 {
+			level, err := placement.Level(database, currentUser.ID, payload.Language)
+			if err != nil {
+				utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if level != "" && !slices.Contains(persona.AllowedLevels, level) {
+				utils.AbortWithError(context, http.StatusConflict, "The pen pal doesn't suit your level")
+				return
+			}
+		}
+		conversation.PersonaID = uuid.NullUUID{UUID: persona.ID, Valid: true}
+	}
+	if err := database.Create(&conversation).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, conversation.ToResponse())
+}
+
+// GetConversations retrieves the conversations of the current user.
+// @Summary Get my conversations
+// @Description Fetches a page of the conversations of the current user, most recently active first.
+// @Description Archived conversations are only returned with archived=true.
+// @Tags conversations
+// @Produce json
+// @Param archived query bool false "Archived"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.ConversationResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /conversations [get]
+func (cc *ConversationController) GetConversations(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Where("user_id = ?", currentUser.ID).Order("updated_at DESC")
+	if context.Query("archived") == "true" {
+		query = query.Where("archived_at IS NOT NULL")
+	} else {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	var conversations []models.Conversation
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&conversations).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.ConversationResponse, 0, len(conversations))
+	for _, conversation := range conversations {
+		responses = append(responses, conversation.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetConversation retrieves a conversation of the current user.
+// @Summary Get a conversation
+// @Description Fetches a conversation of the current user by UUID.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id} [get]
+func (cc *ConversationController) GetConversation(context *gin.Context) {
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, conversation.ToResponse())
+}
+
+// RenameConversation changes the title of a conversation of the current user.
+// @Summary Rename a conversation
+// @Description Changes the title of a conversation of the current user.
+// @Tags conversations
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param payload body models.ConversationRenameInput true "Conversation Data"
+// @Success 200 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id} [put]
+func (cc *ConversationController) RenameConversation(context *gin.Context) {
+	var payload models.ConversationRenameInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := database.Model(&conversation).Update("title", payload.Title).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, conversation.ToResponse())
+}
+
+// ArchiveConversation archives a conversation of the current user.
+// @Summary Archive a conversation
+// @Description Hides a conversation of the current user from the default listing. Its messages are kept.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/archive [post]
+func (cc *ConversationController) ArchiveConversation(context *gin.Context) {
+	setArchived(context, sql.NullTime{Time: time.Now(), Valid: true})
+}
+
+// UnarchiveConversation restores an archived conversation of the current user.
+// @Summary Unarchive a conversation
+// @Description Shows an archived conversation of the current user in the default listing again.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/archive [delete]
+func (cc *ConversationController) UnarchiveConversation(context *gin.Context) {
+	setArchived(context, sql.NullTime{})
+}
+
+// DeleteConversation deletes a conversation of the current user with its messages.
+// @Summary Delete a conversation
+// @Description Deletes a conversation of the current user, all its messages, the facts remembered from them, its
+// @Description public links, its letters and its moderation records. The vocabulary saved from the messages is kept.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id} [delete]
+func (cc *ConversationController) DeleteConversation(context *gin.Context) {
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.Memory{}).Error; err != nil {
+			return err
+		}
+		messages := tx.Model(&models.Message{}).Select("id").Where("conversation_id = ?", conversation.ID)
+		if err := tx.Model(&models.VocabularyItem{}).Where("message_id IN (?)", messages).UpdateColumn("message_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.ModerationRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", conversation.ID).Delete(&models.Letter{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&conversation).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// ExportConversation renders a conversation of the current user as a document.
+// @Summary Export a conversation
+// @Description Renders the active branch of a conversation of the current user as a Markdown document, a JSON
+// @Description document or a printable HTML page, with the corrections of the messages of the user and the words of
+// @Description their vocabulary highlighted. The format defaults to md.
+// @Tags conversations
+// @Produce text/markdown,json,html
+// @Param id path string true "Conversation ID"
+// @Param format query string false "Format, either md, json or html"
+// @Success 200 {file} file
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/export [get]
+func (cc *ConversationController) ExportConversation(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	format, ok := getExportFormat(context)
+	if !ok {
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	document, err := export.Load(database, *currentUser, conversation)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var body bytes.Buffer
+	if err := export.Render(&body, format, document); err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	context.Header("Content-Disposition", `attachment; filename="`+export.Filename(conversation, format)+`"`)
+	context.Data(http.StatusOK, export.ContentTypes[format], body.Bytes())
+}
+
+// ExportConversations renders all the conversations of the current user as a zip archive.
+// @Summary Export all my conversations
+// @Description Streams a zip archive holding a document for each conversation of the current user, archived ones
+// @Description included, in the given format. The format defaults to md.
+// @Tags conversations
+// @Produce application/zip
+// @Param format query string false "Format, either md, json or html"
+// @Success 200 {file} file
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/export [get]
+func (cc *ConversationController) ExportConversations(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	format, ok := getExportFormat(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	context.Header("Content-Type", "application/zip")
+	context.Header("Content-Disposition", `attachment; filename="conversations.zip"`)
+	context.Status(http.StatusOK)
+	if err := export.WriteArchive(context.Writer, database, *currentUser, format); err != nil {
+		log.Printf("could not export the conversations of the user %s: %v", currentUser.ID, err)
+	}
+}
+
+// GetMessages retrieves the messages of a conversation of the current user.
+// @Summary Get the messages of a conversation
+// @Description Fetches a page of the messages of the active branch of a conversation of the current user, in
+// @Description chronological order. The messages of every branch are returned with all=true.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param all query bool false "All the branches"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.MessageResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/messages [get]
+func (cc *ConversationController) GetMessages(context *gin.Context) {
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var messages []models.Message
+	query := database.Where("conversation_id = ?", conversation.ID).Order("created_at")
+	if conversation.ActiveLeafID.Valid && context.Query("all") != "true" {
+		branch, err := chat.Branch(database, conversation)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+		query = query.Where("id IN ?", branch)
+	}
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&messages).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.MessageResponse, 0, len(messages))
+	for _, message := range messages {
+		responses = append(responses, message.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// CreateMessage adds a message of the current user to one of their conversations and the reply of the pen pal.
+// @Summary Send a message
+// @Description Adds a message written by the current user to one of their conversations and generates the reply of the pen pal.
+// @Description Nothing is stored if the reply can't be generated.
+// @Description With stream=true, the reply is sent as server-sent events while it is generated: "delta" events hold
+// @Description pieces of the reply, followed by a "usage" event and a "done" event holding the stored messages.
+// @Description An "error" event is sent instead if the generation fails.
+// @Description A 429 describing the quota is returned once the user has consumed the tokens of their plan.
+// @Description In letters mode, the message is stored at once and a 202 holds the letter scheduling the reply, which
+// @Description is delivered later. A 409 is returned while the previous letter waits for its reply.
+// @Tags conversations
+// @Accept json
+// @Produce json,text/event-stream
+// @Param id path string true "Conversation ID"
+// @Param stream query bool false "Stream the reply"
+// @Param payload body models.MessageInput true "Message Data"
+// @Success 201 {object} models.ExchangeResponse
+// @Success 202 {object} models.LetterScheduledResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 422 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /conversations/{id}/messages [post]
+func (cc *ConversationController) CreateMessage(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	var payload models.MessageInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	exchange, err := cc.chat.Prepare(context.Request.Context(), database, *currentUser, conversation, payload.Content)
+	if err != nil {
+		abortPrepareError(context, err)
+		return
+	}
+	cc.reply(context, database, exchange)
+}
+
+// EditMessage sends a new version of a message of the current user and the reply of the pen pal.
+// @Summary Edit a message
+// @Description Sends a new version of a message written by the current user, answering the same message as the
+// @Description edited one. The edited message and its replies are kept on another branch of the conversation, and
+// @Description the new version becomes the active branch. The reply is generated and streamed, or scheduled in
+// @Description letters mode, as for a new message.
+// @Tags conversations
+// @Accept json
+// @Produce json,text/event-stream
+// @Param id path string true "Conversation ID"
+// @Param messageID path string true "Message ID"
+// @Param stream query bool false "Stream the reply"
+// @Param payload body models.MessageInput true "Message Data"
+// @Success 201 {object} models.ExchangeResponse
+// @Success 202 {object} models.LetterScheduledResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 422 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /conversations/{id}/messages/{messageID} [put]
+func (cc *ConversationController) EditMessage(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	messageID, err := uuid.Parse(context.Param("messageID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	var payload models.MessageInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	exchange, err := cc.chat.Edit(context.Request.Context(), database, *currentUser, conversation, messageID, payload.Content)
+	if err != nil {
+		abortPrepareError(context, err)
+		return
+	}
+	cc.reply(context, database, exchange)
+}
+
+// RegenerateMessage generates another reply of the pen pal to a message of the current user.
+// @Summary Regenerate a reply
+// @Description Generates another reply of the pen pal to the message answered by the given reply, which is kept as an
+// @Description alternative. The new reply becomes the active branch of the conversation. It is generated and
+// @Description streamed, or scheduled in letters mode, as for a new message.
+// @Tags conversations
+// @Produce json,text/event-stream
+// @Param id path string true "Conversation ID"
+// @Param messageID path string true "Reply ID"
+// @Param stream query bool false "Stream the reply"
+// @Success 201 {object} models.ExchangeResponse
+// @Success 202 {object} models.LetterScheduledResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /conversations/{id}/messages/{messageID}/regenerate [post]
+func (cc *ConversationController) RegenerateMessage(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	messageID, err := uuid.Parse(context.Param("messageID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	exchange, err := cc.chat.Regenerate(context.Request.Context(), database, *currentUser, conversation, messageID)
+	if err != nil {
+		abortPrepareError(context, err)
+		return
+	}
+	cc.reply(context, database, exchange)
+}
+
+// GetMessageAlternatives retrieves the versions of a message of a conversation of the current user.
+// @Summary Get the alternatives of a message
+// @Description Fetches the versions of a message, which are the edits of a message of the current user or the
+// @Description regenerated replies of the pen pal, oldest first, with the index of the one on the active branch.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param messageID path string true "Message ID"
+// @Success 200 {object} models.MessageAlternativesResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/messages/{messageID}/alternatives [get]
+func (cc *ConversationController) GetMessageAlternatives(context *gin.Context) {
+	messageID, err := uuid.Parse(context.Param("messageID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	alternatives, active, err := chat.Alternatives(database, conversation, messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found message")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	response := models.MessageAlternativesResponse{Alternatives: make([]models.MessageResponse, 0, len(alternatives)), Active: active}
+	for _, alternative := range alternatives {
+		response.Alternatives = append(response.Alternatives, alternative.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// ActivateMessage switches a conversation of the current user to the branch of one of its messages.
+// @Summary Switch to the branch of a message
+// @Description Makes the branch going through the message, down to its most recent replies, the active branch of
+// @Description the conversation: it is the one listed, and answered by the next message.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param messageID path string true "Message ID"
+// @Success 200 {object} models.ConversationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/messages/{messageID}/activate [post]
+func (cc *ConversationController) ActivateMessage(context *gin.Context) {
+	messageID, err := uuid.Parse(context.Param("messageID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	conversation, err = chat.SwitchBranch(database, conversation, messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found message")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, conversation.ToResponse())
+}
+
+// reply generates the reply to the prepared message, streamed as server-sent events with stream=true, and stores it.
+// In letters mode, the reply is scheduled instead.
+func (cc *ConversationController) reply(context *gin.Context, database *gorm.DB, exchange *chat.Exchange) {
+	if exchange.Conversation.Mode == models.ConversationModeLetters {
+		cc.post(context, database, exchange)
+		return
+	}
+	if context.Query("stream") == "true" {
+		cc.streamReply(context, database, exchange)
+		return
+	}
+
+	response, err := cc.chat.Reply(context.Request.Context(), database, exchange)
+	if err != nil {
+		if errors.Is(err, chat.ErrNoReply) {
+			utils.AbortWithError(context, http.StatusBadGateway, "The pen pal could not answer, please try again")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, response)
+}
+
+// post stores the prepared message and schedules the reply of the pen pal.
+func (cc *ConversationController) post(context *gin.Context, database *gorm.DB, exchange *chat.Exchange) {
+	response, err := cc.chat.Post(database, exchange)
+	if err != nil {
+		if errors.Is(err, chat.ErrLetterPending) {
+			utils.AbortWithError(context, http.StatusConflict, "The pen pal has not answered your previous letter yet")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	utils.SendSuccess(context, http.StatusAccepted, response)
+}
+
+// streamReply generates the reply to the message as server-sent events and stores both once the reply is complete.
+// The generation is cancelled when the client disconnects, in which case only the consumption of the calls is stored.
+func (cc *ConversationController) streamReply(context *gin.Context, database *gorm.DB, exchange *chat.Exchange) {
+	context.Header("Content-Type", "text/event-stream")
+	context.Header("Connection", "keep-alive")
+	context.Header("X-Accel-Buffering", "no")
+	context.Status(http.StatusOK)
+	context.Writer.Flush()
+
+	ctx := context.Request.Context()
+	response, err := cc.chat.StreamReply(ctx, database, exchange, func(delta string) error {
+		context.SSEvent("delta", gin.H{"content": delta})
+		context.Writer.Flush()
+		return ctx.Err()
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		message := "The pen pal could not answer, please try again"
+		if !errors.Is(err, chat.ErrNoReply) {
+			log.Printf("could not stream the reply in the conversation %s: %v", exchange.Conversation.ID, err)
+			message = "Internal server error"
+		}
+		context.SSEvent("error", gin.H{"message": message})
+		return
+	}
+
+	context.SSEvent("usage", llm.Usage{
+		PromptTokens:     response.Reply.PromptTokens,
+		CompletionTokens: response.Reply.CompletionTokens,
+		TotalTokens:      response.Reply.PromptTokens + response.Reply.CompletionTokens,
+	})
+	context.SSEvent("done", response)
+}
+
+// DeleteMessage deletes a message of a conversation of the current user.
+// @Summary Delete a message
+// @Description Deletes a message of a conversation of the current user, the facts remembered from it and its moderation
+// @Description records. The vocabulary saved from it is kept, the answers to the message are attached to the message it
+// @Description answered, and the letters waiting to answer it are cancelled.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param messageID path string true "Message ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/messages/{messageID} [delete]
+func (cc *ConversationController) DeleteMessage(context *gin.Context) {
+	messageID, err := uuid.Parse(context.Param("messageID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var message models.Message
+	if err := database.Where("id = ? AND conversation_id = ?", messageID, conversation.ID).First(&message).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found message")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.Memory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.VocabularyItem{}).Where("message_id = ?", message.ID).UpdateColumn("message_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.ModerationRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Letter{}).Where("message_id = ? AND status = ?", message.ID, models.LetterStatusPending).
+			Updates(map[string]interface{}{"status": models.LetterStatusCancelled, "cancelled_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Message{}).Where("parent_id = ?", message.ID).Update("parent_id", message.ParentID).Error; err != nil {
+			return err
+		}
+		if conversation.ActiveLeafID.Valid && conversation.ActiveLeafID.UUID == message.ID {
+			if err := tx.Model(&conversation).UpdateColumn("active_leaf_id", message.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&message).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// GetLetters retrieves the letters of a conversation of the current user.
+// @Summary Get the letters of a conversation
+// @Description Fetches a page of the letters of a conversation in letters mode, most recent first, with their status
+// @Description and the time from which their reply can be delivered.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.LetterResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/letters [get]
+func (cc *ConversationController) GetLetters(context *gin.Context) {
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var letters []models.Letter
+	if err := database.Where("conversation_id = ?", conversation.ID).Order("created_at DESC").
+		Scopes(utils.GetPagination(context).Scope).Find(&letters).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.LetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		responses = append(responses, letter.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// CancelLetter cancels a letter of a conversation of the current user before its reply is delivered.
+// @Summary Cancel a letter
+// @Description Cancels a letter waiting for its delivery time. The message of the current user is withdrawn with it,
+// @Description unless the letter regenerates a reply. A 409 is returned once the reply is being delivered.
+// @Tags conversations
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param letterID path string true "Letter ID"
+// @Success 200 {object} models.LetterResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/letters/{letterID} [delete]
+func (cc *ConversationController) CancelLetter(context *gin.Context) {
+	letterID, err := uuid.Parse(context.Param("letterID"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	letter, err := cc.chat.CancelLetter(database, conversation, letterID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found letter")
+		} else if errors.Is(err, chat.ErrNotCancellable) {
+			utils.AbortWithError(context, http.StatusConflict, "Only the letters waiting for their delivery can be cancelled")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, letter.ToResponse())
+}
+
+// setArchived updates the archive timestamp of the conversation targeted by the request.
+func setArchived(context *gin.Context, archivedAt sql.NullTime) {
+	conversation, ok := findConversation(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := database.Model(&conversation).Update("archived_at", archivedAt).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	conversation.ArchivedAt = archivedAt
+	utils.SendSuccess(context, http.StatusOK, conversation.ToResponse())
+}
+
+// abortPrepareError aborts the request with the status matching an error preparing a message.
+func abortPrepareError(context *gin.Context, err error) {
+	var quotaErr *metering.QuotaError
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.AbortWithError(context, http.StatusNotFound, "Can't found message")
+	} else if errors.Is(err, chat.ErrArchived) {
+		utils.AbortWithError(context, http.StatusConflict, "The conversation is archived")
+	} else if errors.Is(err, chat.ErrPersonaUnavailable) {
+		utils.AbortWithError(context, http.StatusConflict, "The pen pal is no longer available")
+	} else if errors.Is(err, chat.ErrNotEditable) {
+		utils.AbortWithError(context, http.StatusConflict, "Only your messages can be edited")
+	} else if errors.Is(err, chat.ErrNotRegenerable) {
+		utils.AbortWithError(context, http.StatusConflict, "Only the replies of the pen pal can be regenerated")
+	} else if errors.Is(err, chat.ErrBlocked) {
+		utils.AbortWithError(context, http.StatusUnprocessableEntity, "Your message was blocked by moderation")
+	} else if errors.Is(err, chat.ErrRefused) {
+		utils.AbortWithError(context, http.StatusUnprocessableEntity, "Your message tries to change the instructions of the pen pal")
+	} else if errors.As(err, &quotaErr) {
+		abortQuotaExceeded(context, quotaErr)
+	} else {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// abortQuotaExceeded aborts the request with a 429 describing the exhausted quota, telling the client when to retry.
+func abortQuotaExceeded(context *gin.Context, quotaErr *metering.QuotaError) {
+	context.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))))
+	context.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"status":  "fail",
+		"message": fmt.Sprintf("Your token quota for the %s is exhausted", quotaErr.Period),
+		"quota":   quotaErr,
+	})
+}
+
+// getExportFormat returns the export format of the `format` query parameter, md by default, aborting the request when
+// it is unknown.
+func getExportFormat(context *gin.Context) (string, bool) {
+	format := context.DefaultQuery("format", export.FormatMarkdown)
+	if !slices.Contains(export.Formats, format) {
+		utils.AbortWithError(context, http.StatusBadRequest, "The export format must be md, json or html")
+		return "", false
+	}
+	return format, true
+}
+
+// getCurrentUser returns the user set by the DeserializeUser middleware, aborting the request when there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// findConversation loads the conversation identified by the `id` path parameter, aborting the request
+// when it cannot be found. Conversations of other users are reported as not found.
+func findConversation(context *gin.Context) (models.Conversation, bool) {
+	var conversation models.Conversation
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return conversation, false
+	}
+
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return conversation, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return conversation, false
+	}
+	conversation, err = chat.FindConversation(database, currentUser.ID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found conversation")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return conversation, false
+	}
+	return conversation, true
+}
@@ -0,0 +1,175 @@
+package emailDomain
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/emailpolicy"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailDomainController struct {
+	emailPolicy *emailpolicy.Policy
+}
+
+func NewEmailDomainController(emailPolicy *emailpolicy.Policy) EmailDomainController {
+	return EmailDomainController{emailPolicy: emailPolicy}
+}
+
+// GetEmailDomainRules retrieves the email domain rules.
+// @Summary Get email domain rules
+// @Description Fetches a page of the rules allowing or blocking email domains at registration, optionally filtered by kind.
+// @Tags email-domains
+// @Produce json
+// @Param kind query string false "Kind"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.EmailDomainRuleResponse
+// @Failure 500 {object} object
+// @Router /email-domains [get]
+func (ec *EmailDomainController) GetEmailDomainRules(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("domain")
+	if kind := context.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var rules []models.EmailDomainRule
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&rules).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.EmailDomainRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		responses = append(responses, rule.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// CreateEmailDomainRule allows or blocks an email domain and its subdomains at registration.
+// @Summary Create an email domain rule
+// @Description Allows or blocks the registration of the addresses of a domain and its subdomains.
+// @Tags email-domains
+// @Accept json
+// @Produce json
+// @Param payload body models.EmailDomainRuleInput true "Rule Data"
+// @Success 201 {object} models.EmailDomainRuleResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /email-domains [post]
+func (ec *EmailDomainController) CreateEmailDomainRule(context *gin.Context) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return
+	}
+	currentUser := obj.(*models.User)
+
+	var payload models.EmailDomainRuleInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	payload.Domain = strings.ToLower(strings.TrimSpace(payload.Domain))
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var existing models.EmailDomainRule
+	if err := database.Where("domain = ?", payload.Domain).First(&existing).Error; err == nil {
+		utils.AbortWithError(context, http.StatusConflict, "A rule already exists for this domain")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rule := models.EmailDomainRule{
+		Domain:      payload.Domain,
+		Kind:        payload.Kind,
+		Note:        payload.Note,
+		CreatedByID: currentUser.ID,
+	}
+	if err := database.Create(&rule).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, rule.ToResponse())
+}
+
+// DeleteEmailDomainRule deletes an email domain rule.
+// @Summary Delete an email domain rule
+// @Description Deletes a rule by UUID. The domain falls back to the blocklist file and the policy mode.
+// @Tags email-domains
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /email-domains/{id} [delete]
+func (ec *EmailDomainController) DeleteEmailDomainRule(context *gin.Context) {
+	idStr := context.Param("id")
+	if _, err := uuid.Parse(idStr); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var rule models.EmailDomainRule
+	if err := database.Where("id = ?", idStr).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found rule")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if err := database.Delete(&rule).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// ReloadBlocklist reads the blocklist file again without waiting for the next change check.
+// @Summary Reload the email blocklist
+// @Description Reads the file listing the disposable domains again and returns the number of domains it contains.
+// @Tags email-domains
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Returns the number of blocked domains"
+// @Failure 500 {object} object
+// @Router /email-domains/blocklist/reload [post]
+func (ec *EmailDomainController) ReloadBlocklist(context *gin.Context) {
+	blocklist := ec.emailPolicy.Blocklist()
+	if err := blocklist.Reload(); err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{"blocked_domains": blocklist.Len()})
+}
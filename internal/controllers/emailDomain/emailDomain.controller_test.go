@@ -0,0 +1,220 @@
+package emailDomain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/emailpolicy"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var emailDomainController = NewEmailDomainController(emailpolicy.NewPolicy("", nil))
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetEmailDomainRules(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	router.GET("/email-domains", emailDomainController.GetEmailDomainRules)
+
+	rules := []models.EmailDomainRule{{ID: uuid.New(), Domain: "school.edu", Kind: models.EmailDomainAllow}}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "email_domain_rules" WHERE kind = $1 ORDER BY domain LIMIT $2`)).
+		WithArgs(models.EmailDomainAllow, 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(rules))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/email-domains?kind=allow", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var responses []models.EmailDomainRuleResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &responses))
+	assert.Len(t, responses, 1)
+	assert.Equal(t, "school.edu", responses[0].Domain)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateEmailDomainRule(t *testing.T) {
+	method, url := "POST", "/email-domains"
+	queryExisting := `SELECT * FROM "email_domain_rules" WHERE domain = $1 ORDER BY "email_domain_rules"."id" LIMIT $2`
+	admin := builders.NewUserBuilder().WhereRole("admin").Build()
+
+	tests := []struct {
+		name         string
+		input        models.EmailDomainRuleInput
+		exists       bool
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.EmailDomainRuleInput{Domain: " School.EDU ", Kind: models.EmailDomainAllow, Note: "Lincoln High"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "existing rule",
+			input:        models.EmailDomainRuleInput{Domain: "school.edu", Kind: models.EmailDomainBlock},
+			exists:       true,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "invalid kind",
+			input:        models.EmailDomainRuleInput{Domain: "school.edu", Kind: "maybe"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No body",
+			input:        models.EmailDomainRuleInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST(url, func(context *gin.Context) {
+				context.Set("currentUser", &admin)
+			}, emailDomainController.CreateEmailDomainRule)
+
+			if tt.expectedCode == http.StatusCreated || tt.exists {
+				query := mock.ExpectQuery(regexp.QuoteMeta(queryExisting)).WithArgs("school.edu", 1)
+				if tt.exists {
+					query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.EmailDomainRule{{ID: uuid.New(), Domain: "school.edu"}}))
+				} else {
+					query.WillReturnError(gorm.ErrRecordNotFound)
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "email_domain_rules"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			if tt.expectedCode == http.StatusCreated {
+				var response models.EmailDomainRuleResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "school.edu", response.Domain)
+				assert.Equal(t, admin.ID, response.CreatedByID)
+			}
+		})
+	}
+}
+
+func TestDeleteEmailDomainRule(t *testing.T) {
+	queryFirst := `SELECT * FROM "email_domain_rules" WHERE id = $1 ORDER BY "email_domain_rules"."id" LIMIT $2`
+	rule := models.EmailDomainRule{ID: uuid.New(), Domain: "school.edu", Kind: models.EmailDomainAllow}
+
+	tests := []struct {
+		name         string
+		id           string
+		found        bool
+		expectedCode int
+	}{
+		{
+			name:         "existing rule",
+			id:           rule.ID.String(),
+			found:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown rule",
+			id:           uuid.New().String(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Invalid id",
+			id:           "1234",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/email-domains/:id", emailDomainController.DeleteEmailDomainRule)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				if tt.found {
+					mock.ExpectQuery(regexp.QuoteMeta(queryFirst)).
+						WithArgs(tt.id, 1).
+						WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.EmailDomainRule{rule}))
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "email_domain_rules"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					mock.ExpectQuery(regexp.QuoteMeta(queryFirst)).WillReturnError(gorm.ErrRecordNotFound)
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/email-domains/"+tt.id, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReloadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("mailinator.com\n"), 0o600))
+	blocklist, err := emailpolicy.LoadBlocklist(path)
+	assert.NoError(t, err)
+	controller := NewEmailDomainController(emailpolicy.NewPolicy("", blocklist))
+
+	setupRouter()
+	defer sqlDB.Close()
+	router.POST("/email-domains/blocklist/reload", controller.ReloadBlocklist)
+
+	assert.NoError(t, os.WriteFile(path, []byte("mailinator.com\nyopmail.com\n"), 0o600))
+	w, err := utils.HttpTestRequest(router, "POST", "/email-domains/blocklist/reload", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"blocked_domains": 2}`, w.Body.String())
+
+	assert.NoError(t, os.Remove(path))
+	w, err = utils.HttpTestRequest(router, "POST", "/email-domains/blocklist/reload", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.True(t, blocklist.Contains("yopmail.com"), "the domains are kept when the file can't be read")
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Overall states of the server.
+const (
+	StatusOK          = "ok"          // StatusOK means that every model can be called.
+	StatusDegraded    = "degraded"    // StatusDegraded means that some models are not called, the others answer instead.
+	StatusUnavailable = "unavailable" // StatusUnavailable means that no model is called until their breakers probe them.
+)
+
+// HealthResponse reports the overall state of the server, without the details of the language models,
+// which are only exposed to the administrators by GetLLMStats.
+type HealthResponse struct {
+	Status string `json:"status"` // Status is either ok, degraded or unavailable
+}
+
+type HealthController struct {
+	provider *llm.ResilientProvider
+}
+
+func NewHealthController(provider *llm.ResilientProvider) HealthController {
+	return HealthController{provider: provider}
+}
+
+// GetHealth reports whether the server can answer the learners.
+// @Summary Get the health of the server
+// @Description Reports the overall state of the language models. The status is degraded when some models are not
+// @Description called and unavailable, with a 503 status, when none is.
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /health [get]
+func (hc *HealthController) GetHealth(context *gin.Context) {
+	stats := hc.provider.Stats()
+	response := HealthResponse{Status: StatusOK}
+	open := 0
+	for _, breaker := range stats.Breakers {
+		if breaker.State != llm.BreakerClosed {
+			response.Status = StatusDegraded
+		}
+		if breaker.State == llm.BreakerOpen {
+			open++
+		}
+	}
+	if open > 0 && open == len(stats.Breakers) {
+		response.Status = StatusUnavailable
+		utils.SendSuccess(context, http.StatusServiceUnavailable, response)
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// GetLLMStats retrieves the counts of the calls to the language models.
+// @Summary Get language model statistics
+// @Description Fetches the number of completions requested since the start of the server, the number of retries and
+// @Description fallbacks, and the state and counts of the circuit breaker of every model.
+// @Tags health
+// @Produce json
+// @Success 200 {object} llm.ResilienceStats
+// @Router /health/llm [get]
+func (hc *HealthController) GetLLMStats(context *gin.Context) {
+	utils.SendSuccess(context, http.StatusOK, hc.provider.Stats())
+}
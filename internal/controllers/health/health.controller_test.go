@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		failingModels  int
+		expectedCode   int
+		expectedStatus string
+	}{
+		{
+			name:           "every model answering",
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusOK,
+		},
+		{
+			name:           "fallback model answering",
+			failingModels:  1,
+			expectedCode:   http.StatusOK,
+			expectedStatus: StatusDegraded,
+		},
+		{
+			name:           "no model answering",
+			failingModels:  2,
+			expectedCode:   http.StatusServiceUnavailable,
+			expectedStatus: StatusUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := llm.NewFakeProvider()
+			provider := llm.NewResilientProvider(fake, llm.ResilienceOptions{
+				Name:             llm.ProviderFake,
+				DefaultModel:     llm.FakeModel,
+				FallbackModels:   []string{"fallback"},
+				BreakerThreshold: 1,
+				BreakerCooldown:  time.Hour,
+			})
+			fake.Err = &llm.APIError{StatusCode: http.StatusServiceUnavailable}
+			for _, model := range []string{"fallback", llm.FakeModel}[:tt.failingModels] {
+				_, _ = provider.Complete(context.Background(), llm.Request{Model: model})
+			}
+
+			router := gin.Default()
+			controller := NewHealthController(provider)
+			router.GET("/health", controller.GetHealth)
+
+			w, err := utils.HttpTestRequest(router, "GET", "/health", nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, map[string]interface{}{"status": tt.expectedStatus}, response, "the models are not exposed")
+		})
+	}
+}
+
+func TestGetLLMStats(t *testing.T) {
+	provider := llm.NewResilientProvider(llm.NewFakeProvider(), llm.ResilienceOptions{Name: llm.ProviderFake, DefaultModel: llm.FakeModel})
+	_, err := provider.Complete(context.Background(), llm.Request{})
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	controller := NewHealthController(provider)
+	router.GET("/health/llm", controller.GetLLMStats)
+
+	w, err := utils.HttpTestRequest(router, "GET", "/health/llm", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response llm.ResilienceStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Calls)
+	assert.Equal(t, []llm.BreakerStats{{Provider: llm.ProviderFake, Model: llm.FakeModel, State: llm.BreakerClosed, Calls: 1}}, response.Breakers)
+}
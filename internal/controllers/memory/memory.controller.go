@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MemoryController struct{}
+
+func NewMemoryController() MemoryController {
+	return MemoryController{}
+}
+
+// GetMyMemories retrieves the facts the pen pals remember about the current user.
+// @Summary Get my memories
+// @Description Fetches a page of the facts the pen pals remember about the current user, most recent first, with the
+// @Description message each fact was extracted from.
+// @Tags memories
+// @Produce json
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.MemoryResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/memories [get]
+func (mc *MemoryController) GetMyMemories(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var memories []models.Memory
+	query := database.Where("user_id = ?", currentUser.ID).Order("created_at DESC")
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&memories).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.MemoryResponse, 0, len(memories))
+	for _, memory := range memories {
+		responses = append(responses, memory.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// DeleteMemory makes the pen pals forget a fact about the current user.
+// @Summary Delete a memory
+// @Description Deletes a fact the pen pals remember about the current user.
+// @Tags memories
+// @Produce json
+// @Param id path string true "Memory ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/memories/{id} [delete]
+func (mc *MemoryController) DeleteMemory(context *gin.Context) {
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var memory models.Memory
+	if err := database.Where("id = ? AND user_id = ?", id, currentUser.ID).First(&memory).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found memory")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if err := database.Delete(&memory).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// DeleteMyMemories makes the pen pals forget everything about the current user.
+// @Summary Delete all my memories
+// @Description Deletes all the facts the pen pals remember about the current user.
+// @Tags memories
+// @Produce json
+// @Success 200 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/memories [delete]
+func (mc *MemoryController) DeleteMyMemories(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := database.Where("user_id = ?", currentUser.ID).Delete(&models.Memory{}).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// getCurrentUser returns the user set by the DeserializeUser middleware, aborting the request when there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
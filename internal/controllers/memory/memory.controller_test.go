@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const queryMemory = `SELECT * FROM "memories" WHERE id = $1 AND user_id = $2 ORDER BY "memories"."id" LIMIT $3`
+
+var memoryController = NewMemoryController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func setCurrentUser(user models.User) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetMyMemories(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	memories := []models.Memory{
+		{ID: uuid.New(), UserID: user.ID, ConversationID: uuid.New(), MessageID: uuid.New(), Fact: "Has a cat named Tom."},
+		{ID: uuid.New(), UserID: user.ID, ConversationID: uuid.New(), MessageID: uuid.New(), Fact: "Lives in Lyon."},
+	}
+	router.GET("/me/memories", setCurrentUser(user), memoryController.GetMyMemories)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "memories" WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`)).
+		WithArgs(user.ID, 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(memories))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/memories", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []models.MemoryResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 2)
+	assert.Equal(t, "Has a cat named Tom.", response[0].Fact)
+	assert.Equal(t, memories[0].MessageID, response[0].MessageID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMemory(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	memory := models.Memory{ID: uuid.New(), UserID: user.ID, Fact: "Lives in Lyon."}
+
+	tests := []struct {
+		name         string
+		id           string
+		found        bool
+		expectedCode int
+	}{
+		{name: "existing memory", id: memory.ID.String(), found: true, expectedCode: http.StatusOK},
+		{name: "unknown memory", id: uuid.New().String(), expectedCode: http.StatusNotFound},
+		{name: "invalid id", id: "invalid", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/me/memories/:id", setCurrentUser(user), memoryController.DeleteMemory)
+
+			if test.id != "invalid" {
+				query := mock.ExpectQuery(regexp.QuoteMeta(queryMemory)).WithArgs(test.id, user.ID, 1)
+				if test.found {
+					query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Memory{memory}))
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "memories" WHERE "memories"."id" = $1`)).
+						WithArgs(memory.ID).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					query.WillReturnError(gorm.ErrRecordNotFound)
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/me/memories/"+test.id, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, test.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteMyMemories(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	router.DELETE("/me/memories", setCurrentUser(user), memoryController.DeleteMyMemories)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "memories" WHERE user_id = $1`)).
+		WithArgs(user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	w, err := utils.HttpTestRequest(router, "DELETE", "/me/memories", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
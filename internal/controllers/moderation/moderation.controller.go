@@ -0,0 +1,174 @@
+package moderation
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ModerationController struct{}
+
+func NewModerationController() ModerationController {
+	return ModerationController{}
+}
+
+// GetModerationRecords retrieves the review queue of the moderation.
+// @Summary Get moderation records
+// @Description Fetches a page of the moderated messages, oldest first, filtered by status, pending by default, and optionally by action and category.
+// @Tags moderation
+// @Produce json
+// @Param status query string false "Status, either pending, confirmed or dismissed"
+// @Param action query string false "Action, either flag, redact or block"
+// @Param category query string false "Category"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.ModerationRecordResponse
+// @Failure 400 {object} object
+// @Failure 500 {object} object
+// @Router /moderation [get]
+func (mc *ModerationController) GetModerationRecords(context *gin.Context) {
+	status := context.DefaultQuery("status", models.ModerationStatusPending)
+	if !slices.Contains([]string{models.ModerationStatusPending, models.ModerationStatusConfirmed, models.ModerationStatusDismissed}, status) {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Where("status = ?", status).Order("created_at")
+	if action := context.Query("action"); action != "" {
+		if !slices.Contains(models.ModerationActions, action) {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid action")
+			return
+		}
+		query = query.Where("action = ?", action)
+	}
+	if category := context.Query("category"); category != "" {
+		if !slices.Contains(models.ModerationCategories, category) && !slices.Contains(models.GuardCategories, category) {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid category")
+			return
+		}
+		query = query.Where("categories LIKE ?", `%"`+category+`"%`)
+	}
+
+	var records []models.ModerationRecord
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&records).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.ModerationRecordResponse, 0, len(records))
+	for _, record := range records {
+		responses = append(responses, record.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetModerationRecord retrieves a moderated message.
+// @Summary Get a moderation record
+// @Description Fetches a moderated message with its original content and its review.
+// @Tags moderation
+// @Produce json
+// @Param id path string true "Record ID"
+// @Success 200 {object} models.ModerationRecordResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /moderation/{id} [get]
+func (mc *ModerationController) GetModerationRecord(context *gin.Context) {
+	record, ok := findRecord(context)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, record.ToResponse())
+}
+
+// ReviewModerationRecord records the decision of a moderator on a moderated message.
+// @Summary Review a moderation record
+// @Description Confirms or dismisses the action taken on a moderated message, taking it out of the review queue.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path string true "Record ID"
+// @Param payload body models.ModerationReviewInput true "Review Data"
+// @Success 200 {object} models.ModerationRecordResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /moderation/{id} [put]
+func (mc *ModerationController) ReviewModerationRecord(context *gin.Context) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return
+	}
+	currentUser := obj.(*models.User)
+
+	var payload models.ModerationReviewInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	record, ok := findRecord(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	record.Status = payload.Status
+	record.ReviewNote = payload.Note
+	record.ReviewedBy = uuid.NullUUID{UUID: currentUser.ID, Valid: true}
+	record.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := database.Model(&record).Select("status", "review_note", "reviewed_by", "reviewed_at").Updates(&record).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, record.ToResponse())
+}
+
+// findRecord loads the moderation record of the id path parameter, aborting the request if it doesn't exist.
+func findRecord(context *gin.Context) (models.ModerationRecord, bool) {
+	var record models.ModerationRecord
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return record, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return record, false
+	}
+	if err := database.Where("id = ?", id).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found moderation record")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return record, false
+	}
+	return record, true
+}
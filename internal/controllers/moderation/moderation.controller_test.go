@@ -0,0 +1,237 @@
+package moderation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var moderationController = NewModerationController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+const queryRecord = `SELECT * FROM "moderation_records" WHERE id = $1 ORDER BY "moderation_records"."id" LIMIT $2`
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+// recordRows returns the rows of the moderation record, with its categories serialized.
+func recordRows(record models.ModerationRecord) *sqlmock.Rows {
+	categories, _ := json.Marshal(record.Categories)
+	return sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "role", "content", "action", "categories", "strict", "status", "created_at"}).
+		AddRow(record.ID, record.UserID, record.ConversationID, record.Role, record.Content, record.Action, categories, record.Strict, record.Status, record.CreatedAt)
+}
+
+func TestGetModerationRecords(t *testing.T) {
+	method, url := "GET", "/moderation"
+
+	tests := []struct {
+		name         string
+		query        string
+		sql          string
+		expectedCode int
+	}{
+		{
+			name:         "pending records",
+			query:        "",
+			sql:          `SELECT \* FROM "moderation_records" WHERE status = \$1 ORDER BY created_at LIMIT \$2$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "filtered records",
+			query:        "?status=confirmed&action=block&category=sexual&page=2",
+			sql:          `SELECT \* FROM "moderation_records" WHERE status = \$1 AND action = \$2 AND categories LIKE \$3 ORDER BY created_at LIMIT \$4 OFFSET \$5$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid status",
+			query:        "?status=open",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid action",
+			query:        "?action=delete",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid category",
+			query:        "?category=gambling",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET(url, moderationController.GetModerationRecords)
+
+			if tt.sql != "" {
+				mock.ExpectQuery(tt.sql).WillReturnRows(recordRows(models.ModerationRecord{
+					ID:         uuid.New(),
+					Action:     models.ModerationActionBlock,
+					Categories: []string{models.ModerationCategorySexual},
+					Status:     models.ModerationStatusPending,
+				}))
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url+tt.query, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response []models.ModerationRecordResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Len(t, response, 1)
+				assert.Equal(t, []string{models.ModerationCategorySexual}, response[0].Categories)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetModerationRecord(t *testing.T) {
+	record := models.ModerationRecord{ID: uuid.New(), Content: "¿Dónde vives?", Action: models.ModerationActionBlock, Status: models.ModerationStatusPending}
+
+	tests := []struct {
+		name         string
+		id           string
+		found        bool
+		expectedCode int
+	}{
+		{
+			name:         "existing record",
+			id:           record.ID.String(),
+			found:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing record",
+			id:           record.ID.String(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid ID",
+			id:           "1234",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET("/moderation/:id", moderationController.GetModerationRecord)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				rows := sqlmock.NewRows([]string{"id"})
+				if tt.found {
+					rows = recordRows(record)
+				}
+				mock.ExpectQuery(regexp.QuoteMeta(queryRecord)).WithArgs(record.ID, 1).WillReturnRows(rows)
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", "/moderation/"+tt.id, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.ModerationRecordResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, record.Content, response.Content)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReviewModerationRecord(t *testing.T) {
+	admin := builders.NewUserBuilder().WhereRole("admin").Build()
+	record := models.ModerationRecord{ID: uuid.New(), Content: "¡Qué shit!", Action: models.ModerationActionRedact,
+		Status: models.ModerationStatusPending, CreatedAt: time.Now()}
+
+	tests := []struct {
+		name         string
+		input        models.ModerationReviewInput
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.ModerationReviewInput{Status: models.ModerationStatusDismissed, Note: "Quoting a song"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid status",
+			input:        models.ModerationReviewInput{Status: models.ModerationStatusPending},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No body",
+			input:        models.ModerationReviewInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT("/moderation/:id", func(context *gin.Context) {
+				context.Set("currentUser", &admin)
+			}, moderationController.ReviewModerationRecord)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(regexp.QuoteMeta(queryRecord)).WithArgs(record.ID, 1).WillReturnRows(recordRows(record))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "moderation_records" SET "status"=$1,"reviewed_by"=$2,"review_note"=$3,"reviewed_at"=$4 WHERE "id" = $5`)).
+					WithArgs(tt.input.Status, admin.ID, tt.input.Note, sqlmock.AnyArg(), record.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "PUT", "/moderation/"+record.ID.String(), tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.ModerationRecordResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, models.ModerationStatusDismissed, response.Status)
+				assert.Equal(t, &admin.ID, response.ReviewedBy)
+				assert.Equal(t, "Quoting a song", response.ReviewNote)
+				assert.NotNil(t, response.ReviewedAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,815 @@
+package organization
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/mailer"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// invitationTokenSize is the number of random bytes of an invitation token.
+const invitationTokenSize = 32
+
+// defaultInvitationExpiresIn is used when INVITATION_EXPIRED_IN is not configured.
+const defaultInvitationExpiresIn = 7 * 24 * time.Hour
+
+// errInvitationNotSent rolls back an invitation whose email could not be sent.
+var errInvitationNotSent = errors.New("Could not send the invitation email")
+
+type OrganizationController struct {
+	mailer mailer.Mailer
+}
+
+func NewOrganizationController(mailer mailer.Mailer) OrganizationController {
+	return OrganizationController{mailer: mailer}
+}
+
+// CreateOrganization creates a new organization owned by the current user.
+// @Summary Create an organization
+// @Description Creates a new organization and makes the current user its owner.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param payload body models.OrganizationInput true "Organization Data"
+// @Success 201 {object} models.OrganizationResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /organizations [post]
+func (oc *OrganizationController) CreateOrganization(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	var payload models.OrganizationInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	organization := models.Organization{Name: payload.Name}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&organization).Error; err != nil {
+			return err
+		}
+		owner := models.Membership{
+			OrganizationID: organization.ID,
+			UserID:         currentUser.ID,
+			Role:           models.OrganizationRoleOwner,
+		}
+		return tx.Create(&owner).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := organization.ToResponse()
+	response.Role = models.OrganizationRoleOwner
+	utils.SendSuccess(context, http.StatusCreated, response)
+}
+
+// GetMyOrganizations retrieves the organizations of the current user.
+// @Summary Get my organizations
+// @Description Fetches every organization the current user is a member of, with the user's role.
+// @Tags organizations
+// @Produce json
+// @Success 200 {array} models.OrganizationResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /organizations [get]
+func (oc *OrganizationController) GetMyOrganizations(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var memberships []models.Membership
+	if err := database.Where("user_id = ?", currentUser.ID).Find(&memberships).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	responses := []models.OrganizationResponse{}
+	if len(memberships) == 0 {
+		utils.SendSuccess(context, http.StatusOK, responses)
+		return
+	}
+
+	roles := make(map[uuid.UUID]string, len(memberships))
+	ids := make([]uuid.UUID, 0, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.OrganizationID] = membership.Role
+		ids = append(ids, membership.OrganizationID)
+	}
+
+	var organizations []models.Organization
+	if err := database.Where("id IN ?", ids).Order("name").Find(&organizations).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, organization := range organizations {
+		response := organization.ToResponse()
+		response.Role = roles[organization.ID]
+		responses = append(responses, response)
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetOrganization fetches the organization the request is scoped to.
+// @Summary Get an organization
+// @Description Fetches an organization the current user is a member of.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} models.OrganizationResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID} [get]
+func (oc *OrganizationController) GetOrganization(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	organization, ok := findOrganization(context, membership.OrganizationID)
+	if !ok {
+		return
+	}
+
+	response := organization.ToResponse()
+	response.Role = membership.Role
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// UpdateOrganization renames the organization the request is scoped to.
+// @Summary Rename an organization
+// @Description Renames an organization. Only owners are allowed.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Param payload body models.OrganizationInput true "Organization Data"
+// @Success 200 {object} models.OrganizationResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID} [put]
+func (oc *OrganizationController) UpdateOrganization(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+
+	var payload models.OrganizationInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	organization, ok := findOrganization(context, membership.OrganizationID)
+	if !ok {
+		return
+	}
+	database, _ := utils.GetDatabaseInContext(context)
+	organization.Name = payload.Name
+	if err := database.Save(&organization).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := organization.ToResponse()
+	response.Role = membership.Role
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// DeleteOrganization deletes the organization the request is scoped to, with its memberships and invitations.
+// @Summary Delete an organization
+// @Description Deletes an organization with its memberships and invitations. Only owners are allowed.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID} [delete]
+func (oc *OrganizationController) DeleteOrganization(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", membership.OrganizationID).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", membership.OrganizationID).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Organization{ID: membership.OrganizationID}).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// GetMembers retrieves the members of the organization the request is scoped to.
+// @Summary Get organization members
+// @Description Fetches the members an owner or a teacher can manage: every member for owners, their own students for
+// @Description teachers.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Success 200 {array} models.MemberResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/members [get]
+func (oc *OrganizationController) GetMembers(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("created_at")
+	if membership.Role != models.OrganizationRoleOwner {
+		query = query.Where("role = ? AND teacher_id = ?", models.OrganizationRoleStudent, membership.UserID)
+	}
+	var memberships []models.Membership
+	if err := query.Find(&memberships).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := []models.MemberResponse{}
+	if len(memberships) == 0 {
+		utils.SendSuccess(context, http.StatusOK, responses)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(memberships))
+	for _, member := range memberships {
+		ids = append(ids, member.UserID)
+	}
+	var users []models.User
+	if err := database.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	usersByID := make(map[uuid.UUID]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	for _, member := range memberships {
+		user := usersByID[member.UserID]
+		responses = append(responses, models.MemberResponse{
+			ID:        member.ID,
+			UserID:    member.UserID,
+			FirstName: user.FirstName,
+			Name:      user.Name,
+			Email:     user.Email,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// UpdateMemberRole changes the role of a member of the organization the request is scoped to.
+// @Summary Change the role of a member
+// @Description Changes the role of a member. Only owners are allowed and the last owner cannot be demoted. A student
+// @Description given another role leaves their teacher, and the students of a teacher given another role, as well as
+// @Description the students they invited, are left to the owners.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Param memberID path string true "Membership ID"
+// @Param payload body models.MembershipRoleInput true "Role Data"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/members/{memberID} [put]
+func (oc *OrganizationController) UpdateMemberRole(context *gin.Context) {
+	var payload models.MembershipRoleInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	member, ok := findMember(context)
+	if !ok {
+		return
+	}
+	if member.Role == models.OrganizationRoleOwner && payload.Role != models.OrganizationRoleOwner && !hasAnotherOwner(context, member) {
+		return
+	}
+
+	database, _ := utils.GetDatabaseInContext(context)
+	demoted := member.Role == models.OrganizationRoleTeacher && payload.Role != models.OrganizationRoleTeacher
+	member.Role = payload.Role
+	if member.Role != models.OrganizationRoleStudent {
+		member.TeacherID = uuid.NullUUID{}
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&member).Error; err != nil {
+			return err
+		}
+		if demoted {
+			return releaseStudents(tx, member)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{"id": member.ID, "role": member.Role})
+}
+
+// RemoveMember removes a member from the organization the request is scoped to.
+// @Summary Remove a member
+// @Description Removes a member. Owners can remove anyone but the last owner, teachers can only remove their own
+// @Description students. The students of a removed teacher, as well as the students they invited, are left to the
+// @Description owners.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Param memberID path string true "Membership ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/members/{memberID} [delete]
+func (oc *OrganizationController) RemoveMember(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	member, ok := findMember(context)
+	if !ok {
+		return
+	}
+	if !membership.CanManage(member.Role, member.TeacherID) {
+		utils.AbortWithError(context, http.StatusForbidden, "You are not allowed")
+		return
+	}
+	if member.Role == models.OrganizationRoleOwner && !hasAnotherOwner(context, member) {
+		return
+	}
+
+	database, _ := utils.GetDatabaseInContext(context)
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == models.OrganizationRoleTeacher {
+			return releaseStudents(tx, member)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// InviteMember invites someone into the organization the request is scoped to.
+// @Summary Invite a member
+// @Description Sends an invitation email. Owners can invite any role, teachers can only invite students, who become
+// @Description their own students. No invitation is kept if the email can't be sent, and none is sent to a member.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Param payload body models.InvitationInput true "Invitation Data"
+// @Success 201 {object} models.InvitationResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/invitations [post]
+func (oc *OrganizationController) InviteMember(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+
+	var payload models.InvitationInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	teacherID := membership.StudentsTeacher()
+	if !membership.CanManage(payload.Role, teacherID) {
+		utils.AbortWithError(context, http.StatusForbidden, "You are not allowed")
+		return
+	}
+
+	organization, ok := findOrganization(context, membership.OrganizationID)
+	if !ok {
+		return
+	}
+
+	email := strings.ToLower(payload.Email)
+	database, _ := utils.GetDatabaseInContext(context)
+	var members int64
+	if err := database.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND users.email = ?", membership.OrganizationID, email).
+		Count(&members).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if members > 0 {
+		utils.AbortWithError(context, http.StatusConflict, "This email already belongs to a member of this organization")
+		return
+	}
+
+	config, err := configs.LoadConfig()
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, "Configuration error")
+		return
+	}
+	expiresIn := config.InvitationExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultInvitationExpiresIn
+	}
+
+	token, err := utils.GenerateRandomToken(invitationTokenSize)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	invitation := models.Invitation{
+		Email:       email,
+		Role:        payload.Role,
+		TokenHash:   utils.HashToken(token),
+		InvitedByID: membership.UserID,
+		TeacherID:   teacherID,
+		ExpiresAt:   time.Now().Add(expiresIn),
+	}
+	// The email is sent inside the transaction, so that an invitation nobody received is not kept.
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&invitation).Error; err != nil {
+			return err
+		}
+		subject := fmt.Sprintf("You are invited to join %s", organization.Name)
+		body := fmt.Sprintf("You have been invited to join %s as a %s.\n\nAccept the invitation: %s/invitations/%s\n\nThis invitation expires on %s.",
+			organization.Name, invitation.Role, config.AppBaseURL, token, invitation.ExpiresAt.Format(time.RFC1123))
+		if err := oc.mailer.Send(invitation.Email, subject, body); err != nil {
+			return errInvitationNotSent
+		}
+		return nil
+	})
+	if errors.Is(err, errInvitationNotSent) {
+		utils.AbortWithError(context, http.StatusBadGateway, err.Error())
+		return
+	} else if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(context, http.StatusCreated, invitation.ToResponse())
+}
+
+// GetInvitations retrieves the invitations of the organization the request is scoped to.
+// @Summary Get organization invitations
+// @Description Fetches the invitations of an organization: every invitation for owners, the invitations of their own
+// @Description students for teachers.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Success 200 {array} models.InvitationResponse
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/invitations [get]
+func (oc *OrganizationController) GetInvitations(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("created_at DESC")
+	if membership.Role != models.OrganizationRoleOwner {
+		query = query.Where("role = ? AND teacher_id = ?", models.OrganizationRoleStudent, membership.UserID)
+	}
+	var invitations []models.Invitation
+	if err := query.Find(&invitations).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.InvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		responses = append(responses, invitation.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// RevokeInvitation deletes an invitation of the organization the request is scoped to.
+// @Summary Revoke an invitation
+// @Description Deletes an invitation so it can no longer be accepted.
+// @Tags organizations
+// @Produce json
+// @Param orgID path string true "Organization ID"
+// @Param invitationID path string true "Invitation ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /organizations/{orgID}/invitations/{invitationID} [delete]
+func (oc *OrganizationController) RevokeInvitation(context *gin.Context) {
+	membership, ok := getCurrentMembership(context)
+	if !ok {
+		return
+	}
+	idStr := context.Param("invitationID")
+	if _, err := uuid.Parse(idStr); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var invitation models.Invitation
+	if err := database.Where("id = ?", idStr).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found invitation")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if !membership.CanManage(invitation.Role, invitation.TeacherID) {
+		utils.AbortWithError(context, http.StatusForbidden, "You are not allowed")
+		return
+	}
+
+	if err := database.Delete(&invitation).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// AcceptInvitation makes the current user join the organization of an invitation.
+// @Summary Accept an invitation
+// @Description Accepts an invitation sent to the email address of the current user.
+// @Tags organizations
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} models.OrganizationResponse
+// @Failure 401 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 410 {object} object
+// @Failure 500 {object} object
+// @Router /invitations/{token}/accept [post]
+func (oc *OrganizationController) AcceptInvitation(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var invitation models.Invitation
+	if err := database.Where("token_hash = ?", utils.HashToken(context.Param("token"))).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found invitation")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	if !invitation.IsPending(time.Now()) {
+		utils.AbortWithError(context, http.StatusGone, "This invitation is no longer valid")
+		return
+	}
+	if !strings.EqualFold(invitation.Email, currentUser.Email) {
+		utils.AbortWithError(context, http.StatusForbidden, "This invitation was sent to another email address")
+		return
+	}
+
+	var existing models.Membership
+	err = database.Where("organization_id = ? AND user_id = ?", invitation.OrganizationID, currentUser.ID).First(&existing).Error
+	if err == nil {
+		utils.AbortWithError(context, http.StatusConflict, "You are already a member of this organization")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	membership := models.Membership{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         currentUser.ID,
+		Role:           invitation.Role,
+		TeacherID:      invitation.TeacherID,
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		return tx.Model(&invitation).Update("accepted_at", time.Now()).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	organization, ok := findOrganization(context, invitation.OrganizationID)
+	if !ok {
+		return
+	}
+	response := organization.ToResponse()
+	response.Role = membership.Role
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// getCurrentUser returns the logged-in user, aborting the request when there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// getCurrentMembership returns the membership set by the OrganizationScope middleware,
+// aborting the request when there is none.
+func getCurrentMembership(context *gin.Context) (*models.Membership, bool) {
+	obj, exists := context.Get("currentMembership")
+	if !exists {
+		utils.AbortWithError(context, http.StatusForbidden, "You are not a member of this organization")
+		return nil, false
+	}
+	membership, ok := obj.(*models.Membership)
+	if !ok {
+		utils.AbortWithError(context, http.StatusForbidden, "invalid membership type")
+		return nil, false
+	}
+	return membership, true
+}
+
+// findOrganization loads an organization, aborting the request when it cannot be found.
+func findOrganization(context *gin.Context, id uuid.UUID) (models.Organization, bool) {
+	var organization models.Organization
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return organization, false
+	}
+	if err := database.Where("id = ?", id).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found organization")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return organization, false
+	}
+	return organization, true
+}
+
+// findMember loads the membership identified by the `memberID` path parameter within the
+// scoped organization, aborting the request when it cannot be found.
+func findMember(context *gin.Context) (models.Membership, bool) {
+	var member models.Membership
+	idStr := context.Param("memberID")
+	if _, err := uuid.Parse(idStr); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return member, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return member, false
+	}
+	if err := database.Where("id = ?", idStr).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found member")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return member, false
+	}
+	return member, true
+}
+
+// releaseStudents leaves the students of the teacher, and the pending invitations they sent to students, to the
+// owners of the organization only, once the member stops being a teacher.
+func releaseStudents(tx *gorm.DB, teacher models.Membership) error {
+	if err := tx.Model(&models.Membership{}).
+		Where("organization_id = ? AND teacher_id = ?", teacher.OrganizationID, teacher.UserID).
+		UpdateColumn("teacher_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Invitation{}).
+		Where("organization_id = ? AND teacher_id = ? AND accepted_at IS NULL", teacher.OrganizationID, teacher.UserID).
+		UpdateColumn("teacher_id", nil).Error
+}
+
+// hasAnotherOwner reports whether the organization keeps an owner once member stops being one,
+// aborting the request with 409 (Conflict) when it would not.
+func hasAnotherOwner(context *gin.Context, member models.Membership) bool {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	var owners int64
+	err = database.Model(&models.Membership{}).
+		Where("role = ? AND id <> ?", models.OrganizationRoleOwner, member.ID).
+		Count(&owners).Error
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if owners == 0 {
+		utils.AbortWithError(context, http.StatusConflict, "An organization must keep at least one owner")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,616 @@
+package organization
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type recordingMailer struct {
+	to      []string
+	bodies  []string
+	failure error
+}
+
+func (m *recordingMailer) Send(to string, subject string, body string) error {
+	m.to = append(m.to, to)
+	m.bodies = append(m.bodies, body)
+	return m.failure
+}
+
+var mailService *recordingMailer
+var organizationController OrganizationController
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+	mailService = &recordingMailer{}
+	organizationController = NewOrganizationController(mailService)
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func setCurrentUser(user models.User) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}
+}
+
+func setCurrentMembership(membership models.Membership) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentMembership", &membership)
+	}
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestCreateOrganization(t *testing.T) {
+	method, url := "POST", "/organizations"
+	john := builders.NewUserBuilder().Build()
+
+	tests := []struct {
+		name         string
+		input        interface{}
+		setUser      bool
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.OrganizationInput{Name: "Class 4B"},
+			setUser:      true,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "empty name",
+			input:        models.OrganizationInput{Name: ""},
+			setUser:      true,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no user",
+			input:        models.OrganizationInput{Name: "Class 4B"},
+			setUser:      false,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			if tt.setUser {
+				router.POST(url, setCurrentUser(john), organizationController.CreateOrganization)
+			} else {
+				router.POST(url, organizationController.CreateOrganization)
+			}
+
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO "organizations"`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO "memberships"`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), john.ID, models.OrganizationRoleOwner, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+
+			if tt.expectedCode == http.StatusCreated {
+				var response models.OrganizationResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "Class 4B", response.Name)
+				assert.Equal(t, models.OrganizationRoleOwner, response.Role)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestGetMyOrganizations(t *testing.T) {
+	method, url := "GET", "/organizations"
+	john := builders.NewUserBuilder().Build()
+	organization := models.Organization{ID: uuid.New(), Name: "Class 4B", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	tests := []struct {
+		name          string
+		memberships   []models.Membership
+		expectedCount int
+	}{
+		{
+			name: "one organization",
+			memberships: []models.Membership{
+				{ID: uuid.New(), OrganizationID: organization.ID, UserID: john.ID, Role: models.OrganizationRoleTeacher},
+			},
+			expectedCount: 1,
+		},
+		{
+			name:          "no organization",
+			memberships:   []models.Membership{},
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET(url, setCurrentUser(john), organizationController.GetMyOrganizations)
+
+			mock.ExpectQuery(`SELECT \* FROM "memberships" WHERE user_id = \$1`).
+				WithArgs(john.ID).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.memberships))
+			if len(tt.memberships) > 0 {
+				mock.ExpectQuery(`SELECT \* FROM "organizations" WHERE id IN \(\$1\)`).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Organization{organization}))
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response []models.OrganizationResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Len(t, response, tt.expectedCount)
+			if tt.expectedCount > 0 {
+				assert.Equal(t, models.OrganizationRoleTeacher, response[0].Role)
+			}
+		})
+	}
+}
+
+func TestGetMembers(t *testing.T) {
+	method, url := "GET", "/members"
+	organizationID := uuid.New()
+	julie := builders.NewUserBuilder().WhereFirstName("Julie").Build()
+
+	tests := []struct {
+		name          string
+		role          string
+		expectedQuery string
+	}{
+		{
+			name:          "owner lists every member",
+			role:          models.OrganizationRoleOwner,
+			expectedQuery: `SELECT * FROM "memberships" ORDER BY created_at`,
+		},
+		{
+			name:          "teacher lists their students",
+			role:          models.OrganizationRoleTeacher,
+			expectedQuery: `SELECT * FROM "memberships" WHERE role = $1 AND teacher_id = $2 ORDER BY created_at`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			membership := models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: tt.role}
+			student := models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: julie.ID, Role: models.OrganizationRoleStudent,
+				TeacherID: uuid.NullUUID{UUID: membership.UserID, Valid: true}}
+			router.GET(url, setCurrentMembership(membership), organizationController.GetMembers)
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(tt.expectedQuery))
+			if tt.role == models.OrganizationRoleTeacher {
+				query.WithArgs(models.OrganizationRoleStudent, membership.UserID)
+			}
+			query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Membership{student}))
+			mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN \(\$1\)`).
+				WithArgs(julie.ID).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{julie}))
+
+			w, err := utils.HttpTestRequest(router, method, url, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response []models.MemberResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Len(t, response, 1)
+			assert.Equal(t, "Julie", response[0].FirstName)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInviteMember(t *testing.T) {
+	method, url := "POST", "/invitations"
+	organization := models.Organization{ID: uuid.New(), Name: "Class 4B"}
+
+	tests := []struct {
+		name         string
+		role         string
+		input        models.InvitationInput
+		mailFailure  error
+		members      int
+		expectedCode int
+	}{
+		{
+			name:         "teacher invites a student",
+			role:         models.OrganizationRoleTeacher,
+			input:        models.InvitationInput{Email: "Julie.Doe@mail.pe", Role: models.OrganizationRoleStudent},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "teacher invites a teacher",
+			role:         models.OrganizationRoleTeacher,
+			input:        models.InvitationInput{Email: "julie.doe@mail.pe", Role: models.OrganizationRoleTeacher},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "invalid email",
+			role:         models.OrganizationRoleOwner,
+			input:        models.InvitationInput{Email: "julie.doe", Role: models.OrganizationRoleStudent},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "email not sent",
+			role:         models.OrganizationRoleOwner,
+			input:        models.InvitationInput{Email: "julie.doe@mail.pe", Role: models.OrganizationRoleTeacher},
+			mailFailure:  assert.AnError,
+			expectedCode: http.StatusBadGateway,
+		},
+		{
+			name:         "member invited",
+			role:         models.OrganizationRoleOwner,
+			input:        models.InvitationInput{Email: "Julie.Doe@mail.pe", Role: models.OrganizationRoleTeacher},
+			members:      1,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			mailService.failure = tt.mailFailure
+			membership := models.Membership{ID: uuid.New(), OrganizationID: organization.ID, UserID: uuid.New(), Role: tt.role}
+			router.POST(url, setCurrentMembership(membership), organizationController.InviteMember)
+
+			if tt.expectedCode != http.StatusBadRequest && tt.expectedCode != http.StatusForbidden {
+				mock.ExpectQuery(`SELECT \* FROM "organizations" WHERE id = \$1`).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Organization{organization}))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "memberships" JOIN users ON users.id = memberships.user_id `+
+					`WHERE memberships.organization_id = $1 AND users.email = $2`)).
+					WithArgs(organization.ID, "julie.doe@mail.pe").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.members))
+			}
+			if tt.expectedCode == http.StatusCreated || tt.expectedCode == http.StatusBadGateway {
+				var teacherID interface{}
+				if tt.role == models.OrganizationRoleTeacher {
+					teacherID = membership.UserID
+				}
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO "invitations"`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), tt.input.Role, sqlmock.AnyArg(), membership.UserID, teacherID,
+						sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.mailFailure != nil {
+					mock.ExpectRollback()
+				} else {
+					mock.ExpectCommit()
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+
+			if tt.expectedCode == http.StatusCreated {
+				var response models.InvitationResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "julie.doe@mail.pe", response.Email)
+				assert.Equal(t, []string{"julie.doe@mail.pe"}, mailService.to)
+				assert.Contains(t, mailService.bodies[0], "/invitations/")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// expectReleaseStudents expects the students of the teacher, and the pending invitations of their students, to be
+// left to the owners.
+func expectReleaseStudents(mock sqlmock.Sqlmock, teacher models.Membership) {
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "memberships" SET "teacher_id"=$1 WHERE organization_id = $2 AND teacher_id = $3`)).
+		WithArgs(nil, teacher.OrganizationID, teacher.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "invitations" SET "teacher_id"=$1 WHERE organization_id = $2 AND teacher_id = $3 AND accepted_at IS NULL`)).
+		WithArgs(nil, teacher.OrganizationID, teacher.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	method, url := "PUT", "/members/"
+	organizationID := uuid.New()
+	teacher := models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleTeacher}
+	student := models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleStudent,
+		TeacherID: uuid.NullUUID{UUID: teacher.UserID, Valid: true}}
+
+	tests := []struct {
+		name         string
+		member       models.Membership
+		role         string
+		expectedCode int
+	}{
+		{
+			name:         "teacher demoted",
+			member:       teacher,
+			role:         models.OrganizationRoleStudent,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "student promoted",
+			member:       student,
+			role:         models.OrganizationRoleTeacher,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown role",
+			member:       student,
+			role:         "principal",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT(url+":memberID", organizationController.UpdateMemberRole)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(`SELECT \* FROM "memberships" WHERE id = \$1`).
+					WithArgs(tt.member.ID.String(), 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Membership{tt.member}))
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "memberships" SET`).
+					WithArgs(organizationID, tt.member.UserID, tt.role, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), tt.member.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.member.Role == models.OrganizationRoleTeacher {
+					expectReleaseStudents(mock, tt.member)
+				}
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url+tt.member.ID.String(), models.MembershipRoleInput{Role: tt.role})
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	method, url := "DELETE", "/members/"
+	organizationID := uuid.New()
+	queryFirst := `SELECT \* FROM "memberships" WHERE id = \$1`
+	teacherID := uuid.New()
+	ownStudent := uuid.NullUUID{UUID: teacherID, Valid: true}
+
+	tests := []struct {
+		name         string
+		role         string
+		member       models.Membership
+		otherOwners  int
+		expectedCode int
+	}{
+		{
+			name: "teacher removes their student",
+			role: models.OrganizationRoleTeacher,
+			member: models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleStudent,
+				TeacherID: ownStudent},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "teacher removes the student of another teacher",
+			role: models.OrganizationRoleTeacher,
+			member: models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleStudent,
+				TeacherID: uuid.NullUUID{UUID: uuid.New(), Valid: true}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "teacher removes a teacher",
+			role:         models.OrganizationRoleTeacher,
+			member:       models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleTeacher},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "owner removes a teacher",
+			role:         models.OrganizationRoleOwner,
+			member:       models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleTeacher},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "owner removes the last owner",
+			role:         models.OrganizationRoleOwner,
+			member:       models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleOwner},
+			otherOwners:  0,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "owner removes an owner",
+			role:         models.OrganizationRoleOwner,
+			member:       models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: uuid.New(), Role: models.OrganizationRoleOwner},
+			otherOwners:  1,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			membership := models.Membership{ID: uuid.New(), OrganizationID: organizationID, UserID: teacherID, Role: tt.role}
+			router.DELETE(url+":memberID", setCurrentMembership(membership), organizationController.RemoveMember)
+
+			mock.ExpectQuery(queryFirst).
+				WithArgs(tt.member.ID.String(), 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Membership{tt.member}))
+			if tt.member.Role == models.OrganizationRoleOwner {
+				mock.ExpectQuery(`SELECT count\(\*\) FROM "memberships"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.otherOwners))
+			}
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(`DELETE FROM "memberships" WHERE "memberships"."id" = \$1`).
+					WithArgs(tt.member.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.member.Role == models.OrganizationRoleTeacher {
+					expectReleaseStudents(mock, tt.member)
+				}
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url+tt.member.ID.String(), nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	method, url := "POST", "/invitations/"
+	queryInvitation := `SELECT \* FROM "invitations" WHERE token_hash = \$1`
+	queryMembership := `SELECT \* FROM "memberships" WHERE (.+)`
+	john := builders.NewUserBuilder().Build()
+	organization := models.Organization{ID: uuid.New(), Name: "Class 4B"}
+	token := "invitation-token"
+	teacherID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	tests := []struct {
+		name         string
+		invitation   *models.Invitation
+		isMember     bool
+		expectedCode int
+	}{
+		{
+			name: "valid invitation",
+			invitation: &models.Invitation{
+				ID: uuid.New(), OrganizationID: organization.ID, Email: john.Email, Role: models.OrganizationRoleStudent,
+				TokenHash: utils.HashToken(token), ExpiresAt: time.Now().Add(time.Hour),
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "invitation of a teacher",
+			invitation: &models.Invitation{
+				ID: uuid.New(), OrganizationID: organization.ID, Email: john.Email, Role: models.OrganizationRoleStudent,
+				TokenHash: utils.HashToken(token), TeacherID: teacherID, ExpiresAt: time.Now().Add(time.Hour),
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "expired invitation",
+			invitation: &models.Invitation{
+				ID: uuid.New(), OrganizationID: organization.ID, Email: john.Email, Role: models.OrganizationRoleStudent,
+				TokenHash: utils.HashToken(token), ExpiresAt: time.Now().Add(-time.Hour),
+			},
+			expectedCode: http.StatusGone,
+		},
+		{
+			name: "invitation for another email",
+			invitation: &models.Invitation{
+				ID: uuid.New(), OrganizationID: organization.ID, Email: "julie.doe@mail.pe", Role: models.OrganizationRoleStudent,
+				TokenHash: utils.HashToken(token), ExpiresAt: time.Now().Add(time.Hour),
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name: "already a member",
+			invitation: &models.Invitation{
+				ID: uuid.New(), OrganizationID: organization.ID, Email: john.Email, Role: models.OrganizationRoleStudent,
+				TokenHash: utils.HashToken(token), ExpiresAt: time.Now().Add(time.Hour),
+			},
+			isMember:     true,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "unknown invitation",
+			invitation:   nil,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST(url+":token/accept", setCurrentUser(john), organizationController.AcceptInvitation)
+
+			if tt.invitation == nil {
+				mock.ExpectQuery(queryInvitation).WillReturnError(gorm.ErrRecordNotFound)
+			} else {
+				mock.ExpectQuery(queryInvitation).
+					WithArgs(utils.HashToken(token), 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Invitation{*tt.invitation}))
+			}
+			if tt.isMember {
+				mock.ExpectQuery(queryMembership).WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Membership{
+					{ID: uuid.New(), OrganizationID: organization.ID, UserID: john.ID, Role: models.OrganizationRoleStudent},
+				}))
+			} else if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(queryMembership).WillReturnError(gorm.ErrRecordNotFound)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO "memberships"`).
+					WithArgs(sqlmock.AnyArg(), organization.ID, john.ID, models.OrganizationRoleStudent, tt.invitation.TeacherID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE "invitations" SET "accepted_at"=\$1 WHERE "id" = \$2`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(`SELECT \* FROM "organizations" WHERE id = \$1`).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Organization{organization}))
+			}
+
+			w, err := utils.HttpTestRequest(router, method, url+token+"/accept", nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package persona
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PersonaController struct{}
+
+func NewPersonaController() PersonaController {
+	return PersonaController{}
+}
+
+// GetPersonas retrieves the catalogue of the pen pals.
+// @Summary Get the pen pals
+// @Description Fetches a page of the enabled pen pals, optionally filtered by native language.
+// @Tags personas
+// @Produce json
+// @Param language query string false "Native language"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.PersonaResponse
+// @Failure 500 {object} object
+// @Router /personas [get]
+func (pc *PersonaController) GetPersonas(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Where("enabled = ?", true).Order("name")
+	if language := context.Query("language"); language != "" {
+		query = query.Where("native_language = ?", language)
+	}
+
+	var personas []models.Persona
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&personas).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.PersonaResponse, 0, len(personas))
+	for _, persona := range personas {
+		responses = append(responses, persona.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetPersona retrieves a pen pal of the catalogue.
+// @Summary Get a pen pal
+// @Description Fetches an enabled pen pal by UUID.
+// @Tags personas
+// @Produce json
+// @Param id path string true "Persona ID"
+// @Success 200 {object} models.PersonaResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /personas/{id} [get]
+func (pc *PersonaController) GetPersona(context *gin.Context) {
+	persona, ok := findPersona(context, true)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, persona.ToResponse())
+}
+
+// GetAllPersonas retrieves every pen pal, including the disabled ones.
+// @Summary Get all the pen pals
+// @Description Fetches a page of the pen pals with their prompts and model parameters.
+// @Tags personas
+// @Produce json
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.PersonaAdminResponse
+// @Failure 500 {object} object
+// @Router /personas [get]
+func (pc *PersonaController) GetAllPersonas(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var personas []models.Persona
+	if err := database.Order("name").Scopes(utils.GetPagination(context).Scope).Find(&personas).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.PersonaAdminResponse, 0, len(personas))
+	for _, persona := range personas {
+		responses = append(responses, persona.ToAdminResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetPersonaDetails retrieves a pen pal, even if disabled.
+// @Summary Get the details of a pen pal
+// @Description Fetches a pen pal by UUID with its prompt and model parameters.
+// @Tags personas
+// @Produce json
+// @Param id path string true "Persona ID"
+// @Success 200 {object} models.PersonaAdminResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /personas/{id} [get]
+func (pc *PersonaController) GetPersonaDetails(context *gin.Context) {
+	persona, ok := findPersona(context, false)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, persona.ToAdminResponse())
+}
+
+// CreatePersona creates a pen pal.
+// @Summary Create a pen pal
+// @Description Creates a pen pal. Its system prompt is a Go template receiving the persona, the learner name and the language of the conversation.
+// @Description A published prompt template can be named instead, optionally pinned to one of its versions.
+// @Tags personas
+// @Accept json
+// @Produce json
+// @Param payload body models.PersonaInput true "Persona Data"
+// @Success 201 {object} models.PersonaAdminResponse
+// @Failure 400 {object} object
+// @Failure 500 {object} object
+// @Router /personas [post]
+func (pc *PersonaController) CreatePersona(context *gin.Context) {
+	payload, ok := bindPersonaInput(context)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !checkPromptTemplate(context, database, payload) {
+		return
+	}
+
+	var persona models.Persona
+	persona.Apply(payload)
+	if err := database.Create(&persona).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, persona.ToAdminResponse())
+}
+
+// UpdatePersona updates a pen pal.
+// @Summary Update a pen pal
+// @Description Replaces the details of a pen pal. Disabling a pen pal stops its conversations.
+// @Tags personas
+// @Accept json
+// @Produce json
+// @Param id path string true "Persona ID"
+// @Param payload body models.PersonaInput true "Persona Data"
+// @Success 200 {object} models.PersonaAdminResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /personas/{id} [put]
+func (pc *PersonaController) UpdatePersona(context *gin.Context) {
+	payload, ok := bindPersonaInput(context)
+	if !ok {
+		return
+	}
+	persona, ok := findPersona(context, false)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !checkPromptTemplate(context, database, payload) {
+		return
+	}
+
+	persona.Apply(payload)
+	if err := database.Save(&persona).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, persona.ToAdminResponse())
+}
+
+// DeletePersona deletes a pen pal without conversations.
+// @Summary Delete a pen pal
+// @Description Deletes a pen pal. Pen pals with conversations can't be deleted and should be disabled instead.
+// @Tags personas
+// @Produce json
+// @Param id path string true "Persona ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /personas/{id} [delete]
+func (pc *PersonaController) DeletePersona(context *gin.Context) {
+	persona, ok := findPersona(context, false)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var conversations int64
+	if err := database.Model(&models.Conversation{}).Where("persona_id = ?", persona.ID).Count(&conversations).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if conversations > 0 {
+		utils.AbortWithError(context, http.StatusConflict, "The pen pal has conversations, disable it instead")
+		return
+	}
+
+	if err := database.Delete(&persona).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// bindPersonaInput binds and validates the body of the request, aborting the request when it is not valid.
+func bindPersonaInput(context *gin.Context) (models.PersonaInput, bool) {
+	var payload models.PersonaInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return payload, false
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return payload, false
+	}
+	return payload, true
+}
+
+// checkPromptTemplate checks that the prompt template named by the input, or the version it is pinned to, is published,
+// aborting the request otherwise.
+func checkPromptTemplate(context *gin.Context, database *gorm.DB, payload models.PersonaInput) bool {
+	if payload.PromptName == "" {
+		return true
+	}
+
+	query := database.Model(&models.PromptTemplate{}).Where("name = ? AND status = ?", payload.PromptName, models.PromptStatusPublished)
+	if payload.PromptVersion != "" {
+		query = query.Where("version = ?", payload.PromptVersion)
+	}
+	var versions int64
+	if err := query.Count(&versions).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if versions == 0 {
+		utils.AbortWithError(context, http.StatusBadRequest, "The prompt template has no published version")
+		return false
+	}
+	return true
+}
+
+// findPersona loads the persona identified by the `id` path parameter, aborting the request when it
+// cannot be found. With enabledOnly, disabled personas are reported as not found.
+func findPersona(context *gin.Context, enabledOnly bool) (models.Persona, bool) {
+	var persona models.Persona
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return persona, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return persona, false
+	}
+	query := database.Where("id = ?", id)
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+	if err := query.First(&persona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found persona")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return persona, false
+	}
+	return persona, true
+}
@@ -0,0 +1,279 @@
+package persona
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var personaController = NewPersonaController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func newPersona(enabled bool) models.Persona {
+	return models.Persona{
+		ID:             uuid.New(),
+		Name:           "Lucía",
+		NativeLanguage: "es",
+		SystemPrompt:   "Eres Lucía.",
+		Temperature:    sql.NullFloat64{Float64: 0.9, Valid: true},
+		Enabled:        enabled,
+	}
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetPersonas(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	router.GET("/personas", personaController.GetPersonas)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE enabled = $1 AND native_language = $2 ORDER BY name LIMIT $3`)).
+		WithArgs(true, "es", 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{newPersona(true)}))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/personas?language=es", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "system_prompt")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPersona(t *testing.T) {
+	persona := newPersona(true)
+
+	tests := []struct {
+		name         string
+		id           string
+		personas     []models.Persona
+		expectedCode int
+	}{
+		{
+			name:         "enabled persona",
+			id:           persona.ID.String(),
+			personas:     []models.Persona{persona},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "disabled or unknown persona",
+			id:           persona.ID.String(),
+			personas:     []models.Persona{},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			id:           "lucia",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET("/personas/:id", personaController.GetPersona)
+
+			if tt.personas != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1 AND enabled = $2`)).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.personas))
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", "/personas/"+tt.id, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetPersonaDetails(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	router.GET("/personas/:id", personaController.GetPersonaDetails)
+
+	persona := newPersona(false)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1 ORDER BY`)).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/personas/"+persona.ID.String(), nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PersonaAdminResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, persona.SystemPrompt, response.SystemPrompt)
+	assert.False(t, response.Enabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePersona(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        models.PersonaInput
+		versions     int
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres {{.Persona.Name}}.", AllowedLevels: []string{"A1"}, Enabled: true},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "valid input with prompt template",
+			input:        models.PersonaInput{Name: "Lucía", NativeLanguage: "es", PromptName: "pen-pal", PromptVersion: "1.0.0"},
+			versions:     1,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "unpublished prompt template",
+			input:        models.PersonaInput{Name: "Lucía", NativeLanguage: "es", PromptName: "pen-pal"},
+			versions:     0,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid template",
+			input:        models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres {{.Persona.Name"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No body",
+			input:        models.PersonaInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/personas", personaController.CreatePersona)
+
+			if tt.input.PromptName != "" {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "prompt_templates" WHERE`)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.versions))
+			}
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "personas"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/personas", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusCreated {
+				var response models.PersonaAdminResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.input.Name, response.Name)
+				assert.Equal(t, tt.input.AllowedLevels, response.AllowedLevels)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUpdatePersona(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	router.PUT("/personas/:id", personaController.UpdatePersona)
+
+	persona := newPersona(true)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1 ORDER BY`)).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "personas"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	input := models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres Lucía.", Enabled: false}
+	w, err := utils.HttpTestRequest(router, "PUT", "/personas/"+persona.ID.String(), input)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PersonaAdminResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Enabled)
+	assert.Nil(t, response.Temperature)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeletePersona(t *testing.T) {
+	persona := newPersona(true)
+
+	tests := []struct {
+		name          string
+		conversations int
+		expectedCode  int
+	}{
+		{
+			name:          "persona without conversations",
+			conversations: 0,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "persona with conversations",
+			conversations: 2,
+			expectedCode:  http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/personas/:id", personaController.DeletePersona)
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1 ORDER BY`)).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "conversations" WHERE persona_id = $1`)).
+				WithArgs(persona.ID).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.conversations))
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "personas" WHERE "personas"."id" = $1`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/personas/"+persona.ID.String(), nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
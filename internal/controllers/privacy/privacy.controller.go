@@ -0,0 +1,34 @@
+package privacy
+
+import (
+	"net/http"
+
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyController struct {
+	redactor *redaction.Redactor
+}
+
+func NewPrivacyController(redactor *redaction.Redactor) PrivacyController {
+	return PrivacyController{redactor: redactor}
+}
+
+// GetRedactionStats retrieves the counts of the personal data redacted from the calls to the language model.
+// @Summary Get redaction statistics
+// @Description Fetches the number of calls to the language model screened since the start of the server, and the
+// @Description number of personal data values redacted and restored per category.
+// @Tags privacy
+// @Produce json
+// @Success 200 {object} redaction.Stats
+// @Failure 404 {object} object
+// @Router /privacy/redactions [get]
+func (pc *PrivacyController) GetRedactionStats(context *gin.Context) {
+	if pc.redactor == nil {
+		utils.AbortWithError(context, http.StatusNotFound, "The redaction of personal data is disabled")
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, pc.redactor.Stats())
+}
@@ -0,0 +1,55 @@
+package privacy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRedactionStats(t *testing.T) {
+	redactor, err := redaction.NewRedactor(redaction.Categories, nil)
+	assert.NoError(t, err)
+	redactor.Redact(redaction.NewVault(), "Mi correo es ana@mail.es", "")
+
+	tests := []struct {
+		name         string
+		redactor     *redaction.Redactor
+		expectedCode int
+	}{
+		{
+			name:         "enabled redaction",
+			redactor:     redactor,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "disabled redaction",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.Default()
+			controller := NewPrivacyController(tt.redactor)
+			router.GET("/privacy/redactions", controller.GetRedactionStats)
+
+			w, err := utils.HttpTestRequest(router, "GET", "/privacy/redactions", nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response redaction.Stats
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, int64(1), response.Redacted[redaction.CategoryEmail])
+				assert.Equal(t, int64(0), response.Redacted[redaction.CategoryCard])
+			}
+		})
+	}
+}
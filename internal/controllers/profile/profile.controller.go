@@ -0,0 +1,463 @@
+package profile
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/placement"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ProfileController struct {
+	provider llm.Provider
+	meter    *metering.Meter
+}
+
+func NewProfileController(provider llm.Provider, meter *metering.Meter) ProfileController {
+	return ProfileController{provider, meter}
+}
+
+// GetProfile retrieves the learner profile of the current user.
+// @Summary Get my learner profile
+// @Description Fetches the native language of the current user and the languages they learn, with their level and goals.
+// @Tags profile
+// @Produce json
+// @Success 200 {object} models.ProfileResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/profile [get]
+func (pc *ProfileController) GetProfile(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendProfile(context, database, currentUser)
+}
+
+// UpdateProfile changes the native language of the current user.
+// @Summary Update my learner profile
+// @Description Changes the native language of the current user, in which their mistakes are explained.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Param payload body models.ProfileInput true "Profile Data"
+// @Success 200 {object} models.ProfileResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/profile [put]
+func (pc *ProfileController) UpdateProfile(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	var payload models.ProfileInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := database.Model(currentUser).Update("native_language", payload.NativeLanguage).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendProfile(context, database, currentUser)
+}
+
+// PutLearnerLanguage adds a language to the languages the current user learns, or changes their level and goals in it.
+// @Summary Set a language I learn
+// @Description Sets the level and the goals of the current user in a language, adding it to the languages they learn.
+// @Description A level set by hand replaces the result of a previous placement test.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Param language path string true "Language"
+// @Param payload body models.LearnerLanguageInput true "Language Data"
+// @Success 200 {object} models.LearnerLanguageResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/profile/languages/{language} [put]
+func (pc *ProfileController) PutLearnerLanguage(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	var payload models.LearnerLanguageInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	payload.Language = context.Param("language")
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var languages []models.LearnerLanguage
+	if err := database.Where("user_id = ? AND language = ?", currentUser.ID, payload.Language).Limit(1).Find(&languages).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	learnerLanguage := models.LearnerLanguage{UserID: currentUser.ID, Language: payload.Language}
+	if len(languages) > 0 {
+		learnerLanguage = languages[0]
+	}
+	if learnerLanguage.Level != payload.Level {
+		learnerLanguage.Level, learnerLanguage.PlacedAt = payload.Level, sql.NullTime{}
+	}
+	learnerLanguage.Goals = payload.Goals
+	if err := database.Save(&learnerLanguage).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, learnerLanguage.ToResponse())
+}
+
+// DeleteLearnerLanguage removes a language from the languages the current user learns.
+// @Summary Remove a language I learn
+// @Description Removes a language from the learner profile of the current user, with their level in it.
+// @Tags profile
+// @Param language path string true "Language"
+// @Success 204
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/profile/languages/{language} [delete]
+func (pc *ProfileController) DeleteLearnerLanguage(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := database.Where("user_id = ? AND language = ?", currentUser.ID, context.Param("language")).Delete(&models.LearnerLanguage{})
+	if result.Error != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.AbortWithError(context, http.StatusNotFound, "Can't found language")
+		return
+	}
+	context.Status(http.StatusNoContent)
+}
+
+// StartPlacementTest starts a placement test of the current user in a language.
+// @Summary Start a placement test
+// @Description Starts an adaptive test assessing the CEFR level of the current user in a language, and returns its first question.
+// @Description Each answer is graded by the language model and the next question is one level up after a good answer,
+// @Description one level down after a poor one. The result becomes the level of the user in the language.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Param payload body models.PlacementTestInput true "Placement Test Data"
+// @Success 201 {object} models.PlacementTestResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /me/placement-tests [post]
+func (pc *ProfileController) StartPlacementTest(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	var payload models.PlacementTestInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !pc.checkQuota(context, database, *currentUser) {
+		return
+	}
+
+	test := models.PlacementTest{
+		UserID:   currentUser.ID,
+		Language: payload.Language,
+		Status:   models.PlacementStatusInProgress,
+	}
+	var records []models.UsageRecord
+	if !pc.ask(context, database, *currentUser, &test, placement.StartLevel, &records) {
+		return
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&records).Error; err != nil {
+			return err
+		}
+		return tx.Create(&test).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, test.ToResponse())
+}
+
+// GetPlacementTest retrieves a placement test of the current user.
+// @Summary Get a placement test
+// @Description Fetches a placement test of the current user, with its pending question or its result.
+// @Tags profile
+// @Produce json
+// @Param id path string true "Placement Test ID"
+// @Success 200 {object} models.PlacementTestResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/placement-tests/{id} [get]
+func (pc *ProfileController) GetPlacementTest(context *gin.Context) {
+	test, _, _, ok := findTest(context)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, test.ToResponse())
+}
+
+// AnswerPlacementTest grades the answer of the current user to the pending question of a placement test.
+// @Summary Answer a placement test
+// @Description Grades the answer to the pending question of a placement test and returns the next question, or the
+// @Description result once the last question is answered. The result becomes the level of the user in the language.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Param id path string true "Placement Test ID"
+// @Param payload body models.PlacementAnswerInput true "Answer Data"
+// @Success 200 {object} models.PlacementTestResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /me/placement-tests/{id}/answers [post]
+func (pc *ProfileController) AnswerPlacementTest(context *gin.Context) {
+	var payload models.PlacementAnswerInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	test, currentUser, database, ok := findTest(context)
+	if !ok {
+		return
+	}
+	pending := test.Pending()
+	if test.Status != models.PlacementStatusInProgress || pending == nil {
+		utils.AbortWithError(context, http.StatusConflict, "The placement test is already completed")
+		return
+	}
+	if !pc.checkQuota(context, database, *currentUser) {
+		return
+	}
+
+	pending.Answer = strings.TrimSpace(payload.Answer)
+	ctx := redaction.WithAddress(context.Request.Context(), currentUser.Address.String)
+	completion, err := pc.provider.Complete(ctx, placement.NewGradingRequest(test.Language, *pending, currentUser.NativeLanguage))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadGateway, "The answer could not be graded, please try again")
+		return
+	}
+	records := []models.UsageRecord{pc.meter.NewUserRecord(currentUser.ID, completion)}
+	pending.Score, pending.Feedback, err = placement.ParseGrade(completion.Content)
+	if err != nil {
+		abortAfterCall(context, database, records, "The answer could not be graded, please try again")
+		return
+	}
+
+	now := time.Now()
+	if len(test.Questions) >= models.PlacementTestLength {
+		test.Status = models.PlacementStatusCompleted
+		test.Result = placement.Result(test.Questions)
+		test.CompletedAt = sql.NullTime{Time: now, Valid: true}
+	} else if !pc.ask(context, database, *currentUser, &test, placement.Step(pending.Level, pending.Score), &records) {
+		return
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&records).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&test).Error; err != nil {
+			return err
+		}
+		if test.Status != models.PlacementStatusCompleted {
+			return nil
+		}
+		return placement.Place(tx, test.UserID, test.Language, test.Result, now)
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, test.ToResponse())
+}
+
+// ask adds a question of the level to the placement test, written by the language model, and the consumption of the
+// call to the records. The request is aborted, after storing the records, if the question could not be written.
+func (pc *ProfileController) ask(context *gin.Context, database *gorm.DB, user models.User, test *models.PlacementTest, level string, records *[]models.UsageRecord) bool {
+	request := placement.NewQuestionRequest(test.Language, level, user.NativeLanguage, test.Questions)
+	ctx := redaction.WithAddress(context.Request.Context(), user.Address.String)
+	completion, err := pc.provider.Complete(ctx, request)
+	if err != nil {
+		abortAfterCall(context, database, *records, "The placement question could not be written, please try again")
+		return false
+	}
+	*records = append(*records, pc.meter.NewUserRecord(user.ID, completion))
+	prompt, err := placement.ParseQuestion(completion.Content)
+	if err != nil {
+		abortAfterCall(context, database, *records, "The placement question could not be written, please try again")
+		return false
+	}
+	test.Questions = append(test.Questions, models.PlacementQuestion{Level: level, Prompt: prompt})
+	return true
+}
+
+// checkQuota aborts the request if the user has consumed the tokens of their quota.
+func (pc *ProfileController) checkQuota(context *gin.Context, database *gorm.DB, user models.User) bool {
+	if err := pc.meter.Check(database, user, time.Now()); err != nil {
+		var quotaErr *metering.QuotaError
+		if errors.As(err, &quotaErr) {
+			abortQuotaExceeded(context, quotaErr)
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// abortAfterCall stores the consumption of the calls already made to the language model, then aborts the request
+// with a 502 and the message.
+func abortAfterCall(context *gin.Context, database *gorm.DB, records []models.UsageRecord, message string) {
+	if len(records) > 0 {
+		if err := database.Create(&records).Error; err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	utils.AbortWithError(context, http.StatusBadGateway, message)
+}
+
+// sendProfile sends the learner profile of the user.
+func sendProfile(context *gin.Context, database *gorm.DB, user *models.User) {
+	var languages []models.LearnerLanguage
+	if err := database.Where("user_id = ?", user.ID).Order("language").Find(&languages).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := models.ProfileResponse{
+		NativeLanguage: user.NativeLanguage,
+		Languages:      make([]models.LearnerLanguageResponse, 0, len(languages)),
+	}
+	for _, language := range languages {
+		response.Languages = append(response.Languages, language.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, response)
+}
+
+// findTest loads the placement test of the current user targeted by the request, aborting it if there is none.
+func findTest(context *gin.Context) (models.PlacementTest, *models.User, *gorm.DB, bool) {
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return models.PlacementTest{}, nil, nil, false
+	}
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return models.PlacementTest{}, nil, nil, false
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return models.PlacementTest{}, nil, nil, false
+	}
+
+	var test models.PlacementTest
+	if err := database.Where("id = ? AND user_id = ?", id, currentUser.ID).First(&test).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found placement test")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return models.PlacementTest{}, nil, nil, false
+	}
+	return test, currentUser, database, true
+}
+
+// abortQuotaExceeded aborts the request with a 429 describing the exhausted quota, telling the client when to retry.
+func abortQuotaExceeded(context *gin.Context, quotaErr *metering.QuotaError) {
+	context.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))))
+	context.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"status":  "fail",
+		"message": fmt.Sprintf("Your token quota for the %s is exhausted", quotaErr.Period),
+		"quota":   quotaErr,
+	})
+}
+
+// getCurrentUser returns the user set by the DeserializeUser middleware, aborting the request when there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
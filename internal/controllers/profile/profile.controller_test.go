@@ -0,0 +1,578 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const (
+	queryLanguages = `SELECT * FROM "learner_languages" WHERE user_id = $1 ORDER BY language`
+	queryLanguage  = `SELECT * FROM "learner_languages" WHERE user_id = $1 AND language = $2 LIMIT $3`
+	queryTest      = `SELECT * FROM "placement_tests" WHERE id = $1 AND user_id = $2 ORDER BY "placement_tests"."id" LIMIT $3`
+)
+
+// unmetered meters the calls without enforcing any quota.
+var unmetered = metering.NewMeter(&configs.Config{})
+
+var profileController = NewProfileController(llm.NewFakeProvider(), unmetered)
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func setCurrentUser(user models.User) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}
+}
+
+// gradingProvider grades every answer with Grade, and writes the questions with the embedded FakeProvider.
+type gradingProvider struct {
+	*llm.FakeProvider
+	Grade string
+}
+
+func (p *gradingProvider) Complete(ctx context.Context, request llm.Request) (llm.Response, error) {
+	response, err := p.FakeProvider.Complete(ctx, request)
+	if err == nil && request.Temperature != nil {
+		response.Content = p.Grade
+	}
+	return response, err
+}
+
+// languageRows returns the rows of the languages of a learner, which testUtils can't build because of their goals.
+func languageRows(languages ...models.LearnerLanguage) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "language", "level", "goals", "placed_at"})
+	for _, language := range languages {
+		goals, _ := json.Marshal(language.Goals)
+		rows.AddRow(language.ID, language.UserID, language.Language, language.Level, string(goals), language.PlacedAt)
+	}
+	return rows
+}
+
+// testRows returns the rows of a placement test, which testUtils can't build because of its questions.
+func testRows(test models.PlacementTest) *sqlmock.Rows {
+	questions, _ := json.Marshal(test.Questions)
+	return sqlmock.NewRows([]string{"id", "user_id", "language", "status", "questions", "result"}).
+		AddRow(test.ID, test.UserID, test.Language, test.Status, string(questions), test.Result)
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetProfile(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().WhereNativeLanguage("fr").Build()
+	placedAt := sql.NullTime{Time: time.Now(), Valid: true}
+	router.GET("/me/profile", setCurrentUser(user), profileController.GetProfile)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryLanguages)).
+		WithArgs(user.ID).
+		WillReturnRows(languageRows(
+			models.LearnerLanguage{ID: uuid.New(), UserID: user.ID, Language: "de", Goals: []string{"work"}},
+			models.LearnerLanguage{ID: uuid.New(), UserID: user.ID, Language: "es", Level: "B1", PlacedAt: placedAt},
+		))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/profile", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.ProfileResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "fr", response.NativeLanguage)
+	assert.Len(t, response.Languages, 2)
+	assert.Equal(t, []string{"work"}, response.Languages[0].Goals)
+	assert.Nil(t, response.Languages[0].PlacedAt)
+	assert.Equal(t, "B1", response.Languages[1].Level)
+	assert.NotNil(t, response.Languages[1].PlacedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateProfile(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+
+	tests := []struct {
+		name         string
+		input        models.ProfileInput
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.ProfileInput{NativeLanguage: "pt-BR"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid language",
+			input:        models.ProfileInput{NativeLanguage: "Portuguese"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT("/me/profile", setCurrentUser(user), profileController.UpdateProfile)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "native_language"=$1,"updated_at"=$2 WHERE "id" = $3`)).
+					WithArgs(tt.input.NativeLanguage, sqlmock.AnyArg(), user.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(queryLanguages)).
+					WithArgs(user.ID).
+					WillReturnRows(languageRows())
+			}
+
+			w, err := utils.HttpTestRequest(router, "PUT", "/me/profile", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response models.ProfileResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.input.NativeLanguage, response.NativeLanguage)
+				assert.Empty(t, response.Languages)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPutLearnerLanguage(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	placed := models.LearnerLanguage{
+		ID:       uuid.New(),
+		UserID:   user.ID,
+		Language: "es",
+		Level:    "B1",
+		PlacedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	tests := []struct {
+		name           string
+		language       string
+		input          models.LearnerLanguageInput
+		existing       []models.LearnerLanguage
+		expectedCode   int
+		expectedPlaced bool
+	}{
+		{
+			name:         "new language",
+			language:     "es",
+			input:        models.LearnerLanguageInput{Level: "A2", Goals: []string{"travel"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:           "goals of a placed language",
+			language:       "es",
+			input:          models.LearnerLanguageInput{Level: "B1", Goals: []string{"work", "exams"}},
+			existing:       []models.LearnerLanguage{placed},
+			expectedCode:   http.StatusOK,
+			expectedPlaced: true,
+		},
+		{
+			name:         "level set by hand",
+			language:     "es",
+			input:        models.LearnerLanguageInput{Level: "C1"},
+			existing:     []models.LearnerLanguage{placed},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid level",
+			language:     "es",
+			input:        models.LearnerLanguageInput{Level: "B3"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid goal",
+			language:     "es",
+			input:        models.LearnerLanguageInput{Goals: []string{"fun"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid language",
+			language:     "spanish",
+			input:        models.LearnerLanguageInput{Level: "A2"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT("/me/profile/languages/:language", setCurrentUser(user), profileController.PutLearnerLanguage)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(regexp.QuoteMeta(queryLanguage)).
+					WithArgs(user.ID, tt.language, 1).
+					WillReturnRows(languageRows(tt.existing...))
+				mock.ExpectBegin()
+				if len(tt.existing) == 0 {
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "learner_languages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				} else {
+					mock.ExpectExec(regexp.QuoteMeta(`UPDATE "learner_languages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "PUT", "/me/profile/languages/"+tt.language, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response models.LearnerLanguageResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.language, response.Language)
+				assert.Equal(t, tt.input.Level, response.Level)
+				assert.Equal(t, tt.expectedPlaced, response.PlacedAt != nil)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteLearnerLanguage(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+
+	tests := []struct {
+		name         string
+		rowsAffected int64
+		expectedCode int
+	}{
+		{name: "existing language", rowsAffected: 1, expectedCode: http.StatusNoContent},
+		{name: "unknown language", rowsAffected: 0, expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/me/profile/languages/:language", setCurrentUser(user), profileController.DeleteLearnerLanguage)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "learner_languages" WHERE user_id = $1 AND language = $2`)).
+				WithArgs(user.ID, "es").
+				WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+			mock.ExpectCommit()
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/me/profile/languages/es", nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStartPlacementTest(t *testing.T) {
+	user := builders.NewUserBuilder().WhereNativeLanguage("fr").Build()
+
+	tests := []struct {
+		name         string
+		input        models.PlacementTestInput
+		providerErr  error
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.PlacementTestInput{Language: "es"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "provider error",
+			input:        models.PlacementTestInput{Language: "es"},
+			providerErr:  errors.New("unavailable"),
+			expectedCode: http.StatusBadGateway,
+		},
+		{
+			name:         "invalid language",
+			input:        models.PlacementTestInput{Language: "Spanish"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			provider := &llm.FakeProvider{Reply: "Describe tu ciudad en tres frases.", Err: tt.providerErr}
+			controller := NewProfileController(provider, unmetered)
+			router.POST("/me/placement-tests", setCurrentUser(user), controller.StartPlacementTest)
+
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "placement_tests"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/me/placement-tests", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusCreated {
+				var response models.PlacementTestResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, models.PlacementStatusInProgress, response.Status)
+				assert.Equal(t, &models.PlacementQuestionResponse{Number: 1, Level: "B1", Prompt: "Describe tu ciudad en tres frases."}, response.Question)
+				assert.Contains(t, provider.Requests()[0].Messages[0].Content, `"fr"`)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetPlacementTest(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	test := models.PlacementTest{
+		ID:       uuid.New(),
+		UserID:   user.ID,
+		Language: "es",
+		Status:   models.PlacementStatusInProgress,
+		Questions: []models.PlacementQuestion{
+			{Level: "B1", Prompt: "¿Qué hiciste ayer?", Answer: "Fui al cine.", Score: 70},
+			{Level: "B2", Prompt: "¿Qué opinas del teletrabajo?"},
+		},
+	}
+	router.GET("/me/placement-tests/:id", setCurrentUser(user), profileController.GetPlacementTest)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryTest)).
+		WithArgs(test.ID, user.ID, 1).
+		WillReturnRows(testRows(test))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/placement-tests/"+test.ID.String(), nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.PlacementTestResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Answered, 1)
+	assert.Equal(t, 2, response.Question.Number)
+	assert.Equal(t, "B2", response.Question.Level)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnswerPlacementTest(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	answered := func(levels ...string) []models.PlacementQuestion {
+		questions := make([]models.PlacementQuestion, 0, len(levels))
+		for _, level := range levels {
+			questions = append(questions, models.PlacementQuestion{Level: level, Prompt: "Tarea", Answer: "Respuesta", Score: 75})
+		}
+		return questions
+	}
+
+	tests := []struct {
+		name           string
+		questions      []models.PlacementQuestion
+		status         string
+		grade          string
+		expectedCode   int
+		expectedLevel  string
+		expectedResult string
+	}{
+		{
+			name:          "good answer",
+			questions:     []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}},
+			status:        models.PlacementStatusInProgress,
+			grade:         `{"score": 80, "feedback": "Bien."}`,
+			expectedCode:  http.StatusOK,
+			expectedLevel: "B2",
+		},
+		{
+			name:          "poor answer",
+			questions:     []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}},
+			status:        models.PlacementStatusInProgress,
+			grade:         `{"score": 20, "feedback": "Revisa el pasado."}`,
+			expectedCode:  http.StatusOK,
+			expectedLevel: "A2",
+		},
+		{
+			name:           "last answer",
+			questions:      append(answered("B1", "B2", "C1", "B2", "C1"), models.PlacementQuestion{Level: "C2", Prompt: "Tarea"}),
+			status:         models.PlacementStatusInProgress,
+			grade:          `{"score": 30, "feedback": "Casi."}`,
+			expectedCode:   http.StatusOK,
+			expectedResult: "C1",
+		},
+		{
+			name:         "invalid grade",
+			questions:    []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}},
+			status:       models.PlacementStatusInProgress,
+			grade:        "Very good!",
+			expectedCode: http.StatusBadGateway,
+		},
+		{
+			name:         "completed test",
+			questions:    answered("B1", "B2", "C1", "B2", "C1", "C2"),
+			status:       models.PlacementStatusCompleted,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			test := models.PlacementTest{ID: uuid.New(), UserID: user.ID, Language: "es", Status: tt.status, Questions: tt.questions}
+			provider := &gradingProvider{FakeProvider: &llm.FakeProvider{Reply: "Nueva tarea."}, Grade: tt.grade}
+			controller := NewProfileController(provider, unmetered)
+			router.POST("/me/placement-tests/:id/answers", setCurrentUser(user), controller.AnswerPlacementTest)
+
+			mock.ExpectQuery(regexp.QuoteMeta(queryTest)).
+				WithArgs(test.ID, user.ID, 1).
+				WillReturnRows(testRows(test))
+			switch {
+			case tt.expectedCode == http.StatusBadGateway:
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			case tt.expectedCode == http.StatusOK:
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "placement_tests"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				if tt.expectedResult != "" {
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "learner_languages"`)).
+						WithArgs(sqlmock.AnyArg(), user.ID, "es", tt.expectedResult, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/me/placement-tests/"+test.ID.String()+"/answers", models.PlacementAnswerInput{Answer: "Fui al cine con mis amigos."})
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response models.PlacementTestResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "Fui al cine con mis amigos.", response.Answered[len(response.Answered)-1].Answer)
+				if tt.expectedResult != "" {
+					assert.Equal(t, models.PlacementStatusCompleted, response.Status)
+					assert.Equal(t, tt.expectedResult, response.Result)
+					assert.Nil(t, response.Question)
+					assert.NotNil(t, response.CompletedAt)
+				} else {
+					assert.Equal(t, tt.expectedLevel, response.Question.Level)
+					assert.Equal(t, "Nueva tarea.", response.Question.Prompt)
+				}
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAnswerPlacementTestRedactsAddress(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().WhereAddress(sql.NullString{String: "Calle Mayor 5", Valid: true}).Build()
+	test := models.PlacementTest{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Language:  "es",
+		Status:    models.PlacementStatusInProgress,
+		Questions: []models.PlacementQuestion{{Level: "B1", Prompt: "¿Dónde vives?"}},
+	}
+	redactor, err := redaction.NewRedactor([]string{redaction.CategoryAddress}, nil)
+	assert.NoError(t, err)
+	fake := &llm.FakeProvider{Reply: "Nueva tarea."}
+	controller := NewProfileController(redaction.NewProvider(&gradingProvider{FakeProvider: fake, Grade: `{"score": 80, "feedback": "Bien."}`}, redactor), unmetered)
+	router.POST("/me/placement-tests/:id/answers", setCurrentUser(user), controller.AnswerPlacementTest)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryTest)).
+		WithArgs(test.ID, user.ID, 1).
+		WillReturnRows(testRows(test))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "placement_tests"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, err := utils.HttpTestRequest(router, "POST", "/me/placement-tests/"+test.ID.String()+"/answers", models.PlacementAnswerInput{Answer: "Vivo en calle mayor 5."})
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, fake.Requests(), 2)
+	for _, request := range fake.Requests() {
+		for _, message := range request.Messages {
+			assert.NotContains(t, message.Content, "mayor 5")
+		}
+	}
+	assert.Contains(t, fake.Requests()[0].Messages[1].Content, "Vivo en [ADDRESS_1].")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnswerPlacementTestQuotaExceeded(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	test := models.PlacementTest{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Language:  "es",
+		Status:    models.PlacementStatusInProgress,
+		Questions: []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}},
+	}
+	controller := NewProfileController(llm.NewFakeProvider(), metering.NewMeter(&configs.Config{UsageFreeDailyTokens: 100}))
+	router.POST("/me/placement-tests/:id/answers", setCurrentUser(user), controller.AnswerPlacementTest)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryTest)).
+		WithArgs(test.ID, user.ID, 1).
+		WillReturnRows(testRows(test))
+	for range 2 {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "usage_records" WHERE user_id = $1 AND created_at >= $2`)).
+			WillReturnRows(sqlmock.NewRows([]string{"calls", "prompt_tokens", "completion_tokens", "cost"}).AddRow(3, 60, 40, 0.0001))
+	}
+
+	w, err := utils.HttpTestRequest(router, "POST", "/me/placement-tests/"+test.ID.String()+"/answers", models.PlacementAnswerInput{Answer: "Fui al cine."})
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
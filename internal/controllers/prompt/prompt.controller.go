@@ -0,0 +1,408 @@
+package prompt
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/prompts"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PromptController struct{}
+
+func NewPromptController() PromptController {
+	return PromptController{}
+}
+
+// GetPromptTemplates retrieves the versions of the prompt templates.
+// @Summary Get prompt templates
+// @Description Fetches a page of the versions of the prompt templates, optionally filtered by name and status.
+// @Tags prompts
+// @Produce json
+// @Param name query string false "Name"
+// @Param status query string false "Status"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.PromptTemplateResponse
+// @Failure 500 {object} object
+// @Router /prompts [get]
+func (pc *PromptController) GetPromptTemplates(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("name").Order("created_at DESC")
+	if name := context.Query("name"); name != "" {
+		query = query.Where("name = ?", name)
+	}
+	if status := context.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var templates []models.PromptTemplate
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&templates).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.PromptTemplateResponse, 0, len(templates))
+	for _, tmpl := range templates {
+		responses = append(responses, tmpl.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// GetPromptTemplate retrieves a version of a prompt template.
+// @Summary Get a prompt template
+// @Description Fetches a version of a prompt template by UUID.
+// @Tags prompts
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Success 200 {object} models.PromptTemplateResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id} [get]
+func (pc *PromptController) GetPromptTemplate(context *gin.Context) {
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, tmpl.ToResponse())
+}
+
+// CreatePromptTemplate creates a draft version of a prompt template.
+// @Summary Create a prompt template
+// @Description Creates a draft version of a prompt template. The body is a Go template receiving the persona, the
+// @Description learner name, the language of the conversation and the CEFR level of the learner.
+// @Tags prompts
+// @Accept json
+// @Produce json
+// @Param payload body models.PromptTemplateInput true "Prompt Template Data"
+// @Success 201 {object} models.PromptTemplateResponse
+// @Failure 400 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /prompts [post]
+func (pc *PromptController) CreatePromptTemplate(context *gin.Context) {
+	var payload models.PromptTemplateInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := prompts.Check(payload.Body); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var versions int64
+	if err := database.Model(&models.PromptTemplate{}).Where("name = ? AND version = ?", payload.Name, payload.Version).Count(&versions).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if versions > 0 {
+		utils.AbortWithError(context, http.StatusConflict, "This version of the prompt template already exists")
+		return
+	}
+
+	tmpl := models.PromptTemplate{
+		Name:        payload.Name,
+		Version:     payload.Version,
+		Description: payload.Description,
+		Body:        payload.Body,
+		Variables:   payload.Variables,
+		Status:      models.PromptStatusDraft,
+	}
+	if err := database.Create(&tmpl).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, tmpl.ToResponse())
+}
+
+// UpdatePromptTemplate updates a draft version of a prompt template.
+// @Summary Update a prompt template
+// @Description Replaces the body of a draft version. Published versions are frozen, a new version must be created instead.
+// @Tags prompts
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param payload body models.PromptTemplateUpdateInput true "Prompt Template Data"
+// @Success 200 {object} models.PromptTemplateResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id} [put]
+func (pc *PromptController) UpdatePromptTemplate(context *gin.Context) {
+	var payload models.PromptTemplateUpdateInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := prompts.Check(payload.Body); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+	if tmpl.IsPublished() {
+		utils.AbortWithError(context, http.StatusConflict, "Published versions can't be changed")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tmpl.Description = payload.Description
+	tmpl.Body = payload.Body
+	tmpl.Variables = payload.Variables
+	if err := database.Save(&tmpl).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, tmpl.ToResponse())
+}
+
+// PublishPromptTemplate publishes a draft version of a prompt template.
+// @Summary Publish a prompt template
+// @Description Freezes a draft version and serves it to the given percentage of the learners.
+// @Tags prompts
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param payload body models.PromptRolloutInput true "Rollout Data"
+// @Success 200 {object} models.PromptTemplateResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id}/publish [post]
+func (pc *PromptController) PublishPromptTemplate(context *gin.Context) {
+	payload, ok := bindRolloutInput(context)
+	if !ok {
+		return
+	}
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+	if tmpl.IsPublished() {
+		utils.AbortWithError(context, http.StatusConflict, "This version is already published")
+		return
+	}
+
+	tmpl.Status = models.PromptStatusPublished
+	tmpl.PublishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	tmpl.Rollout = *payload.Rollout
+	saveRollout(context, tmpl)
+}
+
+// SetPromptRollout changes the share of the learners served with a published version of a prompt template.
+// @Summary Roll out a prompt template
+// @Description Changes the percentage of the learners served with a published version. Learners keep their
+// @Description version when the rollout grows, a rollout of 0 only serves the pen pals pinned to the version.
+// @Tags prompts
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param payload body models.PromptRolloutInput true "Rollout Data"
+// @Success 200 {object} models.PromptTemplateResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id}/rollout [put]
+func (pc *PromptController) SetPromptRollout(context *gin.Context) {
+	payload, ok := bindRolloutInput(context)
+	if !ok {
+		return
+	}
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+	if !tmpl.IsPublished() {
+		utils.AbortWithError(context, http.StatusConflict, "Only published versions can be rolled out")
+		return
+	}
+
+	tmpl.Rollout = *payload.Rollout
+	saveRollout(context, tmpl)
+}
+
+// DeletePromptTemplate deletes a draft version of a prompt template.
+// @Summary Delete a prompt template
+// @Description Deletes a draft version. Published versions may be served or pinned and can't be deleted, their rollout can be set to 0 instead.
+// @Tags prompts
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id} [delete]
+func (pc *PromptController) DeletePromptTemplate(context *gin.Context) {
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+	if tmpl.IsPublished() {
+		utils.AbortWithError(context, http.StatusConflict, "Published versions can't be deleted")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := database.Delete(&tmpl).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// PreviewPromptTemplate renders a version of a prompt template for a learner.
+// @Summary Preview a prompt template
+// @Description Renders a version, even a draft, with the variables of the given learner, pen pal and language.
+// @Tags prompts
+// @Accept json
+// @Produce json
+// @Param id path string true "Prompt template ID"
+// @Param payload body models.PromptPreviewInput true "Preview Data"
+// @Success 200 {object} models.PromptPreviewResponse
+// @Failure 400 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /prompts/{id}/preview [post]
+func (pc *PromptController) PreviewPromptTemplate(context *gin.Context) {
+	var payload models.PromptPreviewInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tmpl, ok := findPromptTemplate(context)
+	if !ok {
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.Where("id = ?", payload.UserID).First(&user).Error; err != nil {
+		abortNotFound(context, err, "Can't found user")
+		return
+	}
+	data := prompts.Data{LearnerName: user.FirstName, Language: payload.Language}
+	if payload.PersonaID != nil {
+		if err := database.Where("id = ?", *payload.PersonaID).First(&data.Persona).Error; err != nil {
+			abortNotFound(context, err, "Can't found persona")
+			return
+		}
+	}
+
+	prompt, err := prompts.Render(tmpl.Body, tmpl.Variables, data)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, models.PromptPreviewResponse{Name: tmpl.Name, Version: tmpl.Version, Prompt: prompt})
+}
+
+// bindRolloutInput binds and validates the rollout in the body of the request, aborting the request when it is not valid.
+func bindRolloutInput(context *gin.Context) (models.PromptRolloutInput, bool) {
+	var payload models.PromptRolloutInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return payload, false
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return payload, false
+	}
+	return payload, true
+}
+
+// saveRollout stores the status and the rollout of a version and sends it back.
+func saveRollout(context *gin.Context, tmpl models.PromptTemplate) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := database.Save(&tmpl).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, tmpl.ToResponse())
+}
+
+// findPromptTemplate loads the version identified by the `id` path parameter, aborting the request when it cannot be found.
+func findPromptTemplate(context *gin.Context) (models.PromptTemplate, bool) {
+	var tmpl models.PromptTemplate
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return tmpl, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return tmpl, false
+	}
+	if err := database.Where("id = ?", id).First(&tmpl).Error; err != nil {
+		abortNotFound(context, err, "Can't found prompt template")
+		return tmpl, false
+	}
+	return tmpl, true
+}
+
+// abortNotFound aborts the request with a 404 when err reports a missing record, and with a 500 otherwise.
+func abortNotFound(context *gin.Context, err error, message string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.AbortWithError(context, http.StatusNotFound, message)
+	} else {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,396 @@
+package prompt
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var promptController = NewPromptController()
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+const queryPromptTemplate = `SELECT * FROM "prompt_templates" WHERE id = $1 ORDER BY "prompt_templates"."id" LIMIT $2`
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func newPromptTemplate(status string) models.PromptTemplate {
+	tmpl := models.PromptTemplate{
+		ID:      uuid.New(),
+		Name:    "pen-pal",
+		Version: "1.0.0",
+		Body:    "You are {{.Persona.Name}}, writing to {{.LearnerName}} in {{.Language}}.",
+		Status:  status,
+	}
+	if status == models.PromptStatusPublished {
+		tmpl.Rollout = 100
+		tmpl.PublishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return tmpl
+}
+
+func expectPromptTemplate(tmpl models.PromptTemplate) {
+	mock.ExpectQuery(regexp.QuoteMeta(queryPromptTemplate)).
+		WithArgs(tmpl.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.PromptTemplate{tmpl}))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetPromptTemplates(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	router.GET("/prompts", promptController.GetPromptTemplates)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "prompt_templates" WHERE name = $1 AND status = $2 ORDER BY name,created_at DESC LIMIT $3`)).
+		WithArgs("pen-pal", models.PromptStatusDraft, 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.PromptTemplate{newPromptTemplate(models.PromptStatusDraft)}))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/prompts?name=pen-pal&status=draft", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        models.PromptTemplateInput
+		versions     int
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.PromptTemplateInput{Name: "pen-pal", Version: "1.1.0", Body: "Write to {{.LearnerName}}.", Variables: []string{"LearnerName"}},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "existing version",
+			input:        models.PromptTemplateInput{Name: "pen-pal", Version: "1.0.0", Body: "Write to {{.LearnerName}}."},
+			versions:     1,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "unknown variable in the body",
+			input:        models.PromptTemplateInput{Name: "pen-pal", Version: "1.1.0", Body: "Write to {{.Learner}}."},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid version",
+			input:        models.PromptTemplateInput{Name: "pen-pal", Version: "v1", Body: "Write to {{.LearnerName}}."},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/prompts", promptController.CreatePromptTemplate)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "prompt_templates" WHERE name = $1 AND version = $2`)).
+					WithArgs(tt.input.Name, tt.input.Version).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.versions))
+			}
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "prompt_templates"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/prompts", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusCreated {
+				var response models.PromptTemplateResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, models.PromptStatusDraft, response.Status)
+				assert.Equal(t, tt.input.Variables, response.Variables)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUpdatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		tmpl         models.PromptTemplate
+		expectedCode int
+	}{
+		{
+			name:         "draft version",
+			tmpl:         newPromptTemplate(models.PromptStatusDraft),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "published version",
+			tmpl:         newPromptTemplate(models.PromptStatusPublished),
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT("/prompts/:id", promptController.UpdatePromptTemplate)
+
+			expectPromptTemplate(tt.tmpl)
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "prompt_templates"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			input := models.PromptTemplateUpdateInput{Body: "Write to {{.LearnerName}} at level {{.Level}}.", Variables: []string{"Level"}}
+			w, err := utils.HttpTestRequest(router, "PUT", "/prompts/"+tt.tmpl.ID.String(), input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPublishPromptTemplate(t *testing.T) {
+	rollout := 10
+
+	tests := []struct {
+		name         string
+		tmpl         models.PromptTemplate
+		input        models.PromptRolloutInput
+		expectedCode int
+	}{
+		{
+			name:         "draft version",
+			tmpl:         newPromptTemplate(models.PromptStatusDraft),
+			input:        models.PromptRolloutInput{Rollout: &rollout},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "published version",
+			tmpl:         newPromptTemplate(models.PromptStatusPublished),
+			input:        models.PromptRolloutInput{Rollout: &rollout},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "missing rollout",
+			tmpl:         newPromptTemplate(models.PromptStatusDraft),
+			input:        models.PromptRolloutInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/prompts/:id/publish", promptController.PublishPromptTemplate)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				expectPromptTemplate(tt.tmpl)
+			}
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "prompt_templates"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/prompts/"+tt.tmpl.ID.String()+"/publish", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.PromptTemplateResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, models.PromptStatusPublished, response.Status)
+				assert.Equal(t, rollout, response.Rollout)
+				assert.NotNil(t, response.PublishedAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSetPromptRollout(t *testing.T) {
+	rollout := 50
+
+	tests := []struct {
+		name         string
+		tmpl         models.PromptTemplate
+		expectedCode int
+	}{
+		{
+			name:         "published version",
+			tmpl:         newPromptTemplate(models.PromptStatusPublished),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "draft version",
+			tmpl:         newPromptTemplate(models.PromptStatusDraft),
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.PUT("/prompts/:id/rollout", promptController.SetPromptRollout)
+
+			expectPromptTemplate(tt.tmpl)
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "prompt_templates"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "PUT", "/prompts/"+tt.tmpl.ID.String()+"/rollout", models.PromptRolloutInput{Rollout: &rollout})
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeletePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		tmpl         models.PromptTemplate
+		expectedCode int
+	}{
+		{
+			name:         "draft version",
+			tmpl:         newPromptTemplate(models.PromptStatusDraft),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "published version",
+			tmpl:         newPromptTemplate(models.PromptStatusPublished),
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/prompts/:id", promptController.DeletePromptTemplate)
+
+			expectPromptTemplate(tt.tmpl)
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "prompt_templates" WHERE "prompt_templates"."id" = $1`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/prompts/"+tt.tmpl.ID.String(), nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPreviewPromptTemplate(t *testing.T) {
+	tmpl := newPromptTemplate(models.PromptStatusDraft)
+	user := builders.NewUserBuilder().Build()
+	persona := models.Persona{ID: uuid.New(), Name: "Lucía", NativeLanguage: "es"}
+
+	tests := []struct {
+		name           string
+		input          models.PromptPreviewInput
+		users          []models.User
+		expectedCode   int
+		expectedPrompt string
+	}{
+		{
+			name:           "existing user and persona",
+			input:          models.PromptPreviewInput{UserID: user.ID, PersonaID: &persona.ID, Language: "es"},
+			users:          []models.User{user},
+			expectedCode:   http.StatusOK,
+			expectedPrompt: "You are Lucía, writing to " + user.FirstName + " in es.",
+		},
+		{
+			name:         "unknown user",
+			input:        models.PromptPreviewInput{UserID: user.ID, Language: "es"},
+			users:        []models.User{},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid language",
+			input:        models.PromptPreviewInput{UserID: user.ID, Language: "Spanish"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/prompts/:id/preview", promptController.PreviewPromptTemplate)
+
+			if tt.users != nil {
+				expectPromptTemplate(tmpl)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.users))
+			}
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1`)).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/prompts/"+tmpl.ID.String()+"/preview", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response models.PromptPreviewResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectedPrompt, response.Prompt)
+				assert.Equal(t, tmpl.Version, response.Version)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,473 @@
+package share
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// shareTokenSize is the number of random bytes of a share token.
+const shareTokenSize = 32
+
+// Statuses of the share links listed to administrators.
+const (
+	shareStatusActive    = "active"     // shareStatusActive is a link which can be opened, unless it expired.
+	shareStatusRevoked   = "revoked"    // shareStatusRevoked is a link revoked by its learner.
+	shareStatusTakenDown = "taken_down" // shareStatusTakenDown is a link taken down by an administrator.
+)
+
+type ShareController struct {
+	ages     agegate.Policy
+	scrubber *redaction.Redactor
+}
+
+func NewShareController(ages agegate.Policy) (ShareController, error) {
+	scrubber, err := redaction.NewRedactor(redaction.Categories, nil)
+	if err != nil {
+		return ShareController{}, err
+	}
+	return ShareController{ages: ages, scrubber: scrubber}, nil
+}
+
+// CreateShare creates a public link to a conversation of the current user.
+// @Summary Share a conversation
+// @Description Copies the active branch of a conversation of the current user and returns an unguessable public link
+// @Description to the copy, only returned once. The link can expire after a number of hours, and the emails, phone
+// @Description numbers, bank details and address of the learner can be removed from the copy, along with the
+// @Description corrections of the scrubbed messages. Under-age accounts cannot share their conversations.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param payload body models.ShareInput true "Share Options"
+// @Success 201 {object} models.ShareResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 403 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/shares [post]
+func (sc *ShareController) CreateShare(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	if sc.ages.Restrictions(currentUser.Birthday, time.Now()).PublicSharingDisabled {
+		utils.AbortWithError(context, http.StatusForbidden, "Public sharing is disabled on your account")
+		return
+	}
+
+	var payload models.ShareInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := findConversation(context, currentUser)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	config, err := configs.LoadConfig()
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, "Configuration error")
+		return
+	}
+
+	share, err := sc.snapshot(database, *currentUser, conversation, payload.ScrubPII)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if payload.ExpiresInHours > 0 {
+		share.ExpiresAt = sql.NullTime{Time: time.Now().Add(time.Duration(payload.ExpiresInHours) * time.Hour), Valid: true}
+	}
+	token, err := utils.GenerateRandomToken(shareTokenSize)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	share.TokenHash = utils.HashToken(token)
+	if err := database.Create(&share).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := share.ToResponse()
+	response.URL = config.AppBaseURL + "/shared/" + token
+	utils.SendSuccess(context, http.StatusCreated, response)
+}
+
+// GetShares retrieves the links to a conversation of the current user.
+// @Summary Get the links to a conversation
+// @Description Fetches the public links to a conversation of the current user, newest first, revoked and expired ones
+// @Description included. The links themselves are not returned.
+// @Tags shares
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {array} models.ShareResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /conversations/{id}/shares [get]
+func (sc *ShareController) GetShares(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	conversation, ok := findConversation(context, currentUser)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var shares []models.Share
+	if err := database.Where("conversation_id = ?", conversation.ID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, toResponses(shares))
+}
+
+// RevokeShare revokes a link of the current user.
+// @Summary Revoke a link
+// @Description Revokes a public link to a conversation of the current user: opening it fails from now on.
+// @Tags shares
+// @Produce json
+// @Param id path string true "Share ID"
+// @Success 200 {object} models.ShareResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /shares/{id} [delete]
+func (sc *ShareController) RevokeShare(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	share, ok := findShare(context, &currentUser.ID)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if !share.RevokedAt.Valid {
+		share.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		if err := database.Model(&share).UpdateColumn("revoked_at", share.RevokedAt).Error; err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	utils.SendSuccess(context, http.StatusOK, share.ToResponse())
+}
+
+// GetSharedConversation retrieves a shared conversation from its public link.
+// @Summary Get a shared conversation
+// @Description Fetches the copy of a conversation behind a public link, without logging in, and counts the view.
+// @Description Revoked, expired and taken down links are not found.
+// @Tags shares
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.SharedConversationResponse
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /shared/{token} [get]
+func (sc *ShareController) GetSharedConversation(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var share models.Share
+	now := time.Now()
+	err = database.Where("token_hash = ?", utils.HashToken(context.Param("token"))).First(&share).Error
+	if err == nil && !share.IsActive(now) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found shared conversation")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	err = database.Model(&share).UpdateColumns(map[string]interface{}{
+		"views":          gorm.Expr("views + ?", 1),
+		"last_viewed_at": now,
+	}).Error
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	share.Views++
+
+	context.Header("Cache-Control", "no-store")
+	context.Header("X-Robots-Tag", "noindex")
+	utils.SendSuccess(context, http.StatusOK, share.ToPublicResponse())
+}
+
+// GetAllShares retrieves the public links of every learner.
+// @Summary Get all share links
+// @Description Fetches a page of the public links, newest first, optionally filtered by status and by learner.
+// @Tags shares
+// @Produce json
+// @Param status query string false "Status, either active, revoked or taken_down"
+// @Param user_id query string false "Learner ID"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.ShareResponse
+// @Failure 400 {object} object
+// @Failure 500 {object} object
+// @Router /shares [get]
+func (sc *ShareController) GetAllShares(context *gin.Context) {
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Order("created_at DESC")
+	if status := context.Query("status"); status != "" {
+		if !slices.Contains([]string{shareStatusActive, shareStatusRevoked, shareStatusTakenDown}, status) {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid status")
+			return
+		}
+		switch status {
+		case shareStatusActive:
+			query = query.Where("revoked_at IS NULL AND taken_down_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now())
+		case shareStatusRevoked:
+			query = query.Where("revoked_at IS NOT NULL")
+		case shareStatusTakenDown:
+			query = query.Where("taken_down_at IS NOT NULL")
+		}
+	}
+	if userID := context.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+			return
+		}
+		query = query.Where("user_id = ?", id)
+	}
+
+	var shares []models.Share
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&shares).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, toResponses(shares))
+}
+
+// TakeDownShare takes a public link down.
+// @Summary Take a share link down
+// @Description Disables a public link reported as abusive, recording the administrator and the reason. The learner
+// @Description cannot enable it again.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param id path string true "Share ID"
+// @Param payload body models.ShareTakedownInput true "Takedown Data"
+// @Success 200 {object} models.ShareResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /shares/{id}/takedown [post]
+func (sc *ShareController) TakeDownShare(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+
+	var payload models.ShareTakedownInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	share, ok := findShare(context, nil)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	share.TakenDownAt = sql.NullTime{Time: time.Now(), Valid: true}
+	share.TakenDownBy = uuid.NullUUID{UUID: currentUser.ID, Valid: true}
+	share.TakedownReason = payload.Reason
+	if err := database.Model(&share).Select("taken_down_at", "taken_down_by", "takedown_reason").Updates(&share).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, share.ToResponse())
+}
+
+// snapshot returns the share of the active branch of the conversation of the user, the personal data of the messages
+// removed if scrubbed is set. The corrections of the messages changed by the scrubbing are dropped, as their offsets
+// no longer match the text.
+func (sc *ShareController) snapshot(db *gorm.DB, user models.User, conversation models.Conversation, scrubbed bool) (models.Share, error) {
+	share := models.Share{
+		ConversationID: conversation.ID,
+		UserID:         user.ID,
+		Title:          conversation.Title,
+		Language:       conversation.Language,
+		PenPal:         "Pen pal",
+		Scrubbed:       scrubbed,
+		Messages:       []models.SharedMessage{},
+	}
+	if conversation.PersonaID.Valid {
+		var persona models.Persona
+		err := db.Where("id = ?", conversation.PersonaID.UUID).First(&persona).Error
+		if err == nil {
+			share.PenPal = persona.Name
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return share, err
+		}
+	}
+
+	var messages []models.Message
+	if err := db.Where("conversation_id = ?", conversation.ID).Order("created_at").Find(&messages).Error; err != nil {
+		return share, err
+	}
+	if conversation.ActiveLeafID.Valid {
+		messages = chat.Path(messages, conversation.ActiveLeafID.UUID)
+	}
+
+	vault := redaction.NewVault()
+	scrub := func(text string) string {
+		if !scrubbed {
+			return text
+		}
+		return sc.scrubber.Redact(vault, text, user.Address.String)
+	}
+	share.Title = scrub(share.Title)
+	for _, message := range messages {
+		shared := models.SharedMessage{Role: message.Role, Content: scrub(message.Content), CreatedAt: message.CreatedAt}
+		if shared.Content == message.Content {
+			shared.Corrections = message.Corrections
+		}
+		share.Messages = append(share.Messages, shared)
+	}
+	return share, nil
+}
+
+// toResponses converts the shares into their API representation.
+func toResponses(shares []models.Share) []models.ShareResponse {
+	responses := make([]models.ShareResponse, 0, len(shares))
+	for _, share := range shares {
+		responses = append(responses, share.ToResponse())
+	}
+	return responses
+}
+
+// getCurrentUser returns the logged in user, aborting the request if there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
+
+// findConversation loads the conversation of the id path parameter, aborting the request if it doesn't exist or
+// belongs to another user.
+func findConversation(context *gin.Context, currentUser *models.User) (models.Conversation, bool) {
+	var conversation models.Conversation
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return conversation, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return conversation, false
+	}
+	conversation, err = chat.FindConversation(database, currentUser.ID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found conversation")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return conversation, false
+	}
+	return conversation, true
+}
+
+// findShare loads the share of the id path parameter, aborting the request if it doesn't exist or, when userID is
+// set, belongs to another user.
+func findShare(context *gin.Context, userID *uuid.UUID) (models.Share, bool) {
+	var share models.Share
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return share, false
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return share, false
+	}
+	query := database.Where("id = ?", id)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if err := query.First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found share")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return share, false
+	}
+	return share, true
+}
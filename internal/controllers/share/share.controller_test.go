@@ -0,0 +1,449 @@
+package share
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var shareController ShareController
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+const queryConversation = `SELECT * FROM "conversations" WHERE id = $1 AND user_id = $2 ORDER BY "conversations"."id" LIMIT $3`
+const queryMessages = `SELECT * FROM "messages" WHERE conversation_id = $1 ORDER BY created_at`
+const queryShare = `SELECT * FROM "shares" WHERE id = $1 ORDER BY "shares"."id" LIMIT $2`
+const queryOwnShare = `SELECT * FROM "shares" WHERE id = $1 AND user_id = $2 ORDER BY "shares"."id" LIMIT $3`
+const queryToken = `SELECT * FROM "shares" WHERE token_hash = $1 ORDER BY "shares"."id" LIMIT $2`
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func setCurrentUser(user models.User) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}
+}
+
+func TestMain(m *testing.M) {
+	var err error
+	if shareController, err = NewShareController(agegate.Policy{MinimumAge: 13, ConsentAge: 16}); err != nil {
+		log.Fatal(err)
+	}
+	m.Run()
+}
+
+// shareRows returns the rows of the share, with its messages serialized.
+func shareRows(share models.Share) *sqlmock.Rows {
+	messages, _ := json.Marshal(share.Messages)
+	return sqlmock.NewRows([]string{"id", "conversation_id", "user_id", "token_hash", "title", "language", "pen_pal", "messages", "expires_at", "revoked_at", "taken_down_at", "views", "created_at"}).
+		AddRow(share.ID, share.ConversationID, share.UserID, share.TokenHash, share.Title, share.Language, share.PenPal, messages, share.ExpiresAt, share.RevokedAt, share.TakenDownAt, share.Views, share.CreatedAt)
+}
+
+func TestCreateShare(t *testing.T) {
+	adult := builders.NewUserBuilder().Build()
+	minor := builders.NewUserBuilder().WhereBirthday(time.Now().AddDate(-14, 0, 0)).Build()
+
+	tests := []struct {
+		name         string
+		user         models.User
+		body         interface{}
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			user:         adult,
+			body:         models.ShareInput{ExpiresInHours: 24},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "invalid expiry",
+			user:         adult,
+			body:         models.ShareInput{ExpiresInHours: -1},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "under-age account",
+			user:         minor,
+			body:         models.ShareInput{},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/conversations/:id/shares", setCurrentUser(tt.user), shareController.CreateShare)
+			conversation := models.Conversation{ID: uuid.New(), UserID: tt.user.ID, Language: "es", Title: "En el mercado"}
+
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+					WithArgs(conversation.ID, tt.user.ID, 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+				mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+					WithArgs(conversation.ID).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{
+						{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Hola"},
+					}))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "shares"`)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/conversations/"+conversation.ID.String()+"/shares", tt.body)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusCreated {
+				var response models.ShareResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Contains(t, response.URL, "/shared/")
+				assert.True(t, response.Active)
+				assert.NotNil(t, response.ExpiresAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	user.Address = sql.NullString{String: "12 rue de la Paix", Valid: true}
+	persona := models.Persona{ID: uuid.New(), Name: "Lucía"}
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "Mi correo", PersonaID: uuid.NullUUID{UUID: persona.ID, Valid: true}}
+	corrections := []models.Correction{{Start: 0, End: 4, Original: "Hola", Suggestion: "¡Hola!", Category: "punctuation"}}
+	messages := []models.Message{
+		{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Hola, vivo en 12 rue de la Paix", Corrections: corrections},
+		{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleAssistant, Content: "Escríbeme a lucia@mail.es"},
+		{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Hola otra vez", Corrections: corrections},
+	}
+	for range 2 {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "personas" WHERE id = $1 ORDER BY "personas"."id" LIMIT $2`)).
+			WithArgs(persona.ID, 1).
+			WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+		mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+			WithArgs(conversation.ID).
+			WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+	}
+
+	share, err := shareController.snapshot(database, user, conversation, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lucía", share.PenPal)
+	assert.False(t, share.Scrubbed)
+	assert.Equal(t, "Escríbeme a lucia@mail.es", share.Messages[1].Content)
+
+	share, err = shareController.snapshot(database, user, conversation, true)
+	assert.NoError(t, err)
+	assert.True(t, share.Scrubbed)
+	if assert.Len(t, share.Messages, 3) {
+		assert.Equal(t, "Hola, vivo en [ADDRESS_1]", share.Messages[0].Content)
+		assert.Empty(t, share.Messages[0].Corrections)
+		assert.Equal(t, "Escríbeme a [EMAIL_1]", share.Messages[1].Content)
+		assert.Equal(t, "Hola otra vez", share.Messages[2].Content)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetShares(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "En el mercado"}
+	share := models.Share{ID: uuid.New(), ConversationID: conversation.ID, UserID: user.ID, TokenHash: "hash", Title: conversation.Title, Views: 2}
+	router.GET("/conversations/:id/shares", setCurrentUser(user), shareController.GetShares)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+		WithArgs(conversation.ID, user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "shares" WHERE conversation_id = $1 ORDER BY created_at DESC`)).
+		WithArgs(conversation.ID).
+		WillReturnRows(shareRows(share))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/conversations/"+conversation.ID.String()+"/shares", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "hash")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeShare(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	share := models.Share{ID: uuid.New(), ConversationID: uuid.New(), UserID: user.ID, TokenHash: "hash"}
+
+	tests := []struct {
+		name         string
+		found        bool
+		expectedCode int
+	}{
+		{
+			name:         "own share",
+			found:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "share of another user",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.DELETE("/shares/:id", setCurrentUser(user), shareController.RevokeShare)
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(queryOwnShare)).WithArgs(share.ID, user.ID, 1)
+			if tt.found {
+				query.WillReturnRows(shareRows(share))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "shares" SET "revoked_at"=$1 WHERE "id" = $2`)).
+					WithArgs(sqlmock.AnyArg(), share.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				query.WillReturnError(gorm.ErrRecordNotFound)
+			}
+
+			w, err := utils.HttpTestRequest(router, "DELETE", "/shares/"+share.ID.String(), nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.found {
+				assert.Contains(t, w.Body.String(), `"active":false`)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetSharedConversation(t *testing.T) {
+	now := time.Now()
+	token := "s3cr3t-t0k3n"
+	share := models.Share{
+		ID: uuid.New(), ConversationID: uuid.New(), UserID: uuid.New(), TokenHash: utils.HashToken(token),
+		Title: "En el mercado", Language: "es", PenPal: "Lucía", Views: 4,
+		Messages: []models.SharedMessage{{Role: models.MessageRoleUser, Content: "Hola"}},
+	}
+
+	tests := []struct {
+		name         string
+		share        *models.Share
+		expectedCode int
+	}{
+		{
+			name:         "active link",
+			share:        &share,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown link",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "expired link",
+			share: func() *models.Share {
+				expired := share
+				expired.ExpiresAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+				return &expired
+			}(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "revoked link",
+			share: func() *models.Share {
+				revoked := share
+				revoked.RevokedAt = sql.NullTime{Time: now, Valid: true}
+				return &revoked
+			}(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "taken down link",
+			share: func() *models.Share {
+				takenDown := share
+				takenDown.TakenDownAt = sql.NullTime{Time: now, Valid: true}
+				return &takenDown
+			}(),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET("/shared/:token", shareController.GetSharedConversation)
+
+			query := mock.ExpectQuery(regexp.QuoteMeta(queryToken)).WithArgs(utils.HashToken(token), 1)
+			if tt.share != nil {
+				query.WillReturnRows(shareRows(*tt.share))
+			} else {
+				query.WillReturnError(gorm.ErrRecordNotFound)
+			}
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "shares" SET "last_viewed_at"=$1,"views"=views + $2 WHERE "id" = $3`)).
+					WithArgs(sqlmock.AnyArg(), 1, share.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", "/shared/"+token, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response models.SharedConversationResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, int64(5), response.Views)
+				assert.Equal(t, share.Messages, response.Messages)
+				assert.Equal(t, "noindex", w.Header().Get("X-Robots-Tag"))
+				assert.False(t, strings.Contains(w.Body.String(), share.UserID.String()))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGetAllShares(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name         string
+		query        string
+		sql          string
+		expectedCode int
+	}{
+		{
+			name:         "all shares",
+			query:        "",
+			sql:          `SELECT \* FROM "shares" ORDER BY created_at DESC LIMIT \$1$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "taken down shares of a learner",
+			query:        "?status=taken_down&user_id=" + userID.String(),
+			sql:          `SELECT \* FROM "shares" WHERE taken_down_at IS NOT NULL AND user_id = \$1 ORDER BY created_at DESC LIMIT \$2$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "active shares",
+			query:        "?status=active",
+			sql:          `SELECT \* FROM "shares" WHERE revoked_at IS NULL AND taken_down_at IS NULL AND \(expires_at IS NULL OR expires_at > \$1\) ORDER BY created_at DESC LIMIT \$2$`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid status",
+			query:        "?status=expired",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid user id",
+			query:        "?user_id=42",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET("/shares", shareController.GetAllShares)
+
+			if tt.sql != "" {
+				mock.ExpectQuery(tt.sql).WillReturnRows(shareRows(models.Share{ID: uuid.New(), UserID: userID}))
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", "/shares"+tt.query, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTakeDownShare(t *testing.T) {
+	admin := builders.NewUserBuilder().WhereRole("admin").Build()
+	share := models.Share{ID: uuid.New(), ConversationID: uuid.New(), UserID: uuid.New(), TokenHash: "hash"}
+
+	tests := []struct {
+		name         string
+		input        models.ShareTakedownInput
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.ShareTakedownInput{Reason: "Harassment"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "No reason",
+			input:        models.ShareTakedownInput{},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/shares/:id/takedown", setCurrentUser(admin), shareController.TakeDownShare)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(regexp.QuoteMeta(queryShare)).
+					WithArgs(share.ID, 1).
+					WillReturnRows(shareRows(share))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "shares" SET "taken_down_at"=$1,"taken_down_by"=$2,"takedown_reason"=$3 WHERE "id" = $4`)).
+					WithArgs(sqlmock.AnyArg(), admin.ID, tt.input.Reason, share.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/shares/"+share.ID.String()+"/takedown", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"takedown_reason":"Harassment"`)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package socket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/realtime"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SocketController struct {
+	chat *chat.Service
+	hub  *realtime.Hub
+}
+
+func NewSocketController(chatService *chat.Service, hub *realtime.Hub) SocketController {
+	return SocketController{chat: chatService, hub: hub}
+}
+
+// CreateTicket issues a ticket to open a WebSocket connection.
+// @Summary Get a WebSocket ticket
+// @Description Issues a short-lived, single-use ticket authenticating a WebSocket connection, as browsers can't send an Authorization header when opening one.
+// @Tags realtime
+// @Produce json
+// @Success 201 {object} object
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /ws/tickets [post]
+func (sc *SocketController) CreateTicket(context *gin.Context) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return
+	}
+	currentUser := obj.(*models.User)
+
+	ticket, expiresAt, err := sc.hub.Tickets().Issue(currentUser.ID)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, gin.H{"ticket": ticket, "expires_at": expiresAt})
+}
+
+// Connect opens a WebSocket connection to chat in real time.
+// @Summary Open a WebSocket connection
+// @Description Opens a WebSocket connection authenticated with a ticket. Events are JSON documents carrying the
+// @Description protocol version "v" and a "type". Clients send "message.send", "generation.cancel" and "ping" events,
+// @Description the server answers with "welcome", "typing", "message.delta", "message.done", "generation.cancelled",
+// @Description "pong" and "error" events. In letters mode, a "message.send" event is answered with a "letter.scheduled"
+// @Description event, and a "letter.delivered" event is pushed once the reply of the pen pal is delivered.
+// @Tags realtime
+// @Param ticket query string true "Ticket"
+// @Success 101
+// @Failure 401 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Router /ws [get]
+func (sc *SocketController) Connect(context *gin.Context) {
+	userID, err := sc.hub.Tickets().Redeem(context.Query("ticket"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusUnauthorized, "The ticket is not valid")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var user models.User
+	if err := database.First(&user, "id = ?", userID).Error; err != nil {
+		utils.AbortWithError(context, http.StatusUnauthorized, "the user belonging to this ticket no longer exists")
+		return
+	}
+
+	if !sc.hub.Acquire(user.ID) {
+		utils.AbortWithError(context, http.StatusTooManyRequests, "Too many connections are open")
+		return
+	}
+	defer sc.hub.Release(user.ID)
+
+	conn, err := sc.hub.Upgrade(context.Writer, context.Request)
+	if err != nil {
+		return
+	}
+	sc.hub.Attach(user.ID, conn)
+	defer sc.hub.Detach(user.ID, conn)
+	limits := sc.hub.Limits()
+	welcome := realtime.NewEvent(realtime.TypeWelcome)
+	welcome.Data = realtime.WelcomeData{
+		MaxMessageSize:    limits.MaxMessageSize,
+		MessagesPerMinute: limits.MessagesPerMinute,
+		PingInterval:      int(limits.PingInterval.Seconds()),
+	}
+	_ = conn.Send(welcome)
+
+	s := &session{chat: sc.chat, database: database, user: user, conn: conn}
+	conn.Run(s.handle)
+	s.stop()
+}
+
+// session is the state of a WebSocket connection. A single reply can be generated at a time.
+type session struct {
+	chat     *chat.Service
+	database *gorm.DB
+	user     models.User
+	conn     *realtime.Conn
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// handle answers an event sent by the client.
+func (s *session) handle(event realtime.Event) {
+	switch event.Type {
+	case realtime.TypeSend:
+		s.send(event)
+	case realtime.TypeCancel:
+		s.mu.Lock()
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.mu.Unlock()
+	default:
+		_ = s.conn.Send(realtime.NewError(event, realtime.CodeInvalidEvent, "The event type is not supported"))
+	}
+}
+
+// send starts generating the reply to a message in the background, or schedules it in letters mode.
+func (s *session) send(event realtime.Event) {
+	if event.ConversationID == nil {
+		_ = s.conn.Send(realtime.NewError(event, realtime.CodeInvalidEvent, "conversation_id: cannot be blank."))
+		return
+	}
+	if err := (models.MessageInput{Content: event.Content}).Validate(); err != nil {
+		_ = s.conn.Send(realtime.NewError(event, realtime.CodeInvalidEvent, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		_ = s.conn.Send(realtime.NewError(event, realtime.CodeBusy, "A reply is already being generated"))
+		return
+	}
+
+	conversation, err := chat.FindConversation(s.database, s.user.ID, *event.ConversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodeNotFound, "Can't found conversation"))
+		} else {
+			s.sendInternalError(event, err)
+		}
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exchange, err := s.chat.Prepare(ctx, s.database, s.user, conversation, event.Content)
+	if err != nil {
+		cancel()
+		var quotaErr *metering.QuotaError
+		if errors.Is(err, chat.ErrArchived) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodeArchived, "The conversation is archived"))
+		} else if errors.Is(err, chat.ErrPersonaUnavailable) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodePersonaUnavailable, "The pen pal is no longer available"))
+		} else if errors.Is(err, chat.ErrBlocked) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodeBlocked, "Your message was blocked by moderation"))
+		} else if errors.Is(err, chat.ErrRefused) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodeRefused, "Your message tries to change the instructions of the pen pal"))
+		} else if errors.As(err, &quotaErr) {
+			message := fmt.Sprintf("Your token quota for the %s is exhausted", quotaErr.Period)
+			_ = s.conn.Send(realtime.NewErrorWithDetails(event, realtime.CodeQuotaExceeded, message, quotaErr))
+		} else {
+			s.sendInternalError(event, err)
+		}
+		return
+	}
+
+	if conversation.Mode == models.ConversationModeLetters {
+		cancel()
+		s.post(event, exchange)
+		return
+	}
+
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.reply(ctx, event, exchange)
+}
+
+// post stores a message sent in letters mode and schedules the reply of the pen pal.
+func (s *session) post(event realtime.Event, exchange *chat.Exchange) {
+	response, err := s.chat.Post(s.database, exchange)
+	if err != nil {
+		if errors.Is(err, chat.ErrLetterPending) {
+			_ = s.conn.Send(realtime.NewError(event, realtime.CodeLetterPending, "The pen pal has not answered your previous letter yet"))
+		} else {
+			s.sendInternalError(event, err)
+		}
+		return
+	}
+
+	scheduled := realtime.NewEvent(realtime.TypeScheduled)
+	scheduled.ID = event.ID
+	scheduled.ConversationID = event.ConversationID
+	scheduled.Data = response
+	_ = s.conn.Send(scheduled)
+}
+
+// reply generates the reply to a message and sends it piece by piece.
+func (s *session) reply(ctx context.Context, event realtime.Event, exchange *chat.Exchange) {
+	defer func() {
+		s.mu.Lock()
+		s.cancel()
+		s.cancel = nil
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	s.sendTyping(event, true)
+	response, err := s.chat.StreamReply(ctx, s.database, exchange, func(delta string) error {
+		answer := realtime.NewEvent(realtime.TypeDelta)
+		answer.ID = event.ID
+		answer.ConversationID = event.ConversationID
+		answer.Content = delta
+		return s.conn.Send(answer)
+	})
+	s.sendTyping(event, false)
+
+	switch {
+	case err == nil:
+		done := realtime.NewEvent(realtime.TypeDone)
+		done.ID = event.ID
+		done.ConversationID = event.ConversationID
+		done.Data = response
+		_ = s.conn.Send(done)
+	case ctx.Err() != nil:
+		cancelled := realtime.NewEvent(realtime.TypeCancelled)
+		cancelled.ID = event.ID
+		cancelled.ConversationID = event.ConversationID
+		_ = s.conn.Send(cancelled)
+	case errors.Is(err, chat.ErrNoReply):
+		_ = s.conn.Send(realtime.NewError(event, realtime.CodeNoReply, "The pen pal could not answer, please try again"))
+	default:
+		s.sendInternalError(event, err)
+	}
+}
+
+// sendTyping tells the client whether the pen pal is writing the reply to the event.
+func (s *session) sendTyping(event realtime.Event, typing bool) {
+	answer := realtime.NewEvent(realtime.TypeTyping)
+	answer.ID = event.ID
+	answer.ConversationID = event.ConversationID
+	answer.Data = realtime.TypingData{Typing: typing}
+	_ = s.conn.Send(answer)
+}
+
+// stop cancels the reply being generated, if any, and waits for it to end.
+func (s *session) stop() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// sendInternalError tells the client that its event failed unexpectedly, without disclosing the cause, which is logged.
+func (s *session) sendInternalError(event realtime.Event, err error) {
+	log.Printf("could not handle the %s event of the user %s: %v", event.Type, s.user.ID, err)
+	_ = s.conn.Send(realtime.NewError(event, realtime.CodeInternal, "Internal server error"))
+}
@@ -0,0 +1,344 @@
+package socket
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/realtime"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// unmetered meters the calls without enforcing any quota.
+var unmetered = metering.NewMeter(&configs.Config{})
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+const queryUser = `SELECT * FROM "users" WHERE id = $1 ORDER BY "users"."id" LIMIT $2`
+const queryLevel = `SELECT * FROM "learner_languages" WHERE user_id = $1 AND language = $2 LIMIT $3`
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func newHub() *realtime.Hub {
+	return realtime.NewHub(&configs.Config{
+		WSTicketExpiresIn:       30 * time.Second,
+		WSMaxConnectionsPerUser: 1,
+		WSMaxMessageSize:        16384,
+		WSPingInterval:          time.Minute,
+		WSSendBuffer:            64,
+		WSMessagesPerMinute:     30,
+	})
+}
+
+// dial opens a WebSocket connection to the router with the given ticket.
+func dial(t *testing.T, ticket string) (*websocket.Conn, *http.Response, error) {
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	ws, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?ticket="+ticket, nil)
+	if err == nil {
+		t.Cleanup(func() { ws.Close() })
+	}
+	return ws, response, err
+}
+
+func readEvent(t *testing.T, ws *websocket.Conn) realtime.Event {
+	var event realtime.Event
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	assert.NoError(t, ws.ReadJSON(&event))
+	return event
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestCreateTicket(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	hub := newHub()
+	socketController := NewSocketController(chat.NewService(llm.NewFakeProvider(), unmetered, chat.Options{}), hub)
+	router.POST("/ws/tickets", func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}, socketController.CreateTicket)
+
+	w, err := utils.HttpTestRequest(router, "POST", "/ws/tickets", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response struct {
+		Ticket string `json:"ticket"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	userID, err := hub.Tickets().Redeem(response.Ticket)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+}
+
+func TestConnect(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+
+	tests := []struct {
+		name         string
+		validTicket  bool
+		userExists   bool
+		connected    bool
+		expectedCode int
+	}{
+		{
+			name:         "valid ticket",
+			validTicket:  true,
+			userExists:   true,
+			expectedCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:         "invalid ticket",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "deleted user",
+			validTicket:  true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "too many connections",
+			validTicket:  true,
+			userExists:   true,
+			connected:    true,
+			expectedCode: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			hub := newHub()
+			socketController := NewSocketController(chat.NewService(llm.NewFakeProvider(), unmetered, chat.Options{}), hub)
+			router.GET("/ws", socketController.Connect)
+
+			ticket := "unknown"
+			if tt.validTicket {
+				ticket, _, _ = hub.Tickets().Issue(user.ID)
+				query := mock.ExpectQuery(regexp.QuoteMeta(queryUser)).WithArgs(user.ID, 1)
+				if tt.userExists {
+					query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{user}))
+				} else {
+					query.WillReturnError(gorm.ErrRecordNotFound)
+				}
+			}
+			if tt.connected {
+				hub.Acquire(user.ID)
+			}
+
+			ws, response, err := dial(t, ticket)
+			assert.Equal(t, tt.expectedCode, response.StatusCode)
+			if tt.expectedCode == http.StatusSwitchingProtocols {
+				assert.NoError(t, err)
+				event := readEvent(t, ws)
+				assert.Equal(t, realtime.TypeWelcome, event.Type)
+				assert.Equal(t, realtime.ProtocolVersion, event.Version)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestConnectExchange(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "En el mercado"}
+	queryConversation := `SELECT * FROM "conversations" WHERE id = $1 AND user_id = $2 ORDER BY "conversations"."id" LIMIT $3`
+
+	tests := []struct {
+		name           string
+		event          realtime.Event
+		found          bool
+		lookupErr      error
+		expectedEvents []string
+		expectedCode   string
+	}{
+		{
+			name:           "message sent",
+			event:          realtime.Event{Version: realtime.ProtocolVersion, Type: realtime.TypeSend, ID: "1", ConversationID: &conversation.ID, Content: "Hola"},
+			found:          true,
+			expectedEvents: []string{realtime.TypeTyping, realtime.TypeDelta, realtime.TypeDelta, realtime.TypeDelta, realtime.TypeTyping, realtime.TypeDone},
+		},
+		{
+			name:           "conversation of another user",
+			event:          realtime.Event{Version: realtime.ProtocolVersion, Type: realtime.TypeSend, ID: "1", ConversationID: &conversation.ID, Content: "Hola"},
+			lookupErr:      gorm.ErrRecordNotFound,
+			expectedEvents: []string{realtime.TypeError},
+			expectedCode:   realtime.CodeNotFound,
+		},
+		{
+			name:           "database unavailable",
+			event:          realtime.Event{Version: realtime.ProtocolVersion, Type: realtime.TypeSend, ID: "1", ConversationID: &conversation.ID, Content: "Hola"},
+			lookupErr:      errors.New("connection refused"),
+			expectedEvents: []string{realtime.TypeError},
+			expectedCode:   realtime.CodeInternal,
+		},
+		{
+			name:           "empty message",
+			event:          realtime.Event{Version: realtime.ProtocolVersion, Type: realtime.TypeSend, ID: "1", ConversationID: &conversation.ID},
+			expectedEvents: []string{realtime.TypeError},
+			expectedCode:   realtime.CodeInvalidEvent,
+		},
+		{
+			name:           "unknown event",
+			event:          realtime.Event{Version: realtime.ProtocolVersion, Type: "message.edit", ID: "1"},
+			expectedEvents: []string{realtime.TypeError},
+			expectedCode:   realtime.CodeInvalidEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			hub := newHub()
+			socketController := NewSocketController(chat.NewService(&llm.FakeProvider{Reply: "¡Hola! ¿Qué tal?"}, unmetered, chat.Options{}), hub)
+			router.GET("/ws", socketController.Connect)
+
+			ticket, _, _ := hub.Tickets().Issue(user.ID)
+			mock.ExpectQuery(regexp.QuoteMeta(queryUser)).
+				WithArgs(user.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{user}))
+			if tt.event.Type == realtime.TypeSend && tt.event.Content != "" {
+				query := mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).WithArgs(conversation.ID, user.ID, 1)
+				if tt.found {
+					query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+					mock.ExpectQuery(regexp.QuoteMeta(queryLevel)).
+						WithArgs(user.ID, conversation.Language, 1).
+						WillReturnRows(sqlmock.NewRows([]string{"level"}))
+					mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "messages" WHERE conversation_id = $1 ORDER BY created_at`)).
+						WithArgs(conversation.ID).
+						WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{}))
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "messages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "messages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1,"updated_at"=$2 WHERE "id" = $3`)).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					query.WillReturnError(tt.lookupErr)
+				}
+			}
+
+			ws, _, err := dial(t, ticket)
+			assert.NoError(t, err)
+			assert.Equal(t, realtime.TypeWelcome, readEvent(t, ws).Type)
+			assert.NoError(t, ws.WriteJSON(tt.event))
+
+			var events []realtime.Event
+			for range tt.expectedEvents {
+				events = append(events, readEvent(t, ws))
+			}
+			types := make([]string, 0, len(events))
+			for _, event := range events {
+				types = append(types, event.Type)
+				assert.Equal(t, tt.event.ID, event.ID)
+			}
+			assert.Equal(t, tt.expectedEvents, types)
+
+			last := events[len(events)-1]
+			if tt.expectedCode != "" {
+				assert.Equal(t, tt.expectedCode, last.Data.(map[string]interface{})["code"])
+				assert.NotContains(t, last.Data.(map[string]interface{})["message"], "connection refused")
+			} else {
+				data, _ := json.Marshal(last.Data)
+				var exchange models.ExchangeResponse
+				assert.NoError(t, json.Unmarshal(data, &exchange))
+				assert.Equal(t, "¡Hola! ¿Qué tal?", exchange.Reply.Content)
+			}
+			ws.Close()
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestConnectLetters(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "Cartas", Mode: models.ConversationModeLetters}
+	hub := newHub()
+	socketController := NewSocketController(chat.NewService(llm.NewFakeProvider(), unmetered, chat.Options{}), hub)
+	router.GET("/ws", socketController.Connect)
+
+	ticket, _, _ := hub.Tickets().Issue(user.ID)
+	mock.ExpectQuery(regexp.QuoteMeta(queryUser)).
+		WithArgs(user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{user}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "conversations" WHERE id = $1 AND user_id = $2`)).
+		WithArgs(conversation.ID, user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryLevel)).
+		WithArgs(user.ID, conversation.Language, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"level"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "messages" WHERE conversation_id = $1 ORDER BY created_at`)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "conversations"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(conversation.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "letters"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "messages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "letters"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1,"updated_at"=$2 WHERE "id" = $3`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ws, _, err := dial(t, ticket)
+	assert.NoError(t, err)
+	assert.Equal(t, realtime.TypeWelcome, readEvent(t, ws).Type)
+	assert.NoError(t, ws.WriteJSON(realtime.Event{Version: realtime.ProtocolVersion, Type: realtime.TypeSend, ID: "1",
+		ConversationID: &conversation.ID, Content: "Querida Lucía"}))
+
+	scheduled := readEvent(t, ws)
+	assert.Equal(t, realtime.TypeScheduled, scheduled.Type)
+	assert.Equal(t, "1", scheduled.ID)
+	data, _ := json.Marshal(scheduled.Data)
+	var response models.LetterScheduledResponse
+	assert.NoError(t, json.Unmarshal(data, &response))
+	assert.Equal(t, "Querida Lucía", response.Message.Content)
+	assert.Equal(t, models.LetterStatusPending, response.Letter.Status)
+
+	delivered := realtime.NewEvent(realtime.TypeDelivered)
+	delivered.ConversationID = &conversation.ID
+	assert.Equal(t, 1, hub.Publish(user.ID, delivered))
+	assert.Equal(t, realtime.TypeDelivered, readEvent(t, ws).Type)
+	ws.Close()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
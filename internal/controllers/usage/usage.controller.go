@@ -0,0 +1,127 @@
+package usage
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Formats of the periods of the usage reports, for PostgreSQL's to_char.
+var reportPeriods = map[string]string{
+	metering.PeriodDay:   "YYYY-MM-DD",
+	metering.PeriodMonth: "YYYY-MM",
+}
+
+type UsageController struct {
+	meter *metering.Meter
+}
+
+func NewUsageController(meter *metering.Meter) UsageController {
+	return UsageController{meter}
+}
+
+// GetMyUsage retrieves the consumption of the current user.
+// @Summary Get my usage
+// @Description Fetches the tokens consumed by the current user during the current day and month, in UTC, with the
+// @Description quotas of their plan and the time when they are reset.
+// @Tags usage
+// @Produce json
+// @Success 200 {object} models.UsageResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/usage [get]
+func (uc *UsageController) GetMyUsage(context *gin.Context) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return
+	}
+	currentUser := obj.(*models.User)
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	summary, err := uc.meter.Summary(database, *currentUser, time.Now())
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, summary)
+}
+
+// GetUsageReport retrieves the consumption of the users.
+// @Summary Get the usage report
+// @Description Fetches a page of the consumption of each user per day or per month, in UTC, most recent first,
+// @Description optionally filtered by user and by date.
+// @Tags usage
+// @Produce json
+// @Param period query string false "day (default) or month"
+// @Param user_id query string false "User ID"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.UsageReportResponse
+// @Failure 400 {object} object
+// @Failure 500 {object} object
+// @Router /usage [get]
+func (uc *UsageController) GetUsageReport(context *gin.Context) {
+	format, ok := reportPeriods[context.DefaultQuery("period", metering.PeriodDay)]
+	if !ok {
+		utils.AbortWithError(context, http.StatusBadRequest, "The period must be day or month")
+		return
+	}
+
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	period := "TO_CHAR(usage_records.created_at AT TIME ZONE 'UTC', '" + format + "')"
+	query := database.Table("usage_records").
+		Select("usage_records.user_id, users.email, " + period + " AS period, COUNT(*) AS calls, " +
+			"SUM(usage_records.prompt_tokens) AS prompt_tokens, SUM(usage_records.completion_tokens) AS completion_tokens, " +
+			"SUM(usage_records.cost) AS cost").
+		Joins("LEFT JOIN users ON users.id = usage_records.user_id").
+		Group("usage_records.user_id, users.email, " + period).
+		Order("period DESC, cost DESC")
+	if userID := context.Query("user_id"); userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+			return
+		}
+		query = query.Where("usage_records.user_id = ?", userID)
+	}
+	if from := context.Query("from"); from != "" {
+		day, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid date format")
+			return
+		}
+		query = query.Where("usage_records.created_at >= ?", day)
+	}
+	if to := context.Query("to"); to != "" {
+		day, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid date format")
+			return
+		}
+		query = query.Where("usage_records.created_at < ?", day.AddDate(0, 0, 1))
+	}
+
+	responses := []models.UsageReportResponse{}
+	if err := query.Scopes(utils.GetPagination(context).Scope).Scan(&responses).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
@@ -0,0 +1,130 @@
+package usage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var usageController = NewUsageController(metering.NewMeter(&configs.Config{UsageFreeDailyTokens: 1000, UsageFreeMonthlyTokens: 10000}))
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetMyUsage(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	router.GET("/me/usage", func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}, usageController.GetMyUsage)
+
+	for _, tokens := range []int{100, 2000} {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "usage_records" WHERE user_id = $1 AND created_at >= $2`)).
+			WithArgs(user.ID, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"calls", "prompt_tokens", "completion_tokens", "cost"}).AddRow(2, tokens, tokens, 0.01))
+	}
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/usage", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.UsageResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.PlanFree, response.Plan)
+	assert.Equal(t, int64(200), response.Day.TotalTokens)
+	assert.Equal(t, int64(1000), response.Day.Limit)
+	assert.Equal(t, int64(4000), response.Month.TotalTokens)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUsageReport(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedSQL  string
+		expectedCode int
+	}{
+		{
+			name:         "daily report",
+			query:        "?user_id=" + userID.String() + "&from=2026-03-01&to=2026-03-31",
+			expectedSQL:  `TO_CHAR(usage_records.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS period`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "monthly report",
+			query:        "?period=month",
+			expectedSQL:  `TO_CHAR(usage_records.created_at AT TIME ZONE 'UTC', 'YYYY-MM') AS period`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid period",
+			query:        "?period=week",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid date",
+			query:        "?from=March",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.GET("/usage", usageController.GetUsageReport)
+
+			if tt.expectedCode == http.StatusOK {
+				mock.ExpectQuery(regexp.QuoteMeta(tt.expectedSQL)).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "email", "period", "calls", "prompt_tokens", "completion_tokens", "cost"}).
+						AddRow(userID, "john.doe@mail.pe", "2026-03-14", 4, 1200, 800, 0.00066))
+			}
+
+			w, err := utils.HttpTestRequest(router, "GET", "/usage"+tt.query, nil)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+
+			if tt.expectedCode == http.StatusOK {
+				var response []models.UsageReportResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Len(t, response, 1)
+				assert.Equal(t, int64(1200), response[0].PromptTokens)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
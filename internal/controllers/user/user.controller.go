@@ -3,8 +3,11 @@ package user
 import (
 	"errors"
 	"net/http"
+	"time"
 
+	"github.com/enzo-gbd/GBA/configs"
 	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
 	"github.com/enzo-gbd/GBA/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -185,13 +188,14 @@ func (uc *UserController) DeleteUser(context *gin.Context) {
 
 // GetMe retrieves the current logged in user's information.
 // @Summary Get current user
-// @Description Retrieves information about the current logged in user.
+// @Description Retrieves information about the current logged in user, including the features restricted because of their age.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Success 200 {object} models.UserResponse
 // @Failure 401 {object} object
+// @Failure 500 {object} object
 // @Router /users/me [get]
 func (uc *UserController) GetMe(context *gin.Context) {
 	obj, exists := context.Get("currentUser")
@@ -200,6 +204,12 @@ func (uc *UserController) GetMe(context *gin.Context) {
 		return
 	}
 
+	config, err := configs.LoadConfig()
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, "Configuration error")
+		return
+	}
+
 	currentUser := obj.(*models.User)
 	userResponse := &models.UserResponse{
 		ID:               currentUser.ID,
@@ -208,10 +218,12 @@ func (uc *UserController) GetMe(context *gin.Context) {
 		Gender:           currentUser.Gender,
 		Email:            currentUser.Email,
 		Role:             currentUser.Role,
+		NativeLanguage:   currentUser.NativeLanguage,
 		Address:          currentUser.Address.String,
 		SubscriptionCode: currentUser.SubscriptionCode.String,
 		CreatedAt:        currentUser.CreatedAt,
 		UpdatedAt:        currentUser.UpdatedAt,
+		Restrictions:     agegate.NewPolicy(&config).Restrictions(currentUser.Birthday, time.Now()),
 	}
 
 	utils.SendSuccess(context, http.StatusOK, userResponse)
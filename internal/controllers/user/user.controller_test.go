@@ -184,7 +184,7 @@ func TestGetUserByID(t *testing.T) {
 
 func TestUpdateUser(t *testing.T) {
 	method, url := "PUT", "/"
-	queryUpdate := `UPDATE "users" SET "first_name"=$1,"name"=$2,"birthday"=$3,"gender"=$4,"email"=$5,"password"=$6,"role"=$7,"address"=$8,"subscription_code"=$9,"is_active"=$10,"verification_code"=$11,"verified"=$12,"created_at"=$13,"updated_at"=$14,"deleted_at"=$15 WHERE "id" = $16`
+	queryUpdate := `UPDATE "users" SET "first_name"=$1,"name"=$2,"birthday"=$3,"gender"=$4,"email"=$5,"normalized_email"=$6,"password"=$7,"role"=$8,"native_language"=$9,"address"=$10,"subscription_code"=$11,"is_active"=$12,"verification_code"=$13,"verified"=$14,"created_at"=$15,"updated_at"=$16,"deleted_at"=$17 WHERE "id" = $18`
 	queryFirst := `SELECT * FROM "users" WHERE id = $1 ORDER BY "users"."id" LIMIT $2`
 
 	tests := []struct {
@@ -316,6 +316,8 @@ func TestUpdateUser(t *testing.T) {
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
 					).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit()
@@ -342,6 +344,7 @@ func TestUpdateUser(t *testing.T) {
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
 						sqlmock.AnyArg(),
+						sqlmock.AnyArg(),
 					).
 					WillReturnError(gorm.ErrRecordNotFound)
 				mock.ExpectCommit()
@@ -436,11 +439,15 @@ func TestGetMe(t *testing.T) {
 	john := []models.User{
 		builders.NewUserBuilder().Build(),
 	}
+	lucas := []models.User{
+		builders.NewUserBuilder().WhereFirstName("Lucas").WhereBirthday(time.Now().AddDate(-10, 0, 0)).Build(),
+	}
 
 	tests := []struct {
 		name         string
 		currentUser  []models.User
 		exists       bool
+		restricted   bool
 		expectedCode int
 	}{
 		{
@@ -449,6 +456,13 @@ func TestGetMe(t *testing.T) {
 			exists:       true,
 			expectedCode: http.StatusOK,
 		},
+		{
+			name:         "minor user",
+			currentUser:  lucas,
+			exists:       true,
+			restricted:   true,
+			expectedCode: http.StatusOK,
+		},
 		{
 			name:         "deleted user",
 			currentUser:  john,
@@ -490,6 +504,8 @@ func TestGetMe(t *testing.T) {
 
 				if user == nil {
 					t.Errorf("user got is nil, expected not")
+				} else if user.Restrictions.StrictModeration != tt.restricted || user.Restrictions.PublicSharingDisabled != tt.restricted {
+					t.Errorf("restrictions = %+v, expected restricted %v", user.Restrictions, tt.restricted)
 				}
 			}
 		})
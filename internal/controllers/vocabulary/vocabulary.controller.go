@@ -0,0 +1,493 @@
+package vocabulary
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/flashcards"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type VocabularyController struct {
+	provider llm.Provider
+	meter    *metering.Meter
+}
+
+func NewVocabularyController(provider llm.Provider, meter *metering.Meter) VocabularyController {
+	return VocabularyController{provider, meter}
+}
+
+// GetVocabulary retrieves the words saved by the current user.
+// @Summary Get my vocabulary
+// @Description Fetches a page of the words saved by the current user, most recent first, optionally filtered by language.
+// @Tags vocabulary
+// @Produce json
+// @Param language query string false "Language"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.VocabularyItemResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/vocabulary [get]
+func (vc *VocabularyController) GetVocabulary(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Where("user_id = ?", currentUser.ID).Order("created_at DESC")
+	if language := context.Query("language"); language != "" {
+		query = query.Where("language = ?", language)
+	}
+	sendItems(context, query)
+}
+
+// CreateVocabularyItem saves a word for the current user.
+// @Summary Save a word
+// @Description Saves a word or a phrase to the vocabulary of the current user, optionally from one of their messages.
+// @Description The word is due for review immediately.
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param payload body models.VocabularyInput true "Word Data"
+// @Success 201 {object} models.VocabularyItemResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 409 {object} object
+// @Failure 500 {object} object
+// @Router /me/vocabulary [post]
+func (vc *VocabularyController) CreateVocabularyItem(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	var payload models.VocabularyInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if payload.MessageID != nil {
+		if _, _, ok := findMessage(context, database, currentUser.ID, *payload.MessageID); !ok {
+			return
+		}
+	}
+	item := flashcards.NewItem(currentUser.ID, payload, models.VocabularySourceManual, time.Now())
+	var count int64
+	if err := database.Model(&models.VocabularyItem{}).
+		Where("user_id = ? AND language = ? AND term = ?", currentUser.ID, item.Language, item.Term).
+		Count(&count).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if count > 0 {
+		utils.AbortWithError(context, http.StatusConflict, "The word is already in your vocabulary")
+		return
+	}
+
+	if err := database.Create(&item).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusCreated, item.ToResponse())
+}
+
+// ExtractVocabulary saves the words worth learning in a message of the current user.
+// @Summary Extract words from a message
+// @Description Asks the language model for the words worth learning in a message of a conversation of the current
+// @Description user, translated in their native language, and saves the ones not in their vocabulary yet.
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param payload body models.VocabularyExtractionInput true "Message"
+// @Success 201 {array} models.VocabularyItemResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 429 {object} object
+// @Failure 500 {object} object
+// @Failure 502 {object} object
+// @Router /me/vocabulary/extract [post]
+func (vc *VocabularyController) ExtractVocabulary(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	var payload models.VocabularyExtractionInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	message, conversation, ok := findMessage(context, database, currentUser.ID, payload.MessageID)
+	if !ok {
+		return
+	}
+	if err := vc.meter.Check(database, *currentUser, time.Now()); err != nil {
+		var quotaErr *metering.QuotaError
+		if errors.As(err, &quotaErr) {
+			abortQuotaExceeded(context, quotaErr)
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	request := flashcards.NewExtractionRequest(message.Content, conversation.Language, currentUser.NativeLanguage)
+	ctx := redaction.WithAddress(context.Request.Context(), currentUser.Address.String)
+	completion, err := vc.provider.Complete(ctx, request)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadGateway, "The vocabulary could not be extracted, please try again")
+		return
+	}
+	record := vc.meter.NewRecord(conversation, completion)
+	inputs, err := flashcards.ParseExtraction(completion.Content, conversation.Language)
+	if err != nil {
+		if err := database.Create(&record).Error; err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.AbortWithError(context, http.StatusBadGateway, "The vocabulary could not be extracted, please try again")
+		return
+	}
+
+	terms := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		terms = append(terms, input.Term)
+	}
+	var known []string
+	if len(terms) > 0 {
+		if err := database.Model(&models.VocabularyItem{}).
+			Where("user_id = ? AND language = ? AND term IN ?", currentUser.ID, conversation.Language, terms).
+			Pluck("term", &known).Error; err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	saved := make(map[string]bool, len(known)+len(inputs))
+	for _, term := range known {
+		saved[term] = true
+	}
+	items := make([]models.VocabularyItem, 0, len(inputs))
+	now := time.Now()
+	for _, input := range inputs {
+		input.MessageID = &message.ID
+		item := flashcards.NewItem(currentUser.ID, input, models.VocabularySourceExtracted, now)
+		if !saved[item.Term] {
+			saved[item.Term] = true
+			items = append(items, item)
+		}
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.VocabularyItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, item.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusCreated, responses)
+}
+
+// UpdateVocabularyItem changes the translation and the example of a word of the current user.
+// @Summary Update a word
+// @Description Changes the translation and the example sentence of a word of the current user. Its review schedule is kept.
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param id path string true "Word ID"
+// @Param payload body models.VocabularyUpdateInput true "Word Data"
+// @Success 200 {object} models.VocabularyItemResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/vocabulary/{id} [put]
+func (vc *VocabularyController) UpdateVocabularyItem(context *gin.Context) {
+	var payload models.VocabularyUpdateInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	item, database, ok := findItem(context)
+	if !ok {
+		return
+	}
+
+	item.Translation = payload.Translation
+	item.Example = payload.Example
+	if err := database.Save(&item).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, item.ToResponse())
+}
+
+// DeleteVocabularyItem removes a word from the vocabulary of the current user.
+// @Summary Delete a word
+// @Description Removes a word from the vocabulary of the current user, with its review schedule.
+// @Tags vocabulary
+// @Produce json
+// @Param id path string true "Word ID"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/vocabulary/{id} [delete]
+func (vc *VocabularyController) DeleteVocabularyItem(context *gin.Context) {
+	item, database, ok := findItem(context)
+	if !ok {
+		return
+	}
+	if err := database.Delete(&item).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, gin.H{})
+}
+
+// GetVocabularyStats retrieves the progress of the current user in each language.
+// @Summary Get my vocabulary statistics
+// @Description Fetches, for each language of the current user, the number of words saved, never reviewed, due for
+// @Description review and mastered.
+// @Tags vocabulary
+// @Produce json
+// @Success 200 {array} models.VocabularyStatsResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/vocabulary/stats [get]
+func (vc *VocabularyController) GetVocabularyStats(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	stats := []models.VocabularyStatsResponse{}
+	err = database.Model(&models.VocabularyItem{}).
+		Select("language, COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE last_reviewed_at IS NULL) AS new, "+
+			"COUNT(*) FILTER (WHERE due_at <= ?) AS due, "+
+			"COUNT(*) FILTER (WHERE interval_days >= ?) AS mastered", time.Now(), flashcards.MasteredInterval).
+		Where("user_id = ?", currentUser.ID).
+		Group("language").
+		Order("language").
+		Scan(&stats).Error
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, stats)
+}
+
+// GetReviews retrieves the flashcards the current user should review now.
+// @Summary Get my due reviews
+// @Description Fetches a page of the words of the current user due for review, the most overdue first, optionally
+// @Description filtered by language.
+// @Tags vocabulary
+// @Produce json
+// @Param language query string false "Language"
+// @Param page query int false "Page"
+// @Param page_size query int false "Page size"
+// @Success 200 {array} models.VocabularyItemResponse
+// @Failure 401 {object} object
+// @Failure 500 {object} object
+// @Router /me/reviews [get]
+func (vc *VocabularyController) GetReviews(context *gin.Context) {
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := database.Where("user_id = ? AND due_at <= ?", currentUser.ID, time.Now()).Order("due_at")
+	if language := context.Query("language"); language != "" {
+		query = query.Where("language = ?", language)
+	}
+	sendItems(context, query)
+}
+
+// SubmitReview records the recall of a flashcard by the current user and schedules its next review.
+// @Summary Review a word
+// @Description Grades the recall of a word of the current user, from 0 (forgotten) to 5 (perfect recall), and
+// @Description schedules its next review with the SM-2 algorithm. A grade below 3 starts the repetitions over.
+// @Tags vocabulary
+// @Accept json
+// @Produce json
+// @Param id path string true "Word ID"
+// @Param payload body models.ReviewInput true "Grade"
+// @Success 200 {object} models.VocabularyItemResponse
+// @Failure 400 {object} object
+// @Failure 401 {object} object
+// @Failure 404 {object} object
+// @Failure 500 {object} object
+// @Router /me/reviews/{id} [post]
+func (vc *VocabularyController) SubmitReview(context *gin.Context) {
+	var payload models.ReviewInput
+	if err := context.ShouldBindJSON(&payload); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := payload.Validate(); err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, err.Error())
+		return
+	}
+	item, database, ok := findItem(context)
+	if !ok {
+		return
+	}
+
+	flashcards.Review(&item, *payload.Grade, time.Now())
+	if err := database.Save(&item).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.SendSuccess(context, http.StatusOK, item.ToResponse())
+}
+
+// sendItems sends a page of the vocabulary items selected by the query.
+func sendItems(context *gin.Context, query *gorm.DB) {
+	var items []models.VocabularyItem
+	if err := query.Scopes(utils.GetPagination(context).Scope).Find(&items).Error; err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]models.VocabularyItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, item.ToResponse())
+	}
+	utils.SendSuccess(context, http.StatusOK, responses)
+}
+
+// findItem loads the vocabulary item of the current user targeted by the request, aborting it if there is none.
+func findItem(context *gin.Context) (models.VocabularyItem, *gorm.DB, bool) {
+	id, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+		return models.VocabularyItem{}, nil, false
+	}
+	currentUser, ok := getCurrentUser(context)
+	if !ok {
+		return models.VocabularyItem{}, nil, false
+	}
+	database, err := utils.GetDatabaseInContext(context)
+	if err != nil {
+		utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		return models.VocabularyItem{}, nil, false
+	}
+
+	var item models.VocabularyItem
+	if err := database.Where("id = ? AND user_id = ?", id, currentUser.ID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found word")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return models.VocabularyItem{}, nil, false
+	}
+	return item, database, true
+}
+
+// findMessage loads a message of a conversation of the user, with its conversation, aborting the request if there is none.
+func findMessage(context *gin.Context, database *gorm.DB, userID uuid.UUID, messageID uuid.UUID) (models.Message, models.Conversation, bool) {
+	var message models.Message
+	var conversation models.Conversation
+	err := database.Where("id = ?", messageID).First(&message).Error
+	if err == nil {
+		err = database.Where("id = ? AND user_id = ?", message.ConversationID, userID).First(&conversation).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.AbortWithError(context, http.StatusNotFound, "Can't found message")
+		} else {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+		}
+		return models.Message{}, models.Conversation{}, false
+	}
+	return message, conversation, true
+}
+
+// abortQuotaExceeded aborts the request with a 429 describing the exhausted quota, telling the client when to retry.
+func abortQuotaExceeded(context *gin.Context, quotaErr *metering.QuotaError) {
+	context.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quotaErr.ResetAt).Seconds()))))
+	context.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"status":  "fail",
+		"message": fmt.Sprintf("Your token quota for the %s is exhausted", quotaErr.Period),
+		"quota":   quotaErr,
+	})
+}
+
+// getCurrentUser returns the user set by the DeserializeUser middleware, aborting the request when there is none.
+func getCurrentUser(context *gin.Context) (*models.User, bool) {
+	obj, exists := context.Get("currentUser")
+	if !exists {
+		utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+		return nil, false
+	}
+	currentUser, ok := obj.(*models.User)
+	if !ok {
+		utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+		return nil, false
+	}
+	return currentUser, true
+}
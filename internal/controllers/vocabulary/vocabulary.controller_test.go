@@ -0,0 +1,438 @@
+package vocabulary
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const (
+	queryItem         = `SELECT * FROM "vocabulary_items" WHERE id = $1 AND user_id = $2 ORDER BY "vocabulary_items"."id" LIMIT $3`
+	queryMessage      = `SELECT * FROM "messages" WHERE id = $1 ORDER BY "messages"."id" LIMIT $2`
+	queryConversation = `SELECT * FROM "conversations" WHERE id = $1 AND user_id = $2 ORDER BY "conversations"."id" LIMIT $3`
+)
+
+// unmetered meters the calls without enforcing any quota.
+var unmetered = metering.NewMeter(&configs.Config{})
+
+var vocabularyController = NewVocabularyController(llm.NewFakeProvider(), unmetered)
+var router *gin.Engine
+var database *gorm.DB
+var sqlDB *sql.DB
+var mock sqlmock.Sqlmock
+
+func setupRouter() {
+	router = gin.Default()
+	database, sqlDB, mock = db.InitMockDB()
+
+	router.Use(middlewares.InjectDB(database))
+}
+
+func setCurrentUser(user models.User) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Set("currentUser", &user)
+	}
+}
+
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func TestGetVocabulary(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	items := []models.VocabularyItem{
+		{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "perro", Translation: "dog", EaseFactor: 2.5},
+	}
+	router.GET("/me/vocabulary", setCurrentUser(user), vocabularyController.GetVocabulary)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "vocabulary_items" WHERE user_id = $1 AND language = $2 ORDER BY created_at DESC LIMIT $3`)).
+		WithArgs(user.ID, "es", 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(items))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/vocabulary?language=es", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []models.VocabularyItemResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, "perro", response[0].Term)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateVocabularyItem(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es"}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Content: "Tengo un perro"}
+
+	tests := []struct {
+		name         string
+		input        models.VocabularyInput
+		messageFound bool
+		existing     int
+		expectedCode int
+	}{
+		{
+			name:         "valid input",
+			input:        models.VocabularyInput{Language: "es", Term: "perro", Translation: "dog"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "from a message",
+			input:        models.VocabularyInput{Language: "es", Term: "perro", Translation: "dog", MessageID: &message.ID},
+			messageFound: true,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "unknown message",
+			input:        models.VocabularyInput{Language: "es", Term: "perro", Translation: "dog", MessageID: &conversation.ID},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "already saved",
+			input:        models.VocabularyInput{Language: "es", Term: "perro", Translation: "dog"},
+			existing:     1,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "invalid language",
+			input:        models.VocabularyInput{Language: "spanish", Term: "perro", Translation: "dog"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/me/vocabulary", setCurrentUser(user), vocabularyController.CreateVocabularyItem)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				if tt.input.MessageID != nil {
+					query := mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).WithArgs(*tt.input.MessageID, 1)
+					if tt.messageFound {
+						query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+						mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+							WithArgs(conversation.ID, user.ID, 1).
+							WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+					} else {
+						query.WillReturnError(gorm.ErrRecordNotFound)
+					}
+				}
+				if tt.expectedCode != http.StatusNotFound {
+					mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "vocabulary_items" WHERE user_id = $1 AND language = $2 AND term = $3`)).
+						WithArgs(user.ID, "es", "perro").
+						WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.existing))
+				}
+				if tt.expectedCode == http.StatusCreated {
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "vocabulary_items"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/me/vocabulary", tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusCreated {
+				var response models.VocabularyItemResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, models.VocabularySourceManual, response.Source)
+				assert.Equal(t, tt.input.MessageID, response.MessageID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExtractVocabulary(t *testing.T) {
+	user := builders.NewUserBuilder().WhereNativeLanguage("fr").Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es"}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Content: "Te echo de menos, mi perro también."}
+
+	tests := []struct {
+		name          string
+		reply         string
+		expectedTerms []string
+		expectedCode  int
+	}{
+		{
+			name: "new words",
+			reply: `[{"term": "echar de menos", "translation": "manquer", "example": "Te echo de menos."},` +
+				`{"term": "perro", "translation": "chien"}]`,
+			expectedTerms: []string{"echar de menos"},
+			expectedCode:  http.StatusCreated,
+		},
+		{
+			name:         "invalid extraction",
+			reply:        "No word to learn.",
+			expectedCode: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			provider := &llm.FakeProvider{Reply: tt.reply}
+			controller := NewVocabularyController(provider, unmetered)
+			router.POST("/me/vocabulary/extract", setCurrentUser(user), controller.ExtractVocabulary)
+
+			mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).
+				WithArgs(message.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+			mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+				WithArgs(conversation.ID, user.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+			if tt.expectedCode == http.StatusCreated {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "term" FROM "vocabulary_items" WHERE user_id = $1 AND language = $2 AND term IN ($3,$4)`)).
+					WithArgs(user.ID, "es", "echar de menos", "perro").
+					WillReturnRows(sqlmock.NewRows([]string{"term"}).AddRow("perro"))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "vocabulary_items"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/me/vocabulary/extract", models.VocabularyExtractionInput{MessageID: message.ID})
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Contains(t, provider.Requests()[0].Messages[0].Content, `"fr"`)
+			if tt.expectedCode == http.StatusCreated {
+				var response []models.VocabularyItemResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				var terms []string
+				for _, item := range response {
+					terms = append(terms, item.Term)
+					assert.Equal(t, models.VocabularySourceExtracted, item.Source)
+					assert.Equal(t, message.ID, *item.MessageID)
+				}
+				assert.Equal(t, tt.expectedTerms, terms)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExtractVocabularyRedactsAddress(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().WhereAddress(sql.NullString{String: "Calle Mayor 5", Valid: true}).Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es"}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Content: "Vivo en calle mayor 5, cerca del parque."}
+	redactor, err := redaction.NewRedactor([]string{redaction.CategoryAddress}, nil)
+	assert.NoError(t, err)
+	fake := &llm.FakeProvider{Reply: `[{"term": "parque", "translation": "park"}]`}
+	controller := NewVocabularyController(redaction.NewProvider(fake, redactor), unmetered)
+	router.POST("/me/vocabulary/extract", setCurrentUser(user), controller.ExtractVocabulary)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).
+		WithArgs(message.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+		WithArgs(conversation.ID, user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "term" FROM "vocabulary_items"`)).WillReturnRows(sqlmock.NewRows([]string{"term"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "vocabulary_items"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, err := utils.HttpTestRequest(router, "POST", "/me/vocabulary/extract", models.VocabularyExtractionInput{MessageID: message.ID})
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusCreated, w.Code)
+	for _, message := range fake.Requests()[0].Messages {
+		assert.NotContains(t, message.Content, "mayor 5")
+	}
+	assert.Contains(t, fake.Requests()[0].Messages[1].Content, "Vivo en [ADDRESS_1], cerca del parque.")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExtractVocabularyQuotaExceeded(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es"}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Content: "Hola"}
+	controller := NewVocabularyController(llm.NewFakeProvider(), metering.NewMeter(&configs.Config{UsageFreeDailyTokens: 100}))
+	router.POST("/me/vocabulary/extract", setCurrentUser(user), controller.ExtractVocabulary)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).
+		WithArgs(message.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryConversation)).
+		WithArgs(conversation.ID, user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+	for range 2 {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM "usage_records" WHERE user_id = $1 AND created_at >= $2`)).
+			WillReturnRows(sqlmock.NewRows([]string{"calls", "prompt_tokens", "completion_tokens", "cost"}).AddRow(3, 60, 40, 0.0001))
+	}
+
+	w, err := utils.HttpTestRequest(router, "POST", "/me/vocabulary/extract", models.VocabularyExtractionInput{MessageID: message.ID})
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetVocabularyStats(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	router.GET("/me/vocabulary/stats", setCurrentUser(user), vocabularyController.GetVocabularyStats)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT language, COUNT(*) AS total, COUNT(*) FILTER (WHERE last_reviewed_at IS NULL) AS new, `+
+		`COUNT(*) FILTER (WHERE due_at <= $1) AS due, COUNT(*) FILTER (WHERE interval_days >= $2) AS mastered `+
+		`FROM "vocabulary_items" WHERE user_id = $3 GROUP BY "language" ORDER BY language`)).
+		WithArgs(sqlmock.AnyArg(), 21, user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"language", "total", "new", "due", "mastered"}).
+			AddRow("es", 40, 5, 12, 9).
+			AddRow("it", 3, 3, 3, 0))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/vocabulary/stats", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response []models.VocabularyStatsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []models.VocabularyStatsResponse{
+		{Language: "es", Total: 40, New: 5, Due: 12, Mastered: 9},
+		{Language: "it", Total: 3, New: 3, Due: 3},
+	}, response)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReviews(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	router.GET("/me/reviews", setCurrentUser(user), vocabularyController.GetReviews)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "vocabulary_items" WHERE user_id = $1 AND due_at <= $2 ORDER BY due_at LIMIT $3`)).
+		WithArgs(user.ID, sqlmock.AnyArg(), 20).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.VocabularyItem{
+			{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "perro", Translation: "dog", DueAt: time.Now().Add(-time.Hour)},
+		}))
+
+	w, err := utils.HttpTestRequest(router, "GET", "/me/reviews", nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubmitReview(t *testing.T) {
+	user := builders.NewUserBuilder().Build()
+	item := models.VocabularyItem{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "perro", Translation: "dog",
+		EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2}
+	grade := func(grade int) *int { return &grade }
+
+	tests := []struct {
+		name         string
+		id           string
+		input        models.ReviewInput
+		found        bool
+		expectedCode int
+	}{
+		{name: "successful recall", id: item.ID.String(), input: models.ReviewInput{Grade: grade(5)}, found: true, expectedCode: http.StatusOK},
+		{name: "unknown word", id: uuid.New().String(), input: models.ReviewInput{Grade: grade(5)}, expectedCode: http.StatusNotFound},
+		{name: "invalid grade", id: item.ID.String(), input: models.ReviewInput{Grade: grade(7)}, expectedCode: http.StatusBadRequest},
+		{name: "invalid id", id: "invalid", input: models.ReviewInput{Grade: grade(5)}, expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+			router.POST("/me/reviews/:id", setCurrentUser(user), vocabularyController.SubmitReview)
+
+			if tt.expectedCode != http.StatusBadRequest {
+				query := mock.ExpectQuery(regexp.QuoteMeta(queryItem)).WithArgs(tt.id, user.ID, 1)
+				if tt.found {
+					query.WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.VocabularyItem{item}))
+					mock.ExpectBegin()
+					mock.ExpectExec(regexp.QuoteMeta(`UPDATE "vocabulary_items"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				} else {
+					query.WillReturnError(gorm.ErrRecordNotFound)
+				}
+			}
+
+			w, err := utils.HttpTestRequest(router, "POST", "/me/reviews/"+tt.id, tt.input)
+			if err != nil {
+				t.Errorf("error = %v", err)
+			}
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusOK {
+				var response models.VocabularyItemResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, 15, response.IntervalDays)
+				assert.Equal(t, 3, response.Repetitions)
+				assert.NotNil(t, response.LastReviewedAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteVocabularyItem(t *testing.T) {
+	setupRouter()
+	defer sqlDB.Close()
+	user := builders.NewUserBuilder().Build()
+	item := models.VocabularyItem{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "perro"}
+	router.DELETE("/me/vocabulary/:id", setCurrentUser(user), vocabularyController.DeleteVocabularyItem)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryItem)).
+		WithArgs(item.ID.String(), user.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.VocabularyItem{item}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "vocabulary_items" WHERE "vocabulary_items"."id" = $1`)).
+		WithArgs(item.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w, err := utils.HttpTestRequest(router, "DELETE", "/me/vocabulary/"+item.ID.String(), nil)
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -15,6 +15,7 @@ import (
 // with the provided settings from the config parameter. It sets up the database
 // connection using the Postgres driver. The function will terminate the program
 // with an error if the database connection cannot be established.
+// The tenant scope is registered on the returned database, see RegisterTenantScope.
 func InitDB(config *configs.Config) *gorm.DB {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%v",
 		config.DBHost,
@@ -30,6 +31,9 @@ func InitDB(config *configs.Config) *gorm.DB {
 	if err != nil {
 		log.Fatalf("could not connect with the database: %v", err)
 	}
+	if err := RegisterTenantScope(db); err != nil {
+		log.Fatalf("could not register the tenant scope: %v", err)
+	}
 
 	return db
 }
@@ -21,6 +21,9 @@ func InitMockDB() (*gorm.DB, *sql.DB, sqlmock.Sqlmock) {
 	if err != nil {
 		log.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
 	}
+	if err := RegisterTenantScope(gormDB); err != nil {
+		log.Fatalf("An error '%s' was not expected when registering the tenant scope", err)
+	}
 
 	return gormDB, sqlDB, mock
 }
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantField is the name of the struct field identifying the tenant of a tenant scoped model.
+const tenantField = "OrganizationID"
+
+// organizationContextKey is the context key holding the organization a query is scoped to.
+type organizationContextKey struct{}
+
+// WithOrganization returns a copy of ctx scoping every query run with it to the given organization.
+func WithOrganization(ctx context.Context, organizationID uuid.UUID) context.Context {
+	return context.WithValue(ctx, organizationContextKey{}, organizationID)
+}
+
+// OrganizationFromContext returns the organization a context is scoped to, if any.
+func OrganizationFromContext(ctx context.Context) (uuid.UUID, bool) {
+	if ctx == nil {
+		return uuid.Nil, false
+	}
+	organizationID, ok := ctx.Value(organizationContextKey{}).(uuid.UUID)
+	return organizationID, ok
+}
+
+// RegisterTenantScope installs GORM callbacks enforcing tenant isolation at the data-access layer.
+// When the statement context carries an organization (see WithOrganization), every model having
+// an OrganizationID field is filtered on that organization for queries, updates and deletions,
+// and new records are assigned to it on creation. Controllers therefore never have to filter by
+// organization themselves, they only need to use the scoped database set in the request context.
+func RegisterTenantScope(database *gorm.DB) error {
+	callbacks := database.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", tenantFilter); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", tenantFilter); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", tenantFilter); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", tenantFilter); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenant:create", tenantAssign)
+}
+
+// tenantFilter restricts the statement to the organization found in its context.
+func tenantFilter(tx *gorm.DB) {
+	organizationID, ok := OrganizationFromContext(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: organizationID},
+	}})
+}
+
+// tenantAssign sets the organization found in the statement context on the records being created.
+func tenantAssign(tx *gorm.DB) {
+	organizationID, ok := OrganizationFromContext(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	ctx := tx.Statement.Context
+	switch value := tx.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(value.Index(i)), organizationID); err != nil {
+				_ = tx.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, value, organizationID); err != nil {
+			_ = tx.AddError(err)
+		}
+	}
+}
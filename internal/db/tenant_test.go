@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type scopedRecord struct {
+	ID             uuid.UUID `gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID `gorm:"type:char(36)"`
+	Name           string
+}
+
+type globalRecord struct {
+	ID   uuid.UUID `gorm:"type:char(36);primary_key"`
+	Name string
+}
+
+func TestOrganizationFromContext(t *testing.T) {
+	organizationID := uuid.New()
+
+	_, ok := OrganizationFromContext(context.Background())
+	assert.False(t, ok)
+
+	retrieved, ok := OrganizationFromContext(WithOrganization(context.Background(), organizationID))
+	assert.True(t, ok)
+	assert.Equal(t, organizationID, retrieved)
+}
+
+func TestTenantScopeQuery(t *testing.T) {
+	organizationID := uuid.New()
+
+	tests := []struct {
+		name   string
+		scoped bool
+		model  interface{}
+		query  string
+		args   []driver.Value
+	}{
+		{
+			name:   "scoped model with organization",
+			scoped: true,
+			model:  &[]scopedRecord{},
+			query:  `SELECT * FROM "scoped_records" WHERE name = $1 AND "scoped_records"."organization_id" = $2`,
+			args:   []driver.Value{"john", organizationID},
+		},
+		{
+			name:   "scoped model without organization",
+			scoped: false,
+			model:  &[]scopedRecord{},
+			query:  `SELECT * FROM "scoped_records" WHERE name = $1`,
+			args:   []driver.Value{"john"},
+		},
+		{
+			name:   "global model with organization",
+			scoped: true,
+			model:  &[]globalRecord{},
+			query:  `SELECT * FROM "global_records" WHERE name = $1`,
+			args:   []driver.Value{"john"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := InitMockDB()
+			defer sqlDB.Close()
+
+			ctx := context.Background()
+			if tt.scoped {
+				ctx = WithOrganization(ctx, organizationID)
+			}
+
+			mock.ExpectQuery("^" + regexp.QuoteMeta(tt.query) + "$").
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			err := database.WithContext(ctx).Where("name = ?", "john").Find(tt.model).Error
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTenantScopeCreate(t *testing.T) {
+	database, sqlDB, mock := InitMockDB()
+	defer sqlDB.Close()
+	organizationID := uuid.New()
+
+	record := scopedRecord{ID: uuid.New(), Name: "john"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "scoped_records"`)).
+		WithArgs(record.ID, organizationID, "john").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := database.WithContext(WithOrganization(context.Background(), organizationID)).Create(&record).Error
+	assert.NoError(t, err)
+	assert.Equal(t, organizationID, record.OrganizationID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,97 @@
+// Package middlewares contains middleware functions for handling various
+// aspects of HTTP requests within the application.
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/internal/services/captcha"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaHeader is the request header carrying the human verification token solved by the client.
+const CaptchaHeader = "X-Captcha-Token"
+
+// maxAttemptBodySize is the size above which the body of a request counted by AdaptiveCaptcha is refused.
+const maxAttemptBodySize = 64 << 10
+
+// Captcha returns a middleware handler function that requires every request to carry
+// a human verification token accepted by the verifier.
+//
+// Requests without a token or with a rejected token are aborted with an HTTP status
+// of 403 (Forbidden). If the verifier cannot reach its provider, the request is
+// aborted with an HTTP status of 503 (Service Unavailable).
+func Captcha(verifier captcha.Verifier) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if !verifyCaptcha(context, verifier) {
+			return
+		}
+		context.Next()
+	}
+}
+
+// AdaptiveCaptcha returns a middleware handler function that only requires human verification
+// once the client IP address or the email found in the JSON body reached the failure threshold of the tracker.
+//
+// Requests with a body larger than 64 KiB are aborted with an HTTP status of 413 (Request Entity Too Large).
+//
+// After the handler ran, a 401 (Unauthorized) response counts as a failure for both the IP address
+// and the email, while a successful response forgets the failures of the email.
+// The failures of the IP address are kept, so that a valid account can't be used to reset them.
+func AdaptiveCaptcha(verifier captcha.Verifier, tracker *captcha.AttemptTracker) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		ipKey, emailKey, err := attemptKeys(context)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+		if tracker.RequiresChallenge(ipKey, emailKey) && !verifyCaptcha(context, verifier) {
+			return
+		}
+		context.Next()
+
+		status := context.Writer.Status()
+		if status == http.StatusUnauthorized {
+			tracker.RecordFailure(ipKey, emailKey)
+		} else if status >= 200 && status < 300 {
+			tracker.Reset(emailKey)
+		}
+	}
+}
+
+// verifyCaptcha checks the token of the request and aborts it when the verification fails.
+func verifyCaptcha(context *gin.Context, verifier captcha.Verifier) bool {
+	err := verifier.Verify(context.Request.Context(), context.GetHeader(CaptchaHeader), context.ClientIP())
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, captcha.ErrMissingToken) || errors.Is(err, captcha.ErrRejected) {
+		utils.AbortWithError(context, http.StatusForbidden, err.Error())
+	} else {
+		utils.AbortWithError(context, http.StatusServiceUnavailable, "Human verification is unavailable")
+	}
+	return false
+}
+
+// attemptKeys returns the keys failures are counted under: the client IP address and the email of the JSON body.
+// The body is read up to maxAttemptBodySize and restored so that the handler can still read it.
+func attemptKeys(context *gin.Context) (string, string, error) {
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if context.Request.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(context.Writer, context.Request.Body, maxAttemptBodySize))
+		if err != nil {
+			return "", "", err
+		}
+		_ = json.Unmarshal(body, &payload)
+		context.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return "ip:" + context.ClientIP(), "email:" + strings.ToLower(payload.Email), nil
+}
@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/services/captcha"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenVerifier accepts the "valid" token and fails with err when set.
+type tokenVerifier struct {
+	err error
+}
+
+func (v tokenVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	if v.err != nil {
+		return v.err
+	}
+	switch token {
+	case "":
+		return captcha.ErrMissingToken
+	case "valid":
+		return nil
+	default:
+		return captcha.ErrRejected
+	}
+}
+
+func TestCaptcha(t *testing.T) {
+	method, url := "POST", "/register"
+	tests := []struct {
+		name         string
+		verifier     captcha.Verifier
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "valid token",
+			verifier:     tokenVerifier{},
+			token:        "valid",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing token",
+			verifier:     tokenVerifier{},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "rejected token",
+			verifier:     tokenVerifier{},
+			token:        "forged",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "provider unavailable",
+			verifier:     tokenVerifier{err: errors.New("connection refused")},
+			token:        "valid",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "verification disabled",
+			verifier:     captcha.NoopVerifier{},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			router.POST(url, Captcha(tt.verifier), func(c *gin.Context) {})
+			defer sqlDB.Close()
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, url, nil)
+			if tt.token != "" {
+				req.Header.Set(CaptchaHeader, tt.token)
+			}
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestAdaptiveCaptcha(t *testing.T) {
+	method, url := "POST", "/login"
+	body := `{"email":"John.Doe@mail.pe","password":"Password123."}`
+
+	setupRouter()
+	tracker := captcha.NewAttemptTracker(2, time.Minute)
+	router.POST(url, AdaptiveCaptcha(tokenVerifier{}, tracker), func(c *gin.Context) {
+		payload, _ := io.ReadAll(c.Request.Body)
+		if string(payload) != body {
+			c.Status(http.StatusBadRequest)
+		} else if c.GetHeader("X-Password-Valid") == "true" {
+			c.Status(http.StatusOK)
+		} else {
+			c.Status(http.StatusUnauthorized)
+		}
+	})
+	defer sqlDB.Close()
+
+	login := func(passwordValid bool, token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:4242"
+		if passwordValid {
+			req.Header.Set("X-Password-Valid", "true")
+		}
+		if token != "" {
+			req.Header.Set(CaptchaHeader, token)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login(false, ""), "the body is still readable by the handler")
+	assert.Equal(t, http.StatusUnauthorized, login(false, ""))
+	assert.Equal(t, http.StatusForbidden, login(true, ""), "verification is required after two failures")
+	assert.Equal(t, http.StatusOK, login(true, "valid"))
+	assert.True(t, tracker.RequiresChallenge("ip:192.0.2.1"), "a successful login does not forget the failures of the IP address")
+	assert.False(t, tracker.RequiresChallenge("email:john.doe@mail.pe"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, strings.NewReader(`{"email":"`+strings.Repeat("a", maxAttemptBodySize)+`"}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "a body too large is not read")
+}
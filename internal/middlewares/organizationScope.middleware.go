@@ -0,0 +1,75 @@
+// Package middlewares contains middleware functions for handling various
+// aspects of HTTP requests within the application.
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationScope returns a middleware handler function that scopes the request to the
+// organization identified by the `orgID` path parameter. It must run after DeserializeUser.
+//
+// The middleware loads the membership of the current user in the organization and, when
+// roles are provided, checks that the membership holds one of them. It then replaces the
+// database stored in the context by one scoped to the organization (see db.WithOrganization),
+// so handlers can only read and write data belonging to that organization, and stores the
+// membership under the key 'currentMembership'.
+//
+// It aborts with 400 (Bad Request) for a malformed identifier, 401 (Unauthorized) when no user
+// is logged in and 403 (Forbidden) when the user is not a member or lacks the required role.
+func OrganizationScope(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		value, exists := context.Get("currentUser")
+		if !exists {
+			utils.AbortWithError(context, http.StatusUnauthorized, "You are not logged in")
+			return
+		}
+		currentUser, ok := value.(*models.User)
+		if !ok {
+			utils.AbortWithError(context, http.StatusUnauthorized, "invalid user type")
+			return
+		}
+
+		organizationID, err := uuid.Parse(context.Param("orgID"))
+		if err != nil {
+			utils.AbortWithError(context, http.StatusBadRequest, "Invalid UUID format")
+			return
+		}
+
+		database, err := utils.GetDatabaseInContext(context)
+		if err != nil {
+			utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		var membership models.Membership
+		err = database.Where("organization_id = ? AND user_id = ?", organizationID, currentUser.ID).First(&membership).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.AbortWithError(context, http.StatusForbidden, "You are not a member of this organization")
+			} else {
+				utils.AbortWithError(context, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		if len(roles) > 0 && !slices.Contains(roles, membership.Role) {
+			utils.AbortWithError(context, http.StatusForbidden, "You are not allowed")
+			return
+		}
+
+		scoped := database.WithContext(db.WithOrganization(context.Request.Context(), organizationID))
+		context.Set("db", scoped)
+		context.Set("currentMembership", &membership)
+		context.Next()
+	}
+}
@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gormDB "github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/models/builders"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestOrganizationScope(t *testing.T) {
+	method := "GET"
+	queryFirst := `SELECT \* FROM "memberships" WHERE (.+) LIMIT`
+
+	john := builders.NewUserBuilder().Build()
+	organizationID := uuid.New()
+
+	tests := []struct {
+		name         string
+		orgID        string
+		setUser      bool
+		roles        []string
+		membership   []models.Membership
+		expectedCode int
+	}{
+		{
+			name:    "Member without role requirement",
+			orgID:   organizationID.String(),
+			setUser: true,
+			membership: []models.Membership{
+				{ID: uuid.New(), OrganizationID: organizationID, UserID: john.ID, Role: models.OrganizationRoleStudent},
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:    "Member with required role",
+			orgID:   organizationID.String(),
+			setUser: true,
+			roles:   []string{models.OrganizationRoleOwner, models.OrganizationRoleTeacher},
+			membership: []models.Membership{
+				{ID: uuid.New(), OrganizationID: organizationID, UserID: john.ID, Role: models.OrganizationRoleTeacher},
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:    "Member without required role",
+			orgID:   organizationID.String(),
+			setUser: true,
+			roles:   []string{models.OrganizationRoleOwner},
+			membership: []models.Membership{
+				{ID: uuid.New(), OrganizationID: organizationID, UserID: john.ID, Role: models.OrganizationRoleStudent},
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Not a member",
+			orgID:        organizationID.String(),
+			setUser:      true,
+			membership:   nil,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Invalid organization id",
+			orgID:        "1234",
+			setUser:      true,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "No user",
+			orgID:        organizationID.String(),
+			setUser:      false,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupRouter()
+			defer sqlDB.Close()
+
+			handlers := []gin.HandlerFunc{}
+			if tt.setUser {
+				handlers = append(handlers, setCurrentUser(john))
+			}
+			handlers = append(handlers, OrganizationScope(tt.roles...), func(context *gin.Context) {
+				scoped, err := utils.GetDatabaseInContext(context)
+				if err != nil {
+					t.Errorf("error = %v", err)
+					return
+				}
+				scopedID, ok := gormDB.OrganizationFromContext(scoped.Statement.Context)
+				if !ok || scopedID != organizationID {
+					t.Errorf("database is not scoped to the organization")
+				}
+				if _, exists := context.Get("currentMembership"); !exists {
+					t.Errorf("currentMembership is not set")
+				}
+			})
+			router.GET("/:orgID", handlers...)
+
+			if tt.membership != nil {
+				mock.ExpectQuery(queryFirst).WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.membership))
+			} else {
+				mock.ExpectQuery(queryFirst).WillReturnError(gorm.ErrRecordNotFound)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, "/"+tt.orgID, nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("HTTP status code = %v, expected %v", w.Code, tt.expectedCode)
+			}
+		})
+	}
+}
@@ -22,7 +22,7 @@ func NewUserBuilder() *UserBuilder {
 			ID:        uuid.New(),
 			FirstName: "John",
 			Name:      "Doe",
-			Birthday:  time.Now(),
+			Birthday:  time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC),
 			Gender:    "male",
 			Email:     "john.doe@mail.pe",
 			Password:  "Password123.",
@@ -40,12 +40,14 @@ func (ub *UserBuilder) Build() models.User {
 // BuildSignUpInput constructs and returns a SignUpInput model based on the User model configured by the builder.
 func (ub *UserBuilder) BuildSignUpInput() models.SignUpInput {
 	return models.SignUpInput{
-		FirstName: ub.u.FirstName,
-		Name:      ub.u.Name,
-		Birthday:  ub.u.Birthday,
-		Gender:    ub.u.Gender,
-		Email:     ub.u.Email,
-		Password:  ub.u.Password,
+		FirstName:      ub.u.FirstName,
+		Name:           ub.u.Name,
+		Birthday:       ub.u.Birthday,
+		Gender:         ub.u.Gender,
+		Email:          ub.u.Email,
+		Password:       ub.u.Password,
+		Code:           ub.u.SubscriptionCode.String,
+		NativeLanguage: ub.u.NativeLanguage,
 	}
 }
 
@@ -105,6 +107,12 @@ func (ub *UserBuilder) WhereRole(role string) *UserBuilder {
 	return ub
 }
 
+// WhereNativeLanguage sets the NativeLanguage of the user being built and returns the UserBuilder.
+func (ub *UserBuilder) WhereNativeLanguage(nativeLanguage string) *UserBuilder {
+	ub.u.NativeLanguage = nativeLanguage
+	return ub
+}
+
 // WhereAddress sets the Address of the user being built and returns the UserBuilder.
 func (ub *UserBuilder) WhereAddress(address sql.NullString) *UserBuilder {
 	ub.u.Address = address
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CodeRedemption records a user redeeming a subscription code.
+// @Description CodeRedemption holds the history of code redemptions.
+type CodeRedemption struct {
+	ID         uuid.UUID `gorm:"type:char(36);primary_key"`    // Unique identifier for the redemption
+	CodeID     uuid.UUID `gorm:"type:char(36);index;not null"` // Redeemed code
+	UserID     uuid.UUID `gorm:"type:char(36);index;not null"` // User who redeemed the code
+	RedeemedAt time.Time `gorm:"not null"`                     // Timestamp when the code was redeemed
+}
+
+// BeforeCreate is a GORM hook that is called before a new redemption record is created.
+// It assigns a new UUID to the redemption's ID.
+func (r *CodeRedemption) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+	return
+}
+
+// CodeRedemptionResponse represents a redemption returned by the API.
+// @Description CodeRedemptionResponse holds the data exposed to administrators for a redemption.
+type CodeRedemptionResponse struct {
+	ID         uuid.UUID `json:"id"`          // Unique identifier for the redemption
+	CodeID     uuid.UUID `json:"code_id"`     // Redeemed code
+	Code       string    `json:"code"`        // Redeemed code as typed by the user
+	Plan       string    `json:"plan"`        // Plan granted by the code
+	UserID     uuid.UUID `json:"user_id"`     // User who redeemed the code
+	Email      string    `json:"email"`       // Email address of the user who redeemed the code
+	RedeemedAt time.Time `json:"redeemed_at"` // Timestamp when the code was redeemed
+}
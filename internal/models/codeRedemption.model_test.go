@@ -0,0 +1,17 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeRedemption_BeforeCreate(t *testing.T) {
+	redemption := &models.CodeRedemption{}
+	err := redemption.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, redemption.ID)
+}
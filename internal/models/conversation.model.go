@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// languageCode matches an ISO 639-1 language code, optionally followed by a region, e.g. "es" or "pt-BR".
+var languageCode = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// Modes of a conversation.
+const (
+	ConversationModeChat    = "chat"    // ConversationModeChat answers the messages of the learner at once.
+	ConversationModeLetters = "letters" // ConversationModeLetters answers the messages of the learner like letters, after a delay.
+)
+
+// Conversation represents a chat between a user and an AI pen pal.
+// @Description Conversation holds the details of a conversation.
+type Conversation struct {
+	ID              uuid.UUID     `gorm:"type:char(36);primary_key"`              // Unique identifier for the conversation
+	UserID          uuid.UUID     `gorm:"type:char(36);not null;index"`           // Owner of the conversation
+	PersonaID       uuid.NullUUID `gorm:"type:char(36)"`                          // Optional pen pal the user talks to
+	Language        string        `gorm:"type:varchar(16);not null"`              // Language the conversation is held in
+	Title           string        `gorm:"type:varchar(255);not null"`             // Title of the conversation
+	Mode            string        `gorm:"type:varchar(16);not null;default:chat"` // Either chat or letters
+	Summary         string        `gorm:"type:text"`                              // Rolling summary of the older messages, sent instead of them to the language model
+	SummarizedUntil sql.NullTime  // Timestamp of the last message folded into the summary
+	SummarizedID    uuid.NullUUID `gorm:"type:char(36)"` // Last message folded into the summary, which only applies to the branches going through it
+	ActiveLeafID    uuid.NullUUID `gorm:"type:char(36)"` // Last message of the branch shown to the user and answered next, null until the first message
+	ArchivedAt      sql.NullTime  // Optional timestamp when the conversation was archived
+	CreatedAt       time.Time     `gorm:"not null"` // Timestamp when the conversation was created
+	UpdatedAt       time.Time     `gorm:"not null"` // Timestamp of the last activity in the conversation
+}
+
+// BeforeCreate is a GORM hook that is called before a new conversation record is created.
+// It assigns a new UUID to the conversation's ID.
+func (c *Conversation) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the conversation into the representation exposed by the API.
+func (c Conversation) ToResponse() ConversationResponse {
+	response := ConversationResponse{
+		ID:        c.ID,
+		Language:  c.Language,
+		Title:     c.Title,
+		Mode:      c.Mode,
+		Archived:  c.ArchivedAt.Valid,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.PersonaID.Valid {
+		response.PersonaID = &c.PersonaID.UUID
+	}
+	if c.ActiveLeafID.Valid {
+		response.ActiveLeafID = &c.ActiveLeafID.UUID
+	}
+	return response
+}
+
+// ConversationInput represents the fields required to start a conversation.
+// @Description Fields required to start a conversation.
+type ConversationInput struct {
+	Title     string     `json:"title" binding:"required"`    // Title of the conversation
+	Language  string     `json:"language" binding:"required"` // Language the conversation is held in, e.g. "es"
+	PersonaID *uuid.UUID `json:"persona_id"`                  // Optional pen pal the user talks to
+	Mode      string     `json:"mode"`                        // Either chat, by default, or letters
+}
+
+// Validate performs validation on ConversationInput fields.
+func (i ConversationInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Title, validation.Required, validation.Length(1, 100)),
+		validation.Field(&i.Language, validation.Required, validation.Match(languageCode)),
+		validation.Field(&i.Mode, validation.In(ConversationModeChat, ConversationModeLetters)),
+	)
+}
+
+// ConversationRenameInput represents the fields required to rename a conversation.
+// @Description Fields required to rename a conversation.
+type ConversationRenameInput struct {
+	Title string `json:"title" binding:"required"` // New title of the conversation
+}
+
+// Validate performs validation on ConversationRenameInput fields.
+func (i ConversationRenameInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Title, validation.Required, validation.Length(1, 100)),
+	)
+}
+
+// ConversationResponse represents a conversation returned by the API.
+// @Description ConversationResponse holds the data exposed to the client for a conversation.
+type ConversationResponse struct {
+	ID           uuid.UUID  `json:"id"`                       // Unique identifier for the conversation
+	PersonaID    *uuid.UUID `json:"persona_id,omitempty"`     // Pen pal the user talks to, omitted if none
+	Language     string     `json:"language"`                 // Language the conversation is held in
+	Title        string     `json:"title"`                    // Title of the conversation
+	Mode         string     `json:"mode"`                     // Either chat or letters
+	Archived     bool       `json:"archived"`                 // Whether the conversation was archived
+	ActiveLeafID *uuid.UUID `json:"active_leaf_id,omitempty"` // Last message of the active branch, omitted before the first message
+	CreatedAt    time.Time  `json:"created_at"`               // Timestamp when the conversation was created
+	UpdatedAt    time.Time  `json:"updated_at"`               // Timestamp of the last activity in the conversation
+}
@@ -0,0 +1,99 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversation_BeforeCreate(t *testing.T) {
+	conversation := &models.Conversation{}
+	err := conversation.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, conversation.ID)
+}
+
+func TestConversation_ToResponse(t *testing.T) {
+	conversation := models.Conversation{ID: uuid.New(), Language: "es", Title: "En el mercado", Mode: models.ConversationModeChat}
+	response := conversation.ToResponse()
+	assert.Equal(t, models.ConversationModeChat, response.Mode)
+	assert.Nil(t, response.PersonaID)
+	assert.Nil(t, response.ActiveLeafID)
+	assert.False(t, response.Archived)
+	assert.Equal(t, conversation.Title, response.Title)
+
+	personaID := uuid.New()
+	conversation.PersonaID = uuid.NullUUID{UUID: personaID, Valid: true}
+	conversation.ArchivedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	leafID := uuid.New()
+	conversation.ActiveLeafID = uuid.NullUUID{UUID: leafID, Valid: true}
+	response = conversation.ToResponse()
+	assert.Equal(t, &personaID, response.PersonaID)
+	assert.Equal(t, &leafID, response.ActiveLeafID)
+	assert.True(t, response.Archived)
+}
+
+func TestConversationInputValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.ConversationInput
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.ConversationInput{Title: "En el mercado", Language: "es"},
+			expectedError: false,
+		},
+		{
+			name:          "valid input with region",
+			input:         models.ConversationInput{Title: "Na praia", Language: "pt-BR"},
+			expectedError: false,
+		},
+		{
+			name:          "valid input in letters mode",
+			input:         models.ConversationInput{Title: "Cartas", Language: "es", Mode: models.ConversationModeLetters},
+			expectedError: false,
+		},
+		{
+			name:          "invalid mode",
+			input:         models.ConversationInput{Title: "Cartas", Language: "es", Mode: "email"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid language",
+			input:         models.ConversationInput{Title: "En el mercado", Language: "spanish"},
+			expectedError: true,
+		},
+		{
+			name:          "title too long",
+			input:         models.ConversationInput{Title: string(make([]byte, 101)), Language: "es"},
+			expectedError: true,
+		},
+		{
+			name:          "missing title",
+			input:         models.ConversationInput{Language: "es"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConversationRenameInputValidation(t *testing.T) {
+	assert.NoError(t, models.ConversationRenameInput{Title: "At the market"}.Validate())
+	assert.Error(t, models.ConversationRenameInput{}.Validate())
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Categories of the mistakes of the learners.
+const (
+	CorrectionGrammar     = "grammar"     // CorrectionGrammar is a mistake of conjugation, agreement or construction.
+	CorrectionSpelling    = "spelling"    // CorrectionSpelling is a misspelled word, accents included.
+	CorrectionVocabulary  = "vocabulary"  // CorrectionVocabulary is a wrong or unidiomatic word choice.
+	CorrectionPunctuation = "punctuation" // CorrectionPunctuation is a missing or wrong punctuation mark.
+	CorrectionStyle       = "style"       // CorrectionStyle is a correct but unnatural phrasing.
+)
+
+// CorrectionCategories lists the categories of the mistakes.
+var CorrectionCategories = []interface{}{
+	CorrectionGrammar, CorrectionSpelling, CorrectionVocabulary, CorrectionPunctuation, CorrectionStyle,
+}
+
+// Correction represents a mistake in a message of the learner, located by its offsets in the message so that clients
+// can underline it. Offsets count characters (Unicode code points) from 0, the end being excluded.
+// @Description Correction holds a mistake of the learner and its correction.
+type Correction struct {
+	Start       int    `json:"start"`       // Offset of the first character of the mistake
+	End         int    `json:"end"`         // Offset following the last character of the mistake
+	Original    string `json:"original"`    // Text of the mistake
+	Suggestion  string `json:"suggestion"`  // Corrected text, empty if the text should be removed
+	Category    string `json:"category"`    // Category of the mistake
+	Explanation string `json:"explanation"` // Explanation of the mistake, in the native language of the learner
+}
+
+// Validate performs validation on Correction fields.
+func (c Correction) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Start, validation.Min(0)),
+		validation.Field(&c.End, validation.Required, validation.Min(c.Start+1)),
+		validation.Field(&c.Original, validation.Required, validation.Length(1, 255)),
+		validation.Field(&c.Suggestion, validation.Length(0, 255), validation.NotIn(c.Original).Error("must differ from the original")),
+		validation.Field(&c.Category, validation.Required, validation.In(CorrectionCategories...)),
+		validation.Field(&c.Explanation, validation.Required, validation.Length(1, 500)),
+	)
+}
+
+// Matches reports whether the offsets of the correction locate its original text in the content.
+func (c Correction) Matches(content string) bool {
+	runes := []rune(content)
+	return c.Start >= 0 && c.Start < c.End && c.End <= len(runes) && string(runes[c.Start:c.End]) == c.Original
+}
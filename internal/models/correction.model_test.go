@@ -0,0 +1,53 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrectionValidation(t *testing.T) {
+	valid := models.Correction{
+		Start:       3,
+		End:         8,
+		Original:    "tengo",
+		Suggestion:  "tiene",
+		Category:    models.CorrectionGrammar,
+		Explanation: "« Él » se conjugue à la troisième personne.",
+	}
+
+	tests := []struct {
+		name          string
+		update        func(c *models.Correction)
+		expectedError bool
+	}{
+		{name: "valid correction", update: func(c *models.Correction) {}},
+		{name: "removed text", update: func(c *models.Correction) { c.Suggestion = "" }},
+		{name: "empty span", update: func(c *models.Correction) { c.End = c.Start }, expectedError: true},
+		{name: "negative start", update: func(c *models.Correction) { c.Start = -1 }, expectedError: true},
+		{name: "no original", update: func(c *models.Correction) { c.Original = "" }, expectedError: true},
+		{name: "unchanged text", update: func(c *models.Correction) { c.Suggestion = c.Original }, expectedError: true},
+		{name: "unknown category", update: func(c *models.Correction) { c.Category = "tone" }, expectedError: true},
+		{name: "no explanation", update: func(c *models.Correction) { c.Explanation = "" }, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			correction := valid
+			tt.update(&correction)
+			err := correction.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("Correction.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCorrection_Matches(t *testing.T) {
+	content := "Él tengo un perro"
+	assert.True(t, models.Correction{Start: 3, End: 8, Original: "tengo"}.Matches(content))
+	assert.False(t, models.Correction{Start: 4, End: 9, Original: "tengo"}.Matches(content))
+	assert.False(t, models.Correction{Start: 12, End: 20, Original: "perro"}.Matches(content))
+	assert.False(t, models.Correction{Start: -1, End: 2, Original: "Él"}.Matches(content))
+}
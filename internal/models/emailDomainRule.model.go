@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Kinds of email domain rules.
+const (
+	EmailDomainAllow = "allow" // EmailDomainAllow lets a domain register, even in allowlist mode or when it is in the blocklist file.
+	EmailDomainBlock = "block" // EmailDomainBlock keeps a domain from registering.
+)
+
+// EmailDomainRule represents a rule managed by administrators allowing or blocking the registration
+// of the addresses of a domain and its subdomains.
+// @Description EmailDomainRule holds the details of an email domain rule.
+type EmailDomainRule struct {
+	ID          uuid.UUID `gorm:"type:char(36);primary_key"`              // Unique identifier for the rule
+	Domain      string    `gorm:"type:varchar(255);uniqueIndex;not null"` // Domain the rule applies to, lower case
+	Kind        string    `gorm:"type:varchar(255);not null"`             // Whether the domain is allowed or blocked
+	Note        string    `gorm:"type:varchar(255)"`                      // Optional note, e.g. the school owning the domain
+	CreatedByID uuid.UUID `gorm:"type:char(36);not null"`                 // Administrator who created the rule
+	CreatedAt   time.Time `gorm:"not null"`                               // Timestamp when the rule was created
+}
+
+// BeforeCreate is a GORM hook that is called before a new rule record is created.
+// It assigns a new UUID to the rule's ID.
+func (r *EmailDomainRule) BeforeCreate(tx *gorm.DB) (err error) {
+	r.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the rule into the representation exposed by the API.
+func (r EmailDomainRule) ToResponse() EmailDomainRuleResponse {
+	return EmailDomainRuleResponse{
+		ID:          r.ID,
+		Domain:      r.Domain,
+		Kind:        r.Kind,
+		Note:        r.Note,
+		CreatedByID: r.CreatedByID,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// EmailDomainRuleInput represents the fields required to create an email domain rule.
+// @Description Fields required to create an email domain rule.
+type EmailDomainRuleInput struct {
+	Domain string `json:"domain" binding:"required"` // Domain the rule applies to
+	Kind   string `json:"kind" binding:"required"`   // Either "allow" or "block"
+	Note   string `json:"note"`                      // Optional note
+}
+
+// Validate performs validation on EmailDomainRuleInput fields.
+func (i EmailDomainRuleInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Domain, validation.Required, is.Domain),
+		validation.Field(&i.Kind, validation.Required, validation.In(EmailDomainAllow, EmailDomainBlock)),
+		validation.Field(&i.Note, validation.Length(0, 255)),
+	)
+}
+
+// EmailDomainRuleResponse represents an email domain rule returned by the API.
+// @Description EmailDomainRuleResponse holds the data exposed to administrators for an email domain rule.
+type EmailDomainRuleResponse struct {
+	ID          uuid.UUID `json:"id"`            // Unique identifier for the rule
+	Domain      string    `json:"domain"`        // Domain the rule applies to
+	Kind        string    `json:"kind"`          // Whether the domain is allowed or blocked
+	Note        string    `json:"note"`          // Optional note
+	CreatedByID uuid.UUID `json:"created_by_id"` // Administrator who created the rule
+	CreatedAt   time.Time `json:"created_at"`    // Timestamp when the rule was created
+}
@@ -0,0 +1,53 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailDomainRule_BeforeCreate(t *testing.T) {
+	rule := &models.EmailDomainRule{}
+	err := rule.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, rule.ID)
+}
+
+func TestEmailDomainRuleInputValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.EmailDomainRuleInput
+		expectedError bool
+	}{
+		{
+			name:          "valid allow rule",
+			input:         models.EmailDomainRuleInput{Domain: "school.edu", Kind: models.EmailDomainAllow, Note: "Lincoln High"},
+			expectedError: false,
+		},
+		{
+			name:          "valid block rule",
+			input:         models.EmailDomainRuleInput{Domain: "spam.example", Kind: models.EmailDomainBlock},
+			expectedError: false,
+		},
+		{
+			name:          "invalid domain",
+			input:         models.EmailDomainRuleInput{Domain: "not a domain", Kind: models.EmailDomainBlock},
+			expectedError: true,
+		},
+		{
+			name:          "invalid kind",
+			input:         models.EmailDomainRuleInput{Domain: "school.edu", Kind: "maybe"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.Equal(t, tt.expectedError, err != nil)
+		})
+	}
+}
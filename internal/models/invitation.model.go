@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Invitation represents a pending invitation to join an organization, sent by email.
+// Only the hash of the invitation token is stored.
+// Invitations are tenant scoped: see db.RegisterTenantScope.
+// @Description Invitation holds the details of an invitation to join an organization.
+type Invitation struct {
+	ID             uuid.UUID     `gorm:"type:char(36);primary_key"`             // Unique identifier for the invitation
+	OrganizationID uuid.UUID     `gorm:"type:char(36);not null;index"`          // Organization the invitation is for
+	Email          string        `gorm:"type:varchar(255);not null"`            // Email address of the invited person
+	Role           string        `gorm:"type:varchar(255);not null"`            // Role granted when the invitation is accepted
+	TokenHash      string        `gorm:"type:varchar(64);uniqueIndex;not null"` // SHA-256 hash of the invitation token
+	InvitedByID    uuid.UUID     `gorm:"type:char(36);not null"`                // User who sent the invitation
+	TeacherID      uuid.NullUUID `gorm:"type:char(36)"`                         // Teacher in charge of the student once the invitation is accepted, if any
+	ExpiresAt      time.Time     `gorm:"not null"`                              // Timestamp after which the invitation can no longer be accepted
+	AcceptedAt     sql.NullTime  // Optional timestamp when the invitation was accepted
+	CreatedAt      time.Time     `gorm:"not null"` // Timestamp when the invitation was created
+}
+
+// BeforeCreate is a GORM hook that is called before a new invitation record is created.
+// It assigns a new UUID to the invitation's ID.
+func (i *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	return
+}
+
+// IsPending reports whether the invitation can still be accepted at the given time.
+func (i Invitation) IsPending(now time.Time) bool {
+	return !i.AcceptedAt.Valid && now.Before(i.ExpiresAt)
+}
+
+// ToResponse converts the invitation into the representation exposed by the API.
+func (i Invitation) ToResponse() InvitationResponse {
+	response := InvitationResponse{
+		ID:        i.ID,
+		Email:     i.Email,
+		Role:      i.Role,
+		ExpiresAt: i.ExpiresAt,
+		CreatedAt: i.CreatedAt,
+	}
+	if i.AcceptedAt.Valid {
+		response.AcceptedAt = &i.AcceptedAt.Time
+	}
+	return response
+}
+
+// InvitationInput represents the fields required to invite someone into an organization.
+// @Description Fields required to invite someone into an organization.
+type InvitationInput struct {
+	Email string `json:"email" binding:"required"` // Email address of the invited person
+	Role  string `json:"role" binding:"required"`  // Role granted when the invitation is accepted
+}
+
+// Validate performs validation on InvitationInput fields.
+func (i InvitationInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Email, validation.Required, is.Email),
+		validation.Field(&i.Role, validation.Required, validation.In(OrganizationRoleOwner, OrganizationRoleTeacher, OrganizationRoleStudent)),
+	)
+}
+
+// InvitationResponse represents an invitation returned by the API.
+// @Description InvitationResponse holds the data exposed to the client for an invitation.
+type InvitationResponse struct {
+	ID         uuid.UUID  `json:"id"`                    // Unique identifier for the invitation
+	Email      string     `json:"email"`                 // Email address of the invited person
+	Role       string     `json:"role"`                  // Role granted when the invitation is accepted
+	ExpiresAt  time.Time  `json:"expires_at"`            // Timestamp after which the invitation can no longer be accepted
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"` // Timestamp when the invitation was accepted, omitted if pending
+	CreatedAt  time.Time  `json:"created_at"`            // Timestamp when the invitation was created
+}
@@ -0,0 +1,94 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitation_BeforeCreate(t *testing.T) {
+	invitation := &models.Invitation{}
+	err := invitation.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, invitation.ID)
+}
+
+func TestInvitation_IsPending(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		invitation models.Invitation
+		expected   bool
+	}{
+		{
+			name:       "pending",
+			invitation: models.Invitation{ExpiresAt: now.Add(time.Hour)},
+			expected:   true,
+		},
+		{
+			name:       "expired",
+			invitation: models.Invitation{ExpiresAt: now.Add(-time.Hour)},
+			expected:   false,
+		},
+		{
+			name:       "accepted",
+			invitation: models.Invitation{ExpiresAt: now.Add(time.Hour), AcceptedAt: sql.NullTime{Time: now, Valid: true}},
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.invitation.IsPending(now))
+		})
+	}
+}
+
+func TestInvitationInputValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.InvitationInput
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.InvitationInput{Email: "john.doe@mail.pe", Role: models.OrganizationRoleStudent},
+			expectedError: false,
+		},
+		{
+			name:          "invalid Email",
+			input:         models.InvitationInput{Email: "john.doe", Role: models.OrganizationRoleStudent},
+			expectedError: true,
+		},
+		{
+			name:          "invalid Role",
+			input:         models.InvitationInput{Email: "john.doe@mail.pe", Role: "admin"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("InvitationInput.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestInvitation_ToResponse(t *testing.T) {
+	now := time.Now()
+	invitation := models.Invitation{ID: uuid.New(), Email: "john.doe@mail.pe", TokenHash: "hash"}
+
+	assert.Nil(t, invitation.ToResponse().AcceptedAt)
+
+	invitation.AcceptedAt = sql.NullTime{Time: now, Valid: true}
+	assert.Equal(t, &now, invitation.ToResponse().AcceptedAt)
+}
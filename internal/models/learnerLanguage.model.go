@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LearningGoals lists the reasons a learner can give for learning a language.
+var LearningGoals = []string{"conversation", "travel", "work", "studies", "exams", "culture"}
+
+// LearnerLanguage represents a language a user learns, with their CEFR level and their goals.
+// @Description LearnerLanguage holds the level and the goals of a user in a language they learn.
+type LearnerLanguage struct {
+	ID        uuid.UUID    `gorm:"type:char(36);primary_key"`                                                 // Unique identifier for the language of the learner
+	UserID    uuid.UUID    `gorm:"type:char(36);uniqueIndex:idx_learner_languages_user_language;not null"`    // User learning the language
+	Language  string       `gorm:"type:varchar(16);uniqueIndex:idx_learner_languages_user_language;not null"` // Language learned
+	Level     string       `gorm:"type:varchar(2)"`                                                           // CEFR level of the learner, empty if unknown
+	Goals     []string     `gorm:"type:text;serializer:json"`                                                 // Reasons the learner learns the language
+	PlacedAt  sql.NullTime // Timestamp of the placement test which set the level, null if the learner set it
+	CreatedAt time.Time    `gorm:"not null"` // Timestamp when the language was added
+	UpdatedAt time.Time    `gorm:"not null"` // Timestamp when the language was last updated
+}
+
+// BeforeCreate is a GORM hook that is called before a new learner language record is created.
+// It assigns a new UUID to the language's ID.
+func (l *LearnerLanguage) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the language of the learner into the representation exposed by the API.
+func (l LearnerLanguage) ToResponse() LearnerLanguageResponse {
+	response := LearnerLanguageResponse{
+		Language: l.Language,
+		Level:    l.Level,
+		Goals:    l.Goals,
+	}
+	if response.Goals == nil {
+		response.Goals = []string{}
+	}
+	if l.PlacedAt.Valid {
+		response.PlacedAt = &l.PlacedAt.Time
+	}
+	return response
+}
+
+// ProfileInput represents the fields of the learner profile that can be changed.
+// @Description Fields of the learner profile that can be changed.
+type ProfileInput struct {
+	NativeLanguage string `json:"native_language" binding:"required"` // Language the user speaks natively, e.g. "fr"
+}
+
+// Validate performs validation on ProfileInput fields.
+func (i ProfileInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.NativeLanguage, validation.Required, validation.Match(languageCode)),
+	)
+}
+
+// LearnerLanguageInput represents the fields of a language the user learns.
+// @Description Level and goals of the user in a language they learn.
+type LearnerLanguageInput struct {
+	Language string   `json:"-"`     // Language learned, taken from the path
+	Level    string   `json:"level"` // CEFR level of the learner, empty if unknown
+	Goals    []string `json:"goals"` // Reasons the learner learns the language, among conversation, travel, work, studies, exams and culture
+}
+
+// Validate performs validation on LearnerLanguageInput fields.
+func (i LearnerLanguageInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Language, validation.Required, validation.Match(languageCode)),
+		validation.Field(&i.Level, validation.By(validLevel)),
+		validation.Field(&i.Goals, validation.Length(0, len(LearningGoals)), validation.By(validGoals)),
+	)
+}
+
+// LearnerLanguageResponse represents a language of the learner returned by the API.
+// @Description LearnerLanguageResponse holds the level and the goals of the user in a language.
+type LearnerLanguageResponse struct {
+	Language string     `json:"language"`            // Language learned
+	Level    string     `json:"level"`               // CEFR level of the learner, empty if unknown
+	Goals    []string   `json:"goals"`               // Reasons the learner learns the language
+	PlacedAt *time.Time `json:"placed_at,omitempty"` // Timestamp of the placement test which set the level
+}
+
+// ProfileResponse represents the learner profile of a user.
+// @Description ProfileResponse holds the native language of the user and the languages they learn.
+type ProfileResponse struct {
+	NativeLanguage string                    `json:"native_language"` // Language the user speaks natively
+	Languages      []LearnerLanguageResponse `json:"languages"`       // Languages the user learns
+}
+
+// validLevel is a validation rule checking that a string is empty or a CEFR level.
+func validLevel(value interface{}) error {
+	level, _ := value.(string)
+	if level != "" && !slices.Contains(CEFRLevels, level) {
+		return errors.New("must be a CEFR level")
+	}
+	return nil
+}
+
+// validGoals is a validation rule checking that every element of a list is a learning goal.
+func validGoals(value interface{}) error {
+	goals, _ := value.([]string)
+	for _, goal := range goals {
+		if !slices.Contains(LearningGoals, goal) {
+			return errors.New("must only contain learning goals")
+		}
+	}
+	return nil
+}
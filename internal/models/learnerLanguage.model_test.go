@@ -0,0 +1,50 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLearnerLanguage_BeforeCreate(t *testing.T) {
+	language := &models.LearnerLanguage{}
+	err := language.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, language.ID)
+}
+
+func TestLearnerLanguage_ToResponse(t *testing.T) {
+	language := models.LearnerLanguage{ID: uuid.New(), Language: "es", Level: "B1"}
+	response := language.ToResponse()
+
+	assert.Equal(t, "es", response.Language)
+	assert.Equal(t, "B1", response.Level)
+	assert.Equal(t, []string{}, response.Goals)
+	assert.Nil(t, response.PlacedAt)
+
+	language.Goals = []string{"travel"}
+	language.PlacedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	response = language.ToResponse()
+	assert.Equal(t, []string{"travel"}, response.Goals)
+	assert.Equal(t, language.PlacedAt.Time, *response.PlacedAt)
+}
+
+func TestProfileInputValidation(t *testing.T) {
+	assert.NoError(t, models.ProfileInput{NativeLanguage: "fr"}.Validate())
+	assert.NoError(t, models.ProfileInput{NativeLanguage: "pt-BR"}.Validate())
+	assert.Error(t, models.ProfileInput{NativeLanguage: "French"}.Validate())
+	assert.Error(t, models.ProfileInput{}.Validate())
+}
+
+func TestLearnerLanguageInputValidation(t *testing.T) {
+	assert.NoError(t, models.LearnerLanguageInput{Language: "es"}.Validate())
+	assert.NoError(t, models.LearnerLanguageInput{Language: "es", Level: "C2", Goals: []string{"work", "culture"}}.Validate())
+	assert.Error(t, models.LearnerLanguageInput{Language: "es", Level: "b1"}.Validate())
+	assert.Error(t, models.LearnerLanguageInput{Language: "es", Goals: []string{"fun"}}.Validate())
+	assert.Error(t, models.LearnerLanguageInput{Language: "Spanish", Level: "B1"}.Validate())
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statuses of a letter.
+const (
+	LetterStatusPending   = "pending"   // LetterStatusPending waits for its delivery time.
+	LetterStatusSending   = "sending"   // LetterStatusSending is being answered by the letters worker.
+	LetterStatusDelivered = "delivered" // LetterStatusDelivered was answered.
+	LetterStatusCancelled = "cancelled" // LetterStatusCancelled was cancelled by the learner before its delivery.
+	LetterStatusFailed    = "failed"    // LetterStatusFailed couldn't be answered.
+)
+
+// Letter represents the scheduled reply of the pen pal to a message sent in a conversation in letters mode.
+// The schedule is stored, so that a reply due while the server is down is delivered once it is back up.
+// @Description Letter holds the details of a scheduled reply of a pen pal.
+type Letter struct {
+	ID             uuid.UUID     `gorm:"type:char(36);primary_key"`                       // Unique identifier for the letter
+	ConversationID uuid.UUID     `gorm:"type:char(36);index;not null"`                    // Conversation the letter belongs to
+	UserID         uuid.UUID     `gorm:"type:char(36);index;not null"`                    // Learner waiting for the reply
+	MessageID      uuid.UUID     `gorm:"type:char(36);not null"`                          // Message of the learner answered
+	ReplyID        uuid.NullUUID `gorm:"type:char(36)"`                                   // Reply of the pen pal, null until the letter is delivered
+	Regenerated    bool          `gorm:"not null;default:false"`                          // Whether the letter replaces an earlier reply to the message
+	Status         string        `gorm:"type:varchar(16);index:idx_letters_due;not null"` // Either pending, sending, delivered, cancelled or failed
+	DeliverAt      time.Time     `gorm:"index:idx_letters_due;not null"`                  // Timestamp from which the reply can be delivered
+	Attempts       int           `gorm:"not null;default:0"`                              // Number of times the letters worker tried to deliver the reply
+	LastError      string        `gorm:"type:text"`                                       // Error of the last failed delivery attempt
+	ClaimedAt      sql.NullTime  // Timestamp when the letters worker last started to deliver the reply
+	DeliveredAt    sql.NullTime  // Timestamp when the reply was delivered
+	CancelledAt    sql.NullTime  // Timestamp when the learner cancelled the letter
+	CreatedAt      time.Time     `gorm:"not null"` // Timestamp when the letter was sent
+}
+
+// BeforeCreate is a GORM hook that is called before a new letter record is created.
+// It assigns a new UUID to the letter's ID.
+func (l *Letter) BeforeCreate(tx *gorm.DB) (err error) {
+	l.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the letter into the representation exposed to its learner.
+func (l Letter) ToResponse() LetterResponse {
+	response := LetterResponse{
+		ID:             l.ID,
+		ConversationID: l.ConversationID,
+		MessageID:      l.MessageID,
+		Status:         l.Status,
+		DeliverAt:      l.DeliverAt,
+		CreatedAt:      l.CreatedAt,
+	}
+	if l.ReplyID.Valid {
+		response.ReplyID = &l.ReplyID.UUID
+	}
+	if l.DeliveredAt.Valid {
+		response.DeliveredAt = &l.DeliveredAt.Time
+	}
+	if l.CancelledAt.Valid {
+		response.CancelledAt = &l.CancelledAt.Time
+	}
+	return response
+}
+
+// LetterResponse represents a letter returned to its learner.
+// @Description LetterResponse holds the details of a scheduled reply of a pen pal.
+type LetterResponse struct {
+	ID             uuid.UUID  `json:"id"`                     // Unique identifier for the letter
+	ConversationID uuid.UUID  `json:"conversation_id"`        // Conversation the letter belongs to
+	MessageID      uuid.UUID  `json:"message_id"`             // Message of the learner answered
+	ReplyID        *uuid.UUID `json:"reply_id,omitempty"`     // Reply of the pen pal, omitted until the letter is delivered
+	Status         string     `json:"status"`                 // Either pending, sending, delivered, cancelled or failed
+	DeliverAt      time.Time  `json:"deliver_at"`             // Timestamp from which the reply can be delivered
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"` // Timestamp when the reply was delivered
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty"` // Timestamp when the learner cancelled the letter
+	CreatedAt      time.Time  `json:"created_at"`             // Timestamp when the letter was sent
+}
+
+// LetterScheduledResponse represents a message of the learner sent in letters mode and the letter answering it.
+// @Description LetterScheduledResponse holds a message sent in letters mode and its scheduled reply.
+type LetterScheduledResponse struct {
+	Message MessageResponse `json:"message"` // Message of the learner
+	Letter  LetterResponse  `json:"letter"`  // Scheduled reply of the pen pal
+}
@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLetter_BeforeCreate(t *testing.T) {
+	letter := &models.Letter{}
+	err := letter.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, letter.ID)
+}
+
+func TestLetter_ToResponse(t *testing.T) {
+	now := time.Now()
+	letter := models.Letter{
+		ID:             uuid.New(),
+		ConversationID: uuid.New(),
+		MessageID:      uuid.New(),
+		Status:         models.LetterStatusPending,
+		DeliverAt:      now.Add(time.Hour),
+		CreatedAt:      now,
+	}
+	response := letter.ToResponse()
+	assert.Equal(t, letter.MessageID, response.MessageID)
+	assert.Equal(t, models.LetterStatusPending, response.Status)
+	assert.Nil(t, response.ReplyID)
+	assert.Nil(t, response.DeliveredAt)
+	assert.Nil(t, response.CancelledAt)
+
+	replyID := uuid.New()
+	letter.Status = models.LetterStatusDelivered
+	letter.ReplyID = uuid.NullUUID{UUID: replyID, Valid: true}
+	letter.DeliveredAt = sql.NullTime{Time: now.Add(time.Hour), Valid: true}
+	response = letter.ToResponse()
+	assert.Equal(t, &replyID, response.ReplyID)
+	assert.Equal(t, letter.DeliveredAt.Time, *response.DeliveredAt)
+
+	letter.CancelledAt = sql.NullTime{Time: now, Valid: true}
+	assert.Equal(t, now, *letter.ToResponse().CancelledAt)
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles a user can hold inside an organization.
+const (
+	OrganizationRoleOwner   = "owner"   // OrganizationRoleOwner manages the organization and all of its members.
+	OrganizationRoleTeacher = "teacher" // OrganizationRoleTeacher manages the students they invited.
+	OrganizationRoleStudent = "student" // OrganizationRoleStudent only uses the organization.
+)
+
+// Membership links a user to an organization with a role.
+// Memberships are tenant scoped: see db.RegisterTenantScope.
+// @Description Membership holds the role of a user inside an organization.
+type Membership struct {
+	ID             uuid.UUID     `gorm:"type:char(36);primary_key"`                                   // Unique identifier for the membership
+	OrganizationID uuid.UUID     `gorm:"type:char(36);not null;uniqueIndex:idx_memberships_org_user"` // Organization the user belongs to
+	UserID         uuid.UUID     `gorm:"type:char(36);not null;uniqueIndex:idx_memberships_org_user"` // Member of the organization
+	Role           string        `gorm:"type:varchar(255);not null"`                                  // Role of the user inside the organization
+	TeacherID      uuid.NullUUID `gorm:"type:char(36);index"`                                         // Teacher in charge of the student, null when only the owners are
+	CreatedAt      time.Time     `gorm:"not null"`                                                    // Timestamp when the membership was created
+	UpdatedAt      time.Time     `gorm:"not null"`                                                    // Timestamp when the membership was last updated
+}
+
+// Validate performs validation on Membership fields using ozzo-validation package.
+func (m Membership) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.OrganizationID, validation.NotIn(uuid.Nil.String()).Error("cannot be blank")),
+		validation.Field(&m.UserID, validation.NotIn(uuid.Nil.String()).Error("cannot be blank")),
+		validation.Field(&m.Role, validation.Required, validation.In(OrganizationRoleOwner, OrganizationRoleTeacher, OrganizationRoleStudent)),
+	)
+}
+
+// BeforeCreate is a GORM hook that is called before a new membership record is created.
+// It assigns a new UUID to the membership's ID.
+func (m *Membership) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// CanManage reports whether the holder of this membership may manage a member or an invitation having
+// the given role and teacher. Owners manage everyone while teachers only manage their own students.
+func (m Membership) CanManage(role string, teacherID uuid.NullUUID) bool {
+	switch m.Role {
+	case OrganizationRoleOwner:
+		return true
+	case OrganizationRoleTeacher:
+		return role == OrganizationRoleStudent && teacherID.Valid && teacherID.UUID == m.UserID
+	default:
+		return false
+	}
+}
+
+// StudentsTeacher returns the teacher of the students invited by the holder of this membership:
+// the holder for a teacher, nobody for an owner.
+func (m Membership) StudentsTeacher() uuid.NullUUID {
+	return uuid.NullUUID{UUID: m.UserID, Valid: m.Role == OrganizationRoleTeacher}
+}
+
+// MembershipRoleInput represents the fields required to change the role of a member.
+// @Description Fields required to change the role of a member.
+type MembershipRoleInput struct {
+	Role string `json:"role" binding:"required"` // New role of the member
+}
+
+// Validate performs validation on MembershipRoleInput fields.
+func (i MembershipRoleInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Role, validation.Required, validation.In(OrganizationRoleOwner, OrganizationRoleTeacher, OrganizationRoleStudent)),
+	)
+}
+
+// MemberResponse represents a member of an organization returned by the API.
+// @Description MemberResponse holds the data exposed to the client for an organization member.
+type MemberResponse struct {
+	ID        uuid.UUID `json:"id"`         // Unique identifier for the membership
+	UserID    uuid.UUID `json:"user_id"`    // Unique identifier for the user
+	FirstName string    `json:"first_name"` // First name of the user
+	Name      string    `json:"name"`       // Last name of the user
+	Email     string    `json:"email"`      // Email address of the user
+	Role      string    `json:"role"`       // Role of the user inside the organization
+	CreatedAt time.Time `json:"created_at"` // Timestamp when the user joined the organization
+}
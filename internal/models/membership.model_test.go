@@ -0,0 +1,97 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMembershipValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.Membership
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.Membership{OrganizationID: uuid.New(), UserID: uuid.New(), Role: models.OrganizationRoleStudent},
+			expectedError: false,
+		},
+		{
+			name:          "invalid Role",
+			input:         models.Membership{OrganizationID: uuid.New(), UserID: uuid.New(), Role: "admin"},
+			expectedError: true,
+		},
+		{
+			name:          "missing OrganizationID",
+			input:         models.Membership{UserID: uuid.New(), Role: models.OrganizationRoleOwner},
+			expectedError: true,
+		},
+		{
+			name:          "missing UserID",
+			input:         models.Membership{OrganizationID: uuid.New(), Role: models.OrganizationRoleOwner},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("Membership.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestMembership_BeforeCreate(t *testing.T) {
+	membership := &models.Membership{}
+	err := membership.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, membership.ID)
+}
+
+func TestMembership_CanManage(t *testing.T) {
+	teacherID := uuid.New()
+	own := uuid.NullUUID{UUID: teacherID, Valid: true}
+	other := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	tests := []struct {
+		name      string
+		role      string
+		target    string
+		teacherID uuid.NullUUID
+		expected  bool
+	}{
+		{"owner manages an owner", models.OrganizationRoleOwner, models.OrganizationRoleOwner, uuid.NullUUID{}, true},
+		{"owner manages a teacher", models.OrganizationRoleOwner, models.OrganizationRoleTeacher, uuid.NullUUID{}, true},
+		{"owner manages a student", models.OrganizationRoleOwner, models.OrganizationRoleStudent, other, true},
+		{"teacher manages an owner", models.OrganizationRoleTeacher, models.OrganizationRoleOwner, uuid.NullUUID{}, false},
+		{"teacher manages a teacher", models.OrganizationRoleTeacher, models.OrganizationRoleTeacher, own, false},
+		{"teacher manages their student", models.OrganizationRoleTeacher, models.OrganizationRoleStudent, own, true},
+		{"teacher manages the student of another teacher", models.OrganizationRoleTeacher, models.OrganizationRoleStudent, other, false},
+		{"teacher manages a student without teacher", models.OrganizationRoleTeacher, models.OrganizationRoleStudent, uuid.NullUUID{}, false},
+		{"student manages a student", models.OrganizationRoleStudent, models.OrganizationRoleStudent, uuid.NullUUID{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			membership := models.Membership{UserID: teacherID, Role: tt.role}
+			assert.Equal(t, tt.expected, membership.CanManage(tt.target, tt.teacherID))
+		})
+	}
+}
+
+func TestMembership_StudentsTeacher(t *testing.T) {
+	userID := uuid.New()
+	assert.Equal(t, uuid.NullUUID{UUID: userID, Valid: true}, models.Membership{UserID: userID, Role: models.OrganizationRoleTeacher}.StudentsTeacher())
+	assert.False(t, models.Membership{UserID: userID, Role: models.OrganizationRoleOwner}.StudentsTeacher().Valid)
+}
+
+func TestMembershipRoleInputValidation(t *testing.T) {
+	assert.NoError(t, models.MembershipRoleInput{Role: models.OrganizationRoleTeacher}.Validate())
+	assert.Error(t, models.MembershipRoleInput{Role: "admin"}.Validate())
+}
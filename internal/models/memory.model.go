@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Memory represents a lasting fact a user shared with the pen pals, such as a hobby, a city or a pet.
+// @Description Memory holds a fact the pen pals remember about a user.
+type Memory struct {
+	ID             uuid.UUID `gorm:"type:char(36);primary_key"`    // Unique identifier for the memory
+	UserID         uuid.UUID `gorm:"type:char(36);not null;index"` // User the fact is about
+	ConversationID uuid.UUID `gorm:"type:char(36);not null;index"` // Conversation the fact was shared in
+	MessageID      uuid.UUID `gorm:"type:char(36);not null;index"` // Message the fact was extracted from
+	Fact           string    `gorm:"type:varchar(255);not null"`   // Fact, as a short sentence in English
+	CreatedAt      time.Time `gorm:"not null"`                     // Timestamp when the fact was extracted
+}
+
+// BeforeCreate is a GORM hook that is called before a new memory record is created.
+// It assigns a new UUID to the memory's ID.
+func (m *Memory) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the memory into the representation exposed by the API.
+func (m Memory) ToResponse() MemoryResponse {
+	return MemoryResponse{
+		ID:             m.ID,
+		Fact:           m.Fact,
+		ConversationID: m.ConversationID,
+		MessageID:      m.MessageID,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// MemoryResponse represents a memory returned by the API.
+// @Description MemoryResponse holds a fact the pen pals remember, with the message it comes from.
+type MemoryResponse struct {
+	ID             uuid.UUID `json:"id"`              // Unique identifier for the memory
+	Fact           string    `json:"fact"`            // Fact, as a short sentence in English
+	ConversationID uuid.UUID `json:"conversation_id"` // Conversation the fact was shared in
+	MessageID      uuid.UUID `json:"message_id"`      // Message the fact was extracted from
+	CreatedAt      time.Time `json:"created_at"`      // Timestamp when the fact was extracted
+}
@@ -0,0 +1,36 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_BeforeCreate(t *testing.T) {
+	memory := &models.Memory{}
+	err := memory.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, memory.ID)
+}
+
+func TestMemory_ToResponse(t *testing.T) {
+	memory := models.Memory{
+		ID:             uuid.New(),
+		UserID:         uuid.New(),
+		ConversationID: uuid.New(),
+		MessageID:      uuid.New(),
+		Fact:           "Has a cat named Tom.",
+		CreatedAt:      time.Now(),
+	}
+	response := memory.ToResponse()
+
+	assert.Equal(t, memory.ID, response.ID)
+	assert.Equal(t, memory.Fact, response.Fact)
+	assert.Equal(t, memory.ConversationID, response.ConversationID)
+	assert.Equal(t, memory.MessageID, response.MessageID)
+	assert.Equal(t, memory.CreatedAt, response.CreatedAt)
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Authors of a message.
+const (
+	MessageRoleSystem    = "system"    // MessageRoleSystem is the role of the instructions given to the language model.
+	MessageRoleUser      = "user"      // MessageRoleUser is the role of the messages written by the user.
+	MessageRoleAssistant = "assistant" // MessageRoleAssistant is the role of the replies of the AI pen pal.
+)
+
+// Message represents a single message of a conversation.
+// @Description Message holds the details of a message.
+type Message struct {
+	ID               uuid.UUID     `gorm:"type:char(36);primary_key"`    // Unique identifier for the message
+	ConversationID   uuid.UUID     `gorm:"type:char(36);not null;index"` // Conversation the message belongs to
+	ParentID         uuid.NullUUID `gorm:"type:char(36);index"`          // Message answered by the message, null for the first message of the conversation
+	Role             string        `gorm:"type:varchar(16);not null"`    // Author of the message
+	Content          string        `gorm:"type:text;not null"`           // Text of the message
+	PromptTokens     int           `gorm:"not null;default:0"`           // Tokens sent to the language model to produce the message
+	CompletionTokens int           `gorm:"not null;default:0"`           // Tokens generated by the language model for the message
+	Corrections      []Correction  `gorm:"type:text;serializer:json"`    // Mistakes found in a message of the user
+	Moderation       string        `gorm:"type:varchar(16)"`             // Action of the moderation on the message, either flag, redact or block, empty if none
+	Guard            string        `gorm:"type:varchar(16)"`             // Response of the injection guard, flag or sanitize on a message of the user and leak on a reply, empty if none
+	CreatedAt        time.Time     `gorm:"not null;index"`               // Timestamp when the message was created
+}
+
+// BeforeCreate is a GORM hook that is called before a new message record is created.
+// It assigns a new UUID to the message's ID.
+func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the message into the representation exposed by the API.
+func (m Message) ToResponse() MessageResponse {
+	response := MessageResponse{
+		ID:               m.ID,
+		ConversationID:   m.ConversationID,
+		Role:             m.Role,
+		Content:          m.Content,
+		PromptTokens:     m.PromptTokens,
+		CompletionTokens: m.CompletionTokens,
+		Corrections:      m.Corrections,
+		Moderation:       m.Moderation,
+		Guard:            m.Guard,
+		CreatedAt:        m.CreatedAt,
+	}
+	if m.ParentID.Valid {
+		response.ParentID = &m.ParentID.UUID
+	}
+	return response
+}
+
+// MessageInput represents the fields required to send a message.
+// @Description Fields required to send a message.
+type MessageInput struct {
+	Content string `json:"content" binding:"required"` // Text of the message
+}
+
+// Validate performs validation on MessageInput fields.
+func (i MessageInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Content, validation.Required, validation.Length(1, 4000)),
+	)
+}
+
+// MessageResponse represents a message returned by the API.
+// @Description MessageResponse holds the data exposed to the client for a message.
+type MessageResponse struct {
+	ID               uuid.UUID    `json:"id"`                    // Unique identifier for the message
+	ConversationID   uuid.UUID    `json:"conversation_id"`       // Conversation the message belongs to
+	ParentID         *uuid.UUID   `json:"parent_id,omitempty"`   // Message answered by the message, omitted for the first message
+	Role             string       `json:"role"`                  // Author of the message
+	Content          string       `json:"content"`               // Text of the message
+	PromptTokens     int          `json:"prompt_tokens"`         // Tokens sent to the language model to produce the message
+	CompletionTokens int          `json:"completion_tokens"`     // Tokens generated by the language model for the message
+	Corrections      []Correction `json:"corrections,omitempty"` // Mistakes found in a message of the user
+	Moderation       string       `json:"moderation,omitempty"`  // Action of the moderation on the message, either flag, redact or block
+	Guard            string       `json:"guard,omitempty"`       // Response of the injection guard, either flag, sanitize or leak
+	CreatedAt        time.Time    `json:"created_at"`            // Timestamp when the message was created
+}
+
+// MessageAlternativesResponse represents the versions of a message returned by the API.
+// @Description MessageAlternativesResponse holds the messages answering the same message, oldest first.
+type MessageAlternativesResponse struct {
+	Alternatives []MessageResponse `json:"alternatives"` // Versions of the message, including itself
+	Active       int               `json:"active"`       // Index of the version on the active branch, -1 if none
+}
+
+// ExchangeResponse represents a message of the user and the reply of the pen pal returned by the API.
+// @Description ExchangeResponse holds the message sent by the user and the reply it received.
+type ExchangeResponse struct {
+	Message MessageResponse `json:"message"` // Message sent by the user
+	Reply   MessageResponse `json:"reply"`   // Reply of the pen pal
+}
@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_BeforeCreate(t *testing.T) {
+	message := &models.Message{}
+	err := message.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, message.ID)
+}
+
+func TestMessage_ToResponse(t *testing.T) {
+	message := models.Message{
+		ID:               uuid.New(),
+		ConversationID:   uuid.New(),
+		ParentID:         uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Role:             models.MessageRoleAssistant,
+		Content:          "¡Hola!",
+		PromptTokens:     12,
+		CompletionTokens: 3,
+		Moderation:       models.ModerationActionFlag,
+		Guard:            "leak",
+	}
+	response := message.ToResponse()
+
+	assert.Equal(t, message.ID, response.ID)
+	assert.Equal(t, message.ConversationID, response.ConversationID)
+	assert.Equal(t, &message.ParentID.UUID, response.ParentID)
+	assert.Equal(t, message.Role, response.Role)
+	assert.Equal(t, message.Content, response.Content)
+	assert.Equal(t, 12, response.PromptTokens)
+	assert.Equal(t, 3, response.CompletionTokens)
+	assert.Equal(t, models.ModerationActionFlag, response.Moderation)
+	assert.Equal(t, "leak", response.Guard)
+}
+
+func TestMessageInputValidation(t *testing.T) {
+	assert.NoError(t, models.MessageInput{Content: "Hola, ¿qué tal?"}.Validate())
+	assert.Error(t, models.MessageInput{}.Validate())
+	assert.Error(t, models.MessageInput{Content: strings.Repeat("a", 4001)}.Validate())
+}
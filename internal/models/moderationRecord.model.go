@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Categories of the content screened by the moderation.
+const (
+	ModerationCategorySexual     = "sexual"     // ModerationCategorySexual is sexual content.
+	ModerationCategoryViolence   = "violence"   // ModerationCategoryViolence is violent content or threats.
+	ModerationCategorySelfHarm   = "self_harm"  // ModerationCategorySelfHarm is content about hurting oneself.
+	ModerationCategoryHate       = "hate"       // ModerationCategoryHate is hateful content against a group.
+	ModerationCategoryHarassment = "harassment" // ModerationCategoryHarassment is content insulting or harassing someone.
+	ModerationCategoryDrugs      = "drugs"      // ModerationCategoryDrugs is content about illegal drugs.
+	ModerationCategoryProfanity  = "profanity"  // ModerationCategoryProfanity is swearing.
+	ModerationCategoryContact    = "contact"    // ModerationCategoryContact is asking for personal contact, pictures or a meeting.
+)
+
+// ModerationCategories lists the categories of the content screened by the moderation.
+var ModerationCategories = []string{
+	ModerationCategorySexual,
+	ModerationCategoryViolence,
+	ModerationCategorySelfHarm,
+	ModerationCategoryHate,
+	ModerationCategoryHarassment,
+	ModerationCategoryDrugs,
+	ModerationCategoryProfanity,
+	ModerationCategoryContact,
+}
+
+// Categories of the content screened by the injection guard.
+const (
+	ModerationCategoryInjection  = "injection"   // ModerationCategoryInjection is a message trying to override the instructions of the pen pal.
+	ModerationCategoryPromptLeak = "prompt_leak" // ModerationCategoryPromptLeak is a reply disclosing the instructions of the pen pal.
+)
+
+// GuardCategories lists the categories of the content screened by the injection guard.
+var GuardCategories = []string{ModerationCategoryInjection, ModerationCategoryPromptLeak}
+
+// Actions taken on moderated content, from the mildest to the strictest.
+const (
+	ModerationActionAllow  = "allow"  // ModerationActionAllow lets the content through.
+	ModerationActionFlag   = "flag"   // ModerationActionFlag lets the content through and queues it for review.
+	ModerationActionRedact = "redact" // ModerationActionRedact masks the offending words and queues the content for review.
+	ModerationActionBlock  = "block"  // ModerationActionBlock refuses the content and queues it for review.
+)
+
+// ModerationActions lists the actions taken on moderated content, from the mildest to the strictest.
+var ModerationActions = []string{ModerationActionAllow, ModerationActionFlag, ModerationActionRedact, ModerationActionBlock}
+
+// Statuses of a moderation record in the review queue.
+const (
+	ModerationStatusPending   = "pending"   // ModerationStatusPending is a record waiting for a review.
+	ModerationStatusConfirmed = "confirmed" // ModerationStatusConfirmed is a record whose action a moderator approved.
+	ModerationStatusDismissed = "dismissed" // ModerationStatusDismissed is a record a moderator found harmless.
+)
+
+// ModerationRecord represents a message flagged, redacted or blocked by the moderation, waiting for or after a review.
+// @Description ModerationRecord holds the original content of a moderated message and the action taken.
+type ModerationRecord struct {
+	ID             uuid.UUID     `gorm:"type:char(36);primary_key"`                       // Unique identifier for the record
+	UserID         uuid.UUID     `gorm:"type:char(36);index;not null"`                    // Learner of the conversation
+	ConversationID uuid.UUID     `gorm:"type:char(36);index;not null"`                    // Conversation the message was sent to
+	MessageID      uuid.NullUUID `gorm:"type:char(36);index"`                             // Stored message, null when the message of the learner was blocked
+	Role           string        `gorm:"type:varchar(16);not null"`                       // Either user or assistant
+	Content        string        `gorm:"type:text;not null"`                              // Original content, before any redaction
+	Action         string        `gorm:"type:varchar(16);not null"`                       // Either flag, redact or block
+	Categories     []string      `gorm:"type:text;serializer:json"`                       // Categories found in the content
+	Strict         bool          `gorm:"not null;default:false"`                          // Whether the strict policy of the under-age accounts applied
+	Status         string        `gorm:"type:varchar(16);not null;default:pending;index"` // Either pending, confirmed or dismissed
+	ReviewedBy     uuid.NullUUID `gorm:"type:char(36)"`                                   // Moderator who reviewed the record
+	ReviewNote     string        `gorm:"type:text"`                                       // Note of the moderator
+	ReviewedAt     sql.NullTime  // Timestamp of the review
+	CreatedAt      time.Time     `gorm:"not null"` // Timestamp when the content was moderated
+}
+
+// BeforeCreate is a GORM hook that is called before a new moderation record is created.
+// It assigns a new UUID to the record's ID.
+func (m *ModerationRecord) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the moderation record into the representation exposed to administrators.
+func (m ModerationRecord) ToResponse() ModerationRecordResponse {
+	response := ModerationRecordResponse{
+		ID:             m.ID,
+		UserID:         m.UserID,
+		ConversationID: m.ConversationID,
+		Role:           m.Role,
+		Content:        m.Content,
+		Action:         m.Action,
+		Categories:     m.Categories,
+		Strict:         m.Strict,
+		Status:         m.Status,
+		ReviewNote:     m.ReviewNote,
+		CreatedAt:      m.CreatedAt,
+	}
+	if response.Categories == nil {
+		response.Categories = []string{}
+	}
+	if m.MessageID.Valid {
+		response.MessageID = &m.MessageID.UUID
+	}
+	if m.ReviewedBy.Valid {
+		response.ReviewedBy = &m.ReviewedBy.UUID
+	}
+	if m.ReviewedAt.Valid {
+		response.ReviewedAt = &m.ReviewedAt.Time
+	}
+	return response
+}
+
+// ModerationReviewInput represents the decision of a moderator on a moderation record.
+// @Description Decision of a moderator on a moderated message.
+type ModerationReviewInput struct {
+	Status string `json:"status" binding:"required"` // Either confirmed or dismissed
+	Note   string `json:"note"`                      // Optional note of the moderator
+}
+
+// Validate performs validation on ModerationReviewInput fields.
+func (i ModerationReviewInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Status, validation.Required, validation.In(ModerationStatusConfirmed, ModerationStatusDismissed)),
+		validation.Field(&i.Note, validation.Length(0, 1000)),
+	)
+}
+
+// ModerationRecordResponse represents a moderation record returned to administrators.
+// @Description ModerationRecordResponse holds a moderated message and its review.
+type ModerationRecordResponse struct {
+	ID             uuid.UUID  `json:"id"`                    // Unique identifier for the record
+	UserID         uuid.UUID  `json:"user_id"`               // Learner of the conversation
+	ConversationID uuid.UUID  `json:"conversation_id"`       // Conversation the message was sent to
+	MessageID      *uuid.UUID `json:"message_id,omitempty"`  // Stored message, omitted when the message of the learner was blocked
+	Role           string     `json:"role"`                  // Either user or assistant
+	Content        string     `json:"content"`               // Original content, before any redaction
+	Action         string     `json:"action"`                // Either flag, redact or block
+	Categories     []string   `json:"categories"`            // Categories found in the content
+	Strict         bool       `json:"strict"`                // Whether the strict policy of the under-age accounts applied
+	Status         string     `json:"status"`                // Either pending, confirmed or dismissed
+	ReviewedBy     *uuid.UUID `json:"reviewed_by,omitempty"` // Moderator who reviewed the record
+	ReviewNote     string     `json:"review_note,omitempty"` // Note of the moderator
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"` // Timestamp of the review
+	CreatedAt      time.Time  `json:"created_at"`            // Timestamp when the content was moderated
+}
@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationRecord_BeforeCreate(t *testing.T) {
+	record := &models.ModerationRecord{}
+	err := record.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, record.ID)
+}
+
+func TestModerationRecord_ToResponse(t *testing.T) {
+	record := models.ModerationRecord{
+		ID:             uuid.New(),
+		UserID:         uuid.New(),
+		ConversationID: uuid.New(),
+		Role:           models.MessageRoleUser,
+		Content:        "¿Dónde vives?",
+		Action:         models.ModerationActionBlock,
+		Strict:         true,
+		Status:         models.ModerationStatusPending,
+	}
+	response := record.ToResponse()
+
+	assert.Equal(t, record.ID, response.ID)
+	assert.Equal(t, []string{}, response.Categories)
+	assert.Nil(t, response.MessageID)
+	assert.Nil(t, response.ReviewedBy)
+	assert.Nil(t, response.ReviewedAt)
+
+	messageID, reviewerID := uuid.New(), uuid.New()
+	record.MessageID = uuid.NullUUID{UUID: messageID, Valid: true}
+	record.Categories = []string{models.ModerationCategoryContact}
+	record.Status = models.ModerationStatusConfirmed
+	record.ReviewedBy = uuid.NullUUID{UUID: reviewerID, Valid: true}
+	record.ReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	response = record.ToResponse()
+	assert.Equal(t, &messageID, response.MessageID)
+	assert.Equal(t, []string{models.ModerationCategoryContact}, response.Categories)
+	assert.Equal(t, &reviewerID, response.ReviewedBy)
+	assert.Equal(t, record.ReviewedAt.Time, *response.ReviewedAt)
+}
+
+func TestModerationReviewInput_Validate(t *testing.T) {
+	assert.NoError(t, models.ModerationReviewInput{Status: models.ModerationStatusConfirmed}.Validate())
+	assert.NoError(t, models.ModerationReviewInput{Status: models.ModerationStatusDismissed, Note: "Harmless joke"}.Validate())
+	assert.Error(t, models.ModerationReviewInput{Status: models.ModerationStatusPending}.Validate())
+	assert.Error(t, models.ModerationReviewInput{}.Validate())
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Organization represents a workspace, such as a school classroom, gathering several users.
+// @Description Organization holds the details of a multi-tenant workspace.
+type Organization struct {
+	ID        uuid.UUID `gorm:"type:char(36);primary_key"`  // Unique identifier for the organization
+	Name      string    `gorm:"type:varchar(255);not null"` // Display name of the organization
+	CreatedAt time.Time `gorm:"not null"`                   // Timestamp when the organization was created
+	UpdatedAt time.Time `gorm:"not null"`                   // Timestamp when the organization was last updated
+}
+
+// Validate performs validation on Organization fields using ozzo-validation package.
+func (o Organization) Validate() error {
+	return validation.ValidateStruct(&o,
+		validation.Field(&o.Name, validation.Required, validation.Length(1, 100)),
+	)
+}
+
+// BeforeCreate is a GORM hook that is called before a new organization record is created.
+// It assigns a new UUID to the organization's ID.
+func (o *Organization) BeforeCreate(tx *gorm.DB) (err error) {
+	o.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the organization into the representation exposed by the API.
+func (o Organization) ToResponse() OrganizationResponse {
+	return OrganizationResponse{
+		ID:        o.ID,
+		Name:      o.Name,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+// OrganizationInput represents the fields required to create or rename an organization.
+// @Description Fields required to create or rename an organization.
+type OrganizationInput struct {
+	Name string `json:"name" binding:"required"` // Display name of the organization
+}
+
+// Validate performs validation on OrganizationInput fields.
+func (i OrganizationInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Name, validation.Required, validation.Length(1, 100)),
+	)
+}
+
+// OrganizationResponse represents the organization returned by the API.
+// @Description OrganizationResponse holds the data exposed to the client for an organization.
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`             // Unique identifier for the organization
+	Name      string    `json:"name"`           // Display name of the organization
+	Role      string    `json:"role,omitempty"` // Role of the current user in the organization, omitted if unknown
+	CreatedAt time.Time `json:"created_at"`     // Timestamp when the organization was created
+	UpdatedAt time.Time `json:"updated_at"`     // Timestamp when the organization was last updated
+}
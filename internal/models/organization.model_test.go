@@ -0,0 +1,65 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.Organization
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.Organization{Name: "Class 4B"},
+			expectedError: false,
+		},
+		{
+			name:          "empty Name",
+			input:         models.Organization{},
+			expectedError: true,
+		},
+		{
+			name:          "too long Name",
+			input:         models.Organization{Name: strings.Repeat("a", 101)},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("Organization.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestOrganization_BeforeCreate(t *testing.T) {
+	organization := &models.Organization{}
+	err := organization.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, organization.ID)
+}
+
+func TestOrganizationInputValidation(t *testing.T) {
+	assert.NoError(t, models.OrganizationInput{Name: "Class 4B"}.Validate())
+	assert.Error(t, models.OrganizationInput{}.Validate())
+}
+
+func TestOrganization_ToResponse(t *testing.T) {
+	organization := models.Organization{ID: uuid.New(), Name: "Class 4B"}
+	response := organization.ToResponse()
+
+	assert.Equal(t, organization.ID, response.ID)
+	assert.Equal(t, organization.Name, response.Name)
+	assert.Empty(t, response.Role)
+}
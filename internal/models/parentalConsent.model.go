@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statuses of a parental consent request.
+const (
+	ConsentStatusPending = "pending" // ConsentStatusPending is the status of a request the parent has not answered yet.
+	ConsentStatusGranted = "granted" // ConsentStatusGranted is the status of a request accepted by the parent.
+	ConsentStatusDenied  = "denied"  // ConsentStatusDenied is the status of a request refused by the parent.
+)
+
+// ParentalConsent records the consent request sent to the parent of a minor at registration,
+// and the parent's decision.
+// @Description ParentalConsent holds the details of a parental consent request.
+type ParentalConsent struct {
+	ID          uuid.UUID      `gorm:"type:char(36);primary_key"`          // Unique identifier for the consent request
+	UserID      uuid.UUID      `gorm:"type:char(36);not null;uniqueIndex"` // Minor the consent is requested for
+	ParentEmail string         `gorm:"type:varchar(255);not null"`         // Email address the consent link was sent to
+	Status      string         `gorm:"type:varchar(255);not null"`         // Status of the request
+	ExpiresAt   time.Time      `gorm:"not null"`                           // Timestamp after which the consent link can no longer be used
+	DecidedAt   sql.NullTime   // Optional timestamp when the parent answered
+	DecisionIP  sql.NullString `gorm:"type:varchar(64)"` // Optional IP address the parent answered from
+	CreatedAt   time.Time      `gorm:"not null"`         // Timestamp when the request was created
+	UpdatedAt   time.Time      `gorm:"not null"`         // Timestamp when the request was last updated
+}
+
+// BeforeCreate is a GORM hook that is called before a new consent record is created.
+// It assigns a new UUID to the consent's ID.
+func (c *ParentalConsent) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// IsPending reports whether the parent can still answer the request at the given time.
+func (c ParentalConsent) IsPending(now time.Time) bool {
+	return c.Status == ConsentStatusPending && now.Before(c.ExpiresAt)
+}
+
+// ToResponse converts the consent request into the representation exposed to the parent.
+func (c ParentalConsent) ToResponse(childFirstName string) ParentalConsentResponse {
+	response := ParentalConsentResponse{
+		ID:             c.ID,
+		ChildFirstName: childFirstName,
+		Status:         c.Status,
+		ExpiresAt:      c.ExpiresAt,
+	}
+	if c.DecidedAt.Valid {
+		response.DecidedAt = &c.DecidedAt.Time
+	}
+	return response
+}
+
+// ParentalConsentResponse represents a consent request shown to the parent.
+// @Description ParentalConsentResponse holds the data exposed to the parent for a consent request.
+type ParentalConsentResponse struct {
+	ID             uuid.UUID  `json:"id"`                   // Unique identifier for the consent request
+	ChildFirstName string     `json:"child_first_name"`     // First name of the minor
+	Status         string     `json:"status"`               // Status of the request
+	ExpiresAt      time.Time  `json:"expires_at"`           // Timestamp after which the consent link can no longer be used
+	DecidedAt      *time.Time `json:"decided_at,omitempty"` // Timestamp when the parent answered, omitted if pending
+}
@@ -0,0 +1,68 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParentalConsent_BeforeCreate(t *testing.T) {
+	consent := &models.ParentalConsent{}
+	err := consent.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, consent.ID)
+}
+
+func TestParentalConsent_IsPending(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		consent  models.ParentalConsent
+		expected bool
+	}{
+		{
+			name:     "pending",
+			consent:  models.ParentalConsent{Status: models.ConsentStatusPending, ExpiresAt: now.Add(time.Hour)},
+			expected: true,
+		},
+		{
+			name:     "expired",
+			consent:  models.ParentalConsent{Status: models.ConsentStatusPending, ExpiresAt: now.Add(-time.Hour)},
+			expected: false,
+		},
+		{
+			name:     "granted",
+			consent:  models.ParentalConsent{Status: models.ConsentStatusGranted, ExpiresAt: now.Add(time.Hour)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.consent.IsPending(now))
+		})
+	}
+}
+
+func TestParentalConsent_ToResponse(t *testing.T) {
+	now := time.Now()
+	consent := models.ParentalConsent{
+		ID:        uuid.New(),
+		Status:    models.ConsentStatusGranted,
+		ExpiresAt: now.Add(time.Hour),
+		DecidedAt: sql.NullTime{Time: now, Valid: true},
+	}
+
+	response := consent.ToResponse("Lucas")
+
+	assert.Equal(t, consent.ID, response.ID)
+	assert.Equal(t, "Lucas", response.ChildFirstName)
+	assert.Equal(t, models.ConsentStatusGranted, response.Status)
+	assert.Equal(t, &now, response.DecidedAt)
+}
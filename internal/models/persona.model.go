@@ -0,0 +1,212 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"text/template"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CEFRLevels lists the levels of the Common European Framework of Reference for Languages, from beginner to proficient.
+var CEFRLevels = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+// maxLetterDelay is the longest time, in minutes, a pen pal may take to answer a letter: two weeks.
+const maxLetterDelay = 14 * 24 * 60
+
+// Persona represents an AI pen pal learners can talk to.
+// @Description Persona holds the details of an AI pen pal.
+type Persona struct {
+	ID             uuid.UUID       `gorm:"type:char(36);primary_key"`  // Unique identifier for the persona
+	Name           string          `gorm:"type:varchar(100);not null"` // Name of the pen pal
+	NativeLanguage string          `gorm:"type:varchar(16);not null"`  // Language the pen pal speaks natively
+	Personality    string          `gorm:"type:text"`                  // Description of the personality of the pen pal
+	AvatarURL      string          `gorm:"type:varchar(255)"`          // Picture of the pen pal
+	SystemPrompt   string          `gorm:"type:text;not null"`         // Template of the instructions given to the language model, unless a prompt template is used
+	PromptName     string          `gorm:"type:varchar(100)"`          // Prompt template used instead of the system prompt, if any
+	PromptVersion  string          `gorm:"type:varchar(32)"`           // Version of the prompt template the persona is pinned to, the rollout decides if empty
+	AllowedLevels  []string        `gorm:"type:text;serializer:json"`  // CEFR levels of the learners the pen pal suits, all if empty
+	Model          string          `gorm:"type:varchar(100)"`          // Language model answering as the pen pal, the default one if empty
+	Temperature    sql.NullFloat64 // Sampling temperature, the default one if null
+	MaxTokens      int             `gorm:"not null;default:0"`     // Maximum length of a reply, the default one if 0
+	LetterDelayMin int             `gorm:"not null;default:0"`     // Shortest time, in minutes, the pen pal takes to answer a letter, the default window applies if both bounds are 0
+	LetterDelayMax int             `gorm:"not null;default:0"`     // Longest time, in minutes, the pen pal takes to answer a letter
+	Enabled        bool            `gorm:"not null;default:false"` // Whether learners can start conversations with the pen pal
+	CreatedAt      time.Time       `gorm:"not null"`               // Timestamp when the persona was created
+	UpdatedAt      time.Time       `gorm:"not null"`               // Timestamp when the persona was last updated
+}
+
+// BeforeCreate is a GORM hook that is called before a new persona record is created.
+// It assigns a new UUID to the persona's ID.
+func (p *Persona) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
+
+// Apply copies the fields of the input into the persona.
+func (p *Persona) Apply(input PersonaInput) {
+	p.Name = input.Name
+	p.NativeLanguage = input.NativeLanguage
+	p.Personality = input.Personality
+	p.AvatarURL = input.AvatarURL
+	p.SystemPrompt = input.SystemPrompt
+	p.PromptName = input.PromptName
+	p.PromptVersion = input.PromptVersion
+	p.AllowedLevels = input.AllowedLevels
+	p.Model = input.Model
+	p.Temperature = sql.NullFloat64{}
+	if input.Temperature != nil {
+		p.Temperature = sql.NullFloat64{Float64: *input.Temperature, Valid: true}
+	}
+	p.MaxTokens = input.MaxTokens
+	p.LetterDelayMin = input.LetterDelayMin
+	p.LetterDelayMax = input.LetterDelayMax
+	p.Enabled = input.Enabled
+}
+
+// ToResponse converts the persona into the representation exposed to learners.
+func (p Persona) ToResponse() PersonaResponse {
+	return PersonaResponse{
+		ID:             p.ID,
+		Name:           p.Name,
+		NativeLanguage: p.NativeLanguage,
+		Personality:    p.Personality,
+		AvatarURL:      p.AvatarURL,
+		AllowedLevels:  p.AllowedLevels,
+	}
+}
+
+// ToAdminResponse converts the persona into the representation exposed to administrators.
+func (p Persona) ToAdminResponse() PersonaAdminResponse {
+	response := PersonaAdminResponse{
+		PersonaResponse: p.ToResponse(),
+		SystemPrompt:    p.SystemPrompt,
+		PromptName:      p.PromptName,
+		PromptVersion:   p.PromptVersion,
+		Model:           p.Model,
+		MaxTokens:       p.MaxTokens,
+		LetterDelayMin:  p.LetterDelayMin,
+		LetterDelayMax:  p.LetterDelayMax,
+		Enabled:         p.Enabled,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+	if p.Temperature.Valid {
+		response.Temperature = &p.Temperature.Float64
+	}
+	return response
+}
+
+// PersonaInput represents the fields required to create or update a persona.
+// @Description Fields required to create or update a persona.
+type PersonaInput struct {
+	Name           string   `json:"name" binding:"required"`            // Name of the pen pal
+	NativeLanguage string   `json:"native_language" binding:"required"` // Language the pen pal speaks natively, e.g. "es"
+	Personality    string   `json:"personality"`                        // Description of the personality of the pen pal
+	AvatarURL      string   `json:"avatar_url"`                         // Picture of the pen pal
+	SystemPrompt   string   `json:"system_prompt"`                      // Template of the instructions given to the language model, required without a prompt template
+	PromptName     string   `json:"prompt_name"`                        // Prompt template used instead of the system prompt
+	PromptVersion  string   `json:"prompt_version"`                     // Version of the prompt template the persona is pinned to
+	AllowedLevels  []string `json:"allowed_levels"`                     // CEFR levels of the learners the pen pal suits, all if empty
+	Model          string   `json:"model"`                              // Language model answering as the pen pal
+	Temperature    *float64 `json:"temperature"`                        // Sampling temperature
+	MaxTokens      int      `json:"max_tokens"`                         // Maximum length of a reply
+	LetterDelayMin int      `json:"letter_delay_min"`                   // Shortest time, in minutes, the pen pal takes to answer a letter
+	LetterDelayMax int      `json:"letter_delay_max"`                   // Longest time, in minutes, the pen pal takes to answer a letter, 0 for the default window
+	Enabled        bool     `json:"enabled"`                            // Whether learners can start conversations with the pen pal
+}
+
+// Validate performs validation on PersonaInput fields.
+// The system prompt is only required when the persona doesn't use a prompt template.
+func (i PersonaInput) Validate() error {
+	systemPromptRules := []validation.Rule{validation.Length(1, 8000), validation.By(validTemplate)}
+	promptVersionRules := []validation.Rule{validation.Match(semanticVersion)}
+	if i.PromptName == "" {
+		systemPromptRules = append([]validation.Rule{validation.Required}, systemPromptRules...)
+		promptVersionRules = append(promptVersionRules, validation.By(withoutPromptName))
+	}
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Name, validation.Required, validation.Length(1, 100)),
+		validation.Field(&i.NativeLanguage, validation.Required, validation.Match(languageCode)),
+		validation.Field(&i.Personality, validation.Length(0, 1000)),
+		validation.Field(&i.AvatarURL, is.URL, validation.Length(0, 255)),
+		validation.Field(&i.SystemPrompt, systemPromptRules...),
+		validation.Field(&i.PromptName, validation.Length(0, 100), validation.Match(promptName)),
+		validation.Field(&i.PromptVersion, promptVersionRules...),
+		validation.Field(&i.AllowedLevels, validation.By(validLevels)),
+		validation.Field(&i.Model, validation.Length(0, 100)),
+		validation.Field(&i.Temperature, validation.Min(0.0), validation.Max(2.0)),
+		validation.Field(&i.MaxTokens, validation.Min(0), validation.Max(4096)),
+		validation.Field(&i.LetterDelayMin, validation.Min(0), validation.Max(maxLetterDelay)),
+		validation.Field(&i.LetterDelayMax, validation.Min(0), validation.Max(maxLetterDelay), validation.By(i.validLetterDelay)),
+	)
+}
+
+// PersonaResponse represents a persona returned to learners.
+// @Description PersonaResponse holds the data exposed to learners for a pen pal.
+type PersonaResponse struct {
+	ID             uuid.UUID `json:"id"`              // Unique identifier for the persona
+	Name           string    `json:"name"`            // Name of the pen pal
+	NativeLanguage string    `json:"native_language"` // Language the pen pal speaks natively
+	Personality    string    `json:"personality"`     // Description of the personality of the pen pal
+	AvatarURL      string    `json:"avatar_url"`      // Picture of the pen pal
+	AllowedLevels  []string  `json:"allowed_levels"`  // CEFR levels of the learners the pen pal suits, all if empty
+}
+
+// PersonaAdminResponse represents a persona returned to administrators.
+// @Description PersonaAdminResponse holds the data exposed to administrators for a pen pal.
+type PersonaAdminResponse struct {
+	PersonaResponse
+	SystemPrompt   string    `json:"system_prompt"`            // Template of the instructions given to the language model
+	PromptName     string    `json:"prompt_name,omitempty"`    // Prompt template used instead of the system prompt
+	PromptVersion  string    `json:"prompt_version,omitempty"` // Version of the prompt template the persona is pinned to
+	Model          string    `json:"model"`                    // Language model answering as the pen pal
+	Temperature    *float64  `json:"temperature,omitempty"`    // Sampling temperature, omitted if the default one is used
+	MaxTokens      int       `json:"max_tokens"`               // Maximum length of a reply
+	LetterDelayMin int       `json:"letter_delay_min"`         // Shortest time, in minutes, the pen pal takes to answer a letter
+	LetterDelayMax int       `json:"letter_delay_max"`         // Longest time, in minutes, the pen pal takes to answer a letter, 0 for the default window
+	Enabled        bool      `json:"enabled"`                  // Whether learners can start conversations with the pen pal
+	CreatedAt      time.Time `json:"created_at"`               // Timestamp when the persona was created
+	UpdatedAt      time.Time `json:"updated_at"`               // Timestamp when the persona was last updated
+}
+
+// validTemplate is a validation rule checking that a string is a valid Go template.
+func validTemplate(value interface{}) error {
+	text, _ := value.(string)
+	if _, err := template.New("prompt").Option("missingkey=error").Parse(text); err != nil {
+		return errors.New("must be a valid template")
+	}
+	return nil
+}
+
+// validLevels is a validation rule checking that every element of a list is a CEFR level.
+func validLevels(value interface{}) error {
+	levels, _ := value.([]string)
+	for _, level := range levels {
+		if !slices.Contains(CEFRLevels, level) {
+			return errors.New("must only contain CEFR levels")
+		}
+	}
+	return nil
+}
+
+// withoutPromptName is a validation rule refusing a prompt version when no prompt template is named.
+func withoutPromptName(value interface{}) error {
+	if version, _ := value.(string); version != "" {
+		return errors.New("requires a prompt name")
+	}
+	return nil
+}
+
+// validLetterDelay is a validation rule checking that the letter delay window of the pen pal isn't reversed.
+func (i PersonaInput) validLetterDelay(value interface{}) error {
+	if maximum, _ := value.(int); maximum < i.LetterDelayMin {
+		return errors.New("must be no less than the shortest letter delay")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersona_BeforeCreate(t *testing.T) {
+	persona := &models.Persona{}
+	err := persona.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, persona.ID)
+}
+
+func TestPersona_Apply(t *testing.T) {
+	temperature := 0.9
+	persona := models.Persona{Temperature: sql.NullFloat64{Float64: 0.2, Valid: true}}
+	persona.Apply(models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres Lucía.", Temperature: &temperature, Enabled: true})
+	assert.Equal(t, "Lucía", persona.Name)
+	assert.Equal(t, sql.NullFloat64{Float64: 0.9, Valid: true}, persona.Temperature)
+	assert.True(t, persona.Enabled)
+
+	persona.Apply(models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres Lucía."})
+	assert.False(t, persona.Temperature.Valid)
+	assert.False(t, persona.Enabled)
+}
+
+func TestPersona_ToResponse(t *testing.T) {
+	persona := models.Persona{
+		ID:            uuid.New(),
+		Name:          "Lucía",
+		SystemPrompt:  "Eres Lucía.",
+		AllowedLevels: []string{"A1", "A2"},
+		Model:         "gpt-4o",
+		Temperature:   sql.NullFloat64{Float64: 0.9, Valid: true},
+		Enabled:       true,
+	}
+
+	response := persona.ToResponse()
+	assert.Equal(t, persona.ID, response.ID)
+	assert.Equal(t, persona.AllowedLevels, response.AllowedLevels)
+
+	adminResponse := persona.ToAdminResponse()
+	assert.Equal(t, response, adminResponse.PersonaResponse)
+	assert.Equal(t, persona.SystemPrompt, adminResponse.SystemPrompt)
+	assert.Equal(t, 0.9, *adminResponse.Temperature)
+
+	persona.Temperature = sql.NullFloat64{}
+	assert.Nil(t, persona.ToAdminResponse().Temperature)
+}
+
+func TestPersonaInputValidation(t *testing.T) {
+	temperature := 3.0
+	valid := models.PersonaInput{Name: "Lucía", NativeLanguage: "es", SystemPrompt: "Eres {{.Persona.Name}}. Escribe a {{.LearnerName}}."}
+
+	tests := []struct {
+		name          string
+		update        func(input *models.PersonaInput)
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			update:        func(input *models.PersonaInput) {},
+			expectedError: false,
+		},
+		{
+			name:          "valid levels",
+			update:        func(input *models.PersonaInput) { input.AllowedLevels = []string{"A1", "B2"} },
+			expectedError: false,
+		},
+		{
+			name:          "invalid level",
+			update:        func(input *models.PersonaInput) { input.AllowedLevels = []string{"A1", "D1"} },
+			expectedError: true,
+		},
+		{
+			name:          "invalid template",
+			update:        func(input *models.PersonaInput) { input.SystemPrompt = "Eres {{.Persona.Name" },
+			expectedError: true,
+		},
+		{
+			name:          "invalid language",
+			update:        func(input *models.PersonaInput) { input.NativeLanguage = "Spanish" },
+			expectedError: true,
+		},
+		{
+			name:          "invalid avatar",
+			update:        func(input *models.PersonaInput) { input.AvatarURL = "not a url" },
+			expectedError: true,
+		},
+		{
+			name:          "temperature too high",
+			update:        func(input *models.PersonaInput) { input.Temperature = &temperature },
+			expectedError: true,
+		},
+		{
+			name: "prompt template instead of system prompt",
+			update: func(input *models.PersonaInput) {
+				input.SystemPrompt, input.PromptName, input.PromptVersion = "", "pen-pal", "1.2.0"
+			},
+			expectedError: false,
+		},
+		{
+			name:          "no system prompt nor prompt template",
+			update:        func(input *models.PersonaInput) { input.SystemPrompt = "" },
+			expectedError: true,
+		},
+		{
+			name:          "prompt version without prompt template",
+			update:        func(input *models.PersonaInput) { input.PromptVersion = "1.2.0" },
+			expectedError: true,
+		},
+		{
+			name:          "invalid prompt version",
+			update:        func(input *models.PersonaInput) { input.PromptName, input.PromptVersion = "pen-pal", "latest" },
+			expectedError: true,
+		},
+		{
+			name:          "letter delay window",
+			update:        func(input *models.PersonaInput) { input.LetterDelayMin, input.LetterDelayMax = 60, 240 },
+			expectedError: false,
+		},
+		{
+			name:          "reversed letter delay window",
+			update:        func(input *models.PersonaInput) { input.LetterDelayMin, input.LetterDelayMax = 240, 60 },
+			expectedError: true,
+		},
+		{
+			name:          "letter delay too long",
+			update:        func(input *models.PersonaInput) { input.LetterDelayMax = 30 * 24 * 60 },
+			expectedError: true,
+		},
+		{
+			name:          "missing name",
+			update:        func(input *models.PersonaInput) { input.Name = "" },
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.update(&input)
+			err := input.Validate()
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statuses of a placement test.
+const (
+	PlacementStatusInProgress = "in_progress" // PlacementStatusInProgress is a test waiting for the answer of the learner.
+	PlacementStatusCompleted  = "completed"   // PlacementStatusCompleted is a test whose result was set as the level of the learner.
+)
+
+// PlacementTestLength is the number of questions of a placement test.
+const PlacementTestLength = 6
+
+// PlacementTest represents an adaptive assessment of the CEFR level of a user in a language.
+// @Description PlacementTest holds the questions asked to a user to assess their level in a language.
+type PlacementTest struct {
+	ID          uuid.UUID           `gorm:"type:char(36);primary_key"`                     // Unique identifier for the test
+	UserID      uuid.UUID           `gorm:"type:char(36);index;not null"`                  // User taking the test
+	Language    string              `gorm:"type:varchar(16);not null"`                     // Language assessed
+	Status      string              `gorm:"type:varchar(16);not null;default:in_progress"` // Either in_progress or completed
+	Questions   []PlacementQuestion `gorm:"type:text;serializer:json"`                     // Questions asked, the last one waiting for its answer while in progress
+	Result      string              `gorm:"type:varchar(2)"`                               // CEFR level assessed, empty until completed
+	CreatedAt   time.Time           `gorm:"not null"`                                      // Timestamp when the test was started
+	UpdatedAt   time.Time           `gorm:"not null"`                                      // Timestamp when the test was last answered
+	CompletedAt sql.NullTime        // Timestamp when the test was completed
+}
+
+// PlacementQuestion is a task of a placement test, written at a CEFR level, with the answer of the learner and its grade.
+// @Description PlacementQuestion holds a task of a placement test and its grade.
+type PlacementQuestion struct {
+	Level    string `json:"level"`              // CEFR level the task is written at
+	Prompt   string `json:"prompt"`             // Task given to the learner
+	Answer   string `json:"answer,omitempty"`   // Answer of the learner, empty until answered
+	Score    int    `json:"score"`              // Grade of the answer, from 0 to 100
+	Feedback string `json:"feedback,omitempty"` // Comment of the grader on the answer
+}
+
+// BeforeCreate is a GORM hook that is called before a new placement test record is created.
+// It assigns a new UUID to the test's ID.
+func (p *PlacementTest) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
+
+// Pending returns the question waiting for the answer of the learner, nil if there is none.
+func (p PlacementTest) Pending() *PlacementQuestion {
+	if len(p.Questions) == 0 || p.Questions[len(p.Questions)-1].Answer != "" {
+		return nil
+	}
+	return &p.Questions[len(p.Questions)-1]
+}
+
+// ToResponse converts the placement test into the representation exposed by the API.
+// The answered questions are listed with their grade, the pending one is returned apart.
+func (p PlacementTest) ToResponse() PlacementTestResponse {
+	response := PlacementTestResponse{
+		ID:        p.ID,
+		Language:  p.Language,
+		Status:    p.Status,
+		Length:    PlacementTestLength,
+		Answered:  []PlacementQuestion{},
+		Result:    p.Result,
+		CreatedAt: p.CreatedAt,
+	}
+	for _, question := range p.Questions {
+		if question.Answer == "" {
+			response.Question = &PlacementQuestionResponse{Number: len(response.Answered) + 1, Level: question.Level, Prompt: question.Prompt}
+			continue
+		}
+		response.Answered = append(response.Answered, question)
+	}
+	if p.CompletedAt.Valid {
+		response.CompletedAt = &p.CompletedAt.Time
+	}
+	return response
+}
+
+// PlacementTestInput represents the language a user wants to be assessed in.
+// @Description Language of the placement test.
+type PlacementTestInput struct {
+	Language string `json:"language" binding:"required"` // Language assessed, e.g. "es"
+}
+
+// Validate performs validation on PlacementTestInput fields.
+func (i PlacementTestInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Language, validation.Required, validation.Match(languageCode)),
+	)
+}
+
+// PlacementAnswerInput represents the answer of a user to the pending question of a placement test.
+// @Description Answer to the pending question of a placement test.
+type PlacementAnswerInput struct {
+	Answer string `json:"answer" binding:"required"` // Answer of the learner, written in the language assessed
+}
+
+// Validate performs validation on PlacementAnswerInput fields.
+func (i PlacementAnswerInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Answer, validation.Required, validation.Length(1, 2000)),
+	)
+}
+
+// PlacementQuestionResponse represents the question a user has to answer.
+// @Description PlacementQuestionResponse holds the pending question of a placement test.
+type PlacementQuestionResponse struct {
+	Number int    `json:"number"` // Position of the question in the test, from 1
+	Level  string `json:"level"`  // CEFR level the task is written at
+	Prompt string `json:"prompt"` // Task given to the learner
+}
+
+// PlacementTestResponse represents a placement test returned by the API.
+// @Description PlacementTestResponse holds the progress of a placement test and its result once completed.
+type PlacementTestResponse struct {
+	ID          uuid.UUID                  `json:"id"`                     // Unique identifier for the test
+	Language    string                     `json:"language"`               // Language assessed
+	Status      string                     `json:"status"`                 // Either in_progress or completed
+	Length      int                        `json:"length"`                 // Number of questions of the test
+	Question    *PlacementQuestionResponse `json:"question,omitempty"`     // Question to answer, omitted once completed
+	Answered    []PlacementQuestion        `json:"answered"`               // Questions answered, with their grade
+	Result      string                     `json:"result,omitempty"`       // CEFR level assessed, omitted until completed
+	CreatedAt   time.Time                  `json:"created_at"`             // Timestamp when the test was started
+	CompletedAt *time.Time                 `json:"completed_at,omitempty"` // Timestamp when the test was completed
+}
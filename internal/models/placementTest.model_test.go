@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlacementTest_BeforeCreate(t *testing.T) {
+	test := &models.PlacementTest{}
+	err := test.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, test.ID)
+}
+
+func TestPlacementTest_Pending(t *testing.T) {
+	test := models.PlacementTest{}
+	assert.Nil(t, test.Pending())
+
+	test.Questions = []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}}
+	test.Pending().Answer = "Fui al cine."
+	assert.Equal(t, "Fui al cine.", test.Questions[0].Answer)
+	assert.Nil(t, test.Pending())
+}
+
+func TestPlacementTest_ToResponse(t *testing.T) {
+	test := models.PlacementTest{
+		ID:       uuid.New(),
+		Language: "es",
+		Status:   models.PlacementStatusInProgress,
+		Questions: []models.PlacementQuestion{
+			{Level: "B1", Prompt: "¿Qué hiciste ayer?", Answer: "Fui al cine.", Score: 70},
+			{Level: "B2", Prompt: "¿Qué opinas del teletrabajo?"},
+		},
+	}
+	response := test.ToResponse()
+
+	assert.Equal(t, models.PlacementTestLength, response.Length)
+	assert.Equal(t, test.Questions[:1], response.Answered)
+	assert.Equal(t, &models.PlacementQuestionResponse{Number: 2, Level: "B2", Prompt: "¿Qué opinas del teletrabajo?"}, response.Question)
+	assert.Nil(t, response.CompletedAt)
+
+	test.Questions = test.Questions[:1]
+	test.Status, test.Result = models.PlacementStatusCompleted, "B1"
+	test.CompletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	response = test.ToResponse()
+	assert.Nil(t, response.Question)
+	assert.Equal(t, "B1", response.Result)
+	assert.Equal(t, test.CompletedAt.Time, *response.CompletedAt)
+}
+
+func TestPlacementInputsValidation(t *testing.T) {
+	assert.NoError(t, models.PlacementTestInput{Language: "es"}.Validate())
+	assert.Error(t, models.PlacementTestInput{Language: "Spanish"}.Validate())
+	assert.Error(t, models.PlacementTestInput{}.Validate())
+
+	assert.NoError(t, models.PlacementAnswerInput{Answer: "Fui al cine."}.Validate())
+	assert.Error(t, models.PlacementAnswerInput{}.Validate())
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// States of a prompt template version.
+const (
+	PromptStatusDraft     = "draft"     // PromptStatusDraft is a version being written, it can be edited and is never served.
+	PromptStatusPublished = "published" // PromptStatusPublished is a frozen version served to the learners of its rollout.
+)
+
+// PromptVariables lists the variables a prompt template can require, e.g. {{.LearnerName}} or {{.Persona.Name}}.
+var PromptVariables = []string{"LearnerName", "Language", "Level", "Persona"}
+
+// semanticVersion matches a version made of a major, a minor and a patch number, e.g. "1.4.0".
+var semanticVersion = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)$`)
+
+// promptName matches the name of a prompt template, e.g. "pen-pal".
+var promptName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// PromptTemplate represents a version of the system prompt given to the language model.
+// @Description PromptTemplate holds a version of a system prompt template.
+type PromptTemplate struct {
+	ID          uuid.UUID    `gorm:"type:char(36);primary_key"`                                                // Unique identifier for the version
+	Name        string       `gorm:"type:varchar(100);uniqueIndex:idx_prompt_templates_name_version;not null"` // Name shared by the versions of the template
+	Version     string       `gorm:"type:varchar(32);uniqueIndex:idx_prompt_templates_name_version;not null"`  // Semantic version, e.g. "1.4.0"
+	Description string       `gorm:"type:text"`                                                                // Description of the changes of the version
+	Body        string       `gorm:"type:text;not null"`                                                       // Go template of the instructions given to the language model
+	Variables   []string     `gorm:"type:text;serializer:json"`                                                // Variables that must have a value to render the template
+	Status      string       `gorm:"type:varchar(16);not null;default:draft"`                                  // Either draft or published
+	Rollout     int          `gorm:"not null;default:0"`                                                       // Percentage of the learners served with the version once published
+	PublishedAt sql.NullTime // Timestamp when the version was published
+	CreatedAt   time.Time    `gorm:"not null"` // Timestamp when the version was created
+	UpdatedAt   time.Time    `gorm:"not null"` // Timestamp when the version was last updated
+}
+
+// BeforeCreate is a GORM hook that is called before a new prompt template record is created.
+// It assigns a new UUID to the template's ID.
+func (p *PromptTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
+
+// IsPublished reports whether the version is frozen and can be served.
+func (p PromptTemplate) IsPublished() bool {
+	return p.Status == PromptStatusPublished
+}
+
+// ToResponse converts the prompt template into the representation exposed by the API.
+func (p PromptTemplate) ToResponse() PromptTemplateResponse {
+	response := PromptTemplateResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Version:     p.Version,
+		Description: p.Description,
+		Body:        p.Body,
+		Variables:   p.Variables,
+		Status:      p.Status,
+		Rollout:     p.Rollout,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+	if p.PublishedAt.Valid {
+		response.PublishedAt = &p.PublishedAt.Time
+	}
+	return response
+}
+
+// PromptTemplateInput represents the fields required to create a draft version of a prompt template.
+// @Description Fields required to create a draft version of a prompt template.
+type PromptTemplateInput struct {
+	Name        string   `json:"name" binding:"required"`    // Name shared by the versions of the template
+	Version     string   `json:"version" binding:"required"` // Semantic version, e.g. "1.4.0"
+	Description string   `json:"description"`                // Description of the changes of the version
+	Body        string   `json:"body" binding:"required"`    // Go template of the instructions given to the language model
+	Variables   []string `json:"variables"`                  // Variables that must have a value to render the template
+}
+
+// Validate performs validation on PromptTemplateInput fields.
+func (i PromptTemplateInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Name, validation.Required, validation.Length(1, 100), validation.Match(promptName)),
+		validation.Field(&i.Version, validation.Required, validation.Match(semanticVersion)),
+		validation.Field(&i.Description, validation.Length(0, 1000)),
+		validation.Field(&i.Body, validation.Required, validation.Length(1, 8000), validation.By(validTemplate)),
+		validation.Field(&i.Variables, validation.By(validVariables)),
+	)
+}
+
+// PromptTemplateUpdateInput represents the fields of a draft version that can be changed.
+// @Description Fields of a draft version of a prompt template that can be changed.
+type PromptTemplateUpdateInput struct {
+	Description string   `json:"description"`             // Description of the changes of the version
+	Body        string   `json:"body" binding:"required"` // Go template of the instructions given to the language model
+	Variables   []string `json:"variables"`               // Variables that must have a value to render the template
+}
+
+// Validate performs validation on PromptTemplateUpdateInput fields.
+func (i PromptTemplateUpdateInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Description, validation.Length(0, 1000)),
+		validation.Field(&i.Body, validation.Required, validation.Length(1, 8000), validation.By(validTemplate)),
+		validation.Field(&i.Variables, validation.By(validVariables)),
+	)
+}
+
+// PromptRolloutInput represents the share of the learners served with a published version.
+// @Description Share of the learners served with a published version of a prompt template.
+type PromptRolloutInput struct {
+	Rollout *int `json:"rollout" binding:"required"` // Percentage of the learners, from 0 to 100
+}
+
+// Validate performs validation on PromptRolloutInput fields.
+func (i PromptRolloutInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Rollout, validation.NotNil, validation.Min(0), validation.Max(100)),
+	)
+}
+
+// PromptPreviewInput represents the learner and the context a prompt template is rendered for.
+// @Description Learner and context a prompt template is rendered for.
+type PromptPreviewInput struct {
+	UserID    uuid.UUID  `json:"user_id" binding:"required"`  // Learner the prompt is rendered for
+	PersonaID *uuid.UUID `json:"persona_id"`                  // Optional pen pal the learner talks to
+	Language  string     `json:"language" binding:"required"` // Language of the conversation
+}
+
+// Validate performs validation on PromptPreviewInput fields.
+func (i PromptPreviewInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.UserID, validation.NotIn(uuid.Nil.String()).Error("cannot be blank")),
+		validation.Field(&i.Language, validation.Required, validation.Match(languageCode)),
+	)
+}
+
+// PromptTemplateResponse represents a prompt template returned by the API.
+// @Description PromptTemplateResponse holds the data exposed to administrators for a version of a prompt template.
+type PromptTemplateResponse struct {
+	ID          uuid.UUID  `json:"id"`                     // Unique identifier for the version
+	Name        string     `json:"name"`                   // Name shared by the versions of the template
+	Version     string     `json:"version"`                // Semantic version
+	Description string     `json:"description"`            // Description of the changes of the version
+	Body        string     `json:"body"`                   // Go template of the instructions given to the language model
+	Variables   []string   `json:"variables"`              // Variables that must have a value to render the template
+	Status      string     `json:"status"`                 // Either draft or published
+	Rollout     int        `json:"rollout"`                // Percentage of the learners served with the version
+	PublishedAt *time.Time `json:"published_at,omitempty"` // Timestamp when the version was published, omitted for drafts
+	CreatedAt   time.Time  `json:"created_at"`             // Timestamp when the version was created
+	UpdatedAt   time.Time  `json:"updated_at"`             // Timestamp when the version was last updated
+}
+
+// PromptPreviewResponse represents a prompt template rendered for a learner.
+// @Description PromptPreviewResponse holds a prompt template rendered for a learner.
+type PromptPreviewResponse struct {
+	Name    string `json:"name"`    // Name of the template
+	Version string `json:"version"` // Version of the template
+	Prompt  string `json:"prompt"`  // Instructions that would be given to the language model
+}
+
+// validVariables is a validation rule checking that every element of a list is a known prompt variable.
+func validVariables(value interface{}) error {
+	variables, _ := value.([]string)
+	for _, variable := range variables {
+		if !slices.Contains(PromptVariables, variable) {
+			return errors.New("must only contain known variables")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptTemplate_BeforeCreate(t *testing.T) {
+	tmpl := &models.PromptTemplate{}
+	err := tmpl.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, tmpl.ID)
+}
+
+func TestPromptTemplate_ToResponse(t *testing.T) {
+	tmpl := models.PromptTemplate{ID: uuid.New(), Name: "pen-pal", Version: "1.0.0", Status: models.PromptStatusDraft}
+	response := tmpl.ToResponse()
+	assert.False(t, tmpl.IsPublished())
+	assert.Nil(t, response.PublishedAt)
+	assert.Equal(t, tmpl.Version, response.Version)
+
+	tmpl.Status = models.PromptStatusPublished
+	tmpl.PublishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	response = tmpl.ToResponse()
+	assert.True(t, tmpl.IsPublished())
+	assert.Equal(t, &tmpl.PublishedAt.Time, response.PublishedAt)
+}
+
+func TestPromptTemplateInputValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.PromptTemplateInput
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.PromptTemplateInput{Name: "pen-pal", Version: "1.10.0", Body: "Write to {{.LearnerName}}.", Variables: []string{"LearnerName", "Level"}},
+			expectedError: false,
+		},
+		{
+			name:          "invalid name",
+			input:         models.PromptTemplateInput{Name: "Pen Pal", Version: "1.0.0", Body: "Write to {{.LearnerName}}."},
+			expectedError: true,
+		},
+		{
+			name:          "invalid version",
+			input:         models.PromptTemplateInput{Name: "pen-pal", Version: "1.0", Body: "Write to {{.LearnerName}}."},
+			expectedError: true,
+		},
+		{
+			name:          "invalid template",
+			input:         models.PromptTemplateInput{Name: "pen-pal", Version: "1.0.0", Body: "Write to {{.LearnerName"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown variable",
+			input:         models.PromptTemplateInput{Name: "pen-pal", Version: "1.0.0", Body: "Write to {{.LearnerName}}.", Variables: []string{"Age"}},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPromptRolloutInputValidation(t *testing.T) {
+	zero, half, tooMuch := 0, 50, 101
+	assert.NoError(t, models.PromptRolloutInput{Rollout: &zero}.Validate())
+	assert.NoError(t, models.PromptRolloutInput{Rollout: &half}.Validate())
+	assert.Error(t, models.PromptRolloutInput{Rollout: &tooMuch}.Validate())
+	assert.Error(t, models.PromptRolloutInput{}.Validate())
+}
+
+func TestPromptPreviewInputValidation(t *testing.T) {
+	assert.NoError(t, models.PromptPreviewInput{UserID: uuid.New(), Language: "es"}.Validate())
+	assert.Error(t, models.PromptPreviewInput{UserID: uuid.New(), Language: "Spanish"}.Validate())
+	assert.Error(t, models.PromptPreviewInput{Language: "es"}.Validate())
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Share represents a public read-only link to a snapshot of a conversation.
+// The messages are copied when the link is created, so that the later messages, edits and deletions of the
+// conversation don't leak through it. Only the hash of the share token is stored.
+// @Description Share holds the details of a public link to a conversation.
+type Share struct {
+	ID             uuid.UUID       `gorm:"type:char(36);primary_key"`             // Unique identifier for the share
+	ConversationID uuid.UUID       `gorm:"type:char(36);index;not null"`          // Conversation shared
+	UserID         uuid.UUID       `gorm:"type:char(36);index;not null"`          // Learner who shared the conversation
+	TokenHash      string          `gorm:"type:varchar(64);uniqueIndex;not null"` // SHA-256 hash of the share token
+	Title          string          `gorm:"type:varchar(255);not null"`            // Title of the conversation when it was shared
+	Language       string          `gorm:"type:varchar(16);not null"`             // Language practised in the conversation
+	PenPal         string          `gorm:"type:varchar(255);not null"`            // Name of the pen pal of the conversation
+	Messages       []SharedMessage `gorm:"type:text;serializer:json"`             // Messages of the active branch when the conversation was shared
+	Scrubbed       bool            `gorm:"not null;default:false"`                // Whether the personal data were removed from the messages
+	ExpiresAt      sql.NullTime    // Optional timestamp after which the link no longer works
+	RevokedAt      sql.NullTime    // Timestamp when the learner revoked the link
+	TakenDownAt    sql.NullTime    // Timestamp when an administrator took the link down
+	TakenDownBy    uuid.NullUUID   `gorm:"type:char(36)"`      // Administrator who took the link down
+	TakedownReason string          `gorm:"type:text"`          // Reason given by the administrator
+	Views          int64           `gorm:"not null;default:0"` // Number of times the link was opened
+	LastViewedAt   sql.NullTime    // Timestamp when the link was last opened
+	CreatedAt      time.Time       `gorm:"not null"` // Timestamp when the conversation was shared
+}
+
+// SharedMessage is a message copied into a share.
+// @Description SharedMessage holds a message of a shared conversation.
+type SharedMessage struct {
+	Role        string       `json:"role"`                  // Either user or assistant
+	Content     string       `json:"content"`               // Text of the message
+	Corrections []Correction `json:"corrections,omitempty"` // Mistakes found in a message of the learner
+	CreatedAt   time.Time    `json:"created_at"`            // Timestamp when the message was sent
+}
+
+// BeforeCreate is a GORM hook that is called before a new share record is created.
+// It assigns a new UUID to the share's ID.
+func (s *Share) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+	return
+}
+
+// IsActive reports whether the link of the share can be opened at the given time.
+func (s Share) IsActive(now time.Time) bool {
+	return !s.RevokedAt.Valid && !s.TakenDownAt.Valid && (!s.ExpiresAt.Valid || now.Before(s.ExpiresAt.Time))
+}
+
+// ToResponse converts the share into the representation exposed to its learner and to administrators.
+func (s Share) ToResponse() ShareResponse {
+	response := ShareResponse{
+		ID:             s.ID,
+		ConversationID: s.ConversationID,
+		UserID:         s.UserID,
+		Title:          s.Title,
+		Scrubbed:       s.Scrubbed,
+		Active:         s.IsActive(time.Now()),
+		TakedownReason: s.TakedownReason,
+		Views:          s.Views,
+		CreatedAt:      s.CreatedAt,
+	}
+	if s.ExpiresAt.Valid {
+		response.ExpiresAt = &s.ExpiresAt.Time
+	}
+	if s.RevokedAt.Valid {
+		response.RevokedAt = &s.RevokedAt.Time
+	}
+	if s.TakenDownAt.Valid {
+		response.TakenDownAt = &s.TakenDownAt.Time
+	}
+	if s.LastViewedAt.Valid {
+		response.LastViewedAt = &s.LastViewedAt.Time
+	}
+	return response
+}
+
+// ToPublicResponse converts the share into the read-only representation exposed to anyone holding the link.
+func (s Share) ToPublicResponse() SharedConversationResponse {
+	response := SharedConversationResponse{
+		Title:     s.Title,
+		Language:  s.Language,
+		PenPal:    s.PenPal,
+		Messages:  s.Messages,
+		Views:     s.Views,
+		CreatedAt: s.CreatedAt,
+	}
+	if response.Messages == nil {
+		response.Messages = []SharedMessage{}
+	}
+	return response
+}
+
+// ShareInput represents the options of a new share link.
+// @Description Options of a public link to a conversation.
+type ShareInput struct {
+	ExpiresInHours int  `json:"expires_in_hours"` // Number of hours the link works, 0 for a link which never expires
+	ScrubPII       bool `json:"scrub_pii"`        // Whether to remove the emails, phone numbers, bank details and address of the learner
+}
+
+// Validate performs validation on ShareInput fields.
+func (i ShareInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.ExpiresInHours, validation.Min(0), validation.Max(24*365)),
+	)
+}
+
+// ShareTakedownInput represents the decision of an administrator to take a share link down.
+// @Description Reason why an administrator takes a share link down.
+type ShareTakedownInput struct {
+	Reason string `json:"reason" binding:"required"` // Reason of the takedown
+}
+
+// Validate performs validation on ShareTakedownInput fields.
+func (i ShareTakedownInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Reason, validation.Required, validation.Length(1, 1000)),
+	)
+}
+
+// ShareResponse represents a share link returned to its learner or to administrators.
+// @Description ShareResponse holds the details of a public link to a conversation.
+type ShareResponse struct {
+	ID             uuid.UUID  `json:"id"`                        // Unique identifier for the share
+	ConversationID uuid.UUID  `json:"conversation_id"`           // Conversation shared
+	UserID         uuid.UUID  `json:"user_id"`                   // Learner who shared the conversation
+	Title          string     `json:"title"`                     // Title of the conversation when it was shared
+	URL            string     `json:"url,omitempty"`             // Public link, only returned when the share is created
+	Scrubbed       bool       `json:"scrubbed"`                  // Whether the personal data were removed from the messages
+	Active         bool       `json:"active"`                    // Whether the link can be opened
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // Timestamp after which the link no longer works, omitted if never
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`      // Timestamp when the learner revoked the link
+	TakenDownAt    *time.Time `json:"taken_down_at,omitempty"`   // Timestamp when an administrator took the link down
+	TakedownReason string     `json:"takedown_reason,omitempty"` // Reason given by the administrator
+	Views          int64      `json:"views"`                     // Number of times the link was opened
+	LastViewedAt   *time.Time `json:"last_viewed_at,omitempty"`  // Timestamp when the link was last opened
+	CreatedAt      time.Time  `json:"created_at"`                // Timestamp when the conversation was shared
+}
+
+// SharedConversationResponse represents a shared conversation returned to anyone holding its link.
+// @Description SharedConversationResponse holds the snapshot of a shared conversation.
+type SharedConversationResponse struct {
+	Title     string          `json:"title"`      // Title of the conversation
+	Language  string          `json:"language"`   // Language practised in the conversation
+	PenPal    string          `json:"pen_pal"`    // Name of the pen pal
+	Messages  []SharedMessage `json:"messages"`   // Messages of the conversation, oldest first
+	Views     int64           `json:"views"`      // Number of times the link was opened
+	CreatedAt time.Time       `json:"created_at"` // Timestamp when the conversation was shared
+}
@@ -0,0 +1,130 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_BeforeCreate(t *testing.T) {
+	share := &models.Share{}
+	err := share.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, share.ID)
+}
+
+func TestShare_IsActive(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		share    models.Share
+		expected bool
+	}{
+		{
+			name:     "without expiry",
+			share:    models.Share{},
+			expected: true,
+		},
+		{
+			name:     "not expired yet",
+			share:    models.Share{ExpiresAt: sql.NullTime{Time: now.Add(time.Hour), Valid: true}},
+			expected: true,
+		},
+		{
+			name:     "expired",
+			share:    models.Share{ExpiresAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true}},
+			expected: false,
+		},
+		{
+			name:     "revoked",
+			share:    models.Share{RevokedAt: sql.NullTime{Time: now, Valid: true}},
+			expected: false,
+		},
+		{
+			name:     "taken down",
+			share:    models.Share{TakenDownAt: sql.NullTime{Time: now, Valid: true}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.share.IsActive(now))
+		})
+	}
+}
+
+func TestShareInputValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         models.ShareInput
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.ShareInput{ExpiresInHours: 48, ScrubPII: true},
+			expectedError: false,
+		},
+		{
+			name:          "without expiry",
+			input:         models.ShareInput{},
+			expectedError: false,
+		},
+		{
+			name:          "negative expiry",
+			input:         models.ShareInput{ExpiresInHours: -1},
+			expectedError: true,
+		},
+		{
+			name:          "expiry beyond a year",
+			input:         models.ShareInput{ExpiresInHours: 24*365 + 1},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("ShareInput.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestShareTakedownInputValidation(t *testing.T) {
+	assert.NoError(t, models.ShareTakedownInput{Reason: "Harassment"}.Validate())
+	assert.Error(t, models.ShareTakedownInput{}.Validate())
+}
+
+func TestShare_ToResponse(t *testing.T) {
+	now := time.Now()
+	share := models.Share{ID: uuid.New(), TokenHash: "hash", Title: "En el mercado", Views: 3}
+
+	response := share.ToResponse()
+	assert.True(t, response.Active)
+	assert.Nil(t, response.ExpiresAt)
+	assert.Nil(t, response.TakenDownAt)
+	assert.Empty(t, response.URL)
+
+	share.TakenDownAt = sql.NullTime{Time: now, Valid: true}
+	share.TakedownReason = "Harassment"
+	response = share.ToResponse()
+	assert.False(t, response.Active)
+	assert.Equal(t, &now, response.TakenDownAt)
+	assert.Equal(t, "Harassment", response.TakedownReason)
+}
+
+func TestShare_ToPublicResponse(t *testing.T) {
+	share := models.Share{ID: uuid.New(), UserID: uuid.New(), TokenHash: "hash", Title: "En el mercado", PenPal: "Lucía"}
+
+	response := share.ToPublicResponse()
+	assert.Equal(t, "Lucía", response.PenPal)
+	assert.Equal(t, []models.SharedMessage{}, response.Messages)
+}
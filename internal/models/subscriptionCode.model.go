@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Plans a subscription code can grant.
+const (
+	PlanFree      = "free"      // PlanFree is the plan of users without a subscription code.
+	PlanPremium   = "premium"   // PlanPremium is the individual paid plan.
+	PlanClassroom = "classroom" // PlanClassroom is the plan bought by schools for their students.
+)
+
+// SubscriptionCode represents a code issued by an administrator that can be redeemed at sign-up.
+// @Description SubscriptionCode holds the details of an invitation or subscription code.
+type SubscriptionCode struct {
+	ID              uuid.UUID    `gorm:"type:char(36);primary_key"`             // Unique identifier for the code
+	Code            string       `gorm:"type:varchar(64);uniqueIndex;not null"` // Code typed by the user, stored upper case
+	BatchID         uuid.UUID    `gorm:"type:char(36);index;not null"`          // Batch the code was generated with
+	Plan            string       `gorm:"type:varchar(255);not null"`            // Plan granted by the code
+	MaxRedemptions  int          `gorm:"not null;default:1"`                    // Number of times the code can be redeemed
+	RedemptionCount int          `gorm:"not null;default:0"`                    // Number of times the code has been redeemed
+	ExpiresAt       sql.NullTime // Optional timestamp after which the code can no longer be redeemed
+	CreatedByID     uuid.UUID    `gorm:"type:char(36);not null"` // Administrator who generated the code
+	CreatedAt       time.Time    `gorm:"not null"`               // Timestamp when the code was generated
+}
+
+// BeforeCreate is a GORM hook that is called before a new code record is created.
+// It assigns a new UUID to the code's ID.
+func (c *SubscriptionCode) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// IsExpired reports whether the code can no longer be redeemed at the given time because of its expiry.
+func (c SubscriptionCode) IsExpired(now time.Time) bool {
+	return c.ExpiresAt.Valid && !now.Before(c.ExpiresAt.Time)
+}
+
+// ToResponse converts the code into the representation exposed by the API.
+func (c SubscriptionCode) ToResponse() SubscriptionCodeResponse {
+	response := SubscriptionCodeResponse{
+		ID:              c.ID,
+		Code:            c.Code,
+		BatchID:         c.BatchID,
+		Plan:            c.Plan,
+		MaxRedemptions:  c.MaxRedemptions,
+		RedemptionCount: c.RedemptionCount,
+		CreatedByID:     c.CreatedByID,
+		CreatedAt:       c.CreatedAt,
+	}
+	if c.ExpiresAt.Valid {
+		response.ExpiresAt = &c.ExpiresAt.Time
+	}
+	return response
+}
+
+// CodeBatchInput represents the fields required to generate a batch of codes.
+// @Description Fields required to generate a batch of codes.
+type CodeBatchInput struct {
+	Count          int        `json:"count" binding:"required"`           // Number of codes to generate
+	Plan           string     `json:"plan" binding:"required"`            // Plan granted by the codes
+	MaxRedemptions int        `json:"max_redemptions" binding:"required"` // Number of times each code can be redeemed
+	ExpiresAt      *time.Time `json:"expires_at"`                         // Optional expiry of the codes
+}
+
+// Validate performs validation on CodeBatchInput fields.
+func (i CodeBatchInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Count, validation.Required, validation.Min(1), validation.Max(1000)),
+		validation.Field(&i.Plan, validation.Required, validation.In(PlanPremium, PlanClassroom)),
+		validation.Field(&i.MaxRedemptions, validation.Required, validation.Min(1), validation.Max(10000)),
+		validation.Field(&i.ExpiresAt, validation.By(inTheFuture)),
+	)
+}
+
+// SubscriptionCodeResponse represents a code returned by the API.
+// @Description SubscriptionCodeResponse holds the data exposed to administrators for a code.
+type SubscriptionCodeResponse struct {
+	ID              uuid.UUID  `json:"id"`                   // Unique identifier for the code
+	Code            string     `json:"code"`                 // Code typed by the user
+	BatchID         uuid.UUID  `json:"batch_id"`             // Batch the code was generated with
+	Plan            string     `json:"plan"`                 // Plan granted by the code
+	MaxRedemptions  int        `json:"max_redemptions"`      // Number of times the code can be redeemed
+	RedemptionCount int        `json:"redemption_count"`     // Number of times the code has been redeemed
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"` // Expiry of the code, omitted if it never expires
+	CreatedByID     uuid.UUID  `json:"created_by_id"`        // Administrator who generated the code
+	CreatedAt       time.Time  `json:"created_at"`           // Timestamp when the code was generated
+}
+
+// inTheFuture is a validation rule checking that an optional time is after now.
+func inTheFuture(value interface{}) error {
+	t, _ := value.(*time.Time)
+	if t != nil && !t.After(time.Now()) {
+		return errors.New("must be in the future")
+	}
+	return nil
+}
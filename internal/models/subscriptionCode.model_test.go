@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionCode_BeforeCreate(t *testing.T) {
+	code := &models.SubscriptionCode{}
+	err := code.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, code.ID)
+}
+
+func TestSubscriptionCode_IsExpired(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, models.SubscriptionCode{}.IsExpired(now))
+	assert.False(t, models.SubscriptionCode{ExpiresAt: sql.NullTime{Time: now.Add(time.Hour), Valid: true}}.IsExpired(now))
+	assert.True(t, models.SubscriptionCode{ExpiresAt: sql.NullTime{Time: now, Valid: true}}.IsExpired(now))
+}
+
+func TestSubscriptionCode_ToResponse(t *testing.T) {
+	code := models.SubscriptionCode{ID: uuid.New(), Code: "ABCD-EFGH-JKMN", Plan: models.PlanPremium}
+	assert.Nil(t, code.ToResponse().ExpiresAt)
+
+	expiresAt := time.Now()
+	code.ExpiresAt = sql.NullTime{Time: expiresAt, Valid: true}
+	response := code.ToResponse()
+	assert.Equal(t, &expiresAt, response.ExpiresAt)
+	assert.Equal(t, code.Code, response.Code)
+}
+
+func TestCodeBatchInputValidation(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		input         models.CodeBatchInput
+		expectedError bool
+	}{
+		{
+			name:          "valid input",
+			input:         models.CodeBatchInput{Count: 10, Plan: models.PlanPremium, MaxRedemptions: 1},
+			expectedError: false,
+		},
+		{
+			name:          "valid input with expiry",
+			input:         models.CodeBatchInput{Count: 1, Plan: models.PlanClassroom, MaxRedemptions: 30, ExpiresAt: &future},
+			expectedError: false,
+		},
+		{
+			name:          "expiry in the past",
+			input:         models.CodeBatchInput{Count: 1, Plan: models.PlanClassroom, MaxRedemptions: 30, ExpiresAt: &past},
+			expectedError: true,
+		},
+		{
+			name:          "too many codes",
+			input:         models.CodeBatchInput{Count: 1001, Plan: models.PlanPremium, MaxRedemptions: 1},
+			expectedError: true,
+		},
+		{
+			name:          "free plan",
+			input:         models.CodeBatchInput{Count: 1, Plan: models.PlanFree, MaxRedemptions: 1},
+			expectedError: true,
+		},
+		{
+			name:          "no redemption",
+			input:         models.CodeBatchInput{Count: 1, Plan: models.PlanPremium, MaxRedemptions: 0},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("CodeBatchInput.Validate() error = %v, expectedError %v", err, tt.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UsageRecord represents a call to the language model, with the tokens it consumed and its estimated cost.
+// @Description UsageRecord holds the consumption of a call to the language model.
+type UsageRecord struct {
+	ID               uuid.UUID     `gorm:"type:char(36);primary_key"`    // Unique identifier for the record
+	UserID           uuid.UUID     `gorm:"type:char(36);index;not null"` // User the call was made for
+	ConversationID   uuid.NullUUID `gorm:"type:char(36);index"`          // Conversation the call answered, if any
+	Model            string        `gorm:"type:varchar(100)"`            // Language model that answered
+	PromptTokens     int           `gorm:"not null;default:0"`           // Number of tokens of the prompt
+	CompletionTokens int           `gorm:"not null;default:0"`           // Number of tokens of the reply
+	Cost             float64       `gorm:"not null;default:0"`           // Estimated cost of the call, in US dollars
+	CreatedAt        time.Time     `gorm:"index;not null"`               // Timestamp when the call was made
+}
+
+// BeforeCreate is a GORM hook that is called before a new usage record is created.
+// It assigns a new UUID to the record's ID.
+func (u *UsageRecord) BeforeCreate(tx *gorm.DB) (err error) {
+	u.ID = uuid.New()
+	return
+}
+
+// UsagePeriodResponse represents the consumption of a user over a day or a month.
+// @Description UsagePeriodResponse holds the consumption of a user over a day or a month.
+type UsagePeriodResponse struct {
+	Calls            int64     `json:"calls"`             // Number of calls to the language model
+	PromptTokens     int64     `json:"prompt_tokens"`     // Number of tokens of the prompts
+	CompletionTokens int64     `json:"completion_tokens"` // Number of tokens of the replies
+	TotalTokens      int64     `json:"total_tokens"`      // Number of tokens counted against the quota
+	Limit            int64     `json:"limit"`             // Quota of tokens of the period, 0 if unlimited
+	Cost             float64   `json:"cost"`              // Estimated cost, in US dollars
+	ResetAt          time.Time `json:"reset_at"`          // Timestamp when the period ends
+}
+
+// UsageResponse represents the consumption of the current user.
+// @Description UsageResponse holds the consumption and the quotas of the current user.
+type UsageResponse struct {
+	Plan  string              `json:"plan"`  // Plan the quotas derive from
+	Day   UsagePeriodResponse `json:"day"`   // Consumption of the current day, in UTC
+	Month UsagePeriodResponse `json:"month"` // Consumption of the current month, in UTC
+}
+
+// UsageReportResponse represents the consumption of a user over a day or a month.
+// @Description UsageReportResponse holds the consumption of a user over a day or a month, for administrators.
+type UsageReportResponse struct {
+	UserID           uuid.UUID `json:"user_id"`           // User the calls were made for
+	Email            string    `json:"email"`             // Email address of the user
+	Period           string    `json:"period"`            // Day (YYYY-MM-DD) or month (YYYY-MM) of the calls
+	Calls            int64     `json:"calls"`             // Number of calls to the language model
+	PromptTokens     int64     `json:"prompt_tokens"`     // Number of tokens of the prompts
+	CompletionTokens int64     `json:"completion_tokens"` // Number of tokens of the replies
+	Cost             float64   `json:"cost"`              // Estimated cost, in US dollars
+}
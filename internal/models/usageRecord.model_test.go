@@ -0,0 +1,17 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageRecord_BeforeCreate(t *testing.T) {
+	record := &models.UsageRecord{}
+	err := record.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, record.ID)
+}
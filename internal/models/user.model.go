@@ -13,6 +13,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultNativeLanguage is the native language of the users who didn't give theirs.
+const DefaultNativeLanguage = "en"
+
 // User represents a user profile in the system.
 // @Description User holds all details about a user.
 type User struct {
@@ -22,8 +25,10 @@ type User struct {
 	Birthday         time.Time      `gorm:"not null"`                               // Birthday of the user
 	Gender           string         `gorm:"type:varchar(255);not null"`             // Gender of the user
 	Email            string         `gorm:"type:varchar(255);uniqueIndex;not null"` // Email address of the user, must be unique
+	NormalizedEmail  string         `gorm:"type:varchar(255);index"`                // Canonical form of the email, used to detect duplicate accounts
 	Password         string         `gorm:"type:varchar(255);not null"`             // Password for the user account
 	Role             string         `gorm:"type:varchar(255);default:user"`         // Role of the user in the system
+	NativeLanguage   string         `gorm:"type:varchar(16);not null;default:en"`   // Language the user speaks natively, used to explain their mistakes
 	Address          sql.NullString `gorm:"type:varchar(255)"`                      // Optional address of the user
 	SubscriptionCode sql.NullString `gorm:"type:varchar(255)"`                      // Optional subscription code
 	IsActive         bool           `gorm:"default:1"`                              // Flag indicating if the user account is active
@@ -40,7 +45,7 @@ func (u User) Validate() error {
 	return validation.ValidateStruct(&u,
 		validation.Field(&u.FirstName, validation.Required, validation.Length(1, 20)),
 		validation.Field(&u.Name, validation.Required, validation.Length(1, 20)),
-		validation.Field(&u.Birthday, validation.Required),
+		validation.Field(&u.Birthday, validation.Required, validation.By(utils.BirthdayRequirements)),
 		validation.Field(&u.Gender, validation.Required, validation.In("male", "female", "other")),
 		validation.Field(&u.Email, validation.Required, is.Email),
 		validation.Field(&u.Password, validation.Required, validation.Length(8, 100), is.PrintableASCII, validation.By(utils.PasswordRequirements)),
@@ -55,6 +60,13 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// BeforeSave is a GORM hook that is called before a user record is created or updated.
+// It keeps the normalized email in sync with the email.
+func (u *User) BeforeSave(tx *gorm.DB) (err error) {
+	u.NormalizedEmail = utils.NormalizeEmail(u.Email)
+	return
+}
+
 // String provides a string representation of the user which includes
 // personal details and contact information.
 func (u User) String() string {
@@ -76,12 +88,15 @@ func (u User) IsEqualTo(other User) bool {
 // SignUpInput represents the required fields for a user register.
 // @Description Fields required to register a new user.
 type SignUpInput struct {
-	FirstName string    `json:"first_name" binding:"required"` // First name of the user
-	Name      string    `json:"name" binding:"required"`       // Last name of the user
-	Birthday  time.Time `json:"birthday" binding:"required"`   // Birthday of the user
-	Gender    string    `json:"gender" binding:"required"`     // Gender of the user
-	Email     string    `json:"email" binding:"required"`      // Email address of the user
-	Password  string    `json:"password" binding:"required"`   // Password for the user account
+	FirstName      string    `json:"first_name" binding:"required"` // First name of the user
+	Name           string    `json:"name" binding:"required"`       // Last name of the user
+	Birthday       time.Time `json:"birthday" binding:"required"`   // Birthday of the user
+	Gender         string    `json:"gender" binding:"required"`     // Gender of the user
+	Email          string    `json:"email" binding:"required"`      // Email address of the user
+	Password       string    `json:"password" binding:"required"`   // Password for the user account
+	Code           string    `json:"code"`                          // Optional subscription code redeemed at registration
+	ParentEmail    string    `json:"parent_email"`                  // Email address of a parent, required to register a minor
+	NativeLanguage string    `json:"native_language"`               // Optional language the user speaks natively, e.g. "fr", "en" by default
 }
 
 // Validate performs validation on SignUpInput fields to ensure they meet
@@ -90,10 +105,13 @@ func (s SignUpInput) Validate() error {
 	return validation.ValidateStruct(&s,
 		validation.Field(&s.FirstName, validation.Required, validation.Length(1, 20)),
 		validation.Field(&s.Name, validation.Required, validation.Length(1, 20)),
-		validation.Field(&s.Birthday, validation.Required),
+		validation.Field(&s.Birthday, validation.Required, validation.By(utils.BirthdayRequirements)),
 		validation.Field(&s.Gender, validation.Required, validation.In("male", "female", "other")),
 		validation.Field(&s.Email, validation.Required, is.Email),
 		validation.Field(&s.Password, validation.Required, validation.Length(8, 100), is.PrintableASCII, validation.By(utils.PasswordRequirements)),
+		validation.Field(&s.Code, validation.Length(0, 64)),
+		validation.Field(&s.ParentEmail, is.Email),
+		validation.Field(&s.NativeLanguage, validation.Match(languageCode)),
 	)
 }
 
@@ -123,8 +141,18 @@ type UserResponse struct {
 	Gender           string    `json:"gender,omitempty"`     // Gender of the user, omitted if empty
 	Email            string    `json:"email,omitempty"`      // Email address of the user, omitted if empty
 	Role             string    `json:"role,omitempty"`       // Role of the user within the system, omitted if empty
+	NativeLanguage   string    `json:"native_language"`      // Language the user speaks natively
 	Address          string    `json:"address"`              // Address of the user (present even if empty as a blank string)
 	SubscriptionCode string    `json:"subscription_code"`    // Subscription code related to the user's account (present even if empty as a blank string)
 	CreatedAt        time.Time `json:"created_at"`           // Timestamp when the user was created
 	UpdatedAt        time.Time `json:"updated_at"`           // Timestamp when the user profile was last updated
+
+	Restrictions AccountRestrictions `json:"restrictions"` // Features restricted on the account because of the user's age
+}
+
+// AccountRestrictions lists the features restricted on under-age accounts.
+// @Description AccountRestrictions holds the feature flags derived from the user's age.
+type AccountRestrictions struct {
+	StrictModeration      bool `json:"strict_moderation"`       // AI replies are moderated with the strictest settings
+	PublicSharingDisabled bool `json:"public_sharing_disabled"` // Conversations cannot be shared publicly
 }
@@ -86,6 +86,14 @@ func TestUser_BeforeCreate(t *testing.T) {
 	assert.NotEqual(t, uuid.UUID{}, user.ID)
 }
 
+func TestUser_BeforeSave(t *testing.T) {
+	user := &models.User{Email: "John.Doe+news@gmail.com"}
+	err := user.BeforeSave(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "johndoe@gmail.com", user.NormalizedEmail)
+}
+
 func TestSignUpInputValidation(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -112,6 +120,11 @@ func TestSignUpInputValidation(t *testing.T) {
 			input:         builders.NewUserBuilder().WhereBirthday(time.Time{}).BuildSignUpInput(),
 			expectedError: true,
 		},
+		{
+			name:          "Birthday in the future",
+			input:         builders.NewUserBuilder().WhereBirthday(time.Now().AddDate(0, 0, 1)).BuildSignUpInput(),
+			expectedError: true,
+		},
 		{
 			name:          "invalid Gender",
 			input:         builders.NewUserBuilder().WhereGender("none").BuildSignUpInput(),
@@ -137,6 +150,16 @@ func TestSignUpInputValidation(t *testing.T) {
 			input:         builders.NewUserBuilder().WherePassword("Password.").BuildSignUpInput(),
 			expectedError: true,
 		},
+		{
+			name:          "native language",
+			input:         builders.NewUserBuilder().WhereNativeLanguage("pt-BR").BuildSignUpInput(),
+			expectedError: false,
+		},
+		{
+			name:          "invalid native language",
+			input:         builders.NewUserBuilder().WhereNativeLanguage("french").BuildSignUpInput(),
+			expectedError: true,
+		},
 	}
 
 	for _, tt := range tests {
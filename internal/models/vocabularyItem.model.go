@@ -0,0 +1,159 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Origins of a vocabulary item.
+const (
+	VocabularySourceManual    = "manual"    // VocabularySourceManual is an item saved by the learner.
+	VocabularySourceExtracted = "extracted" // VocabularySourceExtracted is an item extracted from a message by the language model.
+)
+
+// DefaultEaseFactor is the ease factor of a new flashcard, as in the SM-2 algorithm.
+const DefaultEaseFactor = 2.5
+
+// VocabularyItem represents a word or a phrase a user learns, reviewed as a flashcard on a spaced-repetition schedule.
+// @Description VocabularyItem holds a word of a user and its review schedule.
+type VocabularyItem struct {
+	ID             uuid.UUID     `gorm:"type:char(36);primary_key"`                                             // Unique identifier for the item
+	UserID         uuid.UUID     `gorm:"type:char(36);uniqueIndex:idx_vocabulary_items_user_term;not null"`     // User learning the item
+	Language       string        `gorm:"type:varchar(16);uniqueIndex:idx_vocabulary_items_user_term;not null"`  // Language of the item
+	Term           string        `gorm:"type:varchar(255);uniqueIndex:idx_vocabulary_items_user_term;not null"` // Word or phrase
+	Translation    string        `gorm:"type:varchar(255);not null"`                                            // Translation in the native language of the user
+	Example        string        `gorm:"type:text"`                                                             // Example sentence using the item
+	Source         string        `gorm:"type:varchar(16);not null;default:manual"`                              // Either manual or extracted
+	MessageID      uuid.NullUUID `gorm:"type:char(36)"`                                                         // Message the item was saved from, if any
+	EaseFactor     float64       `gorm:"not null;default:2.5"`                                                  // Ease factor of the SM-2 algorithm
+	IntervalDays   int           `gorm:"not null;default:0"`                                                    // Number of days between the last review and the next one
+	Repetitions    int           `gorm:"not null;default:0"`                                                    // Number of successful reviews in a row
+	DueAt          time.Time     `gorm:"not null;index"`                                                        // Timestamp when the item should be reviewed
+	LastReviewedAt sql.NullTime  // Timestamp of the last review
+	CreatedAt      time.Time     `gorm:"not null"` // Timestamp when the item was saved
+	UpdatedAt      time.Time     `gorm:"not null"` // Timestamp when the item was last updated
+}
+
+// BeforeCreate is a GORM hook that is called before a new vocabulary item record is created.
+// It assigns a new UUID to the item's ID.
+func (v *VocabularyItem) BeforeCreate(tx *gorm.DB) (err error) {
+	v.ID = uuid.New()
+	return
+}
+
+// ToResponse converts the vocabulary item into the representation exposed by the API.
+func (v VocabularyItem) ToResponse() VocabularyItemResponse {
+	response := VocabularyItemResponse{
+		ID:           v.ID,
+		Language:     v.Language,
+		Term:         v.Term,
+		Translation:  v.Translation,
+		Example:      v.Example,
+		Source:       v.Source,
+		EaseFactor:   v.EaseFactor,
+		IntervalDays: v.IntervalDays,
+		Repetitions:  v.Repetitions,
+		DueAt:        v.DueAt,
+		CreatedAt:    v.CreatedAt,
+	}
+	if v.MessageID.Valid {
+		response.MessageID = &v.MessageID.UUID
+	}
+	if v.LastReviewedAt.Valid {
+		response.LastReviewedAt = &v.LastReviewedAt.Time
+	}
+	return response
+}
+
+// VocabularyInput represents the fields required to save a word.
+// @Description Fields required to save a word or a phrase.
+type VocabularyInput struct {
+	Language    string     `json:"language" binding:"required"`    // Language of the item, e.g. "es"
+	Term        string     `json:"term" binding:"required"`        // Word or phrase
+	Translation string     `json:"translation" binding:"required"` // Translation in the native language of the user
+	Example     string     `json:"example"`                        // Optional example sentence
+	MessageID   *uuid.UUID `json:"message_id"`                     // Optional message the item is saved from
+}
+
+// Validate performs validation on VocabularyInput fields.
+func (i VocabularyInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Language, validation.Required, validation.Match(languageCode)),
+		validation.Field(&i.Term, validation.Required, validation.Length(1, 255)),
+		validation.Field(&i.Translation, validation.Required, validation.Length(1, 255)),
+		validation.Field(&i.Example, validation.Length(0, 1000)),
+	)
+}
+
+// VocabularyUpdateInput represents the fields of a saved word that can be changed.
+// @Description Fields of a saved word that can be changed.
+type VocabularyUpdateInput struct {
+	Translation string `json:"translation" binding:"required"` // Translation in the native language of the user
+	Example     string `json:"example"`                        // Optional example sentence
+}
+
+// Validate performs validation on VocabularyUpdateInput fields.
+func (i VocabularyUpdateInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Translation, validation.Required, validation.Length(1, 255)),
+		validation.Field(&i.Example, validation.Length(0, 1000)),
+	)
+}
+
+// VocabularyExtractionInput represents the message to extract words from.
+// @Description Message of a conversation of the user to extract words from.
+type VocabularyExtractionInput struct {
+	MessageID uuid.UUID `json:"message_id" binding:"required"` // Message to extract the words from
+}
+
+// Validate performs validation on VocabularyExtractionInput fields.
+func (i VocabularyExtractionInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.MessageID, validation.Required, validation.NotIn(uuid.Nil.String()).Error("cannot be blank")),
+	)
+}
+
+// ReviewInput represents the grade given by the user to their recall of a flashcard.
+// @Description Grade of the recall of a flashcard, from 0 (forgotten) to 5 (perfect recall).
+type ReviewInput struct {
+	Grade *int `json:"grade" binding:"required"` // Quality of the recall, from 0 to 5
+}
+
+// Validate performs validation on ReviewInput fields.
+func (i ReviewInput) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Grade, validation.NotNil, validation.Min(0), validation.Max(5)),
+	)
+}
+
+// VocabularyItemResponse represents a vocabulary item returned by the API.
+// @Description VocabularyItemResponse holds a word of the user and its review schedule.
+type VocabularyItemResponse struct {
+	ID             uuid.UUID  `json:"id"`                         // Unique identifier for the item
+	Language       string     `json:"language"`                   // Language of the item
+	Term           string     `json:"term"`                       // Word or phrase
+	Translation    string     `json:"translation"`                // Translation in the native language of the user
+	Example        string     `json:"example,omitempty"`          // Example sentence using the item
+	Source         string     `json:"source"`                     // Either manual or extracted
+	MessageID      *uuid.UUID `json:"message_id,omitempty"`       // Message the item was saved from
+	EaseFactor     float64    `json:"ease_factor"`                // Ease factor of the SM-2 algorithm
+	IntervalDays   int        `json:"interval_days"`              // Number of days between the last review and the next one
+	Repetitions    int        `json:"repetitions"`                // Number of successful reviews in a row
+	DueAt          time.Time  `json:"due_at"`                     // Timestamp when the item should be reviewed
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"` // Timestamp of the last review
+	CreatedAt      time.Time  `json:"created_at"`                 // Timestamp when the item was saved
+}
+
+// VocabularyStatsResponse represents the progress of a user in a language.
+// @Description VocabularyStatsResponse holds the number of words of a user in a language, by stage of learning.
+type VocabularyStatsResponse struct {
+	Language string `json:"language"` // Language of the words
+	Total    int64  `json:"total"`    // Number of words saved
+	New      int64  `json:"new"`      // Number of words never reviewed
+	Due      int64  `json:"due"`      // Number of words to review now
+	Mastered int64  `json:"mastered"` // Number of words reviewed at intervals of 21 days or more
+}
@@ -0,0 +1,68 @@
+package models_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVocabularyItem_BeforeCreate(t *testing.T) {
+	item := &models.VocabularyItem{}
+	err := item.BeforeCreate(nil)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.UUID{}, item.ID)
+}
+
+func TestVocabularyItem_ToResponse(t *testing.T) {
+	item := models.VocabularyItem{
+		ID:          uuid.New(),
+		Language:    "es",
+		Term:        "perro",
+		Translation: "chien",
+		Source:      models.VocabularySourceManual,
+		EaseFactor:  models.DefaultEaseFactor,
+		DueAt:       time.Now(),
+	}
+	response := item.ToResponse()
+
+	assert.Equal(t, item.ID, response.ID)
+	assert.Equal(t, "perro", response.Term)
+	assert.Equal(t, "chien", response.Translation)
+	assert.Equal(t, 2.5, response.EaseFactor)
+	assert.Nil(t, response.MessageID)
+	assert.Nil(t, response.LastReviewedAt)
+
+	item.MessageID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	item.LastReviewedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	response = item.ToResponse()
+	assert.Equal(t, item.MessageID.UUID, *response.MessageID)
+	assert.Equal(t, item.LastReviewedAt.Time, *response.LastReviewedAt)
+}
+
+func TestVocabularyInputValidation(t *testing.T) {
+	assert.NoError(t, models.VocabularyInput{Language: "es", Term: "echar de menos", Translation: "to miss"}.Validate())
+	assert.Error(t, models.VocabularyInput{Language: "spanish", Term: "perro", Translation: "dog"}.Validate())
+	assert.Error(t, models.VocabularyInput{Language: "es", Translation: "dog"}.Validate())
+	assert.Error(t, models.VocabularyInput{Language: "es", Term: "perro"}.Validate())
+
+	assert.NoError(t, models.VocabularyUpdateInput{Translation: "dog"}.Validate())
+	assert.Error(t, models.VocabularyUpdateInput{}.Validate())
+
+	assert.NoError(t, models.VocabularyExtractionInput{MessageID: uuid.New()}.Validate())
+	assert.Error(t, models.VocabularyExtractionInput{}.Validate())
+}
+
+func TestReviewInputValidation(t *testing.T) {
+	grade := func(grade int) *int { return &grade }
+
+	assert.NoError(t, models.ReviewInput{Grade: grade(0)}.Validate())
+	assert.NoError(t, models.ReviewInput{Grade: grade(5)}.Validate())
+	assert.Error(t, models.ReviewInput{Grade: grade(6)}.Validate())
+	assert.Error(t, models.ReviewInput{Grade: grade(-1)}.Validate())
+	assert.Error(t, models.ReviewInput{}.Validate())
+}
@@ -0,0 +1,26 @@
+// Package admin provides route controllers for managing operations in an administrative context.
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/code"
+	"github.com/gin-gonic/gin"
+)
+
+// CodeAdminRouteController handles the routing of subscription code administration functions.
+type CodeAdminRouteController struct {
+	codeController code.CodeController // codeController manages the subscription codes.
+}
+
+// NewAdminRouteCodeController creates a new instance of CodeAdminRouteController using the provided codeController.
+func NewAdminRouteCodeController(codeController code.CodeController) CodeAdminRouteController {
+	return CodeAdminRouteController{codeController}
+}
+
+// CodeRoute defines routes for subscription code management within an admin-specific router group.
+// The paths include operations to generate a batch of codes, list the codes and list their redemptions.
+func (cc *CodeAdminRouteController) CodeRoute(rg *gin.RouterGroup) {
+	router := rg.Group("codes")
+	router.POST("/", cc.codeController.GenerateCodes)            // GenerateCodes handles the generation of a batch of codes.
+	router.GET("/", cc.codeController.GetCodes)                  // GetCodes handles the retrieval of the codes.
+	router.GET("/redemptions", cc.codeController.GetRedemptions) // GetRedemptions handles the retrieval of the redemption history.
+}
@@ -0,0 +1,26 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/emailDomain"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailDomainAdminRouteController handles the routing of the email policy administration functions.
+type EmailDomainAdminRouteController struct {
+	emailDomainController emailDomain.EmailDomainController // emailDomainController manages the email domain rules.
+}
+
+// NewAdminRouteEmailDomainController creates a new instance of EmailDomainAdminRouteController using the provided emailDomainController.
+func NewAdminRouteEmailDomainController(emailDomainController emailDomain.EmailDomainController) EmailDomainAdminRouteController {
+	return EmailDomainAdminRouteController{emailDomainController}
+}
+
+// EmailDomainRoute defines routes for email policy management within an admin-specific router group.
+// The paths include operations to list, create and delete the domain rules, and to reload the blocklist file.
+func (ec *EmailDomainAdminRouteController) EmailDomainRoute(rg *gin.RouterGroup) {
+	router := rg.Group("email-domains")
+	router.GET("/", ec.emailDomainController.GetEmailDomainRules)              // GetEmailDomainRules handles the retrieval of the rules.
+	router.POST("/", ec.emailDomainController.CreateEmailDomainRule)           // CreateEmailDomainRule handles the creation of a rule.
+	router.DELETE("/:id", ec.emailDomainController.DeleteEmailDomainRule)      // DeleteEmailDomainRule handles the removal of a rule by ID.
+	router.POST("/blocklist/reload", ec.emailDomainController.ReloadBlocklist) // ReloadBlocklist handles the reload of the blocklist file.
+}
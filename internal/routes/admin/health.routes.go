@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/health"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthAdminRouteController handles the routing of the monitoring of the language models.
+type HealthAdminRouteController struct {
+	healthController health.HealthController // healthController reports the calls to the language models.
+}
+
+// NewAdminRouteHealthController creates a new instance of HealthAdminRouteController using the provided healthController.
+func NewAdminRouteHealthController(healthController health.HealthController) HealthAdminRouteController {
+	return HealthAdminRouteController{healthController}
+}
+
+// HealthRoute defines routes for the monitoring of the language models within an admin-specific router group.
+func (hc *HealthAdminRouteController) HealthRoute(rg *gin.RouterGroup) {
+	router := rg.Group("health")
+	router.GET("/llm", hc.healthController.GetLLMStats) // GetLLMStats handles the retrieval of the language model counts.
+}
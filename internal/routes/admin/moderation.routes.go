@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/moderation"
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationAdminRouteController handles the routing of the review queue of the moderation.
+type ModerationAdminRouteController struct {
+	moderationController moderation.ModerationController // moderationController manages the moderation records.
+}
+
+// NewAdminRouteModerationController creates a new instance of ModerationAdminRouteController using the provided moderationController.
+func NewAdminRouteModerationController(moderationController moderation.ModerationController) ModerationAdminRouteController {
+	return ModerationAdminRouteController{moderationController}
+}
+
+// ModerationRoute defines routes for the review of the moderated messages within an admin-specific router group.
+// The paths include operations to list the queue, get a record and review it.
+func (mc *ModerationAdminRouteController) ModerationRoute(rg *gin.RouterGroup) {
+	router := rg.Group("moderation")
+	router.GET("/", mc.moderationController.GetModerationRecords)      // GetModerationRecords handles the retrieval of the review queue.
+	router.GET("/:id", mc.moderationController.GetModerationRecord)    // GetModerationRecord handles the retrieval of a moderated message.
+	router.PUT("/:id", mc.moderationController.ReviewModerationRecord) // ReviewModerationRecord handles the review of a moderated message.
+}
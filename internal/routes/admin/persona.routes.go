@@ -0,0 +1,27 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/persona"
+	"github.com/gin-gonic/gin"
+)
+
+// PersonaAdminRouteController handles the routing of the pen pal administration functions.
+type PersonaAdminRouteController struct {
+	personaController persona.PersonaController // personaController manages the pen pals.
+}
+
+// NewAdminRoutePersonaController creates a new instance of PersonaAdminRouteController using the provided personaController.
+func NewAdminRoutePersonaController(personaController persona.PersonaController) PersonaAdminRouteController {
+	return PersonaAdminRouteController{personaController}
+}
+
+// PersonaRoute defines routes for pen pal management within an admin-specific router group.
+// The paths include operations to list, fetch, create, update and delete the pen pals.
+func (pc *PersonaAdminRouteController) PersonaRoute(rg *gin.RouterGroup) {
+	router := rg.Group("personas")
+	router.GET("/", pc.personaController.GetAllPersonas)       // GetAllPersonas handles the retrieval of the pen pals.
+	router.GET("/:id", pc.personaController.GetPersonaDetails) // GetPersonaDetails handles the retrieval of a pen pal by ID.
+	router.POST("/", pc.personaController.CreatePersona)       // CreatePersona handles the creation of a pen pal.
+	router.PUT("/:id", pc.personaController.UpdatePersona)     // UpdatePersona handles the update of a pen pal by ID.
+	router.DELETE("/:id", pc.personaController.DeletePersona)  // DeletePersona handles the removal of a pen pal by ID.
+}
@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/privacy"
+	"github.com/gin-gonic/gin"
+)
+
+// PrivacyAdminRouteController handles the routing of the monitoring of the personal data redaction.
+type PrivacyAdminRouteController struct {
+	privacyController privacy.PrivacyController // privacyController reports the redaction of the personal data.
+}
+
+// NewAdminRoutePrivacyController creates a new instance of PrivacyAdminRouteController using the provided privacyController.
+func NewAdminRoutePrivacyController(privacyController privacy.PrivacyController) PrivacyAdminRouteController {
+	return PrivacyAdminRouteController{privacyController}
+}
+
+// PrivacyRoute defines routes for the monitoring of the personal data redaction within an admin-specific router group.
+func (pc *PrivacyAdminRouteController) PrivacyRoute(rg *gin.RouterGroup) {
+	router := rg.Group("privacy")
+	router.GET("/redactions", pc.privacyController.GetRedactionStats) // GetRedactionStats handles the retrieval of the redaction counts.
+}
@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/prompt"
+	"github.com/gin-gonic/gin"
+)
+
+// PromptAdminRouteController handles the routing of the prompt template administration functions.
+type PromptAdminRouteController struct {
+	promptController prompt.PromptController // promptController manages the prompt templates.
+}
+
+// NewAdminRoutePromptController creates a new instance of PromptAdminRouteController using the provided promptController.
+func NewAdminRoutePromptController(promptController prompt.PromptController) PromptAdminRouteController {
+	return PromptAdminRouteController{promptController}
+}
+
+// PromptRoute defines routes for prompt template management within an admin-specific router group.
+// The paths include operations to write draft versions, publish and roll them out, and preview them for a learner.
+func (pc *PromptAdminRouteController) PromptRoute(rg *gin.RouterGroup) {
+	router := rg.Group("prompts")
+	router.GET("/", pc.promptController.GetPromptTemplates)                // GetPromptTemplates handles the retrieval of the versions.
+	router.GET("/:id", pc.promptController.GetPromptTemplate)              // GetPromptTemplate handles the retrieval of a version by ID.
+	router.POST("/", pc.promptController.CreatePromptTemplate)             // CreatePromptTemplate handles the creation of a draft version.
+	router.PUT("/:id", pc.promptController.UpdatePromptTemplate)           // UpdatePromptTemplate handles the update of a draft version by ID.
+	router.DELETE("/:id", pc.promptController.DeletePromptTemplate)        // DeletePromptTemplate handles the removal of a draft version by ID.
+	router.POST("/:id/publish", pc.promptController.PublishPromptTemplate) // PublishPromptTemplate handles the publication of a draft version.
+	router.PUT("/:id/rollout", pc.promptController.SetPromptRollout)       // SetPromptRollout handles the rollout of a published version.
+	router.POST("/:id/preview", pc.promptController.PreviewPromptTemplate) // PreviewPromptTemplate handles the rendering of a version for a learner.
+}
@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/share"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareAdminRouteController handles the routing of the public links to the conversations within the admin scope.
+type ShareAdminRouteController struct {
+	shareController share.ShareController // shareController manages the public links.
+}
+
+// NewAdminRouteShareController creates a new instance of ShareAdminRouteController using the provided shareController.
+func NewAdminRouteShareController(shareController share.ShareController) ShareAdminRouteController {
+	return ShareAdminRouteController{shareController}
+}
+
+// ShareRoute defines routes for the monitoring of the public links within an admin-specific router group.
+// The paths include operations to list the links and take them down.
+func (sc *ShareAdminRouteController) ShareRoute(rg *gin.RouterGroup) {
+	router := rg.Group("shares")
+	router.GET("/", sc.shareController.GetAllShares)               // GetAllShares handles the retrieval of the public links.
+	router.POST("/:id/takedown", sc.shareController.TakeDownShare) // TakeDownShare handles the takedown of an abusive link.
+}
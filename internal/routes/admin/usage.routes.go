@@ -0,0 +1,22 @@
+package admin
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageAdminRouteController handles the routing of the usage reports.
+type UsageAdminRouteController struct {
+	usageController usage.UsageController // usageController reports the consumption of the users.
+}
+
+// NewAdminRouteUsageController creates a new instance of UsageAdminRouteController using the provided usageController.
+func NewAdminRouteUsageController(usageController usage.UsageController) UsageAdminRouteController {
+	return UsageAdminRouteController{usageController}
+}
+
+// UsageRoute defines the usage report route within an admin-specific router group.
+func (uc *UsageAdminRouteController) UsageRoute(rg *gin.RouterGroup) {
+	router := rg.Group("usage")
+	router.GET("/", uc.usageController.GetUsageReport) // GetUsageReport handles the retrieval of the consumption per user and period.
+}
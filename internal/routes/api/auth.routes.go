@@ -5,26 +5,32 @@ package api
 import (
 	"github.com/enzo-gbd/GBA/internal/controllers/auth"
 	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/services/captcha"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthRouteController holds a reference to an auth.AuthController to handle authentication-related requests.
+// AuthRouteController holds a reference to an auth.AuthController to handle authentication-related requests,
+// and the human verification guarding registration and login.
 type AuthRouteController struct {
-	authController auth.AuthController
+	authController   auth.AuthController
+	registerVerifier captcha.Verifier
+	loginVerifier    captcha.Verifier
+	loginAttempts    *captcha.AttemptTracker
 }
 
-// NewAuthRouteController creates a new instance of AuthRouteController with the provided authController.
+// NewAuthRouteController creates a new instance of AuthRouteController with the provided authController and verifiers.
+// Registration is always verified, while login is only verified once loginAttempts recorded too many failures.
 // It returns an AuthRouteController which can be used to set up authentication routes.
-func NewAuthRouteController(authController auth.AuthController) AuthRouteController {
-	return AuthRouteController{authController}
+func NewAuthRouteController(authController auth.AuthController, registerVerifier captcha.Verifier, loginVerifier captcha.Verifier, loginAttempts *captcha.AttemptTracker) AuthRouteController {
+	return AuthRouteController{authController, registerVerifier, loginVerifier, loginAttempts}
 }
 
 // AuthRoutes sets up the routing for all authentication-related endpoints under the provided RouterGroup.
 // It registers routes for user registration, login, token refresh, and logout.
 func (ac *AuthRouteController) AuthRoutes(rg *gin.RouterGroup) {
 	router := rg.Group("/auth")
-	router.POST("/register", ac.authController.SignUpUser)                              // Registers a new user.
-	router.POST("/login", ac.authController.SignInUser)                                 // Authenticates a user and returns a session token.
-	router.POST("/refresh", ac.authController.RefreshAccessToken)                       // Refreshes an existing session token.
-	router.POST("/logout", middlewares.DeserializeUser(), ac.authController.LogoutUser) // Ends a user's session.
+	router.POST("/register", middlewares.Captcha(ac.registerVerifier), ac.authController.SignUpUser)                     // Registers a new user.
+	router.POST("/login", middlewares.AdaptiveCaptcha(ac.loginVerifier, ac.loginAttempts), ac.authController.SignInUser) // Authenticates a user and returns a session token.
+	router.POST("/refresh", ac.authController.RefreshAccessToken)                                                        // Refreshes an existing session token.
+	router.POST("/logout", middlewares.DeserializeUser(), ac.authController.LogoutUser)                                  // Ends a user's session.
 }
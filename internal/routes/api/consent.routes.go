@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/consent"
+	"github.com/gin-gonic/gin"
+)
+
+// ConsentRouteController holds a reference to a consent.ConsentController to handle parental consent requests.
+type ConsentRouteController struct {
+	consentController consent.ConsentController
+}
+
+// NewConsentRouteController creates a new instance of ConsentRouteController with the provided consentController.
+func NewConsentRouteController(consentController consent.ConsentController) ConsentRouteController {
+	return ConsentRouteController{consentController}
+}
+
+// ConsentRoute sets up the routing for the parental consent endpoints under the provided RouterGroup.
+// The routes are public: parents are authenticated by the signed link they received by email.
+// They must therefore be registered before any route group requiring a logged in user.
+func (cc *ConsentRouteController) ConsentRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/consents")
+	router.GET("/:id", cc.consentController.GetConsent)          // Fetches a consent request.
+	router.POST("/:id/grant", cc.consentController.GrantConsent) // Grants a consent request.
+	router.POST("/:id/deny", cc.consentController.DenyConsent)   // Denies a consent request.
+}
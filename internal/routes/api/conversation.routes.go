@@ -0,0 +1,43 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/conversation"
+	"github.com/gin-gonic/gin"
+)
+
+// ConversationRouteController handles the routing of conversation-related API endpoints.
+type ConversationRouteController struct {
+	conversationController conversation.ConversationController
+}
+
+// NewConversationRouteController creates a new instance of ConversationRouteController
+// using the provided conversationController.
+func NewConversationRouteController(conversationController conversation.ConversationController) ConversationRouteController {
+	return ConversationRouteController{conversationController}
+}
+
+// ConversationRoute configures the conversation routes in the provided RouterGroup, which must
+// already deserialize the current user. Users can only reach their own conversations.
+func (cc *ConversationRouteController) ConversationRoute(rg *gin.RouterGroup) {
+	router := rg.Group("conversations")
+	router.POST("", cc.conversationController.CreateConversation)                  // Starts a conversation.
+	router.GET("", cc.conversationController.GetConversations)                     // Lists the conversations of the current user.
+	router.GET("/export", cc.conversationController.ExportConversations)           // Exports all the conversations of the current user as a zip archive.
+	router.GET("/:id", cc.conversationController.GetConversation)                  // Fetches a conversation.
+	router.PUT("/:id", cc.conversationController.RenameConversation)               // Renames a conversation.
+	router.DELETE("/:id", cc.conversationController.DeleteConversation)            // Deletes a conversation and its messages.
+	router.POST("/:id/archive", cc.conversationController.ArchiveConversation)     // Archives a conversation.
+	router.DELETE("/:id/archive", cc.conversationController.UnarchiveConversation) // Unarchives a conversation.
+	router.GET("/:id/export", cc.conversationController.ExportConversation)        // Exports a conversation as a document.
+
+	router.GET("/:id/messages", cc.conversationController.GetMessages)                                    // Lists the messages of the active branch of a conversation.
+	router.POST("/:id/messages", cc.conversationController.CreateMessage)                                 // Sends a message.
+	router.PUT("/:id/messages/:messageID", cc.conversationController.EditMessage)                         // Sends a new version of a message.
+	router.DELETE("/:id/messages/:messageID", cc.conversationController.DeleteMessage)                    // Deletes a message.
+	router.POST("/:id/messages/:messageID/regenerate", cc.conversationController.RegenerateMessage)       // Generates another reply.
+	router.GET("/:id/messages/:messageID/alternatives", cc.conversationController.GetMessageAlternatives) // Lists the versions of a message.
+	router.POST("/:id/messages/:messageID/activate", cc.conversationController.ActivateMessage)           // Switches to the branch of a message.
+
+	router.GET("/:id/letters", cc.conversationController.GetLetters)                // Lists the letters of a conversation in letters mode.
+	router.DELETE("/:id/letters/:letterID", cc.conversationController.CancelLetter) // Cancels a letter waiting for its delivery.
+}
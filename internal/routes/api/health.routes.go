@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/health"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthRouteController handles the routing of the health check.
+type HealthRouteController struct {
+	healthController health.HealthController
+}
+
+// NewHealthRouteController creates a new instance of HealthRouteController using the provided healthController.
+func NewHealthRouteController(healthController health.HealthController) HealthRouteController {
+	return HealthRouteController{healthController}
+}
+
+// HealthRoute configures the public health check in the provided RouterGroup.
+// It must be registered before the routes deserializing the user.
+func (hc *HealthRouteController) HealthRoute(rg *gin.RouterGroup) {
+	rg.GET("/health", hc.healthController.GetHealth) // Reports the health of the server.
+}
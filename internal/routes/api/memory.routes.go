@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/memory"
+	"github.com/gin-gonic/gin"
+)
+
+// MemoryRouteController handles the routing of the facts remembered about the current user.
+type MemoryRouteController struct {
+	memoryController memory.MemoryController
+}
+
+// NewMemoryRouteController creates a new instance of MemoryRouteController using the provided memoryController.
+func NewMemoryRouteController(memoryController memory.MemoryController) MemoryRouteController {
+	return MemoryRouteController{memoryController}
+}
+
+// MemoryRoute configures the memory routes in the provided RouterGroup, which must already deserialize the current user.
+func (mc *MemoryRouteController) MemoryRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me/memories")
+	router.GET("", mc.memoryController.GetMyMemories)       // Fetches the facts remembered about the current user.
+	router.DELETE("", mc.memoryController.DeleteMyMemories) // Forgets everything about the current user.
+	router.DELETE("/:id", mc.memoryController.DeleteMemory) // Forgets a fact about the current user.
+}
@@ -0,0 +1,45 @@
+// Package api provides the routing functionalities
+// It sets up routes and associates them with their respective handlers.
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/organization"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationRouteController handles the routing of organization-related API endpoints.
+type OrganizationRouteController struct {
+	organizationController organization.OrganizationController
+}
+
+// NewOrganizationRouteController creates a new instance of OrganizationRouteController
+// using the provided organizationController.
+func NewOrganizationRouteController(organizationController organization.OrganizationController) OrganizationRouteController {
+	return OrganizationRouteController{organizationController}
+}
+
+// OrganizationRoute configures the organization routes in the provided RouterGroup, which must
+// already deserialize the current user. Routes below '/organizations/:orgID' are scoped to the
+// organization by the OrganizationScope middleware, restricted to the roles allowed to use them.
+func (oc *OrganizationRouteController) OrganizationRoute(rg *gin.RouterGroup) {
+	owner := middlewares.OrganizationScope(models.OrganizationRoleOwner)
+	manager := middlewares.OrganizationScope(models.OrganizationRoleOwner, models.OrganizationRoleTeacher)
+
+	router := rg.Group("organizations")
+	router.POST("", oc.organizationController.CreateOrganization)                                     // Creates an organization owned by the current user.
+	router.GET("", oc.organizationController.GetMyOrganizations)                                      // Lists the organizations of the current user.
+	router.GET("/:orgID", middlewares.OrganizationScope(), oc.organizationController.GetOrganization) // Fetches an organization.
+	router.PUT("/:orgID", owner, oc.organizationController.UpdateOrganization)                        // Renames an organization.
+	router.DELETE("/:orgID", owner, oc.organizationController.DeleteOrganization)                     // Deletes an organization.
+
+	router.GET("/:orgID/members", manager, oc.organizationController.GetMembers)                            // Lists the members the user can manage.
+	router.PUT("/:orgID/members/:memberID", owner, oc.organizationController.UpdateMemberRole)              // Changes the role of a member.
+	router.DELETE("/:orgID/members/:memberID", manager, oc.organizationController.RemoveMember)             // Removes a member.
+	router.POST("/:orgID/invitations", manager, oc.organizationController.InviteMember)                     // Invites someone by email.
+	router.GET("/:orgID/invitations", manager, oc.organizationController.GetInvitations)                    // Lists the invitations.
+	router.DELETE("/:orgID/invitations/:invitationID", manager, oc.organizationController.RevokeInvitation) // Revokes an invitation.
+
+	rg.POST("invitations/:token/accept", oc.organizationController.AcceptInvitation) // Accepts an invitation.
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/persona"
+	"github.com/gin-gonic/gin"
+)
+
+// PersonaRouteController handles the routing of the pen pal catalogue.
+type PersonaRouteController struct {
+	personaController persona.PersonaController
+}
+
+// NewPersonaRouteController creates a new instance of PersonaRouteController using the provided personaController.
+func NewPersonaRouteController(personaController persona.PersonaController) PersonaRouteController {
+	return PersonaRouteController{personaController}
+}
+
+// PersonaRoute configures the public routes of the pen pal catalogue in the provided RouterGroup.
+// It must be registered before the routes deserializing the user.
+func (pc *PersonaRouteController) PersonaRoute(rg *gin.RouterGroup) {
+	router := rg.Group("personas")
+	router.GET("", pc.personaController.GetPersonas)    // Lists the enabled pen pals.
+	router.GET("/:id", pc.personaController.GetPersona) // Fetches an enabled pen pal.
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/profile"
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileRouteController handles the routing of the learner profile of the current user and their placement tests.
+type ProfileRouteController struct {
+	profileController profile.ProfileController
+}
+
+// NewProfileRouteController creates a new instance of ProfileRouteController using the provided profileController.
+func NewProfileRouteController(profileController profile.ProfileController) ProfileRouteController {
+	return ProfileRouteController{profileController}
+}
+
+// ProfileRoute configures the learner profile routes in the provided RouterGroup, which must already deserialize the
+// current user.
+func (pc *ProfileRouteController) ProfileRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me")
+	router.GET("/profile", pc.profileController.GetProfile)                                   // Fetches the learner profile of the current user.
+	router.PUT("/profile", pc.profileController.UpdateProfile)                                // Changes the native language of the current user.
+	router.PUT("/profile/languages/:language", pc.profileController.PutLearnerLanguage)       // Sets the level and the goals in a language.
+	router.DELETE("/profile/languages/:language", pc.profileController.DeleteLearnerLanguage) // Removes a language from the profile.
+	router.POST("/placement-tests", pc.profileController.StartPlacementTest)                  // Starts a placement test in a language.
+	router.GET("/placement-tests/:id", pc.profileController.GetPlacementTest)                 // Fetches a placement test.
+	router.POST("/placement-tests/:id/answers", pc.profileController.AnswerPlacementTest)     // Grades the answer to the pending question.
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/share"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareRouteController handles the routing of the public links to the conversations.
+type ShareRouteController struct {
+	shareController share.ShareController
+}
+
+// NewShareRouteController creates a new instance of ShareRouteController using the provided shareController.
+func NewShareRouteController(shareController share.ShareController) ShareRouteController {
+	return ShareRouteController{shareController}
+}
+
+// SharedRoute configures the public route opening the shared conversations in the provided RouterGroup.
+// Anyone holding a link can open it, so it must be registered before the routes deserializing the user.
+func (sc *ShareRouteController) SharedRoute(rg *gin.RouterGroup) {
+	router := rg.Group("shared")
+	router.GET("/:token", sc.shareController.GetSharedConversation) // Fetches a shared conversation.
+}
+
+// ShareRoute configures the routes managing the links of the current user in the provided RouterGroup, which must
+// already deserialize the current user.
+func (sc *ShareRouteController) ShareRoute(rg *gin.RouterGroup) {
+	rg.POST("/conversations/:id/shares", sc.shareController.CreateShare) // Shares a conversation.
+	rg.GET("/conversations/:id/shares", sc.shareController.GetShares)    // Lists the links to a conversation.
+	rg.DELETE("/shares/:id", sc.shareController.RevokeShare)             // Revokes a link.
+}
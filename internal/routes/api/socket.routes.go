@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/socket"
+	"github.com/enzo-gbd/GBA/internal/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+// SocketRouteController handles the routing of the WebSocket endpoints.
+type SocketRouteController struct {
+	socketController socket.SocketController
+}
+
+// NewSocketRouteController creates a new instance of SocketRouteController using the provided socketController.
+func NewSocketRouteController(socketController socket.SocketController) SocketRouteController {
+	return SocketRouteController{socketController}
+}
+
+// SocketRoute configures the WebSocket routes in the provided RouterGroup. It must be registered before
+// the routes deserializing the user: the connection is authenticated with a ticket, which is obtained with
+// the normal session.
+func (sc *SocketRouteController) SocketRoute(rg *gin.RouterGroup) {
+	router := rg.Group("ws")
+	router.GET("", sc.socketController.Connect)                                              // Opens a WebSocket connection.
+	router.POST("/tickets", middlewares.DeserializeUser(), sc.socketController.CreateTicket) // Issues a ticket for the current user.
+}
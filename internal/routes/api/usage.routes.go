@@ -0,0 +1,22 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// UsageRouteController handles the routing of the consumption of the current user.
+type UsageRouteController struct {
+	usageController usage.UsageController
+}
+
+// NewUsageRouteController creates a new instance of UsageRouteController using the provided usageController.
+func NewUsageRouteController(usageController usage.UsageController) UsageRouteController {
+	return UsageRouteController{usageController}
+}
+
+// UsageRoute configures the usage routes in the provided RouterGroup, which must already deserialize the current user.
+func (uc *UsageRouteController) UsageRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me")
+	router.GET("/usage", uc.usageController.GetMyUsage) // Fetches the consumption and the quotas of the current user.
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/enzo-gbd/GBA/internal/controllers/vocabulary"
+	"github.com/gin-gonic/gin"
+)
+
+// VocabularyRouteController handles the routing of the vocabulary of the current user and its reviews.
+type VocabularyRouteController struct {
+	vocabularyController vocabulary.VocabularyController
+}
+
+// NewVocabularyRouteController creates a new instance of VocabularyRouteController using the provided vocabularyController.
+func NewVocabularyRouteController(vocabularyController vocabulary.VocabularyController) VocabularyRouteController {
+	return VocabularyRouteController{vocabularyController}
+}
+
+// VocabularyRoute configures the vocabulary routes in the provided RouterGroup, which must already deserialize the
+// current user.
+func (vc *VocabularyRouteController) VocabularyRoute(rg *gin.RouterGroup) {
+	router := rg.Group("me")
+	router.GET("/vocabulary", vc.vocabularyController.GetVocabulary)               // Lists the words of the current user.
+	router.POST("/vocabulary", vc.vocabularyController.CreateVocabularyItem)       // Saves a word.
+	router.POST("/vocabulary/extract", vc.vocabularyController.ExtractVocabulary)  // Saves the words worth learning in a message.
+	router.GET("/vocabulary/stats", vc.vocabularyController.GetVocabularyStats)    // Fetches the progress in each language.
+	router.PUT("/vocabulary/:id", vc.vocabularyController.UpdateVocabularyItem)    // Changes the translation and the example of a word.
+	router.DELETE("/vocabulary/:id", vc.vocabularyController.DeleteVocabularyItem) // Removes a word.
+	router.GET("/reviews", vc.vocabularyController.GetReviews)                     // Lists the words due for review.
+	router.POST("/reviews/:id", vc.vocabularyController.SubmitReview)              // Grades the recall of a word and schedules its next review.
+}
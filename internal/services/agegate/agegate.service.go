@@ -0,0 +1,119 @@
+// Package agegate provides the age rules applied to users: the minimum age to register,
+// the parental consent required for minors, and the features restricted on their accounts.
+package agegate
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidSignature is returned when a consent link was not signed by the server.
+	ErrInvalidSignature = errors.New("the consent link is not valid")
+	// ErrLinkExpired is returned when a consent link is used after its expiry.
+	ErrLinkExpired = errors.New("the consent link has expired")
+	// ErrNoSecret is returned when no key is configured to sign the consent links.
+	ErrNoSecret = errors.New("PARENTAL_CONSENT_SECRET must be set to sign the parental consent links")
+)
+
+// DefaultLinkLifespan is the lifespan of the consent links when none is configured.
+const DefaultLinkLifespan = 7 * 24 * time.Hour
+
+// Policy holds the age thresholds read from the configuration.
+type Policy struct {
+	MinimumAge int // MinimumAge is the age under which users cannot register.
+	ConsentAge int // ConsentAge is the age under which users need the consent of a parent.
+}
+
+// NewPolicy returns the Policy matching the provided configuration.
+func NewPolicy(config *configs.Config) Policy {
+	return Policy{MinimumAge: config.MinimumAge, ConsentAge: config.ParentalConsentAge}
+}
+
+// CheckConfig reports an error if the consent links can't be signed with the provided configuration.
+func CheckConfig(config *configs.Config) error {
+	if config.ParentalConsentSecret == "" {
+		return ErrNoSecret
+	}
+	return nil
+}
+
+// LinkLifespan returns the lifespan of the consent links, DefaultLinkLifespan unless a positive one is configured.
+func LinkLifespan(config *configs.Config) time.Duration {
+	if config.ParentalConsentExpiresIn <= 0 {
+		return DefaultLinkLifespan
+	}
+	return config.ParentalConsentExpiresIn
+}
+
+// Age returns the age in full years of someone born on birthday at the given time.
+func Age(birthday time.Time, now time.Time) int {
+	birthday = birthday.In(now.Location())
+	age := now.Year() - birthday.Year()
+	if now.Month() < birthday.Month() || (now.Month() == birthday.Month() && now.Day() < birthday.Day()) {
+		age--
+	}
+	return age
+}
+
+// IsTooYoung reports whether someone born on birthday is under the minimum age at the given time.
+func (p Policy) IsTooYoung(birthday time.Time, now time.Time) bool {
+	return Age(birthday, now) < p.MinimumAge
+}
+
+// IsMinor reports whether someone born on birthday needs the consent of a parent at the given time.
+func (p Policy) IsMinor(birthday time.Time, now time.Time) bool {
+	return Age(birthday, now) < p.ConsentAge
+}
+
+// Restrictions returns the features restricted on the account of someone born on birthday.
+// Restrictions are lifted automatically once the user reaches the consent age.
+func (p Policy) Restrictions(birthday time.Time, now time.Time) models.AccountRestrictions {
+	minor := p.IsMinor(birthday, now)
+	return models.AccountRestrictions{
+		StrictModeration:      minor,
+		PublicSharingDisabled: minor,
+	}
+}
+
+// Sign returns the signature of a consent link for the given consent request and expiry.
+func Sign(secret string, consentID uuid.UUID, expiresAt time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s.%d", consentID, expiresAt.Unix())))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and the expiry of a consent link.
+// expires is the Unix timestamp found in the link. No link is valid without a secret.
+func Verify(secret string, consentID uuid.UUID, expires string, signature string, now time.Time) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || secret == "" {
+		return ErrInvalidSignature
+	}
+	expected := Sign(secret, consentID, time.Unix(unix, 0))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if !now.Before(time.Unix(unix, 0)) {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// ConsentLink builds the signed link sent to the parent of a minor.
+func ConsentLink(baseURL string, secret string, consentID uuid.UUID, expiresAt time.Time) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", Sign(secret, consentID, expiresAt))
+	return fmt.Sprintf("%s/consents/%s?%s", baseURL, consentID, query.Encode())
+}
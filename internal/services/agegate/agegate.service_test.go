@@ -0,0 +1,107 @@
+package agegate
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAge(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		birthday time.Time
+		expected int
+	}{
+		{"birthday passed this year", time.Date(2010, time.March, 1, 0, 0, 0, 0, time.UTC), 14},
+		{"birthday today", time.Date(2010, time.June, 15, 0, 0, 0, 0, time.UTC), 14},
+		{"birthday later this year", time.Date(2010, time.June, 16, 0, 0, 0, 0, time.UTC), 13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Age(tt.birthday, now))
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{MinimumAge: 8, ConsentAge: 16}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		birthday time.Time
+		tooYoung bool
+		minor    bool
+	}{
+		{"too young", now.AddDate(-6, 0, 0), true, true},
+		{"minor", now.AddDate(-12, 0, 0), false, true},
+		{"adult", now.AddDate(-30, 0, 0), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.tooYoung, policy.IsTooYoung(tt.birthday, now))
+			assert.Equal(t, tt.minor, policy.IsMinor(tt.birthday, now))
+
+			restrictions := policy.Restrictions(tt.birthday, now)
+			assert.Equal(t, tt.minor, restrictions.StrictModeration)
+			assert.Equal(t, tt.minor, restrictions.PublicSharingDisabled)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := "secret"
+	consentID := uuid.New()
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+
+	link, err := url.Parse(ConsentLink("https://localhost", secret, consentID, expiresAt))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(link.Path, consentID.String()))
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+
+	tests := []struct {
+		name      string
+		secret    string
+		consentID uuid.UUID
+		expires   string
+		signature string
+		now       time.Time
+		expected  error
+	}{
+		{"valid link", secret, consentID, expires, signature, now, nil},
+		{"expired link", secret, consentID, expires, signature, now.Add(2 * time.Hour), ErrLinkExpired},
+		{"other consent", secret, uuid.New(), expires, signature, now, ErrInvalidSignature},
+		{"tampered expiry", secret, consentID, "99999999999", signature, now, ErrInvalidSignature},
+		{"malformed expiry", secret, consentID, "tomorrow", signature, now, ErrInvalidSignature},
+		{"other secret", "other", consentID, expires, signature, now, ErrInvalidSignature},
+		{"no secret", "", consentID, strconv.FormatInt(expiresAt.Unix(), 10), Sign("", consentID, expiresAt), now, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Verify(tt.secret, tt.consentID, tt.expires, tt.signature, tt.now))
+		})
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	assert.Equal(t, ErrNoSecret, CheckConfig(&configs.Config{}))
+	assert.NoError(t, CheckConfig(&configs.Config{ParentalConsentSecret: "secret"}))
+}
+
+func TestLinkLifespan(t *testing.T) {
+	assert.Equal(t, DefaultLinkLifespan, LinkLifespan(&configs.Config{}))
+	assert.Equal(t, DefaultLinkLifespan, LinkLifespan(&configs.Config{ParentalConsentExpiresIn: -time.Hour}))
+	assert.Equal(t, time.Hour, LinkLifespan(&configs.Config{ParentalConsentExpiresIn: time.Hour}))
+}
@@ -0,0 +1,126 @@
+package captcha
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptTracker counts the recent failed attempts per key, such as a client IP or an email address,
+// to ask for human verification only once a key looks suspicious.
+// The counters live in memory and are therefore per instance.
+// Expired keys are swept once per window and at most maxTrackedKeys keys are kept, so that a flood
+// of distinct keys can't grow the memory without bound.
+type AttemptTracker struct {
+	threshold int
+	window    time.Duration
+	maxKeys   int
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastSweep time.Time
+}
+
+// maxTrackedKeys is the number of keys above which the key with the oldest failure is evicted.
+const maxTrackedKeys = 100_000
+
+// NewAttemptTracker returns a tracker requiring verification once a key reaches threshold failures within window.
+// A threshold of 0 always requires verification, a negative threshold never does.
+func NewAttemptTracker(threshold int, window time.Duration) *AttemptTracker {
+	return &AttemptTracker{
+		threshold: threshold,
+		window:    window,
+		maxKeys:   maxTrackedKeys,
+		now:       time.Now,
+		failures:  make(map[string][]time.Time),
+	}
+}
+
+// RecordFailure records a failed attempt for every given key.
+func (t *AttemptTracker) RecordFailure(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	for _, key := range keys {
+		if _, tracked := t.failures[key]; !tracked && len(t.failures) >= t.maxKeys {
+			t.evictOldest()
+		}
+		t.failures[key] = append(t.recent(key, now), now)
+	}
+}
+
+// Reset forgets the failed attempts of every given key.
+func (t *AttemptTracker) Reset(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		delete(t.failures, key)
+	}
+}
+
+// RequiresChallenge reports whether any of the given keys reached the threshold.
+func (t *AttemptTracker) RequiresChallenge(keys ...string) bool {
+	if t.threshold < 0 {
+		return false
+	}
+	if t.threshold == 0 {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, key := range keys {
+		recent := t.recent(key, now)
+		if len(recent) == 0 {
+			delete(t.failures, key)
+		} else {
+			t.failures[key] = recent
+		}
+		if len(recent) >= t.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// recent returns the failures of the key that are still within the window. The caller must hold the lock.
+func (t *AttemptTracker) recent(key string, now time.Time) []time.Time {
+	failures := t.failures[key]
+	i := 0
+	for i < len(failures) && now.Sub(failures[i]) >= t.window {
+		i++
+	}
+	return failures[i:]
+}
+
+// sweep forgets the keys whose failures are all outside the window, at most once per window.
+// The caller must hold the lock.
+func (t *AttemptTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.window {
+		return
+	}
+	t.lastSweep = now
+	for key := range t.failures {
+		if len(t.recent(key, now)) == 0 {
+			delete(t.failures, key)
+		}
+	}
+}
+
+// evictOldest forgets the key whose last failure is the oldest. The caller must hold the lock.
+func (t *AttemptTracker) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, failures := range t.failures {
+		last := failures[len(failures)-1]
+		if oldestKey == "" || last.Before(oldest) {
+			oldestKey, oldest = key, last
+		}
+	}
+	delete(t.failures, oldestKey)
+}
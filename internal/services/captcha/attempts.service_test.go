@@ -0,0 +1,75 @@
+package captcha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewAttemptTracker(2, time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	assert.False(t, tracker.RequiresChallenge("ip:1", "email:a"))
+
+	tracker.RecordFailure("ip:1", "email:a")
+	assert.False(t, tracker.RequiresChallenge("ip:1", "email:a"))
+
+	tracker.RecordFailure("ip:1", "email:b")
+	assert.True(t, tracker.RequiresChallenge("ip:1", "email:c"), "the IP address reached the threshold")
+	assert.False(t, tracker.RequiresChallenge("ip:2", "email:a"))
+
+	tracker.Reset("ip:1")
+	assert.False(t, tracker.RequiresChallenge("ip:1", "email:c"))
+
+	tracker.RecordFailure("email:a")
+	assert.True(t, tracker.RequiresChallenge("ip:2", "email:a"), "the email reached the threshold")
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, tracker.RequiresChallenge("ip:2", "email:a"), "failures outside the window are forgotten")
+}
+
+func TestAttemptTracker_Threshold(t *testing.T) {
+	assert.True(t, NewAttemptTracker(0, time.Minute).RequiresChallenge("ip:1"))
+
+	never := NewAttemptTracker(-1, time.Minute)
+	never.RecordFailure("ip:1")
+	assert.False(t, never.RequiresChallenge("ip:1"))
+}
+
+func TestAttemptTracker_Sweep(t *testing.T) {
+	now := time.Now()
+	tracker := NewAttemptTracker(2, time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordFailure("ip:1", "email:a")
+	now = now.Add(30 * time.Second)
+	tracker.RecordFailure("ip:2")
+	assert.Len(t, tracker.failures, 3, "the keys are not swept more than once per window")
+
+	now = now.Add(45 * time.Second)
+	tracker.RecordFailure("ip:3")
+	assert.Len(t, tracker.failures, 2, "the keys never looked up again are swept")
+	assert.Contains(t, tracker.failures, "ip:2")
+	assert.Contains(t, tracker.failures, "ip:3")
+}
+
+func TestAttemptTracker_MaxKeys(t *testing.T) {
+	now := time.Now()
+	tracker := NewAttemptTracker(1, time.Hour)
+	tracker.now = func() time.Time { return now }
+	tracker.maxKeys = 2
+
+	tracker.RecordFailure("email:a")
+	now = now.Add(time.Second)
+	tracker.RecordFailure("email:b")
+	now = now.Add(time.Second)
+	tracker.RecordFailure("email:b", "email:c")
+
+	assert.Len(t, tracker.failures, 2)
+	assert.False(t, tracker.RequiresChallenge("email:a"), "the key with the oldest failure is evicted")
+	assert.True(t, tracker.RequiresChallenge("email:b"))
+	assert.True(t, tracker.RequiresChallenge("email:c"))
+}
@@ -0,0 +1,139 @@
+// Package captcha provides the human verification used to keep scripted clients away from
+// the registration and login endpoints, and the tracking of failed login attempts.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+)
+
+// Supported verification providers.
+const (
+	ProviderHCaptcha  = "hcaptcha"  // ProviderHCaptcha verifies tokens with hCaptcha.
+	ProviderTurnstile = "turnstile" // ProviderTurnstile verifies tokens with Cloudflare Turnstile.
+)
+
+// Verification endpoints of the supported providers.
+const (
+	HCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+var (
+	// ErrMissingToken is returned when a request requiring verification carries no token.
+	ErrMissingToken = errors.New("human verification is required")
+	// ErrRejected is returned when the provider does not accept the token.
+	ErrRejected = errors.New("human verification failed")
+)
+
+// Verifier is implemented by every human verification backend.
+type Verifier interface {
+	// Verify checks the token solved by the client. remoteIP is forwarded to the provider when not empty.
+	Verify(ctx context.Context, token string, remoteIP string) error
+}
+
+// NewVerifier returns the Verifier protecting the given route, e.g. "register" or "login".
+// A NoopVerifier is returned when no provider is configured or the route is not listed in CAPTCHA_ROUTES.
+func NewVerifier(config *configs.Config, route string) Verifier {
+	routes := strings.Split(config.CaptchaRoutes, ",")
+	for i := range routes {
+		routes[i] = strings.TrimSpace(routes[i])
+	}
+	if !slices.Contains(routes, route) {
+		return NoopVerifier{}
+	}
+
+	verifier := HTTPVerifier{
+		URL:    config.CaptchaVerifyURL,
+		Secret: config.CaptchaSecret,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+	switch config.CaptchaProvider {
+	case ProviderHCaptcha:
+		if verifier.URL == "" {
+			verifier.URL = HCaptchaVerifyURL
+		}
+	case ProviderTurnstile:
+		if verifier.URL == "" {
+			verifier.URL = TurnstileVerifyURL
+		}
+	default:
+		return NoopVerifier{}
+	}
+	return verifier
+}
+
+// NoopVerifier accepts every request. It is used when human verification is disabled.
+type NoopVerifier struct{}
+
+// Verify always succeeds.
+func (NoopVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	return nil
+}
+
+// HTTPVerifier verifies tokens against a siteverify endpoint.
+// hCaptcha and Turnstile share the same protocol: the secret, the token and the client IP are posted
+// as a form, and the answer is a JSON document with a success flag.
+type HTTPVerifier struct {
+	URL    string       // URL is the siteverify endpoint of the provider.
+	Secret string       // Secret is the secret key of the site.
+	Client *http.Client // Client is the HTTP client used to reach the provider.
+}
+
+// siteverifyResponse is the answer of a siteverify endpoint.
+type siteverifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify posts the token to the provider and returns ErrRejected if it is not accepted.
+// Any other error means the provider could not be reached or answered unexpectedly.
+func (v HTTPVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.Secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from the verification provider: %d", response.StatusCode)
+	}
+	var result siteverifyResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		return ErrRejected
+	}
+	return nil
+}
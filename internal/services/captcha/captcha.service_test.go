@@ -0,0 +1,107 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStandIn starts a local siteverify endpoint accepting only the "valid" token for the "secret" key.
+func newStandIn(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("response") == "unavailable" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		success := r.PostForm.Get("secret") == "secret" && r.PostForm.Get("response") == "valid"
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": success, "error-codes": []string{}})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   configs.Config
+		route    string
+		expected Verifier
+	}{
+		{
+			name:     "disabled",
+			config:   configs.Config{CaptchaRoutes: "register,login"},
+			route:    "register",
+			expected: NoopVerifier{},
+		},
+		{
+			name:     "route not protected",
+			config:   configs.Config{CaptchaProvider: ProviderHCaptcha, CaptchaRoutes: "register"},
+			route:    "login",
+			expected: NoopVerifier{},
+		},
+		{
+			name:     "hCaptcha",
+			config:   configs.Config{CaptchaProvider: ProviderHCaptcha, CaptchaSecret: "secret", CaptchaRoutes: "register, login"},
+			route:    "login",
+			expected: HTTPVerifier{URL: HCaptchaVerifyURL, Secret: "secret"},
+		},
+		{
+			name:     "Turnstile with a custom endpoint",
+			config:   configs.Config{CaptchaProvider: ProviderTurnstile, CaptchaSecret: "secret", CaptchaVerifyURL: "http://localhost:8080", CaptchaRoutes: "register"},
+			route:    "register",
+			expected: HTTPVerifier{URL: "http://localhost:8080", Secret: "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(&tt.config, tt.route)
+			if httpVerifier, ok := verifier.(HTTPVerifier); ok {
+				assert.NotNil(t, httpVerifier.Client)
+				httpVerifier.Client = nil
+				verifier = httpVerifier
+			}
+			assert.Equal(t, tt.expected, verifier)
+		})
+	}
+}
+
+func TestHTTPVerifier_Verify(t *testing.T) {
+	server := newStandIn(t)
+
+	tests := []struct {
+		name     string
+		secret   string
+		token    string
+		expected error
+	}{
+		{"valid token", "secret", "valid", nil},
+		{"missing token", "secret", "", ErrMissingToken},
+		{"rejected token", "secret", "forged", ErrRejected},
+		{"wrong secret", "other", "valid", ErrRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := HTTPVerifier{URL: server.URL, Secret: tt.secret, Client: server.Client()}
+			assert.Equal(t, tt.expected, verifier.Verify(context.Background(), tt.token, "127.0.0.1"))
+		})
+	}
+
+	t.Run("provider failure", func(t *testing.T) {
+		verifier := HTTPVerifier{URL: server.URL, Secret: "secret", Client: server.Client()}
+		err := verifier.Verify(context.Background(), "unavailable", "")
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrRejected))
+	})
+}
@@ -0,0 +1,647 @@
+// Package chat runs the exchanges between learners and their AI pen pals, for the HTTP, server-sent events and
+// WebSocket endpoints and the letters worker: it builds the prompt from the active branch of the conversation, asks
+// the language model for the reply and the corrections, and stores the screened messages.
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/guard"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/metering"
+	"github.com/enzo-gbd/GBA/internal/services/moderation"
+	"github.com/enzo-gbd/GBA/internal/services/placement"
+	"github.com/enzo-gbd/GBA/internal/services/prompts"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrArchived is returned when a message is sent to an archived conversation.
+	ErrArchived = errors.New("the conversation is archived")
+	// ErrNoReply is returned when the language model can't generate a reply.
+	ErrNoReply = errors.New("the pen pal could not answer, please try again")
+	// ErrPersonaUnavailable is returned when the pen pal of the conversation was removed or disabled.
+	ErrPersonaUnavailable = errors.New("the pen pal is no longer available")
+	// ErrBlocked is returned when the message of the learner is blocked by the moderation.
+	ErrBlocked = errors.New("the message was blocked by the moderation")
+	// ErrRefused is returned when the message of the learner is refused by the injection guard.
+	ErrRefused = errors.New("the message was refused by the injection guard")
+	// ErrNotEditable is returned when a message which isn't written by the learner is edited.
+	ErrNotEditable = errors.New("only the messages of the learner can be edited")
+	// ErrNotRegenerable is returned when a message which isn't a reply of the pen pal is regenerated.
+	ErrNotRegenerable = errors.New("only the replies of the pen pal can be regenerated")
+)
+
+const (
+	summaryTimeout    = 2 * time.Minute // summaryTimeout bounds the generation of the summary of a conversation.
+	extractionTimeout = time.Minute     // extractionTimeout bounds the extraction of the facts shared in a message.
+)
+
+// Options configures the optional behaviours of a Service. The zero value sends the whole history, remembers nothing,
+// corrects nothing, moderates nothing and guards nothing.
+type Options struct {
+	Window      Window                // Window keeps the prompts within the context window of the language model.
+	Memory      Memory                // Memory configures the facts remembered about the learners.
+	Corrections bool                  // Corrections enables the correction of the messages of the learners.
+	Moderator   *moderation.Moderator // Moderator screens the messages of the learners and the replies, nil disables the moderation.
+	Guard       *guard.Guard          // Guard protects the instructions of the pen pals, nil disables the guard.
+	Letters     Letters               // Letters configures the delay of the replies in letters mode.
+}
+
+// Service generates the replies of the pen pals.
+type Service struct {
+	provider llm.Provider
+	meter    *metering.Meter
+	window   Window
+	memory   Memory
+	correct  bool
+	moderate *moderation.Moderator
+	guard    *guard.Guard
+	letters  Letters
+
+	summarizing sync.Map // IDs of the conversations being summarized
+}
+
+// NewService returns a Service generating the replies with the given provider, within the quotas of the meter.
+func NewService(provider llm.Provider, meter *metering.Meter, options Options) *Service {
+	return &Service{
+		provider: provider,
+		meter:    meter,
+		window:   options.Window,
+		memory:   options.Memory,
+		correct:  options.Corrections,
+		moderate: options.Moderator,
+		guard:    options.Guard,
+		letters:  options.Letters,
+	}
+}
+
+// Exchange is a message of the learner waiting for the reply of the pen pal. Nothing is stored until the reply is
+// generated, or until the letter is posted in letters mode.
+type Exchange struct {
+	Conversation models.Conversation // Conversation the message is sent to
+	Persona      models.Persona      // Pen pal of the conversation, the zero value if it has none
+	Learner      models.User         // Learner who wrote the message
+	Message      models.Message      // Message of the learner
+	Request      llm.Request         // Request sent to the language model
+	Overflow     bool                // Whether older messages were left out of the request and should be summarized
+	Screening    moderation.Verdict  // Moderation of the message of the learner
+	Strict       bool                // Whether the strict moderation of the under-age accounts applies
+	Inspection   guard.Verdict       // Inspection of the message of the learner by the injection guard
+	Instructions string              // System prompt of the pen pal, before the facts remembered about the learner
+	Canary       string              // Token hidden in the system prompt to detect its leaks, empty if the guard is disabled
+	Regenerated  bool                // Whether the message of the learner is already stored and only a new reply is generated
+	Letter       *models.Letter      // Letter delivered by the reply, nil outside of letters mode
+}
+
+// FindConversation returns the conversation with the given ID if it belongs to the user.
+// gorm.ErrRecordNotFound is returned otherwise.
+func FindConversation(db *gorm.DB, userID uuid.UUID, conversationID uuid.UUID) (models.Conversation, error) {
+	var conversation models.Conversation
+	err := db.Where("id = ? AND user_id = ?", conversationID, userID).First(&conversation).Error
+	return conversation, err
+}
+
+// Prepare loads the conversation history and builds the prompt answering the content written by the learner.
+// A *metering.QuotaError is returned if the learner has consumed the tokens of their quota.
+// When the conversation has a pen pal, its system prompt, or the version of its prompt template served to the
+// learner, and its model parameters are used. The replies are pitched at the CEFR level of the learner in the
+// language of the conversation. The facts remembered about the learner which are the most relevant to the content
+// are added to the system prompt.
+// The content is moderated first: ErrBlocked is returned, and the content is queued for review, if it is blocked, and
+// the offending words are masked if it is redacted. It is then inspected by the injection guard: ErrRefused is returned,
+// and the content is queued for review, if it is refused, and the offending phrases are stripped if it is sanitized.
+// The message answers the last message of the active branch of the conversation.
+func (s *Service) Prepare(ctx context.Context, db *gorm.DB, user models.User, conversation models.Conversation, content string) (*Exchange, error) {
+	return s.prepare(ctx, db, user, conversation, conversation.ActiveLeafID, content)
+}
+
+// Edit prepares a new version of a message of the learner, as Prepare does: it answers the same message as the
+// edited one, starting a new branch of the conversation. gorm.ErrRecordNotFound is returned if the message doesn't
+// belong to the conversation, and ErrNotEditable if it isn't written by the learner.
+func (s *Service) Edit(ctx context.Context, db *gorm.DB, user models.User, conversation models.Conversation, messageID uuid.UUID, content string) (*Exchange, error) {
+	edited, err := FindMessage(db, conversation.ID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if edited.Role != models.MessageRoleUser {
+		return nil, ErrNotEditable
+	}
+	return s.prepare(ctx, db, user, conversation, edited.ParentID, content)
+}
+
+// Regenerate prepares another reply to the message of the learner answered by the reply, which is kept as an
+// alternative. The message was screened when it was sent, so it is neither moderated, inspected nor corrected again.
+// gorm.ErrRecordNotFound is returned if the reply doesn't belong to the conversation, and ErrNotRegenerable if it
+// isn't written by the pen pal.
+func (s *Service) Regenerate(ctx context.Context, db *gorm.DB, user models.User, conversation models.Conversation, replyID uuid.UUID) (*Exchange, error) {
+	if conversation.ArchivedAt.Valid {
+		return nil, ErrArchived
+	}
+	now := time.Now()
+	if err := s.meter.Check(db, user, now); err != nil {
+		return nil, err
+	}
+	reply, err := FindMessage(db, conversation.ID, replyID)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Role != models.MessageRoleAssistant || !reply.ParentID.Valid {
+		return nil, ErrNotRegenerable
+	}
+	message, err := FindMessage(db, conversation.ID, reply.ParentID.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange, err := s.build(db, user, conversation, message, message.Guard != "")
+	if err != nil {
+		return nil, err
+	}
+	exchange.Strict = s.moderate != nil && s.moderate.IsStrict(user, now)
+	exchange.Regenerated = true
+	return exchange, nil
+}
+
+// prepare screens the content of a message of the learner answering the parent, and builds the prompt answering it.
+func (s *Service) prepare(ctx context.Context, db *gorm.DB, user models.User, conversation models.Conversation, parent uuid.NullUUID, content string) (*Exchange, error) {
+	if conversation.ArchivedAt.Valid {
+		return nil, ErrArchived
+	}
+	now := time.Now()
+	if err := s.meter.Check(db, user, now); err != nil {
+		return nil, err
+	}
+
+	var screening moderation.Verdict
+	strict := false
+	if s.moderate != nil {
+		strict = s.moderate.IsStrict(user, now)
+		screening = s.moderate.Moderate(ctx, content, strict)
+		if screening.Blocked() {
+			record := screening.Record(user.ID, conversation.ID, uuid.NullUUID{}, models.MessageRoleUser)
+			if err := db.Create(&record).Error; err != nil {
+				return nil, err
+			}
+			return nil, ErrBlocked
+		}
+		content = screening.Content
+	}
+
+	var inspection guard.Verdict
+	if s.guard != nil {
+		inspection = s.guard.Inspect(ctx, content)
+		if inspection.Refused() {
+			records := []models.ModerationRecord{inspection.Record(user.ID, conversation.ID, uuid.NullUUID{})}
+			if screening.Moderated() {
+				records = append(records, screening.Record(user.ID, conversation.ID, uuid.NullUUID{}, models.MessageRoleUser))
+			}
+			if err := db.Create(&records).Error; err != nil {
+				return nil, err
+			}
+			return nil, ErrRefused
+		}
+		content = inspection.Content
+	}
+
+	message := models.Message{
+		ConversationID: conversation.ID,
+		ParentID:       parent,
+		Role:           models.MessageRoleUser,
+		Content:        content,
+		Guard:          inspection.Response,
+	}
+	if screening.Moderated() {
+		message.Moderation = screening.Action
+	}
+	exchange, err := s.build(db, user, conversation, message, inspection.Suspicious())
+	if err != nil {
+		return nil, err
+	}
+	exchange.Screening = screening
+	exchange.Strict = strict
+	exchange.Inspection = inspection
+	return exchange, nil
+}
+
+// build builds the prompt answering the message of the learner, following the branch of the conversation leading to
+// the message. suspicious reminds the pen pal to keep its instructions when the injection guard is enabled.
+func (s *Service) build(db *gorm.DB, user models.User, conversation models.Conversation, message models.Message, suspicious bool) (*Exchange, error) {
+	level, err := placement.Level(db, user.ID, conversation.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	request := llm.Request{}
+	systemPrompt := fmt.Sprintf("You are a friendly pen pal writing to a language learner. "+
+		"Always reply in the language with the code %q, in short and natural messages.", conversation.Language)
+	if level != "" {
+		systemPrompt += fmt.Sprintf(" The learner has the level %s of the CEFR: keep your vocabulary and grammar "+
+			"within their reach.", level)
+	}
+	var persona models.Persona
+	if conversation.PersonaID.Valid {
+		if err := db.Where("id = ? AND enabled = ?", conversation.PersonaID.UUID, true).First(&persona).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrPersonaUnavailable
+			}
+			return nil, err
+		}
+		text, required := persona.SystemPrompt, []string(nil)
+		if persona.PromptName != "" {
+			tmpl, err := prompts.Resolve(db, persona, user.ID)
+			if err != nil {
+				if errors.Is(err, prompts.ErrNoPublishedVersion) {
+					return nil, fmt.Errorf("%w: %w", ErrPersonaUnavailable, err)
+				}
+				return nil, err
+			}
+			text, required = tmpl.Body, tmpl.Variables
+		}
+		prompt, err := prompts.Render(text, required, prompts.Data{
+			Persona:     persona,
+			LearnerName: user.FirstName,
+			Language:    conversation.Language,
+			Level:       level,
+		})
+		if err != nil {
+			if errors.Is(err, prompts.ErrMissingVariable) {
+				return nil, fmt.Errorf("%w: %w", ErrPersonaUnavailable, err)
+			}
+			return nil, err
+		}
+		systemPrompt = prompt
+		request.Model = persona.Model
+		request.MaxTokens = persona.MaxTokens
+		if persona.Temperature.Valid {
+			request.Temperature = &persona.Temperature.Float64
+		}
+	}
+
+	var canary string
+	if s.guard != nil {
+		if suspicious {
+			systemPrompt += "\n\n" + guard.Reminder
+		}
+		canary = guard.NewCanary()
+		systemPrompt = guard.WithCanary(systemPrompt, canary)
+	}
+	instructions := systemPrompt
+
+	memories, err := s.recall(db, user, message.Content)
+	if err != nil {
+		return nil, err
+	}
+	systemPrompt = withMemories(systemPrompt, memories)
+
+	history, summary, err := unsummarized(db, conversation, message.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var overflow bool
+	request.Messages, overflow = s.window.Build(systemPrompt, summary, append(history, message))
+	return &Exchange{
+		Conversation: conversation,
+		Persona:      persona,
+		Learner:      user,
+		Message:      message,
+		Request:      request,
+		Overflow:     overflow,
+		Instructions: instructions,
+		Canary:       canary,
+	}, nil
+}
+
+// Reply generates the reply of the pen pal and stores it with the message of the learner and its corrections.
+// A reply blocked by the moderation is replaced by moderation.WithheldReply, and a reply disclosing the instructions
+// of the pen pal by guard.WithheldReply.
+func (s *Service) Reply(ctx context.Context, db *gorm.DB, exchange *Exchange) (models.ExchangeResponse, error) {
+	ctx = redaction.WithAddress(ctx, exchange.Learner.Address.String)
+	corrected := s.correctAsync(ctx, exchange)
+	completion, err := s.provider.Complete(ctx, exchange.Request)
+	if err != nil {
+		s.recordCancelled(ctx, db, exchange, nil, corrected)
+		return models.ExchangeResponse{}, noReply(ctx, err)
+	}
+	return s.save(ctx, db, exchange, completion, s.check(ctx, exchange, completion.Content), <-corrected)
+}
+
+// StreamReply generates the reply of the pen pal, passing each piece of it to onDelta once it is screened, and stores
+// it with the message of the learner and its corrections once it is complete. If ctx is cancelled before, only the
+// consumption of the part of the reply already generated and of the correction of the message is stored. When the moderation is enabled, the pieces are moderated sentence by sentence before they are passed on,
+// and moderation.WithheldReply is passed instead of a blocked sentence and the rest of the reply. When the guard is
+// enabled, the end of the reply is held back until it is checked for leaks, and guard.WithheldReply is passed instead
+// of a leak.
+func (s *Service) StreamReply(ctx context.Context, db *gorm.DB, exchange *Exchange, onDelta llm.DeltaHandler) (models.ExchangeResponse, error) {
+	ctx = redaction.WithAddress(ctx, exchange.Learner.Address.String)
+	corrected := s.correctAsync(ctx, exchange)
+	gate := s.newGate(ctx, exchange, onDelta)
+	completion, err := s.provider.Stream(ctx, exchange.Request, gate.write)
+	if err != nil {
+		s.recordCancelled(ctx, db, exchange, gate.consumed(), corrected)
+		return models.ExchangeResponse{}, noReply(ctx, err)
+	}
+	checked, err := gate.close(completion.Content)
+	if err != nil {
+		s.recordCancelled(ctx, db, exchange, gate.consumed(), corrected)
+		return models.ExchangeResponse{}, noReply(ctx, err)
+	}
+	return s.save(ctx, db, exchange, completion, checked, <-corrected)
+}
+
+// recordCancelled stores in the background, if ctx is cancelled, the consumption of the part of the reply generated
+// before, if any, and of the correction of the message once it completes.
+func (s *Service) recordCancelled(ctx context.Context, db *gorm.DB, exchange *Exchange, partial *llm.Response, corrected <-chan correction) {
+	if ctx.Err() == nil {
+		return
+	}
+	go func() {
+		var records []models.UsageRecord
+		if partial != nil {
+			records = append(records, s.meter.NewRecord(exchange.Conversation, *partial))
+		}
+		if completion := (<-corrected).completion; completion != nil {
+			records = append(records, s.meter.NewRecord(exchange.Conversation, *completion))
+		}
+		if len(records) == 0 {
+			return
+		}
+		if err := db.Create(&records).Error; err != nil {
+			log.Printf("could not record the consumption of the cancelled reply in the conversation %s: %v", exchange.Conversation.ID, err)
+		}
+	}()
+}
+
+// replyCheck is the outcome of the screening of a reply of the pen pal.
+type replyCheck struct {
+	verdict moderation.Verdict // Moderation of the reply, the zero value if the moderation is disabled
+	leaked  bool               // Whether the reply discloses the instructions of the pen pal
+}
+
+// check moderates the reply of the pen pal and checks it for leaks of the instructions.
+func (s *Service) check(ctx context.Context, exchange *Exchange, content string) replyCheck {
+	var checked replyCheck
+	if s.moderate != nil {
+		checked.verdict = s.moderate.Moderate(ctx, content, exchange.Strict)
+	}
+	checked.leaked = s.guard != nil && guard.Leaks(content, exchange.Instructions, exchange.Canary)
+	return checked
+}
+
+// noReply wraps an error of the provider into ErrNoReply, unless the generation was cancelled.
+func noReply(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w: %w", ErrNoReply, err)
+}
+
+// save stores the message of the learner with its corrections, unless it is regenerated, the reply as screened by
+// check, the consumption of the calls and the moderation records of both messages, and makes the reply the end of the
+// active branch of the conversation. The message of a letter is already stored, only its corrections are, and the
+// letter is marked delivered.
+func (s *Service) save(ctx context.Context, db *gorm.DB, exchange *Exchange, completion llm.Response, checked replyCheck, corrected correction) (models.ExchangeResponse, error) {
+	message := exchange.Message
+	if !exchange.Regenerated {
+		message.Corrections = corrected.corrections
+	}
+	reply := models.Message{
+		ConversationID:   exchange.Conversation.ID,
+		Role:             models.MessageRoleAssistant,
+		Content:          completion.Content,
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+	}
+	verdict, leaked := checked.verdict, checked.leaked
+	if verdict.Moderated() {
+		reply.Moderation = verdict.Action
+		reply.Content = verdict.Content
+		if verdict.Blocked() {
+			reply.Content = moderation.WithheldReply
+		}
+	}
+	if leaked {
+		reply.Content = guard.WithheldReply
+		reply.Guard = guard.Leak
+	}
+	record := s.meter.NewRecord(exchange.Conversation, completion)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !exchange.Regenerated && exchange.Letter == nil {
+			if err := tx.Create(&message).Error; err != nil {
+				return err
+			}
+		} else if len(message.Corrections) > 0 {
+			if err := tx.Model(&message).Select("corrections").Updates(&message).Error; err != nil {
+				return err
+			}
+		}
+		reply.ParentID = uuid.NullUUID{UUID: message.ID, Valid: true}
+		if err := tx.Create(&reply).Error; err != nil {
+			return err
+		}
+		if exchange.Screening.Moderated() {
+			moderationRecord := exchange.Screening.Record(exchange.Learner.ID, exchange.Conversation.ID,
+				uuid.NullUUID{UUID: message.ID, Valid: true}, models.MessageRoleUser)
+			if err := tx.Create(&moderationRecord).Error; err != nil {
+				return err
+			}
+		}
+		if verdict.Moderated() {
+			moderationRecord := verdict.Record(exchange.Learner.ID, exchange.Conversation.ID,
+				uuid.NullUUID{UUID: reply.ID, Valid: true}, models.MessageRoleAssistant)
+			if err := tx.Create(&moderationRecord).Error; err != nil {
+				return err
+			}
+		}
+		if exchange.Inspection.Suspicious() {
+			guardRecord := exchange.Inspection.Record(exchange.Learner.ID, exchange.Conversation.ID, uuid.NullUUID{UUID: message.ID, Valid: true})
+			if err := tx.Create(&guardRecord).Error; err != nil {
+				return err
+			}
+		}
+		if leaked {
+			leakRecord := guard.LeakRecord(exchange.Learner.ID, exchange.Conversation.ID, reply.ID, completion.Content)
+			if err := tx.Create(&leakRecord).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if corrected.completion != nil {
+			correctionRecord := s.meter.NewRecord(exchange.Conversation, *corrected.completion)
+			if err := tx.Create(&correctionRecord).Error; err != nil {
+				return err
+			}
+		}
+		if exchange.Letter != nil {
+			// The letter is only delivered by the attempt still holding it, not by one whose lease expired meanwhile.
+			result := tx.Model(&models.Letter{}).
+				Where("id = ? AND status = ? AND attempts = ?", exchange.Letter.ID, models.LetterStatusSending, exchange.Letter.Attempts).
+				Updates(map[string]interface{}{
+					"status":       models.LetterStatusDelivered,
+					"reply_id":     reply.ID,
+					"delivered_at": reply.CreatedAt,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrLeaseLost
+			}
+		}
+		conversation := tx.Model(&exchange.Conversation)
+		if exchange.Letter != nil {
+			// The learner may have moved to another branch while the letter waited for its delivery, the active branch
+			// only follows the reply if it still ends with the letter or with an earlier reply to it.
+			conversation = conversation.Where("active_leaf_id = ? OR active_leaf_id IN (?)", exchange.Letter.MessageID,
+				tx.Model(&models.Message{}).Select("id").Where("parent_id = ?", exchange.Letter.MessageID))
+		}
+		return conversation.Updates(map[string]interface{}{
+			"active_leaf_id": reply.ID,
+			"updated_at":     reply.CreatedAt,
+		}).Error
+	})
+	if err != nil {
+		return models.ExchangeResponse{}, err
+	}
+	if exchange.Overflow {
+		s.summarizeLater(db, exchange.Learner, exchange.Conversation.ID)
+	}
+	if s.memory.Enabled() && !exchange.Regenerated {
+		s.extractLater(db, exchange.Learner, exchange.Conversation, message)
+	}
+	return models.ExchangeResponse{Message: message.ToResponse(), Reply: reply.ToResponse()}, nil
+}
+
+// Summarize folds the older messages of the conversation of the learner into its summary, keeping out of it the most
+// recent messages filling half of the context window.
+func (s *Service) Summarize(ctx context.Context, db *gorm.DB, learner models.User, conversationID uuid.UUID) error {
+	ctx = redaction.WithAddress(ctx, learner.Address.String)
+	var conversation models.Conversation
+	if err := db.Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+		return err
+	}
+	history, summary, err := unsummarized(db, conversation, conversation.ActiveLeafID)
+	if err != nil {
+		return err
+	}
+	kept := Window{Budget: s.window.Budget / 2}.fit(0, history)
+	if kept == 0 {
+		return nil
+	}
+	folded := history[:kept]
+
+	completion, err := s.provider.Complete(ctx, llm.Request{
+		MaxTokens: s.window.SummaryTokens,
+		Messages:  buildSummaryPrompt(summary, folded),
+	})
+	if err != nil {
+		return err
+	}
+
+	record := s.meter.NewRecord(conversation, completion)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&conversation).UpdateColumns(map[string]interface{}{
+			"summary":          completion.Content,
+			"summarized_id":    folded[len(folded)-1].ID,
+			"summarized_until": folded[len(folded)-1].CreatedAt,
+		}).Error
+	})
+}
+
+// summarizeLater summarizes the conversation in the background. A conversation is only summarized once at a time.
+func (s *Service) summarizeLater(db *gorm.DB, learner models.User, conversationID uuid.UUID) {
+	if _, running := s.summarizing.LoadOrStore(conversationID, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer s.summarizing.Delete(conversationID)
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		defer cancel()
+		if err := s.Summarize(ctx, db, learner, conversationID); err != nil {
+			log.Printf("could not summarize the conversation %s: %v", conversationID, err)
+		}
+	}()
+}
+
+// extractLater extracts the facts shared in the message of the learner in the background.
+func (s *Service) extractLater(db *gorm.DB, learner models.User, conversation models.Conversation, message models.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), extractionTimeout)
+		defer cancel()
+		if _, err := s.ExtractMemories(ctx, db, learner, conversation, message); err != nil {
+			log.Printf("could not extract the facts of the message %s: %v", message.ID, err)
+		}
+	}()
+}
+
+// unsummarized returns the messages of the branch of the conversation leading to the leaf that are not folded into
+// its summary, oldest first, and the summary of the branch. The summary only applies to the branches going through
+// the last message it folds: the other branches are returned whole, without summary, until they are summarized in
+// turn. All the messages are returned for the conversations without an active branch, whose messages predate the
+// branches.
+func unsummarized(db *gorm.DB, conversation models.Conversation, leaf uuid.NullUUID) ([]models.Message, string, error) {
+	var history []models.Message
+	if err := db.Where("conversation_id = ?", conversation.ID).Order("created_at").Find(&history).Error; err != nil {
+		return nil, "", err
+	}
+	if !conversation.ActiveLeafID.Valid {
+		if conversation.SummarizedUntil.Valid {
+			history = slices.DeleteFunc(history, func(message models.Message) bool {
+				return !message.CreatedAt.After(conversation.SummarizedUntil.Time)
+			})
+		}
+		return history, conversation.Summary, nil
+	}
+	if !leaf.Valid {
+		return nil, "", nil
+	}
+	path := Path(history, leaf.UUID)
+	if conversation.SummarizedID.Valid {
+		for i, message := range path {
+			if message.ID == conversation.SummarizedID.UUID {
+				return path[i+1:], conversation.Summary, nil
+			}
+		}
+	}
+	return path, "", nil
+}
+
+// buildSummaryPrompt returns the messages asking the language model to fold messages into the summary of a conversation.
+func buildSummaryPrompt(summary string, messages []models.Message) []llm.Message {
+	var letters strings.Builder
+	if summary != "" {
+		letters.WriteString("Summary so far:\n" + summary + "\n\n")
+	}
+	letters.WriteString("Messages to add to the summary:\n")
+	for _, message := range messages {
+		author := "Pen pal"
+		if message.Role == models.MessageRoleUser {
+			author = "Learner"
+		}
+		letters.WriteString(author + ": " + message.Content + "\n")
+	}
+
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: "You summarize the correspondence between a language learner and their pen pal, " +
+			"so that the pen pal can keep writing without reading the older messages. Keep what the learner told about " +
+			"themselves, the topics discussed, the questions left open and the mistakes the learner often makes. " +
+			"Write a few short sentences in English."},
+		{Role: llm.RoleUser, Content: letters.String()},
+	}
+}
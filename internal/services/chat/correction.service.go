@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"context"
+	"log"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/corrections"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+)
+
+// correction is the outcome of the correction of a message of the learner.
+type correction struct {
+	corrections []models.Correction // Mistakes found in the message
+	completion  *llm.Response       // Answer of the language model, nil if it was not called or failed
+}
+
+// correctAsync corrects the message of the learner while the reply of the pen pal is generated. A failed correction
+// is logged and leaves the message without corrections, it never prevents the reply. A regenerated message keeps its
+// corrections. The correction isn't cancelled with ctx, so that its consumption is recorded even if the reply is.
+func (s *Service) correctAsync(ctx context.Context, exchange *Exchange) <-chan correction {
+	ctx = context.WithoutCancel(ctx)
+	result := make(chan correction, 1)
+	if !s.correct || exchange.Regenerated {
+		result <- correction{}
+		return result
+	}
+
+	go func() {
+		request := corrections.NewRequest(exchange.Message.Content, exchange.Conversation.Language, exchange.Learner.NativeLanguage)
+		completion, err := s.provider.Complete(ctx, request)
+		if err != nil {
+			log.Printf("could not correct the message of the conversation %s: %v", exchange.Conversation.ID, err)
+			result <- correction{}
+			return
+		}
+		found, err := corrections.Parse(completion.Content, exchange.Message.Content)
+		if err != nil {
+			log.Printf("could not correct the message of the conversation %s: %v", exchange.Conversation.ID, err)
+		}
+		result <- correction{corrections: found, completion: &completion}
+	}()
+	return result
+}
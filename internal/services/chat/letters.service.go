@@ -0,0 +1,185 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrLetterPending is returned when a letter is sent to a conversation still waiting for the reply to the previous one.
+	ErrLetterPending = errors.New("the pen pal has not answered the previous letter yet")
+	// ErrNotCancellable is returned when a letter which isn't waiting for its delivery time is cancelled.
+	ErrNotCancellable = errors.New("only the letters waiting for their delivery can be cancelled")
+	// ErrLeaseLost is returned when a letter is answered after it was claimed again, or cancelled, once the lease of
+	// its delivery expired. Nothing is stored.
+	ErrLeaseLost = errors.New("the letter is no longer held by this delivery")
+)
+
+// Letters configures the delay of the replies of the pen pals in the conversations in letters mode.
+type Letters struct {
+	MinDelay time.Duration // MinDelay is the shortest delay of a reply when the pen pal has no delay window.
+	MaxDelay time.Duration // MaxDelay is the longest delay of a reply when the pen pal has no delay window.
+}
+
+// delay returns a random delay within the letter delay window of the pen pal, or within the default one if it has none.
+func (l Letters) delay(persona models.Persona) time.Duration {
+	minimum, maximum := l.MinDelay, l.MaxDelay
+	if persona.LetterDelayMin != 0 || persona.LetterDelayMax != 0 {
+		minimum = time.Duration(persona.LetterDelayMin) * time.Minute
+		maximum = time.Duration(persona.LetterDelayMax) * time.Minute
+	}
+	if maximum <= minimum {
+		return minimum
+	}
+	return minimum + time.Duration(rand.Int64N(int64(maximum-minimum)+1))
+}
+
+// Post stores the message of the learner and schedules the reply of the pen pal instead of generating it, for the
+// conversations in letters mode. The reply is generated by Deliver once the delivery time of the letter is reached.
+// ErrLetterPending is returned if the conversation is still waiting for the reply to a previous letter.
+func (s *Service) Post(db *gorm.DB, exchange *Exchange) (models.LetterScheduledResponse, error) {
+	message := exchange.Message
+	letter := models.Letter{
+		ConversationID: exchange.Conversation.ID,
+		UserID:         exchange.Learner.ID,
+		Regenerated:    exchange.Regenerated,
+		Status:         models.LetterStatusPending,
+		DeliverAt:      time.Now().Add(s.letters.delay(exchange.Persona)),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The conversation is locked so that two letters posted at once can't both find no letter pending.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("id = ?", exchange.Conversation.ID).Take(&models.Conversation{}).Error; err != nil {
+			return err
+		}
+		var pending int64
+		if err := tx.Model(&models.Letter{}).
+			Where("conversation_id = ? AND status IN ?", exchange.Conversation.ID, []string{models.LetterStatusPending, models.LetterStatusSending}).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrLetterPending
+		}
+
+		if !exchange.Regenerated {
+			if err := tx.Create(&message).Error; err != nil {
+				return err
+			}
+		}
+		if exchange.Screening.Moderated() {
+			moderationRecord := exchange.Screening.Record(exchange.Learner.ID, exchange.Conversation.ID,
+				uuid.NullUUID{UUID: message.ID, Valid: true}, models.MessageRoleUser)
+			if err := tx.Create(&moderationRecord).Error; err != nil {
+				return err
+			}
+		}
+		if exchange.Inspection.Suspicious() {
+			guardRecord := exchange.Inspection.Record(exchange.Learner.ID, exchange.Conversation.ID, uuid.NullUUID{UUID: message.ID, Valid: true})
+			if err := tx.Create(&guardRecord).Error; err != nil {
+				return err
+			}
+		}
+		letter.MessageID = message.ID
+		if err := tx.Create(&letter).Error; err != nil {
+			return err
+		}
+		if exchange.Regenerated {
+			return nil
+		}
+		return tx.Model(&exchange.Conversation).Updates(map[string]interface{}{
+			"active_leaf_id": message.ID,
+			"updated_at":     message.CreatedAt,
+		}).Error
+	})
+	if err != nil {
+		return models.LetterScheduledResponse{}, err
+	}
+	return models.LetterScheduledResponse{Message: message.ToResponse(), Letter: letter.ToResponse()}, nil
+}
+
+// Deliver generates the reply of the pen pal to the letter, as Reply does, and marks the letter delivered with it.
+// The message of the learner was screened when it was posted, so it is neither moderated nor inspected again.
+func (s *Service) Deliver(ctx context.Context, db *gorm.DB, letter models.Letter) (*Exchange, models.ExchangeResponse, error) {
+	var conversation models.Conversation
+	if err := db.Where("id = ?", letter.ConversationID).First(&conversation).Error; err != nil {
+		return nil, models.ExchangeResponse{}, err
+	}
+	var learner models.User
+	if err := db.Where("id = ?", letter.UserID).First(&learner).Error; err != nil {
+		return nil, models.ExchangeResponse{}, err
+	}
+	message, err := FindMessage(db, conversation.ID, letter.MessageID)
+	if err != nil {
+		return nil, models.ExchangeResponse{}, err
+	}
+
+	exchange, err := s.build(db, learner, conversation, message, message.Guard != "")
+	if err != nil {
+		return nil, models.ExchangeResponse{}, err
+	}
+	exchange.Strict = s.moderate != nil && s.moderate.IsStrict(learner, time.Now())
+	exchange.Regenerated = letter.Regenerated
+	exchange.Letter = &letter
+	response, err := s.Reply(ctx, db, exchange)
+	return exchange, response, err
+}
+
+// CancelLetter cancels the letter of the conversation waiting for its delivery time. The message of the learner is
+// withdrawn with it, unless the letter regenerates a reply, and the active branch goes back to the message it answered.
+// gorm.ErrRecordNotFound is returned if the letter doesn't belong to the conversation, and ErrNotCancellable if it
+// isn't pending anymore.
+func (s *Service) CancelLetter(db *gorm.DB, conversation models.Conversation, letterID uuid.UUID) (models.Letter, error) {
+	var letter models.Letter
+	if err := db.Where("id = ? AND conversation_id = ?", letterID, conversation.ID).First(&letter).Error; err != nil {
+		return letter, err
+	}
+	if letter.Status != models.LetterStatusPending {
+		return letter, ErrNotCancellable
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Letter{}).
+			Where("id = ? AND status = ?", letter.ID, models.LetterStatusPending).
+			Updates(map[string]interface{}{"status": models.LetterStatusCancelled, "cancelled_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotCancellable
+		}
+		if letter.Regenerated {
+			return nil
+		}
+		message, err := FindMessage(tx, conversation.ID, letter.MessageID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.VocabularyItem{}).Where("message_id = ?", message.ID).UpdateColumn("message_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&models.ModerationRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&message).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Conversation{}).
+			Where("id = ? AND active_leaf_id = ?", conversation.ID, message.ID).
+			UpdateColumn("active_leaf_id", message.ParentID).Error
+	})
+	if err != nil {
+		return letter, err
+	}
+	letter.Status = models.LetterStatusCancelled
+	letter.CancelledAt.Time, letter.CancelledAt.Valid = now, true
+	return letter, nil
+}
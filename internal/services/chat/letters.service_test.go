@@ -0,0 +1,248 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const queryLetter = `SELECT * FROM "letters" WHERE id = $1 AND conversation_id = $2 ORDER BY "letters"."id" LIMIT $3`
+
+func TestLetters_Delay(t *testing.T) {
+	letters := Letters{MinDelay: time.Hour, MaxDelay: 3 * time.Hour}
+
+	for range 20 {
+		delay := letters.delay(models.Persona{})
+		assert.GreaterOrEqual(t, delay, time.Hour)
+		assert.LessOrEqual(t, delay, 3*time.Hour)
+
+		delay = letters.delay(models.Persona{LetterDelayMin: 10, LetterDelayMax: 20})
+		assert.GreaterOrEqual(t, delay, 10*time.Minute)
+		assert.LessOrEqual(t, delay, 20*time.Minute)
+	}
+	assert.Equal(t, 15*time.Minute, letters.delay(models.Persona{LetterDelayMin: 15, LetterDelayMax: 15}))
+	assert.Equal(t, time.Duration(0), Letters{}.delay(models.Persona{}))
+}
+
+func TestService_Post(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	conversation := models.Conversation{ID: uuid.New(), Mode: models.ConversationModeLetters}
+	exchange := &Exchange{
+		Conversation: conversation,
+		Learner:      models.User{ID: uuid.New()},
+		Message:      models.Message{ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Querida Lucía"},
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "conversations" WHERE id = $1 LIMIT $2 FOR UPDATE`)).
+		WithArgs(conversation.ID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(conversation.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "letters" WHERE conversation_id = $1 AND status IN ($2,$3)`)).
+		WithArgs(conversation.ID, models.LetterStatusPending, models.LetterStatusSending).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "messages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "letters"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1,"updated_at"=$2 WHERE "id" = $3`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	service := NewService(llm.NewFakeProvider(), unmetered, Options{Letters: Letters{MinDelay: time.Hour, MaxDelay: 2 * time.Hour}})
+
+	before := time.Now()
+	response, err := service.Post(database, exchange)
+	assert.NoError(t, err)
+	assert.Equal(t, "Querida Lucía", response.Message.Content)
+	assert.Equal(t, response.Message.ID, response.Letter.MessageID)
+	assert.Equal(t, models.LetterStatusPending, response.Letter.Status)
+	assert.True(t, response.Letter.DeliverAt.After(before.Add(time.Hour-time.Second)))
+	assert.True(t, response.Letter.DeliverAt.Before(time.Now().Add(2*time.Hour+time.Second)))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "conversations"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(conversation.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "letters"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+	_, err = service.Post(database, exchange)
+	assert.ErrorIs(t, err, ErrLetterPending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Deliver(t *testing.T) {
+	conversation := models.Conversation{ID: uuid.New(), Language: "es", Mode: models.ConversationModeLetters}
+	learner := models.User{ID: uuid.New(), NativeLanguage: "fr"}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Él tengo un perro"}
+	conversation.ActiveLeafID = uuid.NullUUID{UUID: message.ID, Valid: true}
+	letter := models.Letter{ID: uuid.New(), ConversationID: conversation.ID, UserID: learner.ID, MessageID: message.ID, Status: models.LetterStatusSending, Attempts: 2}
+
+	tests := []struct {
+		name          string
+		letterUpdated int64
+		expectedError error
+	}{
+		{name: "Delivered", letterUpdated: 1},
+		{name: "Claimed again meanwhile", letterUpdated: 0, expectedError: ErrLeaseLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "conversations" WHERE id = $1`)).
+				WithArgs(conversation.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation}))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+				WithArgs(learner.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.User{learner}))
+			mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).
+				WithArgs(message.ID, conversation.ID, 1).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+			expectLevel(mock, "")
+			mock.ExpectQuery(regexp.QuoteMeta(queryHistory)).
+				WithArgs(conversation.ID).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "messages" SET "corrections"=$1 WHERE "id" = $2`)).
+				WithArgs(sqlmock.AnyArg(), message.ID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "messages"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters" SET "delivered_at"=$1,"reply_id"=$2,"status"=$3 WHERE id = $4 AND status = $5 AND attempts = $6`)).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), models.LetterStatusDelivered, letter.ID, models.LetterStatusSending, 2).
+				WillReturnResult(sqlmock.NewResult(0, tt.letterUpdated))
+			if tt.expectedError != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1,"updated_at"=$2 `+
+					`WHERE (active_leaf_id = $3 OR active_leaf_id IN (SELECT "id" FROM "messages" WHERE parent_id = $4)) AND "id" = $5`)).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), message.ID, message.ID, conversation.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+			provider := correctingProvider{FakeProvider: llm.NewFakeProvider(), corrections: `[{"start": 3, "end": 8, ` +
+				`"original": "tengo", "suggestion": "tiene", "category": "grammar", "explanation": "Troisième personne."}]`}
+
+			exchange, response, err := NewService(provider, unmetered, Options{Corrections: true}).Deliver(context.Background(), database, letter)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, letter.ID, exchange.Letter.ID)
+			assert.Equal(t, message.ID, response.Message.ID)
+			assert.Len(t, response.Message.Corrections, 1)
+			assert.Equal(t, message.ID, *response.Reply.ParentID)
+			assert.Equal(t, "You said: Él tengo un perro", response.Reply.Content)
+		})
+	}
+}
+
+func TestService_CancelLetter(t *testing.T) {
+	conversation := models.Conversation{ID: uuid.New()}
+	parentID := uuid.New()
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, ParentID: uuid.NullUUID{UUID: parentID, Valid: true},
+		Role: models.MessageRoleUser, Content: "Querida Lucía"}
+	pending := models.Letter{ID: uuid.New(), ConversationID: conversation.ID, MessageID: message.ID, Status: models.LetterStatusPending}
+
+	tests := []struct {
+		name          string
+		letter        models.Letter
+		setupMock     func(mock sqlmock.Sqlmock)
+		expectedError error
+	}{
+		{
+			name:   "Pending letter",
+			letter: pending,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters" SET "cancelled_at"=$1,"status"=$2 WHERE id = $3 AND status = $4`)).
+					WithArgs(sqlmock.AnyArg(), models.LetterStatusCancelled, pending.ID, models.LetterStatusPending).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(queryMessage)).
+					WithArgs(message.ID, conversation.ID, 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{message}))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "vocabulary_items" SET "message_id"=$1 WHERE message_id = $2`)).
+					WithArgs(nil, message.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "moderation_records" WHERE message_id = $1`)).
+					WithArgs(message.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "messages" WHERE "messages"."id" = $1`)).
+					WithArgs(message.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1 WHERE id = $2 AND active_leaf_id = $3`)).
+					WithArgs(parentID, conversation.ID, message.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "Regenerated letter",
+			letter: models.Letter{ID: uuid.New(), ConversationID: conversation.ID, MessageID: message.ID, Regenerated: true, Status: models.LetterStatusPending},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:          "Letter being delivered",
+			letter:        models.Letter{ID: uuid.New(), ConversationID: conversation.ID, MessageID: message.ID, Status: models.LetterStatusSending},
+			setupMock:     func(mock sqlmock.Sqlmock) {},
+			expectedError: ErrNotCancellable,
+		},
+		{
+			name:   "Letter claimed meanwhile",
+			letter: pending,
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedError: ErrNotCancellable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			mock.ExpectQuery(regexp.QuoteMeta(queryLetter)).
+				WithArgs(tt.letter.ID, conversation.ID, 1).
+				WillReturnRows(letterRows(tt.letter))
+			tt.setupMock(mock)
+
+			letter, err := NewService(llm.NewFakeProvider(), unmetered, Options{}).CancelLetter(database, conversation, tt.letter.ID)
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError == nil {
+				assert.Equal(t, models.LetterStatusCancelled, letter.Status)
+				assert.True(t, letter.CancelledAt.Valid)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(queryLetter)).WillReturnError(gorm.ErrRecordNotFound)
+	_, err := NewService(llm.NewFakeProvider(), unmetered, Options{}).CancelLetter(database, conversation, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+// letterRows returns the rows of the letter, whose nullable columns the generic conversion doesn't handle.
+func letterRows(letter models.Letter) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "conversation_id", "user_id", "message_id", "regenerated", "status", "deliver_at", "attempts", "claimed_at"}).
+		AddRow(letter.ID, letter.ConversationID, letter.UserID, letter.MessageID, letter.Regenerated, letter.Status, letter.DeliverAt, letter.Attempts, sql.NullTime{})
+}
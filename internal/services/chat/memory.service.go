@@ -0,0 +1,192 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"gorm.io/gorm"
+)
+
+const (
+	memoryCandidates = 200 // memoryCandidates is the number of the most recent facts of a learner considered for a prompt.
+	memoryMaxFacts   = 5   // memoryMaxFacts is the maximum number of facts extracted from a message.
+	memoryMaxLength  = 255 // memoryMaxLength is the maximum length of a fact, in characters.
+	memoryTokens     = 200 // memoryTokens is the maximum length of the extraction, in tokens.
+)
+
+// errNoFacts is returned when the language model doesn't answer the extraction with a JSON array.
+var errNoFacts = errors.New("the extraction is not a JSON array of facts")
+
+// Memory configures the facts the pen pals remember about the learners across their conversations.
+type Memory struct {
+	Recall int // Recall is the number of facts added to the prompt, 0 disables the memory.
+}
+
+// Enabled reports whether facts are extracted from the messages and recalled in the prompts.
+func (m Memory) Enabled() bool {
+	return m.Recall > 0
+}
+
+// recall returns the facts about the user which are the most relevant to the content, most recent first among
+// the equally relevant ones.
+func (s *Service) recall(db *gorm.DB, user models.User, content string) ([]models.Memory, error) {
+	if !s.memory.Enabled() {
+		return nil, nil
+	}
+	memories, err := knownFacts(db, user)
+	if err != nil {
+		return nil, err
+	}
+
+	words := wordSet(content)
+	scores := make(map[int]int, len(memories))
+	for i, memory := range memories {
+		for word := range wordSet(memory.Fact) {
+			if words[word] {
+				scores[i]++
+			}
+		}
+	}
+	indexes := make([]int, len(memories))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool { return scores[indexes[a]] > scores[indexes[b]] })
+
+	recalled := make([]models.Memory, 0, min(len(memories), s.memory.Recall))
+	for _, i := range indexes[:cap(recalled)] {
+		recalled = append(recalled, memories[i])
+	}
+	return recalled, nil
+}
+
+// ExtractMemories asks the language model for the lasting facts the learner shared about themselves in the message,
+// and stores the ones the pen pals don't know yet.
+func (s *Service) ExtractMemories(ctx context.Context, db *gorm.DB, learner models.User, conversation models.Conversation, message models.Message) ([]models.Memory, error) {
+	ctx = redaction.WithAddress(ctx, learner.Address.String)
+	known, err := knownFacts(db, learner)
+	if err != nil {
+		return nil, err
+	}
+
+	completion, err := s.provider.Complete(ctx, llm.Request{
+		MaxTokens: memoryTokens,
+		Messages:  buildExtractionPrompt(known, message.Content),
+	})
+	if err != nil {
+		return nil, err
+	}
+	facts, err := parseFacts(completion.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(known)+len(facts))
+	for _, memory := range known {
+		seen[strings.ToLower(memory.Fact)] = true
+	}
+	var memories []models.Memory
+	for _, fact := range facts {
+		fact = strings.TrimSpace(fact)
+		if runes := []rune(fact); len(runes) > memoryMaxLength {
+			fact = string(runes[:memoryMaxLength])
+		}
+		if fact == "" || seen[strings.ToLower(fact)] || len(memories) == memoryMaxFacts {
+			continue
+		}
+		seen[strings.ToLower(fact)] = true
+		memories = append(memories, models.Memory{
+			UserID:         conversation.UserID,
+			ConversationID: conversation.ID,
+			MessageID:      message.ID,
+			Fact:           fact,
+		})
+	}
+
+	record := s.meter.NewRecord(conversation, completion)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		if len(memories) == 0 {
+			return nil
+		}
+		return tx.Create(&memories).Error
+	})
+	return memories, err
+}
+
+// knownFacts returns the most recent facts about the user.
+func knownFacts(db *gorm.DB, user models.User) ([]models.Memory, error) {
+	var memories []models.Memory
+	err := db.Where("user_id = ?", user.ID).Order("created_at DESC").Limit(memoryCandidates).Find(&memories).Error
+	return memories, err
+}
+
+// withMemories appends the facts remembered about the learner to the system prompt.
+func withMemories(systemPrompt string, memories []models.Memory) string {
+	if len(memories) == 0 {
+		return systemPrompt
+	}
+	var prompt strings.Builder
+	prompt.WriteString(systemPrompt + "\n\nWhat the learner told you in your earlier exchanges:")
+	for _, memory := range memories {
+		prompt.WriteString("\n- " + memory.Fact)
+	}
+	return prompt.String()
+}
+
+// buildExtractionPrompt returns the messages asking the language model for the facts shared in a message.
+func buildExtractionPrompt(known []models.Memory, content string) []llm.Message {
+	var letter strings.Builder
+	if len(known) > 0 {
+		letter.WriteString("Known facts:\n")
+		for _, memory := range known {
+			letter.WriteString("- " + memory.Fact + "\n")
+		}
+		letter.WriteString("\n")
+	}
+	letter.WriteString("Message:\n" + content)
+
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: "You read the messages a language learner writes to their pen pal and note the " +
+			"lasting facts they share about themselves, such as their hobbies, city, job, family or pets. Ignore the " +
+			"facts which are already known, the passing moods and the exercises. Answer only with a JSON array of short " +
+			"sentences in English about the learner, such as [\"Lives in Lyon.\", \"Has a cat named Tom.\"], or [] when " +
+			"the message shares no new fact."},
+		{Role: llm.RoleUser, Content: letter.String()},
+	}
+}
+
+// parseFacts reads the JSON array of facts answered by the language model, ignoring the text around it.
+func parseFacts(content string) ([]string, error) {
+	start, end := strings.Index(content, "["), strings.LastIndex(content, "]")
+	if start < 0 || end < start {
+		return nil, errNoFacts
+	}
+	var facts []string
+	if err := json.Unmarshal([]byte(content[start:end+1]), &facts); err != nil {
+		return nil, errors.Join(errNoFacts, err)
+	}
+	return facts, nil
+}
+
+// wordSet returns the lowercase words of the text, ignoring the words of less than 3 letters.
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 {
+			words[word] = true
+		}
+	}
+	return words
+}
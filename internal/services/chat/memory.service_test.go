@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/redaction"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const queryKnownFacts = `SELECT * FROM "memories" WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+func TestParseFacts(t *testing.T) {
+	facts, err := parseFacts(`Here are the facts: ["Lives in Lyon.", "Has a cat named Tom."]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Lives in Lyon.", "Has a cat named Tom."}, facts)
+
+	facts, err = parseFacts("[]")
+	assert.NoError(t, err)
+	assert.Empty(t, facts)
+
+	_, err = parseFacts("The learner lives in Lyon.")
+	assert.ErrorIs(t, err, errNoFacts)
+	_, err = parseFacts(`["Lives in Lyon.", 3]`)
+	assert.ErrorIs(t, err, errNoFacts)
+}
+
+func TestService_PrepareMemories(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	expectLevel(mock, "")
+	user := models.User{ID: uuid.New(), FirstName: "John"}
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es"}
+	memories := []models.Memory{
+		{ID: uuid.New(), UserID: user.ID, Fact: "Works as a nurse."},
+		{ID: uuid.New(), UserID: user.ID, Fact: "Lives in Lyon."},
+		{ID: uuid.New(), UserID: user.ID, Fact: "Has a cat named Tom."},
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(queryKnownFacts)).
+		WithArgs(user.ID, memoryCandidates).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(memories))
+	mock.ExpectQuery(regexp.QuoteMeta(queryHistory)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{}))
+
+	exchange, err := NewService(llm.NewFakeProvider(), unmetered, Options{Memory: Memory{Recall: 2}}).Prepare(context.Background(), database, user, conversation, "Mi cat Tom está enfermo")
+	assert.NoError(t, err)
+	assert.Contains(t, exchange.Request.Messages[0].Content,
+		"\n\nWhat the learner told you in your earlier exchanges:\n- Has a cat named Tom.\n- Works as a nurse.")
+	assert.NotContains(t, exchange.Request.Messages[0].Content, "Lyon")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_ExtractMemories(t *testing.T) {
+	conversation := models.Conversation{ID: uuid.New(), UserID: uuid.New()}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Vivo en Lyon con mi gato Tom"}
+	known := []models.Memory{{ID: uuid.New(), UserID: conversation.UserID, Fact: "Lives in Lyon.", CreatedAt: time.Now()}}
+
+	tests := []struct {
+		name     string
+		reply    string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "New facts",
+			reply:    `["lives in Lyon.", "Has a cat named Tom.", " "]`,
+			expected: []string{"Has a cat named Tom."},
+		},
+		{
+			name:  "No new fact",
+			reply: `[]`,
+		},
+		{
+			name:  "Invalid extraction",
+			reply: "The learner has a cat.",
+			err:   errNoFacts,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			mock.ExpectQuery(regexp.QuoteMeta(queryKnownFacts)).
+				WithArgs(conversation.UserID, memoryCandidates).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows(known))
+			if test.err == nil {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				if len(test.expected) > 0 {
+					mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "memories"`)).
+						WithArgs(sqlmock.AnyArg(), conversation.UserID, conversation.ID, message.ID, "Has a cat named Tom.", sqlmock.AnyArg()).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			fake := &llm.FakeProvider{Reply: test.reply}
+			memories, err := NewService(fake, unmetered, Options{Memory: Memory{Recall: 10}}).ExtractMemories(context.Background(), database, models.User{ID: conversation.UserID}, conversation, message)
+			assert.ErrorIs(t, err, test.err)
+			var facts []string
+			for _, memory := range memories {
+				facts = append(facts, memory.Fact)
+			}
+			assert.Equal(t, test.expected, facts)
+			assert.Contains(t, fake.Requests()[0].Messages[1].Content, "Known facts:\n- Lives in Lyon.\n\nMessage:\nVivo en Lyon")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestService_ExtractMemoriesRedactsAddress(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	learner := models.User{ID: uuid.New(), Address: sql.NullString{String: "Calle Mayor 5", Valid: true}}
+	conversation := models.Conversation{ID: uuid.New(), UserID: learner.ID}
+	message := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Vivo en calle mayor 5"}
+	mock.ExpectQuery(regexp.QuoteMeta(queryKnownFacts)).
+		WithArgs(learner.ID, memoryCandidates).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Memory{}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "usage_records"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	redactor, err := redaction.NewRedactor([]string{redaction.CategoryAddress}, nil)
+	assert.NoError(t, err)
+	fake := &llm.FakeProvider{Reply: `[]`}
+
+	_, err = NewService(redaction.NewProvider(fake, redactor), unmetered, Options{Memory: Memory{Recall: 10}}).
+		ExtractMemories(context.Background(), database, learner, conversation, message)
+	assert.NoError(t, err)
+	assert.Contains(t, fake.Requests()[0].Messages[1].Content, "Message:\nVivo en [ADDRESS_1]")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
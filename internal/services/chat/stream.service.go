@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/enzo-gbd/GBA/internal/services/guard"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/moderation"
+)
+
+// sentenceEnds are the characters ending the sentences moderated one by one while a reply is streamed.
+const sentenceEnds = ".!?;\n"
+
+// streamGate passes the pieces of a streamed reply on to the learner once they are screened, so that nothing the
+// moderation blocks nor any leak of the instructions of the pen pal reaches them. Without moderation nor guard, the
+// pieces are passed on as they come. With moderation, they are held back until a sentence is complete, and each
+// sentence is moderated before it is passed on, masked when it is redacted. With the guard, the last guard.LeakWindow
+// runes are held back, and the whole reply received so far is checked for leaks before anything is passed on. Once a
+// sentence is blocked or a leak found, moderation.WithheldReply or guard.WithheldReply is passed instead and the
+// rest of the reply is dropped.
+type streamGate struct {
+	ctx      context.Context
+	service  *Service
+	exchange *Exchange
+	onDelta  llm.DeltaHandler
+
+	received strings.Builder     // Content streamed by the language model so far
+	sent     int                 // Length of the received content already passed on, in bytes
+	blocked  *moderation.Verdict // Moderation of the first blocked sentence, nil while none is
+	leaked   bool                // Whether the reply received so far discloses the instructions of the pen pal
+}
+
+// newGate returns the gate screening the reply to the exchange before passing it on to onDelta.
+func (s *Service) newGate(ctx context.Context, exchange *Exchange, onDelta llm.DeltaHandler) *streamGate {
+	return &streamGate{ctx: ctx, service: s, exchange: exchange, onDelta: onDelta}
+}
+
+// write receives a piece of the reply and passes on the sentences it completes.
+func (g *streamGate) write(delta string) error {
+	if g.blocked != nil || g.leaked {
+		return nil
+	}
+	g.received.WriteString(delta)
+	content := g.received.String()
+	end := len(content)
+	if g.service.guard != nil {
+		if guard.Leaks(content, g.exchange.Instructions, g.exchange.Canary) {
+			g.leaked = true
+			return g.onDelta(guard.WithheldReply)
+		}
+		end = max(heldBack(content), g.sent)
+	}
+	if g.service.moderate != nil {
+		end = g.sent + strings.LastIndexAny(content[g.sent:end], sentenceEnds) + 1
+	}
+	if end <= g.sent {
+		return nil
+	}
+
+	piece := content[g.sent:end]
+	if g.service.moderate != nil {
+		verdict := g.service.moderate.Moderate(g.ctx, piece, g.exchange.Strict)
+		if verdict.Blocked() {
+			g.blocked = &verdict
+			return g.onDelta(moderation.WithheldReply)
+		}
+		piece = verdict.Content
+	}
+	g.sent = end
+	return g.onDelta(piece)
+}
+
+// close screens the complete reply and passes on the part still held back. A reply with a sentence blocked, or a
+// leak found, while it was streamed stays so, even if the complete reply isn't.
+func (g *streamGate) close(content string) (replyCheck, error) {
+	checked := g.service.check(g.ctx, g.exchange, content)
+	checked.leaked = checked.leaked || g.leaked
+	if g.blocked != nil && !checked.verdict.Blocked() {
+		checked.verdict = *g.blocked
+		checked.verdict.Original, checked.verdict.Content = content, content
+	}
+	if g.blocked != nil || g.leaked {
+		return checked, nil
+	}
+	if checked.leaked {
+		return checked, g.onDelta(guard.WithheldReply)
+	}
+	if checked.verdict.Blocked() {
+		return checked, g.onDelta(moderation.WithheldReply)
+	}
+
+	// The masking of the moderation keeps the runes in place, so the part held back starts at the same rune.
+	rest := []rune(content)
+	if checked.verdict.Moderated() {
+		rest = []rune(checked.verdict.Content)
+	}
+	rest = rest[min(utf8.RuneCountInString(content[:min(g.sent, len(content))]), len(rest)):]
+	if len(rest) == 0 {
+		return checked, nil
+	}
+	return checked, g.onDelta(string(rest))
+}
+
+// consumed returns the consumption of the reply cancelled while it was streamed, nil if none of it was generated. The
+// providers only report it at the end of the stream, so it is estimated from the request and the content received.
+func (g *streamGate) consumed() *llm.Response {
+	if g.received.Len() == 0 {
+		return nil
+	}
+	var prompt int
+	for _, message := range g.exchange.Request.Messages {
+		prompt += CountTokens(message.Content)
+	}
+	completion := CountTokens(g.received.String())
+	return &llm.Response{
+		Model:   g.exchange.Request.Model,
+		Content: g.received.String(),
+		Usage:   llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+	}
+}
+
+// heldBack returns the length of the content that can be passed on once the guard checked it, in bytes: the last
+// guard.LeakWindow runes are held back, so that a passage of the instructions is complete enough to be detected before
+// its start is passed on.
+func heldBack(content string) int {
+	end := len(content)
+	for range guard.LeakWindow {
+		if end == 0 {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(content[:end])
+		end -= size
+	}
+	return end
+}
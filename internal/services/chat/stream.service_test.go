@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/enzo-gbd/GBA/internal/services/guard"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/services/moderation"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestModerator returns a moderator masking the profanities and blocking the sexual content.
+func newTestModerator() *moderation.Moderator {
+	return moderation.NewModerator(agegate.Policy{ConsentAge: 16},
+		moderation.Policy{models.ModerationCategoryProfanity: models.ModerationActionRedact, models.ModerationCategorySexual: models.ModerationActionBlock},
+		moderation.Policy{}, moderation.NewRulesClassifier(moderation.DefaultRules()))
+}
+
+func TestStreamGate(t *testing.T) {
+	injectionGuard, err := guard.NewGuard(0.6, guard.ResponseFlag, nil)
+	assert.NoError(t, err)
+	instructions := "You are a friendly pen pal writing to a language learner. Always reply in Spanish."
+	canary := "CANARY-0123456789abcdef"
+	letter := "Querida Ana, hoy fui al mercado con mi hermana y compramos fruta. Sigo aprendiendo, "
+
+	tests := []struct {
+		name           string
+		options        Options
+		exchange       Exchange
+		reply          string
+		expectedDeltas []string
+		expectedAction string
+		expectedLeak   bool
+	}{
+		{
+			name:           "Passed on as it comes without moderation",
+			reply:          "¡Hola! ¿Qué tal?",
+			expectedDeltas: []string{"¡Hola! ", "¿Qué ", "tal?"},
+		},
+		{
+			name:           "Passed on sentence by sentence",
+			options:        Options{Moderator: newTestModerator()},
+			reply:          "¡Hola! ¿Qué tal?",
+			expectedDeltas: []string{"¡Hola!", " ¿Qué tal?"},
+		},
+		{
+			name:           "Redacted",
+			options:        Options{Moderator: newTestModerator()},
+			reply:          "¡Hola! What a shit day",
+			expectedDeltas: []string{"¡Hola!", " What a **** day"},
+			expectedAction: models.ModerationActionRedact,
+		},
+		{
+			name:           "Blocked",
+			options:        Options{Moderator: newTestModerator()},
+			reply:          "¡Hola! Look at this porn. ¿Qué tal?",
+			expectedDeltas: []string{"¡Hola!", moderation.WithheldReply},
+			expectedAction: models.ModerationActionBlock,
+		},
+		{
+			name:           "Blocked once complete",
+			options:        Options{Moderator: newTestModerator()},
+			reply:          "¡Hola! Look at this porn",
+			expectedDeltas: []string{"¡Hola!", moderation.WithheldReply},
+			expectedAction: models.ModerationActionBlock,
+		},
+		{
+			name:     "Held back for the guard",
+			options:  Options{Guard: injectionGuard},
+			exchange: Exchange{Instructions: instructions, Canary: canary},
+			reply:    letter + "¿Qué tal?",
+		},
+		{
+			name:         "Instructions leaked",
+			options:      Options{Guard: injectionGuard},
+			exchange:     Exchange{Instructions: instructions, Canary: canary},
+			reply:        letter + instructions,
+			expectedLeak: true,
+		},
+		{
+			name:         "Canary leaked",
+			options:      Options{Guard: injectionGuard},
+			exchange:     Exchange{Instructions: instructions, Canary: canary},
+			reply:        letter + "Mi marcador es " + canary + " y nada más.",
+			expectedLeak: true,
+		},
+		{
+			name:         "Leaked once complete",
+			options:      Options{Guard: injectionGuard},
+			exchange:     Exchange{Instructions: instructions, Canary: canary},
+			reply:        canary,
+			expectedLeak: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deltas []string
+			gate := NewService(llm.NewFakeProvider(), unmetered, tt.options).newGate(context.Background(), &tt.exchange,
+				func(delta string) error {
+					deltas = append(deltas, delta)
+					return nil
+				})
+			for _, delta := range strings.SplitAfter(tt.reply, " ") {
+				assert.NoError(t, gate.write(delta))
+			}
+			checked, err := gate.close(tt.reply)
+			assert.NoError(t, err)
+			text := strings.Join(deltas, "")
+			switch {
+			case tt.expectedLeak:
+				assert.True(t, strings.HasSuffix(text, guard.WithheldReply), "the leak is replaced")
+				assert.NotContains(t, text, "CANARY")
+				assert.NotContains(t, text, "pen pal")
+			case tt.expectedDeltas != nil:
+				assert.Equal(t, tt.expectedDeltas, deltas)
+			default:
+				assert.Equal(t, tt.reply, text)
+				assert.Greater(t, len(deltas), 1, "the reply is still streamed")
+			}
+			assert.Equal(t, tt.expectedAction, checked.verdict.Action)
+			assert.Equal(t, tt.expectedLeak, checked.leaked)
+			if tt.options.Moderator != nil {
+				assert.Equal(t, tt.reply, checked.verdict.Original)
+			}
+		})
+	}
+}
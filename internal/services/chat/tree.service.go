@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"slices"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FindMessage returns the message with the given ID if it belongs to the conversation.
+// gorm.ErrRecordNotFound is returned otherwise.
+func FindMessage(db *gorm.DB, conversationID uuid.UUID, messageID uuid.UUID) (models.Message, error) {
+	var message models.Message
+	err := db.Where("id = ? AND conversation_id = ?", messageID, conversationID).First(&message).Error
+	return message, err
+}
+
+// Path returns the messages leading to the leaf, oldest first. The path stops at the first message whose parent
+// isn't among the messages, and is empty if the leaf isn't among them.
+func Path(messages []models.Message, leafID uuid.UUID) []models.Message {
+	byID := make(map[uuid.UUID]models.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+	var path []models.Message
+	for message, found := byID[leafID]; found && len(path) < len(messages); message, found = byID[message.ParentID.UUID] {
+		path = append(path, message)
+		if !message.ParentID.Valid {
+			break
+		}
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// Leaf returns the last message of the branch going through the message, following the most recent answer at each
+// step. The messages must be sorted oldest first.
+func Leaf(messages []models.Message, messageID uuid.UUID) uuid.UUID {
+	latest := make(map[uuid.UUID]uuid.UUID, len(messages))
+	for _, message := range messages {
+		if message.ParentID.Valid {
+			latest[message.ParentID.UUID] = message.ID
+		}
+	}
+	leaf := messageID
+	for depth := 0; depth < len(messages); depth++ {
+		next, found := latest[leaf]
+		if !found {
+			break
+		}
+		leaf = next
+	}
+	return leaf
+}
+
+// Branch returns the IDs of the messages of the active branch of the conversation, oldest first.
+func Branch(db *gorm.DB, conversation models.Conversation) ([]uuid.UUID, error) {
+	messages, err := tree(db, conversation.ID)
+	if err != nil || !conversation.ActiveLeafID.Valid {
+		return nil, err
+	}
+	path := Path(messages, conversation.ActiveLeafID.UUID)
+	ids := make([]uuid.UUID, 0, len(path))
+	for _, message := range path {
+		ids = append(ids, message.ID)
+	}
+	return ids, nil
+}
+
+// Alternatives returns the versions of the message, which are the messages of the same author answering the same
+// message, oldest first, with the index of the version on the active branch of the conversation, -1 if none.
+// gorm.ErrRecordNotFound is returned if the message doesn't belong to the conversation.
+func Alternatives(db *gorm.DB, conversation models.Conversation, messageID uuid.UUID) ([]models.Message, int, error) {
+	messages, err := tree(db, conversation.ID)
+	if err != nil {
+		return nil, -1, err
+	}
+	index := slices.IndexFunc(messages, func(m models.Message) bool { return m.ID == messageID })
+	if index < 0 {
+		return nil, -1, gorm.ErrRecordNotFound
+	}
+	message := messages[index]
+
+	var active []models.Message
+	if conversation.ActiveLeafID.Valid {
+		active = Path(messages, conversation.ActiveLeafID.UUID)
+	}
+	var alternatives []models.Message
+	current := -1
+	for _, candidate := range messages {
+		if candidate.Role != message.Role || candidate.ParentID != message.ParentID {
+			continue
+		}
+		if slices.ContainsFunc(active, func(m models.Message) bool { return m.ID == candidate.ID }) {
+			current = len(alternatives)
+		}
+		alternatives = append(alternatives, candidate)
+	}
+	return alternatives, current, nil
+}
+
+// SwitchBranch makes the branch going through the message the active branch of the conversation, down to its most
+// recent answers. gorm.ErrRecordNotFound is returned if the message doesn't belong to the conversation.
+func SwitchBranch(db *gorm.DB, conversation models.Conversation, messageID uuid.UUID) (models.Conversation, error) {
+	messages, err := tree(db, conversation.ID)
+	if err != nil {
+		return conversation, err
+	}
+	if !slices.ContainsFunc(messages, func(m models.Message) bool { return m.ID == messageID }) {
+		return conversation, gorm.ErrRecordNotFound
+	}
+	leaf := uuid.NullUUID{UUID: Leaf(messages, messageID), Valid: true}
+	if err := db.Model(&conversation).UpdateColumn("active_leaf_id", leaf).Error; err != nil {
+		return conversation, err
+	}
+	conversation.ActiveLeafID = leaf
+	return conversation, nil
+}
+
+// tree returns the messages of the conversation, oldest first.
+func tree(db *gorm.DB, conversationID uuid.UUID) ([]models.Message, error) {
+	var messages []models.Message
+	err := db.Where("conversation_id = ?", conversationID).Order("created_at").Find(&messages).Error
+	return messages, err
+}
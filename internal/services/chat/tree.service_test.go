@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const queryTree = `SELECT * FROM "messages" WHERE conversation_id = $1 ORDER BY created_at`
+
+// branchedConversation returns a conversation whose first message was edited and whose reply to the edit was
+// regenerated, the active branch ending with the first reply to the edit:
+//
+//	hola ─ reply
+//	buenas ─┬─ first (active)
+//	        └─ second
+func branchedConversation() (models.Conversation, []models.Message) {
+	conversation := models.Conversation{ID: uuid.New(), Language: "es"}
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	message := func(parent *models.Message, role string, content string, minutes int) models.Message {
+		m := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Role: role, Content: content, CreatedAt: start.Add(time.Duration(minutes) * time.Minute)}
+		if parent != nil {
+			m.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		}
+		return m
+	}
+	hola := message(nil, models.MessageRoleUser, "Hola", 0)
+	reply := message(&hola, models.MessageRoleAssistant, "¡Hola!", 1)
+	buenas := message(nil, models.MessageRoleUser, "Buenas", 2)
+	first := message(&buenas, models.MessageRoleAssistant, "¡Buenas!", 3)
+	second := message(&buenas, models.MessageRoleAssistant, "¿Qué tal?", 4)
+	conversation.ActiveLeafID = uuid.NullUUID{UUID: first.ID, Valid: true}
+	return conversation, []models.Message{hola, reply, buenas, first, second}
+}
+
+func TestPath(t *testing.T) {
+	_, messages := branchedConversation()
+
+	assert.Equal(t, []models.Message{messages[2], messages[3]}, Path(messages, messages[3].ID))
+	assert.Equal(t, []models.Message{messages[0], messages[1]}, Path(messages, messages[1].ID))
+	assert.Equal(t, []models.Message{messages[3]}, Path(messages[3:], messages[3].ID))
+	assert.Empty(t, Path(messages, uuid.New()))
+}
+
+func TestLeaf(t *testing.T) {
+	_, messages := branchedConversation()
+
+	assert.Equal(t, messages[4].ID, Leaf(messages, messages[2].ID))
+	assert.Equal(t, messages[1].ID, Leaf(messages, messages[0].ID))
+	assert.Equal(t, messages[3].ID, Leaf(messages, messages[3].ID))
+}
+
+func TestBranch(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	conversation, messages := branchedConversation()
+	mock.ExpectQuery(regexp.QuoteMeta(queryTree)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+
+	branch, err := Branch(database, conversation)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{messages[2].ID, messages[3].ID}, branch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAlternatives(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	conversation, messages := branchedConversation()
+	for range 3 {
+		mock.ExpectQuery(regexp.QuoteMeta(queryTree)).
+			WithArgs(conversation.ID).
+			WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+	}
+
+	alternatives, active, err := Alternatives(database, conversation, messages[4].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{messages[3], messages[4]}, alternatives)
+	assert.Equal(t, 0, active)
+
+	alternatives, active, err = Alternatives(database, conversation, messages[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{messages[0], messages[2]}, alternatives)
+	assert.Equal(t, 1, active)
+
+	_, _, err = Alternatives(database, conversation, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSwitchBranch(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	conversation, messages := branchedConversation()
+	mock.ExpectQuery(regexp.QuoteMeta(queryTree)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "conversations" SET "active_leaf_id"=$1 WHERE "id" = $2`)).
+		WithArgs(messages[1].ID, conversation.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(queryTree)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+
+	switched, err := SwitchBranch(database, conversation, messages[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, messages[1].ID, switched.ActiveLeafID.UUID)
+
+	_, err = SwitchBranch(database, conversation, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"unicode/utf8"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+)
+
+// messageOverhead is the number of tokens the chat format adds to each message.
+const messageOverhead = 4
+
+// Window keeps the prompt of a conversation within a token budget.
+type Window struct {
+	Budget        int // Budget is the number of tokens of the prompt, 0 is unlimited.
+	SummaryTokens int // SummaryTokens is the maximum length of the summary of the older messages, in tokens.
+}
+
+// CountTokens estimates the number of tokens of a message. Language models count about 4 characters per token.
+func CountTokens(content string) int {
+	return (utf8.RuneCountInString(content)+3)/4 + messageOverhead
+}
+
+// Build returns the messages sent to the language model: the system prompt, followed by the summary of the older
+// messages if any, and by as many of the most recent messages as the budget allows. The last message is always sent.
+// overflow reports whether messages were left out, in which case they should be summarized.
+func (w Window) Build(systemPrompt string, summary string, history []models.Message) (messages []llm.Message, overflow bool) {
+	if summary != "" {
+		systemPrompt += "\n\nSummary of your earlier exchanges with the learner:\n" + summary
+	}
+
+	first := w.fit(CountTokens(systemPrompt), history)
+	messages = make([]llm.Message, 0, len(history)-first+1)
+	messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: systemPrompt})
+	for _, message := range history[first:] {
+		messages = append(messages, llm.Message{Role: message.Role, Content: message.Content})
+	}
+	return messages, first > 0
+}
+
+// fit returns the index of the oldest message of the history fitting in the budget after the given tokens.
+func (w Window) fit(used int, history []models.Message) int {
+	if w.Budget <= 0 {
+		return 0
+	}
+	first := len(history)
+	for first > 0 {
+		used += CountTokens(history[first-1].Content)
+		if used > w.Budget && first < len(history) {
+			break
+		}
+		first--
+	}
+	return first
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountTokens(t *testing.T) {
+	assert.Equal(t, messageOverhead, CountTokens(""))
+	assert.Equal(t, 1+messageOverhead, CountTokens("Hola"))
+	assert.Equal(t, 3+messageOverhead, CountTokens("¿Qué tal?"))
+}
+
+func TestWindow_Build(t *testing.T) {
+	letter := strings.Repeat("a", 36) // 14 tokens with the overhead, the system prompt has 6
+	history := []models.Message{
+		{Role: models.MessageRoleUser, Content: letter + "1"},
+		{Role: models.MessageRoleAssistant, Content: letter + "2"},
+		{Role: models.MessageRoleUser, Content: letter + "3"},
+	}
+
+	tests := []struct {
+		name     string
+		window   Window
+		summary  string
+		history  []models.Message
+		system   string
+		contents []string
+		overflow bool
+	}{
+		{
+			name:     "Unlimited",
+			window:   Window{},
+			history:  history,
+			system:   "Prompt",
+			contents: []string{letter + "1", letter + "2", letter + "3"},
+		},
+		{
+			name:     "Within the budget",
+			window:   Window{Budget: 48},
+			history:  history,
+			system:   "Prompt",
+			contents: []string{letter + "1", letter + "2", letter + "3"},
+		},
+		{
+			name:     "Overflow",
+			window:   Window{Budget: 47},
+			history:  history,
+			system:   "Prompt",
+			contents: []string{letter + "2", letter + "3"},
+			overflow: true,
+		},
+		{
+			name:     "Last message over the budget",
+			window:   Window{Budget: 10},
+			history:  history,
+			system:   "Prompt",
+			contents: []string{letter + "3"},
+			overflow: true,
+		},
+		{
+			name:     "Summary",
+			window:   Window{Budget: 100},
+			summary:  "John likes football.",
+			history:  history[2:],
+			system:   "Prompt\n\nSummary of your earlier exchanges with the learner:\nJohn likes football.",
+			contents: []string{letter + "3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, overflow := test.window.Build("Prompt", test.summary, test.history)
+			assert.Equal(t, test.overflow, overflow)
+			assert.Equal(t, llm.Message{Role: llm.RoleSystem, Content: test.system}, messages[0])
+			var contents []string
+			for _, message := range messages[1:] {
+				contents = append(contents, message.Content)
+			}
+			assert.Equal(t, test.contents, contents)
+		})
+	}
+}
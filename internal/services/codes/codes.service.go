@@ -0,0 +1,127 @@
+// Package codes issues and redeems the subscription codes that can be typed at sign-up.
+package codes
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// alphabet excludes characters that are easily confused when read aloud or typed (0/O, 1/I/L).
+const alphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const (
+	groupCount = 3 // groupCount is the number of dash separated groups of a code.
+	groupSize  = 4 // groupSize is the number of characters of each group.
+)
+
+var (
+	// ErrInvalidCode is returned when the code does not exist.
+	ErrInvalidCode = errors.New("invalid code")
+	// ErrCodeExpired is returned when the code is past its expiry.
+	ErrCodeExpired = errors.New("this code has expired")
+	// ErrCodeExhausted is returned when the code reached its maximum number of redemptions.
+	ErrCodeExhausted = errors.New("this code has already been used")
+)
+
+// Normalize returns the canonical form of a code as typed by a user.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// GenerateCode returns a new random code such as "ABCD-EFGH-JKMN".
+func GenerateCode() (string, error) {
+	groups := make([]string, 0, groupCount)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < groupCount; i++ {
+		group := make([]byte, groupSize)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("could not generate code: %w", err)
+			}
+			group[j] = alphabet[n.Int64()]
+		}
+		groups = append(groups, string(group))
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// GenerateBatch creates a batch of codes sharing the same plan, redemption limit and expiry.
+func GenerateBatch(database *gorm.DB, input models.CodeBatchInput, createdByID uuid.UUID) ([]models.SubscriptionCode, error) {
+	batchID := uuid.New()
+	codes := make([]models.SubscriptionCode, 0, input.Count)
+	for i := 0; i < input.Count; i++ {
+		code, err := GenerateCode()
+		if err != nil {
+			return nil, err
+		}
+		subscriptionCode := models.SubscriptionCode{
+			Code:           code,
+			BatchID:        batchID,
+			Plan:           input.Plan,
+			MaxRedemptions: input.MaxRedemptions,
+			CreatedByID:    createdByID,
+		}
+		if input.ExpiresAt != nil {
+			subscriptionCode.ExpiresAt.Time = *input.ExpiresAt
+			subscriptionCode.ExpiresAt.Valid = true
+		}
+		codes = append(codes, subscriptionCode)
+	}
+
+	if err := database.Create(&codes).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Redeem redeems a code for a user. It must be called within the transaction creating the user so
+// a failed redemption also cancels the registration. The redemption counter is incremented with a
+// conditional update, which guarantees a code is never redeemed more than its limit even when
+// several users redeem it concurrently.
+func Redeem(tx *gorm.DB, code string, userID uuid.UUID, now time.Time) (*models.SubscriptionCode, error) {
+	var subscriptionCode models.SubscriptionCode
+	if err := tx.Where("code = ?", Normalize(code)).First(&subscriptionCode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+	if subscriptionCode.IsExpired(now) {
+		return nil, ErrCodeExpired
+	}
+
+	result := tx.Model(&models.SubscriptionCode{}).
+		Where("id = ? AND redemption_count < max_redemptions", subscriptionCode.ID).
+		UpdateColumn("redemption_count", gorm.Expr("redemption_count + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCodeExhausted
+	}
+	subscriptionCode.RedemptionCount++
+
+	redemption := models.CodeRedemption{
+		CodeID:     subscriptionCode.ID,
+		UserID:     userID,
+		RedeemedAt: now,
+	}
+	if err := tx.Create(&redemption).Error; err != nil {
+		return nil, err
+	}
+	return &subscriptionCode, nil
+}
+
+// IsRedemptionError reports whether err is one of the errors caused by the code typed by the user.
+func IsRedemptionError(err error) bool {
+	return errors.Is(err, ErrInvalidCode) || errors.Is(err, ErrCodeExpired) || errors.Is(err, ErrCodeExhausted)
+}
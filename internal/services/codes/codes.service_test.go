@@ -0,0 +1,136 @@
+package codes
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "ABCD-EFGH-JKMN", Normalize("  abcd-efgh-jkmn "))
+}
+
+func TestGenerateCode(t *testing.T) {
+	pattern := regexp.MustCompile(`^[` + alphabet + `]{4}-[` + alphabet + `]{4}-[` + alphabet + `]{4}$`)
+
+	first, err := GenerateCode()
+	assert.NoError(t, err)
+	assert.Regexp(t, pattern, first)
+
+	second, err := GenerateCode()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestGenerateBatch(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	expiresAt := time.Now().Add(time.Hour)
+	adminID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "subscription_codes"`).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	generated, err := GenerateBatch(database, models.CodeBatchInput{
+		Count: 3, Plan: models.PlanPremium, MaxRedemptions: 5, ExpiresAt: &expiresAt,
+	}, adminID)
+	assert.NoError(t, err)
+	assert.Len(t, generated, 3)
+	for _, code := range generated {
+		assert.Equal(t, generated[0].BatchID, code.BatchID)
+		assert.Equal(t, models.PlanPremium, code.Plan)
+		assert.Equal(t, 5, code.MaxRedemptions)
+		assert.Equal(t, adminID, code.CreatedByID)
+		assert.True(t, code.ExpiresAt.Valid)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedeem(t *testing.T) {
+	querySelect := `SELECT * FROM "subscription_codes" WHERE code = $1 ORDER BY "subscription_codes"."id" LIMIT $2`
+	queryUpdate := `UPDATE "subscription_codes" SET "redemption_count"=redemption_count + 1 WHERE id = $1 AND redemption_count < max_redemptions`
+	now := time.Now()
+	userID := uuid.New()
+
+	valid := models.SubscriptionCode{ID: uuid.New(), Code: "ABCD-EFGH-JKMN", Plan: models.PlanPremium, MaxRedemptions: 2, RedemptionCount: 1}
+	expired := valid
+	expired.ExpiresAt = sql.NullTime{Time: now.Add(-time.Hour), Valid: true}
+
+	tests := []struct {
+		name          string
+		code          *models.SubscriptionCode
+		rowsAffected  int64
+		expectedError error
+	}{
+		{
+			name:          "valid code",
+			code:          &valid,
+			rowsAffected:  1,
+			expectedError: nil,
+		},
+		{
+			name:          "unknown code",
+			code:          nil,
+			expectedError: ErrInvalidCode,
+		},
+		{
+			name:          "expired code",
+			code:          &expired,
+			expectedError: ErrCodeExpired,
+		},
+		{
+			name:          "exhausted code",
+			code:          &valid,
+			rowsAffected:  0,
+			expectedError: ErrCodeExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+
+			if tt.code == nil {
+				mock.ExpectQuery(regexp.QuoteMeta(querySelect)).WillReturnError(gorm.ErrRecordNotFound)
+			} else {
+				mock.ExpectQuery(regexp.QuoteMeta(querySelect)).
+					WithArgs("ABCD-EFGH-JKMN", 1).
+					WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.SubscriptionCode{*tt.code}))
+			}
+			if tt.expectedError == nil || tt.expectedError == ErrCodeExhausted {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryUpdate)).
+					WithArgs(tt.code.ID).
+					WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				mock.ExpectCommit()
+			}
+			if tt.expectedError == nil {
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO "code_redemptions"`).
+					WithArgs(sqlmock.AnyArg(), tt.code.ID, userID, now).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			redeemed, err := Redeem(database, "abcd-efgh-jkmn", userID, now)
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError == nil {
+				assert.Equal(t, 2, redeemed.RedemptionCount)
+			} else {
+				assert.True(t, IsRedemptionError(err))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
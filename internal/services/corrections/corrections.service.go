@@ -0,0 +1,113 @@
+// Package corrections asks the language model for the mistakes of the learners and checks its answer: each mistake
+// must be located in the message, so that clients can underline it, and explained in the native language of the learner.
+package corrections
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+)
+
+// MaxTokens is the maximum length of the corrections of a message, in tokens.
+const MaxTokens = 800
+
+// ErrInvalidCorrections is returned when the language model doesn't answer with a JSON array of corrections.
+var ErrInvalidCorrections = errors.New("the corrections are not a JSON array")
+
+// NewRequest returns the request asking the language model for the mistakes of a message written in the language,
+// explained in the native language of the learner.
+func NewRequest(content string, language string, nativeLanguage string) llm.Request {
+	if nativeLanguage == "" {
+		nativeLanguage = models.DefaultNativeLanguage
+	}
+	temperature := 0.0
+	return llm.Request{
+		MaxTokens:   MaxTokens,
+		Temperature: &temperature,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: fmt.Sprintf("You are a language teacher correcting the messages a learner writes "+
+				"in the language with the code %q. List the mistakes of the message as a JSON array of objects with the "+
+				"fields start and end, the offsets of the mistake in the message counted in characters from 0 with end "+
+				"excluded, original, the text of the mistake, suggestion, the corrected text, category, one of %s, and "+
+				"explanation, a short explanation written in the language with the code %q. Only correct real mistakes, "+
+				"answer [] when there is none, and answer only with the JSON array.",
+				language, strings.Join(categories(), ", "), nativeLanguage)},
+			{Role: llm.RoleUser, Content: content},
+		},
+	}
+}
+
+// Parse reads the corrections answered by the language model for the content. The offsets of a correction not
+// matching its original text are moved to the closest occurrence of the text. The corrections which are invalid,
+// can't be located or overlap a previous one are left out.
+func Parse(answer string, content string) ([]models.Correction, error) {
+	start, end := strings.Index(answer, "["), strings.LastIndex(answer, "]")
+	if start < 0 || end < start {
+		return nil, ErrInvalidCorrections
+	}
+	var candidates []models.Correction
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &candidates); err != nil {
+		return nil, errors.Join(ErrInvalidCorrections, err)
+	}
+
+	corrections := make([]models.Correction, 0, len(candidates))
+	for _, correction := range candidates {
+		correction, ok := locate(correction, content)
+		if ok && correction.Validate() == nil {
+			corrections = append(corrections, correction)
+		}
+	}
+	sort.SliceStable(corrections, func(i, j int) bool { return corrections[i].Start < corrections[j].Start })
+
+	kept := corrections[:0]
+	for _, correction := range corrections {
+		if len(kept) == 0 || kept[len(kept)-1].End <= correction.Start {
+			kept = append(kept, correction)
+		}
+	}
+	return kept, nil
+}
+
+// locate returns the correction with its offsets set to the occurrence of its original text closest to them.
+func locate(correction models.Correction, content string) (models.Correction, bool) {
+	if correction.Matches(content) {
+		return correction, true
+	}
+	runes, original := []rune(content), []rune(correction.Original)
+	if len(original) == 0 {
+		return correction, false
+	}
+	best := -1
+	for i := 0; i+len(original) <= len(runes); i++ {
+		if string(runes[i:i+len(original)]) == correction.Original && (best < 0 || distance(i, correction.Start) < distance(best, correction.Start)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return correction, false
+	}
+	correction.Start, correction.End = best, best+len(original)
+	return correction, true
+}
+
+// distance returns the distance between two offsets.
+func distance(a int, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// categories returns the names of the categories of the mistakes.
+func categories() []string {
+	names := make([]string, 0, len(models.CorrectionCategories))
+	for _, category := range models.CorrectionCategories {
+		names = append(names, category.(string))
+	}
+	return names
+}
@@ -0,0 +1,82 @@
+package corrections
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequest(t *testing.T) {
+	request := NewRequest("Él tengo un perro", "es", "fr")
+	assert.Equal(t, MaxTokens, request.MaxTokens)
+	assert.Equal(t, 0.0, *request.Temperature)
+	assert.Contains(t, request.Messages[0].Content, `"es"`)
+	assert.Contains(t, request.Messages[0].Content, `written in the language with the code "fr"`)
+	assert.Contains(t, request.Messages[0].Content, "grammar, spelling, vocabulary, punctuation, style")
+	assert.Equal(t, "Él tengo un perro", request.Messages[1].Content)
+
+	request = NewRequest("Hola", "es", "")
+	assert.Contains(t, request.Messages[0].Content, `written in the language with the code "en"`)
+}
+
+func TestParse(t *testing.T) {
+	content := "Él tengo un perro y el perro es muy bonita."
+
+	tests := []struct {
+		name     string
+		answer   string
+		expected []models.Correction
+		err      error
+	}{
+		{
+			name: "located corrections",
+			answer: `Here are the mistakes: [{"start": 3, "end": 8, "original": "tengo", "suggestion": "tiene", ` +
+				`"category": "grammar", "explanation": "Il faut la troisième personne."}]`,
+			expected: []models.Correction{{Start: 3, End: 8, Original: "tengo", Suggestion: "tiene",
+				Category: models.CorrectionGrammar, Explanation: "Il faut la troisième personne."}},
+		},
+		{
+			name: "misplaced offsets",
+			answer: `[{"start": 40, "end": 46, "original": "bonita", "suggestion": "bonito", "category": "grammar", "explanation": "Perro est masculin."},` +
+				`{"start": 0, "end": 5, "original": "tengo", "suggestion": "tiene", "category": "grammar", "explanation": "Troisième personne."}]`,
+			expected: []models.Correction{
+				{Start: 3, End: 8, Original: "tengo", Suggestion: "tiene", Category: models.CorrectionGrammar, Explanation: "Troisième personne."},
+				{Start: 36, End: 42, Original: "bonita", Suggestion: "bonito", Category: models.CorrectionGrammar, Explanation: "Perro est masculin."},
+			},
+		},
+		{
+			name: "invalid and overlapping corrections",
+			answer: `[{"start": 3, "end": 8, "original": "tengo", "suggestion": "tiene", "category": "grammar", "explanation": "Troisième personne."},` +
+				`{"start": 3, "end": 11, "original": "tengo un", "suggestion": "tiene un", "category": "grammar", "explanation": "Idem."},` +
+				`{"start": 12, "end": 17, "original": "gato", "suggestion": "gata", "category": "grammar", "explanation": "Absent."},` +
+				`{"start": 33, "end": 35, "original": "es", "suggestion": "está", "category": "tone", "explanation": "Catégorie inconnue."}]`,
+			expected: []models.Correction{
+				{Start: 3, End: 8, Original: "tengo", Suggestion: "tiene", Category: models.CorrectionGrammar, Explanation: "Troisième personne."},
+			},
+		},
+		{
+			name:     "no mistake",
+			answer:   "[]",
+			expected: []models.Correction{},
+		},
+		{
+			name:   "not an array",
+			answer: "The message has no mistake.",
+			err:    ErrInvalidCorrections,
+		},
+		{
+			name:   "invalid JSON",
+			answer: `[{"start": "3"}]`,
+			err:    ErrInvalidCorrections,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrections, err := Parse(tt.answer, content)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, corrections)
+		})
+	}
+}
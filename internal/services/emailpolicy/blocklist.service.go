@@ -0,0 +1,123 @@
+package emailpolicy
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist holds the disposable email domains read from a local file, one domain per line.
+// Empty lines and lines starting with '#' are ignored.
+// The file is read again when it changes, see Watch.
+type Blocklist struct {
+	path string
+
+	mu      sync.RWMutex
+	domains map[string]struct{}
+	modTime time.Time
+}
+
+// LoadBlocklist reads the blocklist file at path. An empty path returns an empty blocklist.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	blocklist := &Blocklist{path: path, domains: make(map[string]struct{})}
+	if path == "" {
+		return blocklist, nil
+	}
+	return blocklist, blocklist.Reload()
+}
+
+// Contains reports whether the domain is in the blocklist.
+func (b *Blocklist) Contains(domain string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, found := b.domains[domain]
+	return found
+}
+
+// Len returns the number of domains in the blocklist.
+func (b *Blocklist) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.domains)
+}
+
+// Reload reads the file again and replaces the domains of the blocklist.
+// The current domains are kept if the file can't be read.
+func (b *Blocklist) Reload() error {
+	if b.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains = domains
+	b.modTime = info.ModTime()
+	return nil
+}
+
+// changed reports whether the file was modified since it was last read.
+func (b *Blocklist) changed() bool {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return !info.ModTime().Equal(b.modTime)
+}
+
+// Watch checks the file every interval and reloads it when it changed, until ctx is done.
+// It is meant to run in its own goroutine.
+func (b *Blocklist) Watch(ctx context.Context, interval time.Duration) {
+	if b.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !b.changed() {
+				continue
+			}
+			if err := b.Reload(); err != nil {
+				log.Printf("could not reload the email blocklist %s: %v", b.path, err)
+			} else {
+				log.Printf("reloaded the email blocklist %s: %d domains", b.path, b.Len())
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package emailpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# comment\nMailinator.com\n\n  yopmail.com  \n"), 0o600))
+
+	blocklist, err := LoadBlocklist(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, blocklist.Len())
+	assert.True(t, blocklist.Contains("mailinator.com"))
+	assert.True(t, blocklist.Contains("yopmail.com"))
+	assert.False(t, blocklist.Contains("# comment"))
+
+	_, err = LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+
+	empty, err := LoadBlocklist("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, empty.Len())
+}
+
+func TestBlocklist_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("mailinator.com\n"), 0o600))
+
+	blocklist, err := LoadBlocklist(path)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go blocklist.Watch(ctx, 10*time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte("mailinator.com\nyopmail.com\n"), 0o600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+
+	assert.Eventually(t, func() bool {
+		return blocklist.Contains("yopmail.com")
+	}, time.Second, 10*time.Millisecond)
+}
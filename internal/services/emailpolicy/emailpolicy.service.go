@@ -0,0 +1,105 @@
+// Package emailpolicy decides which email addresses can register: disposable domains are blocked,
+// administrators can allow or block domains, and registration can be restricted to allowed domains,
+// e.g. for schools.
+package emailpolicy
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"gorm.io/gorm"
+)
+
+// ModeAllowlist is the policy mode in which only the domains allowed by an administrator can register.
+const ModeAllowlist = "allowlist"
+
+var (
+	// ErrDisposableDomain is returned for addresses of a domain listed in the blocklist file.
+	ErrDisposableDomain = errors.New("disposable email addresses are not allowed")
+	// ErrBlockedDomain is returned for addresses of a domain blocked by an administrator.
+	ErrBlockedDomain = errors.New("this email domain is not allowed")
+	// ErrDomainNotAllowed is returned in allowlist mode for addresses of a domain that was not allowed.
+	ErrDomainNotAllowed = errors.New("only addresses from approved domains can register")
+)
+
+// IsPolicyError reports whether err is one of the errors returned when an address is refused by the policy.
+func IsPolicyError(err error) bool {
+	return errors.Is(err, ErrDisposableDomain) || errors.Is(err, ErrBlockedDomain) || errors.Is(err, ErrDomainNotAllowed)
+}
+
+// Policy checks email addresses against the blocklist file and the rules stored in the database.
+type Policy struct {
+	mode      string
+	blocklist *Blocklist
+}
+
+// NewPolicy returns a Policy in the given mode using the given blocklist, which may be nil.
+func NewPolicy(mode string, blocklist *Blocklist) *Policy {
+	if blocklist == nil {
+		blocklist, _ = LoadBlocklist("")
+	}
+	return &Policy{mode: mode, blocklist: blocklist}
+}
+
+// NewPolicyFromConfig returns the Policy matching the provided configuration and loads its blocklist file.
+func NewPolicyFromConfig(config *configs.Config) (*Policy, error) {
+	blocklist, err := LoadBlocklist(config.EmailBlocklistFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicy(config.EmailPolicyMode, blocklist), nil
+}
+
+// Blocklist returns the blocklist read from the file.
+func (p *Policy) Blocklist() *Blocklist {
+	return p.blocklist
+}
+
+// Check returns an error if the address can't register.
+// Rules apply to a domain and its subdomains, and the most specific rule wins.
+// An allow rule takes precedence over the blocklist file.
+func (p *Policy) Check(db *gorm.DB, email string) error {
+	candidates := parentDomains(utils.EmailDomain(email))
+	if len(candidates) == 0 {
+		return ErrBlockedDomain
+	}
+
+	var rules []models.EmailDomainRule
+	if err := db.Where("domain IN ?", candidates).Find(&rules).Error; err != nil {
+		return err
+	}
+	kinds := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		kinds[rule.Domain] = rule.Kind
+	}
+
+	for _, domain := range candidates {
+		switch kinds[domain] {
+		case models.EmailDomainAllow:
+			return nil
+		case models.EmailDomainBlock:
+			return ErrBlockedDomain
+		}
+		if p.blocklist.Contains(domain) {
+			return ErrDisposableDomain
+		}
+	}
+	if p.mode == ModeAllowlist {
+		return ErrDomainNotAllowed
+	}
+	return nil
+}
+
+// parentDomains returns the domain followed by its parent domains, most specific first,
+// stopping before the top-level domain: "a.school.edu" gives "a.school.edu" and "school.edu".
+func parentDomains(domain string) []string {
+	var domains []string
+	for strings.Contains(domain, ".") {
+		domains = append(domains, domain)
+		domain = domain[strings.Index(domain, ".")+1:]
+	}
+	return domains
+}
@@ -0,0 +1,116 @@
+package emailpolicy
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParentDomains(t *testing.T) {
+	assert.Equal(t, []string{"a.school.edu", "school.edu"}, parentDomains("a.school.edu"))
+	assert.Equal(t, []string{"mail.pe"}, parentDomains("mail.pe"))
+	assert.Empty(t, parentDomains("localhost"))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	queryRules := `SELECT * FROM "email_domain_rules" WHERE domain IN ($1,$2)`
+
+	blocklist, _ := LoadBlocklist("")
+	blocklist.domains["mailinator.com"] = struct{}{}
+
+	tests := []struct {
+		name     string
+		mode     string
+		rules    []models.EmailDomainRule
+		email    string
+		expected error
+	}{
+		{
+			name:     "unlisted domain",
+			email:    "john@class.school.edu",
+			expected: nil,
+		},
+		{
+			name:     "disposable subdomain",
+			email:    "john@eu.mailinator.com",
+			expected: ErrDisposableDomain,
+		},
+		{
+			name:     "disposable domain allowed by an administrator",
+			rules:    []models.EmailDomainRule{{Domain: "eu.mailinator.com", Kind: models.EmailDomainAllow}},
+			email:    "john@eu.mailinator.com",
+			expected: nil,
+		},
+		{
+			name:     "blocked parent domain",
+			rules:    []models.EmailDomainRule{{Domain: "school.edu", Kind: models.EmailDomainBlock}},
+			email:    "john@class.school.edu",
+			expected: ErrBlockedDomain,
+		},
+		{
+			name:     "most specific rule wins",
+			rules:    []models.EmailDomainRule{{Domain: "school.edu", Kind: models.EmailDomainBlock}, {Domain: "class.school.edu", Kind: models.EmailDomainAllow}},
+			email:    "john@class.school.edu",
+			expected: nil,
+		},
+		{
+			name:     "allowlist mode without rule",
+			mode:     ModeAllowlist,
+			email:    "john@class.school.edu",
+			expected: ErrDomainNotAllowed,
+		},
+		{
+			name:     "allowlist mode with allowed domain",
+			mode:     ModeAllowlist,
+			rules:    []models.EmailDomainRule{{Domain: "school.edu", Kind: models.EmailDomainAllow}},
+			email:    "john@class.school.edu",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(queryRules)).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.rules))
+
+			err := NewPolicy(tt.mode, blocklist).Check(database, tt.email)
+			assert.Equal(t, tt.expected, err)
+			assert.Equal(t, tt.expected != nil, IsPolicyError(err))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("database failure", func(t *testing.T) {
+		database, sqlDB, mock := db.InitMockDB()
+		defer sqlDB.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta(queryRules)).WillReturnError(errors.New("connection lost"))
+
+		err := NewPolicy("", blocklist).Check(database, "john@school.edu")
+		assert.Error(t, err)
+		assert.False(t, IsPolicyError(err))
+	})
+
+	t.Run("address without domain", func(t *testing.T) {
+		assert.Equal(t, ErrBlockedDomain, NewPolicy("", blocklist).Check(nil, "john"))
+	})
+}
+
+func TestNewPolicyFromConfig(t *testing.T) {
+	policy, err := NewPolicyFromConfig(&configs.Config{EmailPolicyMode: ModeAllowlist})
+	assert.NoError(t, err)
+	assert.Equal(t, ModeAllowlist, policy.mode)
+	assert.Equal(t, 0, policy.Blocklist().Len())
+
+	_, err = NewPolicyFromConfig(&configs.Config{EmailBlocklistFile: "does/not/exist.txt"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"archive/zip"
+	"io"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"gorm.io/gorm"
+)
+
+// archiveBatchSize is the number of conversations loaded at once while writing an archive.
+const archiveBatchSize = 50
+
+// WriteArchive writes a zip archive of all the conversations of the user, each rendered in the format, to w. The
+// conversations are loaded by batches and each file is written as soon as it is rendered, so that the archive is
+// streamed without holding the conversations in memory.
+func WriteArchive(w io.Writer, db *gorm.DB, user models.User, format string) error {
+	if _, found := ContentTypes[format]; !found {
+		return ErrUnknownFormat
+	}
+	archive := zip.NewWriter(w)
+	var batch []models.Conversation
+	err := db.Where("user_id = ?", user.ID).FindInBatches(&batch, archiveBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, conversation := range batch {
+			document, err := Load(db, user, conversation)
+			if err != nil {
+				return err
+			}
+			file, err := archive.CreateHeader(&zip.FileHeader{
+				Name:     Filename(conversation, format),
+				Method:   zip.Deflate,
+				Modified: conversation.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if err := Render(file, format, document); err != nil {
+				return err
+			}
+		}
+		return archive.Flush()
+	}).Error
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
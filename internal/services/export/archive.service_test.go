@@ -0,0 +1,67 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const queryConversations = `SELECT * FROM "conversations" WHERE user_id = $1 ORDER BY "conversations"."id" LIMIT $2`
+
+func TestWriteArchive(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	user := models.User{ID: uuid.New(), FirstName: "Ana"}
+	conversation, messages, vocabulary := marketConversation(user)
+	empty := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "Vacía"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryConversations)).
+		WithArgs(user.ID, archiveBatchSize).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Conversation{conversation, empty}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(messages))
+	mock.ExpectQuery(regexp.QuoteMeta(queryVocabulary)).
+		WithArgs(user.ID, "es").
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(vocabulary))
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+		WithArgs(empty.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryVocabulary)).
+		WithArgs(user.ID, "es").
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(vocabulary))
+
+	var body bytes.Buffer
+	assert.NoError(t, WriteArchive(&body, database, user, FormatMarkdown))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	archive, err := zip.NewReader(bytes.NewReader(body.Bytes()), int64(body.Len()))
+	assert.NoError(t, err)
+	if assert.Len(t, archive.File, 2) {
+		assert.Equal(t, Filename(conversation, FormatMarkdown), archive.File[0].Name)
+		assert.Equal(t, Filename(empty, FormatMarkdown), archive.File[1].Name)
+		file, err := archive.File[0].Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), "# En el mercado\n"))
+		assert.Contains(t, string(content), "Yo quiero comprar **manzanas**")
+	}
+}
+
+func TestWriteArchiveUnknownFormat(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+
+	assert.ErrorIs(t, WriteArchive(&bytes.Buffer{}, database, models.User{ID: uuid.New()}, "pdf"), ErrUnknownFormat)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
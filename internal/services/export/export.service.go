@@ -0,0 +1,319 @@
+// Package export renders the conversations of the learners as documents to print or to archive: Markdown, JSON or
+// a printable HTML page. The active branch of the conversation is rendered with the corrections of the messages of
+// the learner, and the words of their vocabulary are highlighted in the messages.
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Formats of the exported documents.
+const (
+	FormatMarkdown = "md"   // FormatMarkdown is a Markdown document.
+	FormatJSON     = "json" // FormatJSON is a JSON document.
+	FormatHTML     = "html" // FormatHTML is a printable HTML page.
+)
+
+// Formats lists the formats of the exported documents.
+var Formats = []string{FormatMarkdown, FormatJSON, FormatHTML}
+
+// ContentTypes maps the formats to the content type of their documents.
+var ContentTypes = map[string]string{
+	FormatMarkdown: "text/markdown; charset=utf-8",
+	FormatJSON:     "application/json; charset=utf-8",
+	FormatHTML:     "text/html; charset=utf-8",
+}
+
+// ErrUnknownFormat is returned when a document is rendered in a format which isn't supported.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Kinds of the highlighted spans of a message.
+const (
+	highlightMistake    = "mistake"    // highlightMistake is a mistake of the learner.
+	highlightVocabulary = "vocabulary" // highlightVocabulary is a word of the vocabulary of the learner.
+)
+
+// Document is a conversation ready to be rendered.
+type Document struct {
+	Conversation models.Conversation     // Conversation exported
+	Learner      string                  // Name of the learner
+	PenPal       string                  // Name of the pen pal
+	Messages     []models.Message        // Messages of the active branch, oldest first
+	Vocabulary   []models.VocabularyItem // Words of the vocabulary of the learner found in the messages, alphabetically
+	ExportedAt   time.Time               // Timestamp of the export
+}
+
+// Load returns the document of the conversation of the user.
+func Load(db *gorm.DB, user models.User, conversation models.Conversation) (Document, error) {
+	document := Document{Conversation: conversation, Learner: user.FirstName, PenPal: "Pen pal", ExportedAt: time.Now()}
+	if document.Learner == "" {
+		document.Learner = "Learner"
+	}
+	if conversation.PersonaID.Valid {
+		var persona models.Persona
+		err := db.Where("id = ?", conversation.PersonaID.UUID).First(&persona).Error
+		if err == nil {
+			document.PenPal = persona.Name
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return document, err
+		}
+	}
+
+	if err := db.Where("conversation_id = ?", conversation.ID).Order("created_at").Find(&document.Messages).Error; err != nil {
+		return document, err
+	}
+	if conversation.ActiveLeafID.Valid {
+		document.Messages = chat.Path(document.Messages, conversation.ActiveLeafID.UUID)
+	}
+
+	var vocabulary []models.VocabularyItem
+	if err := db.Where("user_id = ? AND language = ?", user.ID, conversation.Language).Order("term").Find(&vocabulary).Error; err != nil {
+		return document, err
+	}
+	pattern, found := vocabularyPattern(vocabulary), map[string]bool{}
+	for _, message := range document.Messages {
+		for _, term := range findTerms(pattern, message.Content) {
+			found[strings.ToLower(message.Content[term.start:term.end])] = true
+		}
+	}
+	for _, item := range vocabulary {
+		if found[strings.ToLower(strings.TrimSpace(item.Term))] {
+			document.Vocabulary = append(document.Vocabulary, item)
+		}
+	}
+	return document, nil
+}
+
+// Render writes the document in the format to w.
+func Render(w io.Writer, format string, document Document) error {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(w, document.view())
+	case FormatJSON:
+		return renderJSON(w, document)
+	case FormatHTML:
+		return renderHTML(w, document.view())
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+// Filename returns the name of the file of the conversation exported in the format, made of its title and the
+// start of its ID so that the files of an archive don't collide.
+func Filename(conversation models.Conversation, format string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(conversation.Title) {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if slug.Len() >= 50 {
+			break
+		}
+	}
+	if slug.Len() == 0 {
+		slug.WriteString("conversation")
+	}
+	return fmt.Sprintf("%s-%s.%s", slug.String(), conversation.ID.String()[:8], format)
+}
+
+// documentView is the document laid out for the Markdown and HTML templates.
+type documentView struct {
+	Title      string
+	Language   string
+	Learner    string
+	PenPal     string
+	StartedAt  string
+	ExportedAt string
+	Messages   []messageView
+	Vocabulary []models.VocabularyItem
+}
+
+// messageView is a message laid out for the templates.
+type messageView struct {
+	Role        string
+	Author      string
+	Date        string
+	Segments    []segment
+	Corrections []models.Correction
+}
+
+// segment is a piece of the text of a message, highlighted unless its kind is empty.
+type segment struct {
+	Text string
+	Kind string
+}
+
+// dateLayout formats the dates of the documents.
+const dateLayout = "2006-01-02 15:04"
+
+// view lays the document out for the templates.
+func (d Document) view() documentView {
+	view := documentView{
+		Title:      d.Conversation.Title,
+		Language:   d.Conversation.Language,
+		Learner:    d.Learner,
+		PenPal:     d.PenPal,
+		StartedAt:  d.Conversation.CreatedAt.Format(dateLayout),
+		ExportedAt: d.ExportedAt.Format(dateLayout),
+		Vocabulary: d.Vocabulary,
+	}
+	pattern := vocabularyPattern(d.Vocabulary)
+	for _, message := range d.Messages {
+		author := d.PenPal
+		if message.Role == models.MessageRoleUser {
+			author = d.Learner
+		}
+		view.Messages = append(view.Messages, messageView{
+			Role:        message.Role,
+			Author:      author,
+			Date:        message.CreatedAt.Format(dateLayout),
+			Segments:    segments(message, pattern),
+			Corrections: message.Corrections,
+		})
+	}
+	return view
+}
+
+// span is a highlighted part of a text, in bytes.
+type span struct {
+	start, end int
+	kind       string
+}
+
+// segments splits the content of the message into the mistakes of its corrections, the words of the vocabulary
+// which don't overlap them, and the plain text around them.
+func segments(message models.Message, pattern *regexp.Regexp) []segment {
+	content := message.Content
+	offsets := make([]int, 0, len(content)+1)
+	for i := range content {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(content))
+
+	var spans []span
+	for _, correction := range message.Corrections {
+		if correction.Start >= 0 && correction.Start < correction.End && correction.End < len(offsets) {
+			spans = append(spans, span{start: offsets[correction.Start], end: offsets[correction.End], kind: highlightMistake})
+		}
+	}
+	for _, term := range findTerms(pattern, content) {
+		if !slices.ContainsFunc(spans, func(s span) bool { return s.start < term.end && term.start < s.end }) {
+			spans = append(spans, term)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var result []segment
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		if s.start > last {
+			result = append(result, segment{Text: content[last:s.start]})
+		}
+		result = append(result, segment{Text: content[s.start:s.end], Kind: s.kind})
+		last = s.end
+	}
+	if last < len(content) {
+		result = append(result, segment{Text: content[last:]})
+	}
+	return result
+}
+
+// vocabularyPattern returns the pattern matching the terms of the vocabulary whatever their case, the longest first,
+// nil if the vocabulary is empty.
+func vocabularyPattern(vocabulary []models.VocabularyItem) *regexp.Regexp {
+	terms := make([]string, 0, len(vocabulary))
+	for _, item := range vocabulary {
+		if term := strings.TrimSpace(item.Term); term != "" {
+			terms = append(terms, regexp.QuoteMeta(term))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// findTerms returns the terms of the pattern found as whole words in the text.
+func findTerms(pattern *regexp.Regexp, text string) []span {
+	if pattern == nil {
+		return nil
+	}
+	var found []span
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:match[0]])
+		after, _ := utf8.DecodeRuneInString(text[match[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			found = append(found, span{start: match[0], end: match[1], kind: highlightVocabulary})
+		}
+	}
+	return found
+}
+
+// isWordRune reports whether the rune belongs to a word.
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// documentJSON is the JSON representation of a document.
+type documentJSON struct {
+	Conversation models.ConversationResponse `json:"conversation"`
+	Learner      string                      `json:"learner"`
+	PenPal       string                      `json:"pen_pal"`
+	Messages     []models.MessageResponse    `json:"messages"`
+	Vocabulary   []vocabularyJSON            `json:"vocabulary"`
+	ExportedAt   time.Time                   `json:"exported_at"`
+}
+
+// vocabularyJSON is the JSON representation of a word of the vocabulary of the learner.
+type vocabularyJSON struct {
+	ID          uuid.UUID `json:"id"`
+	Term        string    `json:"term"`
+	Translation string    `json:"translation"`
+	Example     string    `json:"example,omitempty"`
+}
+
+// renderJSON writes the document as JSON to w.
+func renderJSON(w io.Writer, document Document) error {
+	output := documentJSON{
+		Conversation: document.Conversation.ToResponse(),
+		Learner:      document.Learner,
+		PenPal:       document.PenPal,
+		Messages:     make([]models.MessageResponse, 0, len(document.Messages)),
+		Vocabulary:   make([]vocabularyJSON, 0, len(document.Vocabulary)),
+		ExportedAt:   document.ExportedAt,
+	}
+	for _, message := range document.Messages {
+		output.Messages = append(output.Messages, message.ToResponse())
+	}
+	for _, item := range document.Vocabulary {
+		output.Vocabulary = append(output.Vocabulary, vocabularyJSON{ID: item.ID, Term: item.Term, Translation: item.Translation, Example: item.Example})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
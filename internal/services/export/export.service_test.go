@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const (
+	queryPersona    = `SELECT * FROM "personas" WHERE id = $1 ORDER BY "personas"."id" LIMIT $2`
+	queryMessages   = `SELECT * FROM "messages" WHERE conversation_id = $1 ORDER BY created_at`
+	queryVocabulary = `SELECT * FROM "vocabulary_items" WHERE user_id = $1 AND language = $2 ORDER BY term`
+)
+
+// marketConversation returns a conversation of the user in Spanish with a mistake corrected in the first message,
+// and the vocabulary of the user.
+func marketConversation(user models.User) (models.Conversation, []models.Message, []models.VocabularyItem) {
+	start := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	conversation := models.Conversation{ID: uuid.New(), UserID: user.ID, Language: "es", Title: "En el mercado", CreatedAt: start}
+	question := models.Message{
+		ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Yo quiero comprar manzanas rojo",
+		Corrections: []models.Correction{{Start: 27, End: 31, Original: "rojo", Suggestion: "rojas", Category: "grammar", Explanation: "Agreement"}},
+		CreatedAt:   start,
+	}
+	answer := models.Message{
+		ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleAssistant, Content: "¡Claro! Las manzanas cuestan dos euros.",
+		ParentID: uuid.NullUUID{UUID: question.ID, Valid: true}, CreatedAt: start.Add(time.Minute),
+	}
+	vocabulary := []models.VocabularyItem{
+		{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "Euro", Translation: "euro"},
+		{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "manzana", Translation: "apple"},
+		{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "manzanas", Translation: "apples"},
+		{ID: uuid.New(), UserID: user.ID, Language: "es", Term: "rojo", Translation: "red"},
+	}
+	return conversation, []models.Message{question, answer}, vocabulary
+}
+
+func TestLoad(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	user := models.User{ID: uuid.New(), FirstName: "Ana"}
+	conversation, messages, vocabulary := marketConversation(user)
+	persona := models.Persona{ID: uuid.New(), Name: "Lucía"}
+	conversation.PersonaID = uuid.NullUUID{UUID: persona.ID, Valid: true}
+	conversation.ActiveLeafID = uuid.NullUUID{UUID: messages[1].ID, Valid: true}
+	stale := models.Message{ID: uuid.New(), ConversationID: conversation.ID, Role: models.MessageRoleUser, Content: "Hola", CreatedAt: messages[0].CreatedAt.Add(-time.Minute)}
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryPersona)).
+		WithArgs(persona.ID, 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Persona{persona}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(append([]models.Message{stale}, messages...)))
+	mock.ExpectQuery(regexp.QuoteMeta(queryVocabulary)).
+		WithArgs(user.ID, "es").
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows(vocabulary))
+
+	document, err := Load(database, user, conversation)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ana", document.Learner)
+	assert.Equal(t, "Lucía", document.PenPal)
+	assert.Equal(t, []uuid.UUID{messages[0].ID, messages[1].ID}, []uuid.UUID{document.Messages[0].ID, document.Messages[1].ID})
+	assert.Len(t, document.Messages, 2)
+	terms := []string{}
+	for _, item := range document.Vocabulary {
+		terms = append(terms, item.Term)
+	}
+	assert.Equal(t, []string{"manzanas", "rojo"}, terms)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadMissingPersona(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	user := models.User{ID: uuid.New()}
+	conversation, _, _ := marketConversation(user)
+	conversation.PersonaID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryPersona)).
+		WithArgs(conversation.PersonaID.UUID, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectQuery(regexp.QuoteMeta(queryMessages)).
+		WithArgs(conversation.ID).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.Message{}))
+	mock.ExpectQuery(regexp.QuoteMeta(queryVocabulary)).
+		WithArgs(user.ID, "es").
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.VocabularyItem{}))
+
+	document, err := Load(database, user, conversation)
+	assert.NoError(t, err)
+	assert.Equal(t, "Learner", document.Learner)
+	assert.Equal(t, "Pen pal", document.PenPal)
+	assert.Empty(t, document.Messages)
+	assert.Empty(t, document.Vocabulary)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSegments(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	_, messages, vocabulary := marketConversation(user)
+	pattern := vocabularyPattern(vocabulary)
+
+	assert.Equal(t, []segment{
+		{Text: "Yo quiero comprar "},
+		{Text: "manzanas", Kind: highlightVocabulary},
+		{Text: " "},
+		{Text: "rojo", Kind: highlightMistake},
+	}, segments(messages[0], pattern))
+	assert.Equal(t, []segment{
+		{Text: "¡Claro! Las "},
+		{Text: "manzanas", Kind: highlightVocabulary},
+		{Text: " cuestan dos euros."},
+	}, segments(messages[1], pattern))
+
+	accented := models.Message{Content: "¿Qué quieres? Quieres manzanas", Corrections: []models.Correction{{Start: 14, End: 21}}}
+	assert.Equal(t, []segment{
+		{Text: "¿Qué quieres? "},
+		{Text: "Quieres", Kind: highlightMistake},
+		{Text: " "},
+		{Text: "manzanas", Kind: highlightVocabulary},
+	}, segments(accented, pattern))
+
+	assert.Equal(t, []segment{{Text: "Sin vocabulario"}}, segments(models.Message{Content: "Sin vocabulario"}, nil))
+}
+
+func TestFilename(t *testing.T) {
+	id := uuid.MustParse("0f8fad5b-d9cb-469f-a165-70867728950e")
+
+	assert.Equal(t, "en-el-mercado-0f8fad5b.md", Filename(models.Conversation{ID: id, Title: "En el mercado!"}, FormatMarkdown))
+	assert.Equal(t, "caf-con-leche-0f8fad5b.html", Filename(models.Conversation{ID: id, Title: "  Café con leche"}, FormatHTML))
+	assert.Equal(t, "conversation-0f8fad5b.json", Filename(models.Conversation{ID: id, Title: "¿¡…!?"}, FormatJSON))
+}
+
+func TestRenderJSON(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	conversation, messages, vocabulary := marketConversation(user)
+	document := Document{Conversation: conversation, Learner: "Ana", PenPal: "Lucía", Messages: messages, Vocabulary: vocabulary[2:3]}
+
+	var body bytes.Buffer
+	assert.NoError(t, Render(&body, FormatJSON, document))
+	var output struct {
+		Conversation models.ConversationResponse `json:"conversation"`
+		Learner      string                      `json:"learner"`
+		PenPal       string                      `json:"pen_pal"`
+		Messages     []models.MessageResponse    `json:"messages"`
+		Vocabulary   []map[string]string         `json:"vocabulary"`
+	}
+	assert.NoError(t, json.Unmarshal(body.Bytes(), &output))
+	assert.Equal(t, conversation.ID, output.Conversation.ID)
+	assert.Equal(t, "Lucía", output.PenPal)
+	assert.Len(t, output.Messages, 2)
+	assert.Equal(t, messages[0].Corrections, output.Messages[0].Corrections)
+	assert.Equal(t, "manzanas", output.Vocabulary[0]["term"])
+	assert.Equal(t, "apples", output.Vocabulary[0]["translation"])
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, "pdf", Document{}), ErrUnknownFormat)
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"html/template"
+	"io"
+)
+
+// htmlTemplate is the printable page of a conversation. The mistakes of the learner are struck through, with their
+// corrections listed below the message, and the words of the vocabulary are highlighted.
+var htmlTemplate = template.Must(template.New("conversation").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Georgia, serif; color: #222; line-height: 1.5; max-width: 42em; margin: 2em auto; padding: 0 1em; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; font-size: 0.85em; }
+.message { margin: 1.2em 0; padding: 0.6em 1em; border-left: 4px solid #bbb; page-break-inside: avoid; }
+.message.user { border-left-color: #3a7bd5; }
+.text { white-space: pre-wrap; }
+del.mistake { color: #b00020; }
+mark { background: #fff3a0; }
+.corrections { font-size: 0.9em; margin: 0.5em 0 0; padding-left: 1.2em; }
+ins { color: #1b7a2f; text-decoration: none; font-weight: bold; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+@media print {
+  body { margin: 0; max-width: none; font-size: 11pt; }
+  .message { border-left-width: 2px; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Learner}} and {{.PenPal}} · language {{.Language}} · started {{.StartedAt}} · exported {{.ExportedAt}}</p>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="meta">{{.Author}} · {{.Date}}</div>
+<div class="text">{{range .Segments}}{{if eq .Kind "mistake"}}<del class="mistake">{{.Text}}</del>{{else if eq .Kind "vocabulary"}}<mark>{{.Text}}</mark>{{else}}{{.Text}}{{end}}{{end}}</div>
+{{with .Corrections}}<ul class="corrections">
+{{range .}}<li><del>{{.Original}}</del> → {{if .Suggestion}}<ins>{{.Suggestion}}</ins>{{else}}<em>(remove)</em>{{end}} <em>({{.Category}})</em>{{with .Explanation}}: {{.}}{{end}}</li>
+{{end}}</ul>
+{{end}}</div>
+{{end}}{{with .Vocabulary}}<h2>Vocabulary</h2>
+<table>
+<tr><th>Term</th><th>Translation</th><th>Example</th></tr>
+{{range .}}<tr><td>{{.Term}}</td><td>{{.Translation}}</td><td>{{.Example}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// renderHTML writes the document as a printable HTML page to w.
+func renderHTML(w io.Writer, view documentView) error {
+	return htmlTemplate.Execute(w, view)
+}
@@ -0,0 +1,29 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHTML(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	conversation, messages, vocabulary := marketConversation(user)
+	messages[1].Content = "<script>alert(1)</script> las manzanas"
+	document := Document{Conversation: conversation, Learner: "Ana", PenPal: "Lucía", Messages: messages, Vocabulary: vocabulary[2:3], ExportedAt: conversation.CreatedAt}
+
+	var body bytes.Buffer
+	assert.NoError(t, Render(&body, FormatHTML, document))
+	page := body.String()
+	assert.Contains(t, page, `<html lang="es">`)
+	assert.Contains(t, page, `<title>En el mercado</title>`)
+	assert.Contains(t, page, `@media print`)
+	assert.Contains(t, page, `Yo quiero comprar <mark>manzanas</mark> <del class="mistake">rojo</del>`)
+	assert.Contains(t, page, `<li><del>rojo</del> → <ins>rojas</ins> <em>(grammar)</em>: Agreement</li>`)
+	assert.Contains(t, page, `&lt;script&gt;alert(1)&lt;/script&gt; las <mark>manzanas</mark>`)
+	assert.NotContains(t, page, `<script>`)
+	assert.Contains(t, page, `<tr><td>manzanas</td><td>apples</td><td></td></tr>`)
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes the characters of the messages which Markdown would interpret.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// renderMarkdown writes the document as Markdown to w. The mistakes of the learner are struck through, with their
+// corrections quoted below the message, and the words of the vocabulary are in bold.
+func renderMarkdown(w io.Writer, view documentView) error {
+	var document strings.Builder
+	document.WriteString("# " + markdownEscaper.Replace(view.Title) + "\n\n")
+	document.WriteString("*" + markdownEscaper.Replace(view.Learner) + " and " + markdownEscaper.Replace(view.PenPal) +
+		" · language " + view.Language + " · started " + view.StartedAt + " · exported " + view.ExportedAt + "*\n")
+
+	for _, message := range view.Messages {
+		document.WriteString("\n## " + markdownEscaper.Replace(message.Author) + " · " + message.Date + "\n\n")
+		for i, line := range strings.Split(renderSegments(message.Segments), "\n") {
+			if i > 0 {
+				document.WriteString("  \n")
+			}
+			document.WriteString(line)
+		}
+		document.WriteString("\n")
+		if len(message.Corrections) > 0 {
+			document.WriteString("\n> **Corrections**\n")
+			for _, correction := range message.Corrections {
+				suggestion := markdownEscaper.Replace(correction.Suggestion)
+				if suggestion == "" {
+					suggestion = "*(remove)*"
+				}
+				document.WriteString("> - ~~" + markdownEscaper.Replace(correction.Original) + "~~ → " + suggestion +
+					" (" + correction.Category + ")")
+				if correction.Explanation != "" {
+					document.WriteString(": " + markdownEscaper.Replace(correction.Explanation))
+				}
+				document.WriteString("\n")
+			}
+		}
+	}
+
+	if len(view.Vocabulary) > 0 {
+		document.WriteString("\n## Vocabulary\n\n| Term | Translation | Example |\n| --- | --- | --- |\n")
+		for _, item := range view.Vocabulary {
+			document.WriteString("| " + markdownEscaper.Replace(item.Term) + " | " + markdownEscaper.Replace(item.Translation) +
+				" | " + markdownEscaper.Replace(strings.ReplaceAll(item.Example, "\n", " ")) + " |\n")
+		}
+	}
+	_, err := io.WriteString(w, document.String())
+	return err
+}
+
+// renderSegments returns the segments as Markdown, the mistakes struck through and the words of the vocabulary in bold.
+func renderSegments(segments []segment) string {
+	var text strings.Builder
+	for _, s := range segments {
+		escaped := markdownEscaper.Replace(s.Text)
+		switch s.Kind {
+		case highlightMistake:
+			text.WriteString("~~" + escaped + "~~")
+		case highlightVocabulary:
+			text.WriteString("**" + escaped + "**")
+		default:
+			text.WriteString(escaped)
+		}
+	}
+	return text.String()
+}
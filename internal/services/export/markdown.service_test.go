@@ -0,0 +1,39 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	user := models.User{ID: uuid.New()}
+	conversation, messages, vocabulary := marketConversation(user)
+	messages[1].Content = "*Claro*, las manzanas.\nHasta luego"
+	document := Document{Conversation: conversation, Learner: "Ana", PenPal: "Lucía", Messages: messages, Vocabulary: vocabulary[2:3], ExportedAt: conversation.CreatedAt}
+
+	var body bytes.Buffer
+	assert.NoError(t, Render(&body, FormatMarkdown, document))
+	assert.Equal(t, "# En el mercado\n\n"+
+		"*Ana and Lucía · language es · started 2024-06-01 10:00 · exported 2024-06-01 10:00*\n"+
+		"\n## Ana · 2024-06-01 10:00\n\n"+
+		"Yo quiero comprar **manzanas** ~~rojo~~\n"+
+		"\n> **Corrections**\n"+
+		"> - ~~rojo~~ → rojas (grammar): Agreement\n"+
+		"\n## Lucía · 2024-06-01 10:01\n\n"+
+		"\\*Claro\\*, las **manzanas**.  \nHasta luego\n"+
+		"\n## Vocabulary\n\n| Term | Translation | Example |\n| --- | --- | --- |\n"+
+		"| manzanas | apples |  |\n", body.String())
+}
+
+func TestRenderSegments(t *testing.T) {
+	assert.Equal(t, `a\_b ~~c\|d~~ **e**`, renderSegments([]segment{
+		{Text: "a_b "},
+		{Text: "c|d", Kind: highlightMistake},
+		{Text: " "},
+		{Text: "e", Kind: highlightVocabulary},
+	}))
+}
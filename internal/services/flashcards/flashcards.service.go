@@ -0,0 +1,116 @@
+// Package flashcards schedules the reviews of the vocabulary of the learners with the SM-2 spaced-repetition
+// algorithm, and asks the language model for the words worth learning in a message.
+package flashcards
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/google/uuid"
+)
+
+const (
+	// MinEaseFactor is the lowest ease factor of a flashcard, as in the SM-2 algorithm.
+	MinEaseFactor = 1.3
+	// PassingGrade is the lowest grade of a successful recall.
+	PassingGrade = 3
+	// MasteredInterval is the interval, in days, from which a word is considered mastered.
+	MasteredInterval = 21
+	// MaxExtractedItems is the maximum number of words extracted from a message.
+	MaxExtractedItems = 8
+	// MaxTokens is the maximum length of the extraction, in tokens.
+	MaxTokens = 600
+)
+
+// ErrInvalidExtraction is returned when the language model doesn't answer with a JSON array of words.
+var ErrInvalidExtraction = errors.New("the extracted vocabulary is not a JSON array")
+
+// NewItem returns a new flashcard of the user, due immediately.
+func NewItem(userID uuid.UUID, input models.VocabularyInput, source string, now time.Time) models.VocabularyItem {
+	item := models.VocabularyItem{
+		UserID:      userID,
+		Language:    input.Language,
+		Term:        strings.TrimSpace(input.Term),
+		Translation: strings.TrimSpace(input.Translation),
+		Example:     strings.TrimSpace(input.Example),
+		Source:      source,
+		EaseFactor:  models.DefaultEaseFactor,
+		DueAt:       now,
+	}
+	if input.MessageID != nil {
+		item.MessageID = uuid.NullUUID{UUID: *input.MessageID, Valid: true}
+	}
+	return item
+}
+
+// Review schedules the next review of the item after a recall graded from 0 (forgotten) to 5 (perfect recall).
+// A failed recall starts the repetitions over, to be reviewed the next day.
+func Review(item *models.VocabularyItem, grade int, now time.Time) {
+	if grade >= PassingGrade {
+		switch item.Repetitions {
+		case 0:
+			item.IntervalDays = 1
+		case 1:
+			item.IntervalDays = 6
+		default:
+			item.IntervalDays = int(math.Round(float64(item.IntervalDays) * item.EaseFactor))
+		}
+		item.Repetitions++
+	} else {
+		item.Repetitions = 0
+		item.IntervalDays = 1
+	}
+
+	missed := float64(5 - grade)
+	item.EaseFactor = math.Max(MinEaseFactor, item.EaseFactor+0.1-missed*(0.08+missed*0.02))
+	item.DueAt = now.AddDate(0, 0, item.IntervalDays)
+	item.LastReviewedAt.Time, item.LastReviewedAt.Valid = now, true
+}
+
+// NewExtractionRequest returns the request asking the language model for the words worth learning in a message
+// written in the language, translated in the native language of the learner.
+func NewExtractionRequest(content string, language string, nativeLanguage string) llm.Request {
+	if nativeLanguage == "" {
+		nativeLanguage = models.DefaultNativeLanguage
+	}
+	temperature := 0.0
+	return llm.Request{
+		MaxTokens:   MaxTokens,
+		Temperature: &temperature,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: fmt.Sprintf("You help a learner of the language with the code %q build their "+
+				"vocabulary. Pick at most %d words or idiomatic phrases of the message worth learning, in their dictionary "+
+				"form, leaving out names and the most basic words. Answer only with a JSON array of objects with the fields "+
+				"term, translation, in the language with the code %q, and example, a short sentence of the message using the term.",
+				language, MaxExtractedItems, nativeLanguage)},
+			{Role: llm.RoleUser, Content: content},
+		},
+	}
+}
+
+// ParseExtraction reads the words answered by the language model. The invalid words are left out.
+func ParseExtraction(answer string, language string) ([]models.VocabularyInput, error) {
+	start, end := strings.Index(answer, "["), strings.LastIndex(answer, "]")
+	if start < 0 || end < start {
+		return nil, ErrInvalidExtraction
+	}
+	var candidates []models.VocabularyInput
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &candidates); err != nil {
+		return nil, errors.Join(ErrInvalidExtraction, err)
+	}
+
+	inputs := make([]models.VocabularyInput, 0, len(candidates))
+	for _, input := range candidates {
+		input.Language, input.MessageID = language, nil
+		if input.Validate() == nil && len(inputs) < MaxExtractedItems {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs, nil
+}
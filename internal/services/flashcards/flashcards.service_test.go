@@ -0,0 +1,79 @@
+package flashcards
+
+import (
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewItem(t *testing.T) {
+	now := time.Now()
+	userID, messageID := uuid.New(), uuid.New()
+	item := NewItem(userID, models.VocabularyInput{
+		Language:    "es",
+		Term:        " echar de menos ",
+		Translation: "to miss",
+		MessageID:   &messageID,
+	}, models.VocabularySourceExtracted, now)
+
+	assert.Equal(t, userID, item.UserID)
+	assert.Equal(t, "echar de menos", item.Term)
+	assert.Equal(t, models.VocabularySourceExtracted, item.Source)
+	assert.Equal(t, models.DefaultEaseFactor, item.EaseFactor)
+	assert.Equal(t, now, item.DueAt)
+	assert.Equal(t, uuid.NullUUID{UUID: messageID, Valid: true}, item.MessageID)
+}
+
+func TestReview(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 10, 0, 0, 0, time.UTC)
+	item := models.VocabularyItem{EaseFactor: models.DefaultEaseFactor}
+
+	tests := []struct {
+		grade       int
+		interval    int
+		repetitions int
+		easeFactor  float64
+	}{
+		{grade: 4, interval: 1, repetitions: 1, easeFactor: 2.5},
+		{grade: 5, interval: 6, repetitions: 2, easeFactor: 2.6},
+		{grade: 3, interval: 16, repetitions: 3, easeFactor: 2.46},
+		{grade: 1, interval: 1, repetitions: 0, easeFactor: 1.92},
+		{grade: 0, interval: 1, repetitions: 0, easeFactor: 1.3},
+		{grade: 4, interval: 1, repetitions: 1, easeFactor: 1.3},
+	}
+
+	for _, test := range tests {
+		Review(&item, test.grade, now)
+		assert.Equal(t, test.interval, item.IntervalDays)
+		assert.Equal(t, test.repetitions, item.Repetitions)
+		assert.InDelta(t, test.easeFactor, item.EaseFactor, 0.0001)
+		assert.Equal(t, now.AddDate(0, 0, test.interval), item.DueAt)
+		assert.Equal(t, now, item.LastReviewedAt.Time)
+	}
+}
+
+func TestNewExtractionRequest(t *testing.T) {
+	request := NewExtractionRequest("Te echo de menos", "es", "fr")
+	assert.Equal(t, MaxTokens, request.MaxTokens)
+	assert.Contains(t, request.Messages[0].Content, `language with the code "es"`)
+	assert.Contains(t, request.Messages[0].Content, `translation, in the language with the code "fr"`)
+	assert.Equal(t, "Te echo de menos", request.Messages[1].Content)
+}
+
+func TestParseExtraction(t *testing.T) {
+	inputs, err := ParseExtraction(`Words: [{"term": "echar de menos", "translation": "manquer", "example": "Te echo de menos."},`+
+		`{"term": "", "translation": "vide"}, {"term": "perro", "translation": "chien", "language": "en"}]`, "es")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.VocabularyInput{
+		{Language: "es", Term: "echar de menos", Translation: "manquer", Example: "Te echo de menos."},
+		{Language: "es", Term: "perro", Translation: "chien"},
+	}, inputs)
+
+	_, err = ParseExtraction("No word to learn.", "es")
+	assert.ErrorIs(t, err, ErrInvalidExtraction)
+	_, err = ParseExtraction(`[{"term": 3}]`, "es")
+	assert.ErrorIs(t, err, ErrInvalidExtraction)
+}
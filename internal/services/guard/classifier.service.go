@@ -0,0 +1,62 @@
+package guard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// classifierTimeout bounds a call to the classifier, which delays the reply.
+const classifierTimeout = 5 * time.Second
+
+// HTTPClassifier is a Classifier calling an external scoring endpoint, such as a prompt injection detection model
+// served over HTTP. The endpoint receives {"text": "..."} and answers {"score": 0.97}.
+type HTTPClassifier struct {
+	URL    string       // URL is the endpoint scoring the texts.
+	Client *http.Client // Client is the HTTP client used to reach the endpoint.
+}
+
+// NewHTTPClassifier returns an HTTPClassifier calling the endpoint.
+func NewHTTPClassifier(url string) *HTTPClassifier {
+	return &HTTPClassifier{URL: url, Client: &http.Client{Timeout: classifierTimeout}}
+}
+
+// Score implements Classifier.
+func (c *HTTPClassifier) Score(ctx context.Context, text string) (float64, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return 0, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("injection classifier returned status %d", response.StatusCode)
+	}
+	var result struct {
+		Score *float64 `json:"score"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	if result.Score == nil || *result.Score < 0 || *result.Score > 1 {
+		return 0, errors.New("injection classifier returned an invalid score")
+	}
+	return *result.Score, nil
+}
@@ -0,0 +1,42 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClassifier_Score(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch body["text"] {
+		case "attack":
+			_, _ = w.Write([]byte(`{"score": 0.97}`))
+		case "invalid":
+			_, _ = w.Write([]byte(`{"score": 2}`))
+		case "missing":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	classifier := NewHTTPClassifier(server.URL)
+	ctx := context.Background()
+
+	score, err := classifier.Score(ctx, "attack")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.97, score)
+
+	_, err = classifier.Score(ctx, "invalid")
+	assert.Error(t, err)
+	_, err = classifier.Score(ctx, "missing")
+	assert.Error(t, err)
+	_, err = classifier.Score(ctx, "down")
+	assert.Error(t, err)
+}
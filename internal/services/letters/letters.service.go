@@ -0,0 +1,170 @@
+// Package letters delivers the replies of the pen pals in the conversations in letters mode once their delivery time
+// is reached, and notifies the learners. The schedule is read from the database at every poll, so a letter due while
+// the server was down is delivered once it is back up, and a letter whose delivery was interrupted is claimed again
+// once its lease expires. The letters are claimed with a conditional update, so several servers can run the worker.
+package letters
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"gorm.io/gorm"
+)
+
+// errInterrupted is recorded on the letters whose delivery was interrupted more often than allowed.
+var errInterrupted = errors.New("the delivery was interrupted too many times")
+
+// Deliverer generates the reply of the pen pal to a letter. It is implemented by *chat.Service.
+type Deliverer interface {
+	Deliver(ctx context.Context, db *gorm.DB, letter models.Letter) (*chat.Exchange, models.ExchangeResponse, error)
+}
+
+// Options configures the polling and the retries of a Worker.
+type Options struct {
+	PollInterval time.Duration // PollInterval is the time between two lookups of the due letters, 0 disables the worker.
+	BatchSize    int           // BatchSize is the largest number of letters delivered at each poll.
+	Lease        time.Duration // Lease bounds the delivery of a letter, after which it can be claimed again.
+	MaxAttempts  int           // MaxAttempts is the number of times the delivery of a letter is tried before it fails, at least 1.
+	RetryDelay   time.Duration // RetryDelay is multiplied by the number of attempts to postpone a failed delivery.
+}
+
+// Worker delivers the due letters and notifies their learners.
+type Worker struct {
+	deliverer Deliverer
+	options   Options
+	notifiers []Notifier
+}
+
+// NewWorker returns a Worker delivering the letters with the deliverer and announcing them with the notifiers.
+func NewWorker(deliverer Deliverer, options Options, notifiers ...Notifier) *Worker {
+	options.MaxAttempts = max(options.MaxAttempts, 1)
+	return &Worker{deliverer: deliverer, options: options, notifiers: notifiers}
+}
+
+// Run delivers the due letters every poll interval, until ctx is done.
+// It is meant to run in its own goroutine.
+func (w *Worker) Run(ctx context.Context, db *gorm.DB) {
+	if w.options.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.options.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.DeliverDue(ctx, db, time.Now()); err != nil {
+			log.Printf("could not deliver the due letters: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue delivers the pending letters whose delivery time is reached, and the letters whose lease expired, oldest
+// first. It returns the number of letters delivered.
+func (w *Worker) DeliverDue(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	var due []models.Letter
+	if err := db.Where("(status = ? AND deliver_at <= ?) OR (status = ? AND claimed_at <= ?)",
+		models.LetterStatusPending, now, models.LetterStatusSending, now.Add(-w.options.Lease)).
+		Order("deliver_at").
+		Limit(max(w.options.BatchSize, 1)).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, letter := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		if letter.Attempts >= w.options.MaxAttempts {
+			w.fail(db, letter, errInterrupted, now)
+			continue
+		}
+		claimed, err := w.claim(db, &letter, now)
+		if err != nil {
+			return delivered, err
+		}
+		if claimed && w.deliver(ctx, db, letter, now) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// claim marks the letter as being delivered. It returns false if another worker claimed it, or if it was cancelled,
+// since it was read.
+func (w *Worker) claim(db *gorm.DB, letter *models.Letter, now time.Time) (bool, error) {
+	result := db.Model(&models.Letter{}).
+		Where("id = ? AND status = ? AND attempts = ?", letter.ID, letter.Status, letter.Attempts).
+		Updates(map[string]interface{}{
+			"status":     models.LetterStatusSending,
+			"attempts":   letter.Attempts + 1,
+			"claimed_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	letter.Status = models.LetterStatusSending
+	letter.Attempts++
+	return result.RowsAffected == 1, nil
+}
+
+// deliver generates the reply to the claimed letter and notifies its learner. A failed delivery is retried later, or
+// the letter fails. It returns whether the letter was delivered.
+func (w *Worker) deliver(ctx context.Context, db *gorm.DB, letter models.Letter, now time.Time) bool {
+	if w.options.Lease > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.options.Lease)
+		defer cancel()
+	}
+	exchange, response, err := w.deliverer.Deliver(ctx, db, letter)
+	if errors.Is(err, chat.ErrLeaseLost) {
+		log.Printf("could not deliver the letter %s: %v", letter.ID, err)
+		return false
+	} else if err != nil {
+		w.fail(db, letter, err, now)
+		return false
+	}
+
+	delivery := Delivery{
+		Letter:       letter,
+		Conversation: exchange.Conversation,
+		Learner:      exchange.Learner,
+		PenPal:       exchange.Persona.Name,
+		Exchange:     response,
+	}
+	for _, notifier := range w.notifiers {
+		if err := notifier.Notify(delivery); err != nil {
+			log.Printf("could not notify the delivery of the letter %s: %v", letter.ID, err)
+		}
+	}
+	return true
+}
+
+// fail postpones the delivery of the letter, or marks it failed if it can't succeed or was tried too many times.
+// Nothing is recorded if the letter was claimed again, or cancelled, since it was read.
+func (w *Worker) fail(db *gorm.DB, letter models.Letter, cause error, now time.Time) {
+	log.Printf("could not deliver the letter %s: %v", letter.ID, cause)
+	updates := map[string]interface{}{"status": models.LetterStatusFailed, "last_error": cause.Error()}
+	if letter.Attempts < w.options.MaxAttempts && retryable(cause) {
+		updates["status"] = models.LetterStatusPending
+		updates["deliver_at"] = now.Add(w.options.RetryDelay * time.Duration(letter.Attempts))
+	}
+	if err := db.Model(&models.Letter{}).
+		Where("id = ? AND status = ? AND attempts = ?", letter.ID, letter.Status, letter.Attempts).
+		Updates(updates).Error; err != nil {
+		log.Printf("could not record the failed delivery of the letter %s: %v", letter.ID, err)
+	}
+}
+
+// retryable reports whether a later delivery of a letter could succeed after the error.
+func retryable(err error) bool {
+	return !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, chat.ErrPersonaUnavailable) && !errors.Is(err, errInterrupted)
+}
@@ -0,0 +1,213 @@
+package letters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/chat"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const (
+	queryDue    = `SELECT * FROM "letters" WHERE (status = $1 AND deliver_at <= $2) OR (status = $3 AND claimed_at <= $4) ORDER BY deliver_at LIMIT $5`
+	updateClaim = `UPDATE "letters" SET "attempts"=$1,"claimed_at"=$2,"status"=$3 WHERE id = $4 AND status = $5 AND attempts = $6`
+)
+
+// fakeDeliverer answers every letter, or fails with its error.
+type fakeDeliverer struct {
+	err       error
+	delivered []models.Letter
+}
+
+func (d *fakeDeliverer) Deliver(ctx context.Context, db *gorm.DB, letter models.Letter) (*chat.Exchange, models.ExchangeResponse, error) {
+	if d.err != nil {
+		return nil, models.ExchangeResponse{}, d.err
+	}
+	d.delivered = append(d.delivered, letter)
+	exchange := &chat.Exchange{
+		Conversation: models.Conversation{ID: letter.ConversationID, Title: "Cartas"},
+		Learner:      models.User{ID: letter.UserID},
+		Persona:      models.Persona{Name: "Lucía"},
+	}
+	return exchange, models.ExchangeResponse{Reply: models.MessageResponse{Content: "Querido John"}}, nil
+}
+
+// recordingNotifier records the deliveries it is told about.
+type recordingNotifier struct {
+	deliveries []Delivery
+}
+
+func (n *recordingNotifier) Notify(delivery Delivery) error {
+	n.deliveries = append(n.deliveries, delivery)
+	return errors.New("the notification is only recorded")
+}
+
+func letterRows(letters ...models.Letter) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "conversation_id", "user_id", "message_id", "status", "deliver_at", "attempts", "claimed_at"})
+	for _, letter := range letters {
+		rows.AddRow(letter.ID, letter.ConversationID, letter.UserID, letter.MessageID, letter.Status, letter.DeliverAt, letter.Attempts, letter.ClaimedAt)
+	}
+	return rows
+}
+
+func TestWorker_DeliverDue(t *testing.T) {
+	now := time.Now()
+	options := Options{BatchSize: 10, Lease: 5 * time.Minute, MaxAttempts: 3, RetryDelay: time.Minute}
+	pending := models.Letter{ID: uuid.New(), ConversationID: uuid.New(), UserID: uuid.New(), MessageID: uuid.New(),
+		Status: models.LetterStatusPending, DeliverAt: now.Add(-time.Minute)}
+	stale := models.Letter{ID: uuid.New(), ConversationID: uuid.New(), UserID: uuid.New(), MessageID: uuid.New(),
+		Status: models.LetterStatusSending, DeliverAt: now.Add(-time.Hour), Attempts: 1,
+		ClaimedAt: sql.NullTime{Time: now.Add(-10 * time.Minute), Valid: true}}
+	exhausted := stale
+	exhausted.Attempts = 3
+
+	tests := []struct {
+		name              string
+		letter            models.Letter
+		deliveryError     error
+		setupMock         func(mock sqlmock.Sqlmock, letter models.Letter)
+		expectedDelivered int
+	}{
+		{
+			name:   "Pending letter",
+			letter: pending,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).
+					WithArgs(1, now, models.LetterStatusSending, letter.ID, models.LetterStatusPending, 0).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedDelivered: 1,
+		},
+		{
+			name:   "Letter whose lease expired",
+			letter: stale,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).
+					WithArgs(2, now, models.LetterStatusSending, letter.ID, models.LetterStatusSending, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedDelivered: 1,
+		},
+		{
+			name:   "Letter claimed by another worker",
+			letter: pending,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:          "Reply retried later",
+			letter:        pending,
+			deliveryError: chat.ErrNoReply,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters" SET "deliver_at"=$1,"last_error"=$2,"status"=$3 WHERE id = $4 AND status = $5 AND attempts = $6`)).
+					WithArgs(now.Add(time.Minute), chat.ErrNoReply.Error(), models.LetterStatusPending, letter.ID, models.LetterStatusSending, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:          "Pen pal unavailable",
+			letter:        pending,
+			deliveryError: chat.ErrPersonaUnavailable,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters" SET "last_error"=$1,"status"=$2 WHERE id = $3 AND status = $4 AND attempts = $5`)).
+					WithArgs(chat.ErrPersonaUnavailable.Error(), models.LetterStatusFailed, letter.ID, models.LetterStatusSending, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:          "Letter claimed again during its delivery",
+			letter:        pending,
+			deliveryError: chat.ErrLeaseLost,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(updateClaim)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "Delivery interrupted too many times",
+			letter: exhausted,
+			setupMock: func(mock sqlmock.Sqlmock, letter models.Letter) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "letters" SET "last_error"=$1,"status"=$2 WHERE id = $3 AND status = $4 AND attempts = $5`)).
+					WithArgs(errInterrupted.Error(), models.LetterStatusFailed, letter.ID, models.LetterStatusSending, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			mock.ExpectQuery(regexp.QuoteMeta(queryDue)).
+				WithArgs(models.LetterStatusPending, now, models.LetterStatusSending, now.Add(-options.Lease), 10).
+				WillReturnRows(letterRows(tt.letter))
+			tt.setupMock(mock, tt.letter)
+			deliverer := &fakeDeliverer{err: tt.deliveryError}
+			notifier := &recordingNotifier{}
+
+			delivered, err := NewWorker(deliverer, options, notifier).DeliverDue(context.Background(), database, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDelivered, delivered)
+			assert.Len(t, deliverer.delivered, tt.expectedDelivered)
+			assert.Len(t, notifier.deliveries, tt.expectedDelivered)
+			if tt.expectedDelivered > 0 {
+				assert.Equal(t, "Lucía", notifier.deliveries[0].PenPal)
+				assert.Equal(t, tt.letter.ConversationID, notifier.deliveries[0].Conversation.ID)
+				assert.Equal(t, models.LetterStatusSending, deliverer.delivered[0].Status)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestNewWorker(t *testing.T) {
+	assert.Equal(t, 1, NewWorker(&fakeDeliverer{}, Options{}).options.MaxAttempts)
+	assert.Equal(t, 3, NewWorker(&fakeDeliverer{}, Options{MaxAttempts: 3}).options.MaxAttempts)
+}
+
+func TestWorker_DeliverDueError(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "letters"`)).WillReturnError(errors.New("connection refused"))
+
+	_, err := NewWorker(&fakeDeliverer{}, Options{}).DeliverDue(context.Background(), database, time.Now())
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWorker_RunDisabled(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+
+	NewWorker(&fakeDeliverer{}, Options{}).Run(context.Background(), database)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,56 @@
+package letters
+
+import (
+	"fmt"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/mailer"
+	"github.com/enzo-gbd/GBA/internal/services/realtime"
+)
+
+// Delivery is a letter answered by the pen pal.
+type Delivery struct {
+	Letter       models.Letter           // Letter delivered
+	Conversation models.Conversation     // Conversation the letter belongs to
+	Learner      models.User             // Learner who wrote the letter
+	PenPal       string                  // Name of the pen pal, empty for the conversations without one
+	Exchange     models.ExchangeResponse // Message of the learner and reply of the pen pal
+}
+
+// Notifier is implemented by every way of telling the learners that their letter was answered.
+type Notifier interface {
+	// Notify tells the learner of the delivery that their letter was answered.
+	Notify(delivery Delivery) error
+}
+
+// HubNotifier publishes a "letter.delivered" event to the open WebSocket connections of the learner.
+type HubNotifier struct {
+	Hub *realtime.Hub // Hub is the hub of the WebSocket connections.
+}
+
+// Notify publishes the message of the learner and the reply to the connections of the learner, if any.
+func (n HubNotifier) Notify(delivery Delivery) error {
+	event := realtime.NewEvent(realtime.TypeDelivered)
+	event.ConversationID = &delivery.Conversation.ID
+	event.Data = delivery.Exchange
+	n.Hub.Publish(delivery.Learner.ID, event)
+	return nil
+}
+
+// MailNotifier emails the learner a link to the conversation.
+type MailNotifier struct {
+	Mailer  mailer.Mailer // Mailer sends the emails.
+	BaseURL string        // BaseURL is the address of the web application.
+}
+
+// Notify emails the learner of the delivery.
+func (n MailNotifier) Notify(delivery Delivery) error {
+	penPal := delivery.PenPal
+	if penPal == "" {
+		penPal = "Your pen pal"
+	}
+	subject := fmt.Sprintf("%s answered your letter", penPal)
+	body := fmt.Sprintf("Hello %s,\n\n%s answered your letter in the conversation \"%s\".\n\nRead the reply: %s/conversations/%s",
+		delivery.Learner.FirstName, penPal, delivery.Conversation.Title, n.BaseURL, delivery.Conversation.ID)
+	return n.Mailer.Send(delivery.Learner.Email, subject, body)
+}
@@ -0,0 +1,42 @@
+package letters
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMailer struct {
+	to       []string
+	subjects []string
+	bodies   []string
+}
+
+func (m *recordingMailer) Send(to string, subject string, body string) error {
+	m.to = append(m.to, to)
+	m.subjects = append(m.subjects, subject)
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+func TestMailNotifier_Notify(t *testing.T) {
+	mailService := &recordingMailer{}
+	notifier := MailNotifier{Mailer: mailService, BaseURL: "https://localhost"}
+	delivery := Delivery{
+		Conversation: models.Conversation{ID: uuid.New(), Title: "Cartas"},
+		Learner:      models.User{FirstName: "John", Email: "john@mail.pe"},
+		PenPal:       "Lucía",
+	}
+
+	assert.NoError(t, notifier.Notify(delivery))
+	delivery.PenPal = ""
+	assert.NoError(t, notifier.Notify(delivery))
+
+	assert.Equal(t, []string{"john@mail.pe", "john@mail.pe"}, mailService.to)
+	assert.Equal(t, []string{"Lucía answered your letter", "Your pen pal answered your letter"}, mailService.subjects)
+	assert.Contains(t, mailService.bodies[0], "Hello John")
+	assert.Contains(t, mailService.bodies[0], `"Cartas"`)
+	assert.Contains(t, mailService.bodies[0], "https://localhost/conversations/"+delivery.Conversation.ID.String())
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// FakeModel is the model name reported by the FakeProvider.
+const FakeModel = "fake"
+
+// FakeProvider answers without any network call, so the chat can be exercised offline and in tests.
+// Unless Reply or Err is set, it echoes the last user message. Tokens are counted as words.
+type FakeProvider struct {
+	Reply string // Reply is returned as the completion when not empty.
+	Err   error  // Err is returned instead of a completion when not nil.
+
+	mutex    sync.Mutex
+	requests []Request
+}
+
+// NewFakeProvider returns a FakeProvider echoing the last user message.
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{}
+}
+
+// Complete implements Provider.
+func (p *FakeProvider) Complete(ctx context.Context, request Request) (Response, error) {
+	p.mutex.Lock()
+	p.requests = append(p.requests, request)
+	p.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	if p.Err != nil {
+		return Response{}, p.Err
+	}
+
+	content := p.Reply
+	if content == "" {
+		content = "You said: " + lastUserMessage(request.Messages)
+	}
+	usage := Usage{CompletionTokens: len(strings.Fields(content))}
+	for _, message := range request.Messages {
+		usage.PromptTokens += len(strings.Fields(message.Content))
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	model := request.Model
+	if model == "" {
+		model = FakeModel
+	}
+	return Response{Content: content, Model: model, FinishReason: "stop", Usage: usage}, nil
+}
+
+// Stream implements Provider. The reply of Complete is passed to onDelta word by word.
+func (p *FakeProvider) Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error) {
+	response, err := p.Complete(ctx, request)
+	if err != nil {
+		return Response{}, err
+	}
+	for _, delta := range strings.SplitAfter(response.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return Response{}, err
+		}
+		if err := onDelta(delta); err != nil {
+			return Response{}, err
+		}
+	}
+	return response, nil
+}
+
+// Requests returns the requests received so far, oldest first.
+func (p *FakeProvider) Requests() []Request {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]Request(nil), p.requests...)
+}
+
+// lastUserMessage returns the content of the last message written by the learner.
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeProvider_Complete(t *testing.T) {
+	provider := NewFakeProvider()
+	request := Request{Messages: []Message{
+		{Role: RoleSystem, Content: "Reply in Spanish."},
+		{Role: RoleUser, Content: "Hello there"},
+	}}
+
+	response, err := provider.Complete(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, Response{
+		Content:      "You said: Hello there",
+		Model:        FakeModel,
+		FinishReason: "stop",
+		Usage:        Usage{PromptTokens: 5, CompletionTokens: 4, TotalTokens: 9},
+	}, response)
+
+	again, err := provider.Complete(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, response, again)
+	assert.Len(t, provider.Requests(), 2)
+}
+
+func TestFakeProvider_CompleteReply(t *testing.T) {
+	provider := &FakeProvider{Reply: "¡Hola!"}
+
+	response, err := provider.Complete(context.Background(), Request{Model: "gpt-4o", Messages: []Message{{Role: RoleUser, Content: "Hello"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "¡Hola!", response.Content)
+	assert.Equal(t, "gpt-4o", response.Model)
+}
+
+func TestFakeProvider_CompleteError(t *testing.T) {
+	failure := errors.New("unavailable")
+	provider := &FakeProvider{Err: failure}
+
+	_, err := provider.Complete(context.Background(), Request{})
+	assert.ErrorIs(t, err, failure)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = NewFakeProvider().Complete(ctx, Request{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFakeProvider_Stream(t *testing.T) {
+	provider := &FakeProvider{Reply: "¡Hola! ¿Qué tal?"}
+
+	var deltas []string
+	response, err := provider.Stream(context.Background(), Request{}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"¡Hola! ", "¿Qué ", "tal?"}, deltas)
+	assert.Equal(t, "¡Hola! ¿Qué tal?", response.Content)
+
+	stop := errors.New("client disconnected")
+	_, err = provider.Stream(context.Background(), Request{}, func(delta string) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
@@ -0,0 +1,106 @@
+// Package llm provides the language models answering as AI pen pals. Providers share a chat completion
+// interface so the hosted API used in production can be swapped for a deterministic fake offline.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+)
+
+// Supported providers.
+const (
+	ProviderOpenAI = "openai" // ProviderOpenAI talks to an OpenAI-compatible chat completion API.
+	ProviderFake   = "fake"   // ProviderFake answers deterministically without any network call.
+)
+
+// Roles of the messages sent to a provider.
+const (
+	RoleSystem    = "system"    // RoleSystem is the role of the instructions given to the model.
+	RoleUser      = "user"      // RoleUser is the role of the messages written by the learner.
+	RoleAssistant = "assistant" // RoleAssistant is the role of the replies of the model.
+)
+
+// ErrEmptyCompletion is returned when a provider answers without any choice.
+var ErrEmptyCompletion = errors.New("the language model returned no completion")
+
+// Message is a single turn of the chat sent to a provider.
+type Message struct {
+	Role    string `json:"role"`    // Role is the author of the message.
+	Content string `json:"content"` // Content is the text of the message.
+}
+
+// Request describes a chat completion.
+type Request struct {
+	Model       string    // Model overrides the default model of the provider when not empty.
+	Messages    []Message // Messages is the chat so far, oldest first.
+	Temperature *float64  // Temperature overrides the default sampling temperature when not nil.
+	MaxTokens   int       // MaxTokens overrides the default completion length when positive.
+	Stop        []string  // Stop lists the sequences at which the model stops generating.
+}
+
+// Usage reports the tokens consumed by a completion.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`     // PromptTokens is the number of tokens read by the model.
+	CompletionTokens int `json:"completion_tokens"` // CompletionTokens is the number of tokens generated by the model.
+	TotalTokens      int `json:"total_tokens"`      // TotalTokens is the sum of both.
+}
+
+// Response is the result of a chat completion.
+type Response struct {
+	Content      string // Content is the text generated by the model.
+	Model        string // Model is the model that actually answered.
+	FinishReason string // FinishReason tells why the generation stopped, e.g. "stop" or "length".
+	Usage        Usage  // Usage reports the tokens consumed.
+}
+
+// DeltaHandler receives the pieces of a reply as they are generated. Returning an error stops the generation.
+type DeltaHandler func(delta string) error
+
+// Provider is implemented by every language model backend.
+type Provider interface {
+	// Complete generates the next assistant message of the chat.
+	Complete(ctx context.Context, request Request) (Response, error)
+	// Stream generates the next assistant message of the chat, passing each piece of it to onDelta as soon as
+	// it is generated. The returned Response holds the whole message. Cancelling ctx stops the generation.
+	Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error)
+}
+
+// APIError is returned when a provider answers with an unsuccessful status.
+type APIError struct {
+	StatusCode int           // StatusCode is the HTTP status returned by the provider.
+	Message    string        // Message is the error message returned by the provider, if any.
+	RetryAfter time.Duration // RetryAfter is how long the provider asked to wait before calling it again, if it did.
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("language model provider returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("language model provider returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// NewProvider returns the Provider matching the provided configuration.
+func NewProvider(config *configs.Config) (Provider, error) {
+	switch config.LLMProvider {
+	case ProviderOpenAI:
+		temperature := config.LLMTemperature
+		return &OpenAIProvider{
+			BaseURL:     config.LLMBaseURL,
+			APIKey:      config.LLMAPIKey,
+			Model:       config.LLMModel,
+			Temperature: &temperature,
+			MaxTokens:   config.LLMMaxTokens,
+			Client:      &http.Client{Timeout: config.LLMTimeout},
+		}, nil
+	case ProviderFake, "":
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown language model provider %q", config.LLMProvider)
+	}
+}
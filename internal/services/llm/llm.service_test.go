@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider(t *testing.T) {
+	provider, err := NewProvider(&configs.Config{})
+	assert.NoError(t, err)
+	assert.IsType(t, &FakeProvider{}, provider)
+
+	provider, err = NewProvider(&configs.Config{
+		LLMProvider:    ProviderOpenAI,
+		LLMBaseURL:     "http://localhost:8080/v1",
+		LLMAPIKey:      "key",
+		LLMModel:       "gpt-4o-mini",
+		LLMTemperature: 0.7,
+		LLMMaxTokens:   512,
+		LLMTimeout:     time.Minute,
+	})
+	assert.NoError(t, err)
+	openAI, ok := provider.(*OpenAIProvider)
+	assert.True(t, ok)
+	assert.Equal(t, "gpt-4o-mini", openAI.Model)
+	assert.Equal(t, 0.7, *openAI.Temperature)
+	assert.Equal(t, 512, openAI.MaxTokens)
+	assert.Equal(t, time.Minute, openAI.Client.Timeout)
+
+	_, err = NewProvider(&configs.Config{LLMProvider: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestAPIError(t *testing.T) {
+	assert.Equal(t, "language model provider returned status 429", (&APIError{StatusCode: http.StatusTooManyRequests}).Error())
+	assert.Equal(t, "language model provider returned status 401: invalid key",
+		(&APIError{StatusCode: http.StatusUnauthorized, Message: "invalid key"}).Error())
+}
@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider calls a chat completion API compatible with the one of OpenAI, which most hosted
+// and self-hosted model servers implement.
+type OpenAIProvider struct {
+	BaseURL     string       // BaseURL is the root of the API, e.g. "https://api.openai.com/v1".
+	APIKey      string       // APIKey is sent as a bearer token when not empty.
+	Model       string       // Model is used when the request does not name one.
+	Temperature *float64     // Temperature is used when the request does not set one.
+	MaxTokens   int          // MaxTokens is used when the request does not set one.
+	Client      *http.Client // Client is the HTTP client used to reach the API.
+}
+
+// chatCompletionRequest is the body of a chat completion call.
+type chatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+// streamOptions asks the API to report the usage at the end of a stream.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatCompletionResponse is the answer of a chat completion call.
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+// chatCompletionChunk is a server-sent event of a streamed chat completion call.
+type chatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta        Message `json:"delta"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// errorResponse is the answer of the API when a call fails.
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete implements Provider.
+func (p *OpenAIProvider) Complete(ctx context.Context, request Request) (Response, error) {
+	response, err := p.post(ctx, p.body(request))
+	if err != nil {
+		return Response{}, err
+	}
+	defer response.Body.Close()
+
+	var result chatCompletionResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return Response{}, err
+	}
+	if len(result.Choices) == 0 {
+		return Response{}, ErrEmptyCompletion
+	}
+	return Response{
+		Content:      result.Choices[0].Message.Content,
+		Model:        result.Model,
+		FinishReason: result.Choices[0].FinishReason,
+		Usage:        result.Usage,
+	}, nil
+}
+
+// Stream implements Provider. The API sends the reply as server-sent events, each holding a piece of it,
+// followed by the usage and a final "[DONE]" event.
+func (p *OpenAIProvider) Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error) {
+	body := p.body(request)
+	body.Stream = true
+	body.StreamOptions = &streamOptions{IncludeUsage: true}
+
+	response, err := p.post(ctx, body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer response.Body.Close()
+
+	var result Response
+	var content strings.Builder
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, found := strings.CutPrefix(scanner.Text(), "data:")
+		if !found {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return Response{}, err
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			result.FinishReason = chunk.Choices[0].FinishReason
+		}
+		if delta := chunk.Choices[0].Delta.Content; delta != "" {
+			content.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return Response{}, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, err
+	}
+	if content.Len() == 0 {
+		return Response{}, ErrEmptyCompletion
+	}
+	result.Content = content.String()
+	return result, nil
+}
+
+// post sends the chat completion call and returns the response when its status is successful.
+// The caller must close the body of the returned response.
+func (p *OpenAIProvider) post(ctx context.Context, request chatCompletionRequest) (*http.Response, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(p.BaseURL, "/") + "/chat/completions"
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		apiError := &APIError{StatusCode: response.StatusCode, RetryAfter: retryAfter(response.Header.Get("Retry-After"), time.Now())}
+		var result errorResponse
+		if json.NewDecoder(response.Body).Decode(&result) == nil {
+			apiError.Message = result.Error.Message
+		}
+		return nil, apiError
+	}
+	return response, nil
+}
+
+// retryAfter parses the value of a Retry-After header, either a number of seconds or a date.
+// It returns 0 when the header is missing or invalid.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// body fills the defaults of the provider into the request.
+func (p *OpenAIProvider) body(request Request) chatCompletionRequest {
+	body := chatCompletionRequest{
+		Model:       request.Model,
+		Messages:    request.Messages,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Stop:        request.Stop,
+	}
+	if body.Model == "" {
+		body.Model = p.Model
+	}
+	if body.Temperature == nil {
+		body.Temperature = p.Temperature
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = p.MaxTokens
+	}
+	return body
+}
@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStandIn starts a local chat completion endpoint answering "¡Hola!" to requests authenticated with "key".
+// The received bodies are sent to the returned channel.
+func newStandIn(t *testing.T) (*httptest.Server, chan chatCompletionRequest) {
+	bodies := make(chan chatCompletionRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"message": "invalid key"}}`))
+			return
+		}
+		var body chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		bodies <- body
+		_, _ = w.Write([]byte(`{
+			"model": "` + body.Model + `-2024",
+			"choices": [{"message": {"role": "assistant", "content": "¡Hola!"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 2, "total_tokens": 14}
+		}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestOpenAIProvider_Complete(t *testing.T) {
+	server, bodies := newStandIn(t)
+	temperature := 0.7
+	provider := &OpenAIProvider{BaseURL: server.URL + "/v1/", APIKey: "key", Model: "gpt-4o-mini", Temperature: &temperature, MaxTokens: 512}
+
+	response, err := provider.Complete(context.Background(), Request{
+		Messages: []Message{{Role: RoleSystem, Content: "Reply in Spanish."}, {Role: RoleUser, Content: "Hello"}},
+		Stop:     []string{"\n\n"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Response{
+		Content:      "¡Hola!",
+		Model:        "gpt-4o-mini-2024",
+		FinishReason: "stop",
+		Usage:        Usage{PromptTokens: 12, CompletionTokens: 2, TotalTokens: 14},
+	}, response)
+
+	body := <-bodies
+	assert.Equal(t, "gpt-4o-mini", body.Model)
+	assert.Equal(t, 0.7, *body.Temperature)
+	assert.Equal(t, 512, body.MaxTokens)
+	assert.Equal(t, []string{"\n\n"}, body.Stop)
+	assert.Len(t, body.Messages, 2)
+}
+
+func TestOpenAIProvider_CompleteOverrides(t *testing.T) {
+	server, bodies := newStandIn(t)
+	temperature, override := 0.7, 0.0
+	provider := &OpenAIProvider{BaseURL: server.URL + "/v1", APIKey: "key", Model: "gpt-4o-mini", Temperature: &temperature, MaxTokens: 512}
+
+	_, err := provider.Complete(context.Background(), Request{
+		Model:       "gpt-4o",
+		Messages:    []Message{{Role: RoleUser, Content: "Hello"}},
+		Temperature: &override,
+		MaxTokens:   64,
+	})
+	assert.NoError(t, err)
+
+	body := <-bodies
+	assert.Equal(t, "gpt-4o", body.Model)
+	assert.Equal(t, 0.0, *body.Temperature)
+	assert.Equal(t, 64, body.MaxTokens)
+}
+
+func TestOpenAIProvider_CompleteError(t *testing.T) {
+	server, _ := newStandIn(t)
+	provider := &OpenAIProvider{BaseURL: server.URL + "/v1", APIKey: "wrong", Model: "gpt-4o-mini"}
+
+	_, err := provider.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hello"}}})
+	var apiError *APIError
+	assert.True(t, errors.As(err, &apiError))
+	assert.Equal(t, http.StatusUnauthorized, apiError.StatusCode)
+	assert.Equal(t, "invalid key", apiError.Message)
+}
+
+func TestOpenAIProvider_CompleteEmpty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"model": "gpt-4o-mini", "choices": []}`))
+	}))
+	defer server.Close()
+	provider := &OpenAIProvider{BaseURL: server.URL, Model: "gpt-4o-mini"}
+
+	_, err := provider.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hello"}}})
+	assert.ErrorIs(t, err, ErrEmptyCompletion)
+}
+
+// newStreamStandIn starts a local chat completion endpoint streaming "¡Hola! ¿Qué tal?" in three pieces.
+func newStreamStandIn(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body chatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body.Stream || body.StreamOptions == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"model": "gpt-4o-mini-2024", "choices": [{"delta": {"role": "assistant", "content": ""}}]}`,
+			`{"model": "gpt-4o-mini-2024", "choices": [{"delta": {"content": "¡Hola! "}}]}`,
+			`{"model": "gpt-4o-mini-2024", "choices": [{"delta": {"content": "¿Qué "}}]}`,
+			`{"model": "gpt-4o-mini-2024", "choices": [{"delta": {"content": "tal?"}, "finish_reason": "stop"}]}`,
+			`{"model": "gpt-4o-mini-2024", "choices": [], "usage": {"prompt_tokens": 12, "completion_tokens": 5, "total_tokens": 17}}`,
+			`[DONE]`,
+		} {
+			_, _ = w.Write([]byte("data: " + event + "\n\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIProvider_Stream(t *testing.T) {
+	server := newStreamStandIn(t)
+	provider := &OpenAIProvider{BaseURL: server.URL, Model: "gpt-4o-mini"}
+
+	var deltas []string
+	response, err := provider.Stream(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hello"}}},
+		func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"¡Hola! ", "¿Qué ", "tal?"}, deltas)
+	assert.Equal(t, Response{
+		Content:      "¡Hola! ¿Qué tal?",
+		Model:        "gpt-4o-mini-2024",
+		FinishReason: "stop",
+		Usage:        Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17},
+	}, response)
+}
+
+func TestOpenAIProvider_StreamStopped(t *testing.T) {
+	server := newStreamStandIn(t)
+	provider := &OpenAIProvider{BaseURL: server.URL, Model: "gpt-4o-mini"}
+	stop := errors.New("client disconnected")
+
+	calls := 0
+	_, err := provider.Stream(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hello"}}},
+		func(delta string) error {
+			calls++
+			return stop
+		})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 20*time.Second, retryAfter("20", now))
+	assert.Equal(t, 90*time.Second, retryAfter("Wed, 01 May 2024 12:01:30 GMT", now))
+	assert.Equal(t, time.Duration(0), retryAfter("Wed, 01 May 2024 11:00:00 GMT", now))
+	assert.Equal(t, time.Duration(0), retryAfter("soon", now))
+	assert.Equal(t, time.Duration(0), retryAfter("", now))
+}
@@ -0,0 +1,365 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+)
+
+// States of a circuit breaker.
+const (
+	BreakerClosed   = "closed"    // BreakerClosed lets the calls through.
+	BreakerOpen     = "open"      // BreakerOpen refuses the calls until the end of the cooldown.
+	BreakerHalfOpen = "half-open" // BreakerHalfOpen lets a single call through to probe whether the model recovered.
+)
+
+// ErrCircuitOpen is returned when the circuit breakers of every model refuse the call.
+var ErrCircuitOpen = errors.New("the language model is unavailable, its circuit breaker is open")
+
+// ResilienceOptions configures the deadlines, the retries, the circuit breakers and the fallbacks of a
+// ResilientProvider.
+type ResilienceOptions struct {
+	Name             string        // Name is the name of the wrapped provider, which keys the circuit breakers with the model.
+	DefaultModel     string        // DefaultModel is the model of the wrapped provider when the request does not name one.
+	FallbackModels   []string      // FallbackModels are tried in order when the requested model can't answer.
+	CallTimeout      time.Duration // CallTimeout bounds each call to the wrapped provider, 0 disables it.
+	MaxRetries       int           // MaxRetries is the number of times a failed call is retried before falling back.
+	RetryBaseDelay   time.Duration // RetryBaseDelay is the wait before the first retry, doubled at each retry.
+	RetryMaxDelay    time.Duration // RetryMaxDelay bounds the wait between retries, longer Retry-After fall back at once.
+	BreakerThreshold int           // BreakerThreshold is the number of consecutive failures opening a breaker, 0 disables them.
+	BreakerCooldown  time.Duration // BreakerCooldown is how long an open breaker refuses the calls before probing the model.
+}
+
+// BreakerStats reports the state of the circuit breaker of a model.
+type BreakerStats struct {
+	Provider            string     `json:"provider"`             // Provider is the name of the provider
+	Model               string     `json:"model"`                // Model is the model guarded by the breaker
+	State               string     `json:"state"`                // State is either closed, open or half-open
+	ConsecutiveFailures int        `json:"consecutive_failures"` // ConsecutiveFailures is the number of failures since the last success
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`  // OpenedAt is the time the breaker last opened, if it is not closed
+	Calls               int64      `json:"calls"`                // Calls is the number of calls sent to the model
+	Failures            int64      `json:"failures"`             // Failures is the number of calls that failed with a retryable error
+	Rejected            int64      `json:"rejected"`             // Rejected is the number of calls refused by the breaker
+	Trips               int64      `json:"trips"`                // Trips is the number of times the breaker opened
+}
+
+// ResilienceStats counts the calls to the language model since the start of the server.
+type ResilienceStats struct {
+	Since     time.Time      `json:"since"`     // Since is the start of the count
+	Calls     int64          `json:"calls"`     // Calls is the number of completions requested
+	Retries   int64          `json:"retries"`   // Retries is the number of calls retried after a failure
+	Fallbacks int64          `json:"fallbacks"` // Fallbacks is the number of completions answered by a fallback model
+	Failed    int64          `json:"failed"`    // Failed is the number of completions no model could answer
+	Breakers  []BreakerStats `json:"breakers"`  // Breakers reports the circuit breaker of every model called, by model
+}
+
+// breaker is the circuit breaker of a model.
+type breaker struct {
+	stats    BreakerStats
+	openedAt time.Time
+	probing  bool
+}
+
+// ResilientProvider wraps a Provider to bound each call with a deadline, retry the calls failing with a retryable
+// error with an exponential back-off honouring the Retry-After of the provider, stop calling the models failing
+// repeatedly with a circuit breaker per model, and fall back to the next models in order.
+type ResilientProvider struct {
+	provider Provider
+	options  ResilienceOptions
+
+	now   func() time.Time
+	sleep func(ctx context.Context, delay time.Duration) error
+
+	mutex    sync.Mutex
+	stats    ResilienceStats
+	breakers map[string]*breaker
+}
+
+// NewResilientProvider returns a ResilientProvider wrapping the provider.
+func NewResilientProvider(provider Provider, options ResilienceOptions) *ResilientProvider {
+	p := &ResilientProvider{
+		provider: provider,
+		options:  options,
+		now:      time.Now,
+		sleep:    sleep,
+		stats:    ResilienceStats{Since: time.Now()},
+		breakers: map[string]*breaker{},
+	}
+	for _, model := range p.models("") {
+		p.breaker(model)
+	}
+	return p
+}
+
+// NewResilientProviderFromConfig returns a ResilientProvider wrapping the provider built from the configuration.
+func NewResilientProviderFromConfig(provider Provider, config *configs.Config) *ResilientProvider {
+	name, model := config.LLMProvider, config.LLMModel
+	if name == "" || name == ProviderFake {
+		name, model = ProviderFake, FakeModel
+	}
+	var fallbackModels []string
+	for _, fallbackModel := range strings.Split(config.LLMFallbackModels, ",") {
+		if fallbackModel = strings.TrimSpace(fallbackModel); fallbackModel != "" {
+			fallbackModels = append(fallbackModels, fallbackModel)
+		}
+	}
+	return NewResilientProvider(provider, ResilienceOptions{
+		Name:             name,
+		DefaultModel:     model,
+		FallbackModels:   fallbackModels,
+		CallTimeout:      config.LLMTimeout,
+		MaxRetries:       config.LLMMaxRetries,
+		RetryBaseDelay:   config.LLMRetryBaseDelay,
+		RetryMaxDelay:    config.LLMRetryMaxDelay,
+		BreakerThreshold: config.LLMBreakerThreshold,
+		BreakerCooldown:  config.LLMBreakerCooldown,
+	})
+}
+
+// Complete implements Provider.
+func (p *ResilientProvider) Complete(ctx context.Context, request Request) (Response, error) {
+	return p.call(ctx, request, func(ctx context.Context, request Request) (Response, bool, error) {
+		response, err := p.provider.Complete(ctx, request)
+		return response, true, err
+	})
+}
+
+// Stream implements Provider. A call is only retried, or falls back, if no piece of the reply was passed to onDelta,
+// and the deadline bounds the whole generation.
+func (p *ResilientProvider) Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error) {
+	return p.call(ctx, request, func(ctx context.Context, request Request) (Response, bool, error) {
+		started := false
+		response, err := p.provider.Stream(ctx, request, func(delta string) error {
+			started = true
+			return onDelta(delta)
+		})
+		return response, !started, err
+	})
+}
+
+// attempt calls the wrapped provider once. It returns whether the call can be tried again after a failure.
+type attempt func(ctx context.Context, request Request) (Response, bool, error)
+
+// Stats returns the counts of the calls and the state of the circuit breakers.
+func (p *ResilientProvider) Stats() ResilienceStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stats := p.stats
+	stats.Breakers = make([]BreakerStats, 0, len(p.breakers))
+	now := p.now()
+	for _, b := range p.breakers {
+		breakerStats := b.stats
+		if breakerStats.State == BreakerOpen && now.Sub(b.openedAt) >= p.options.BreakerCooldown {
+			breakerStats.State = BreakerHalfOpen
+		}
+		if breakerStats.State != BreakerClosed {
+			openedAt := b.openedAt
+			breakerStats.OpenedAt = &openedAt
+		}
+		stats.Breakers = append(stats.Breakers, breakerStats)
+	}
+	sort.Slice(stats.Breakers, func(i, j int) bool { return stats.Breakers[i].Model < stats.Breakers[j].Model })
+	return stats
+}
+
+// call tries the requested model, then the fallback models, until one of them answers.
+func (p *ResilientProvider) call(ctx context.Context, request Request, attempt attempt) (Response, error) {
+	p.count(&p.stats.Calls)
+	last := ErrCircuitOpen
+	for i, model := range p.models(request.Model) {
+		modelRequest := request
+		if i > 0 {
+			modelRequest.Model = model
+		}
+		response, fallback, err := p.try(ctx, model, modelRequest, attempt)
+		if err == nil {
+			if i > 0 {
+				p.count(&p.stats.Fallbacks)
+			}
+			return response, nil
+		}
+		if !fallback {
+			p.count(&p.stats.Failed)
+			return Response{}, err
+		}
+		if !errors.Is(err, ErrCircuitOpen) || last == ErrCircuitOpen {
+			last = err
+		}
+	}
+	p.count(&p.stats.Failed)
+	return Response{}, last
+}
+
+// try calls a model and retries it while the error is retryable. It returns whether the next model can be tried.
+func (p *ResilientProvider) try(ctx context.Context, model string, request Request, attempt attempt) (Response, bool, error) {
+	b := p.breaker(model)
+	for retry := 0; ; retry++ {
+		if !p.allow(b) {
+			return Response{}, true, fmt.Errorf("%w: %s", ErrCircuitOpen, model)
+		}
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.options.CallTimeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, p.options.CallTimeout)
+		}
+		response, again, err := attempt(callCtx, request)
+		cancel()
+
+		if err != nil && ctx.Err() != nil {
+			p.release(b)
+			return Response{}, false, err
+		}
+		failed := err != nil && Retryable(err)
+		p.record(b, failed)
+		if err == nil {
+			return response, false, nil
+		}
+		if !failed || !again {
+			return Response{}, false, err
+		}
+		if retry >= p.options.MaxRetries {
+			return Response{}, true, err
+		}
+		delay, ok := p.delay(retry, err)
+		if !ok {
+			return Response{}, true, err
+		}
+		if err := p.sleep(ctx, delay); err != nil {
+			return Response{}, false, err
+		}
+		p.count(&p.stats.Retries)
+	}
+}
+
+// models returns the requested model followed by the fallback models, without duplicates.
+func (p *ResilientProvider) models(requested string) []string {
+	if requested == "" {
+		requested = p.options.DefaultModel
+	}
+	models := []string{requested}
+	for _, model := range p.options.FallbackModels {
+		if !slices.Contains(models, model) {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// delay returns the wait before the retry, honouring the Retry-After of the provider. It returns false if the
+// provider asked to wait longer than the largest delay.
+func (p *ResilientProvider) delay(retry int, err error) (time.Duration, bool) {
+	delay := p.options.RetryBaseDelay << min(retry, 16)
+	if p.options.RetryMaxDelay > 0 && delay > p.options.RetryMaxDelay {
+		delay = p.options.RetryMaxDelay
+	}
+	var apiError *APIError
+	if errors.As(err, &apiError) && apiError.RetryAfter > delay {
+		if p.options.RetryMaxDelay > 0 && apiError.RetryAfter > p.options.RetryMaxDelay {
+			return 0, false
+		}
+		delay = apiError.RetryAfter
+	}
+	return delay, true
+}
+
+// breaker returns the circuit breaker of the model, creating it if needed.
+func (p *ResilientProvider) breaker(model string) *breaker {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b, found := p.breakers[model]
+	if !found {
+		b = &breaker{stats: BreakerStats{Provider: p.options.Name, Model: model, State: BreakerClosed}}
+		p.breakers[model] = b
+	}
+	return b
+}
+
+// allow reports whether the breaker lets a call through. An open breaker becomes half-open at the end of its
+// cooldown and lets a single call probe the model.
+func (p *ResilientProvider) allow(b *breaker) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.options.BreakerThreshold <= 0 {
+		return true
+	}
+	if b.stats.State == BreakerOpen && p.now().Sub(b.openedAt) >= p.options.BreakerCooldown {
+		b.stats.State = BreakerHalfOpen
+	}
+	if b.stats.State == BreakerOpen || (b.stats.State == BreakerHalfOpen && b.probing) {
+		b.stats.Rejected++
+		return false
+	}
+	b.probing = b.stats.State == BreakerHalfOpen
+	return true
+}
+
+// record updates the breaker with the outcome of a call. A failure opens the breaker once the threshold is reached,
+// or at once when the call probed the model.
+func (p *ResilientProvider) record(b *breaker, failed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b.stats.Calls++
+	b.probing = false
+	if !failed {
+		b.stats.ConsecutiveFailures = 0
+		b.stats.State = BreakerClosed
+		return
+	}
+	b.stats.Failures++
+	b.stats.ConsecutiveFailures++
+	if p.options.BreakerThreshold > 0 && (b.stats.State == BreakerHalfOpen || b.stats.ConsecutiveFailures >= p.options.BreakerThreshold) {
+		if b.stats.State != BreakerOpen {
+			b.stats.Trips++
+		}
+		b.stats.State = BreakerOpen
+		b.openedAt = p.now()
+	}
+}
+
+// release lets another call probe the model when the call was interrupted by its caller.
+func (p *ResilientProvider) release(b *breaker) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	b.probing = false
+}
+
+// count increments a counter of the stats.
+func (p *ResilientProvider) count(counter *int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	*counter++
+}
+
+// Retryable reports whether a later call could succeed after the error: the provider timed out, was unreachable,
+// rate-limited the call or failed.
+func Retryable(err error) bool {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == http.StatusRequestTimeout || apiError.StatusCode == http.StatusTooManyRequests ||
+			apiError.StatusCode >= http.StatusInternalServerError
+	}
+	var netError net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netError)
+}
+
+// sleep waits for the delay, or until ctx is done.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
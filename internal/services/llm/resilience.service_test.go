@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedProvider fails the calls to a model with the errors scripted for it, in order, then answers them.
+type scriptedProvider struct {
+	errors map[string][]error
+	models []string
+	deltas bool
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, request Request) (Response, error) {
+	model := request.Model
+	if model == "" {
+		model = "primary"
+	}
+	p.models = append(p.models, model)
+	if errs := p.errors[model]; len(errs) > 0 {
+		p.errors[model] = errs[1:]
+		return Response{}, errs[0]
+	}
+	return Response{Content: "¡Hola!", Model: model}, nil
+}
+
+func (p *scriptedProvider) Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error) {
+	if p.deltas {
+		if err := onDelta("¡Ho"); err != nil {
+			return Response{}, err
+		}
+	}
+	return p.Complete(ctx, request)
+}
+
+// newResilient returns a ResilientProvider over the provider recording its waits instead of sleeping.
+func newResilient(provider Provider, options ResilienceOptions) (*ResilientProvider, *[]time.Duration) {
+	options.Name = "openai"
+	options.DefaultModel = "primary"
+	resilient := NewResilientProvider(provider, options)
+	var delays []time.Duration
+	resilient.sleep = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return ctx.Err()
+	}
+	return resilient, &delays
+}
+
+func TestResilientProvider_Complete(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	limited := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}
+	options := ResilienceOptions{
+		FallbackModels: []string{"secondary", "primary"},
+		MaxRetries:     2,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  10 * time.Second,
+	}
+
+	tests := []struct {
+		name           string
+		errors         map[string][]error
+		expectedModels []string
+		expectedDelays []time.Duration
+		expectedModel  string
+		expectedError  error
+	}{
+		{
+			name:           "Answered at once",
+			expectedModels: []string{"primary"},
+			expectedModel:  "primary",
+		},
+		{
+			name:           "Retried with an exponential back-off",
+			errors:         map[string][]error{"primary": {unavailable, unavailable}},
+			expectedModels: []string{"primary", "primary", "primary"},
+			expectedDelays: []time.Duration{time.Second, 2 * time.Second},
+			expectedModel:  "primary",
+		},
+		{
+			name:           "Retried after the delay asked by the provider",
+			errors:         map[string][]error{"primary": {limited}},
+			expectedModels: []string{"primary", "primary"},
+			expectedDelays: []time.Duration{3 * time.Second},
+			expectedModel:  "primary",
+		},
+		{
+			name:           "Fallback once the retries are exhausted",
+			errors:         map[string][]error{"primary": {unavailable, unavailable, unavailable}},
+			expectedModels: []string{"primary", "primary", "primary", "secondary"},
+			expectedDelays: []time.Duration{time.Second, 2 * time.Second},
+			expectedModel:  "secondary",
+		},
+		{
+			name:           "Fallback when the provider asks to wait too long",
+			errors:         map[string][]error{"primary": {&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}}},
+			expectedModels: []string{"primary", "secondary"},
+			expectedModel:  "secondary",
+		},
+		{
+			name:           "Error not retried",
+			errors:         map[string][]error{"primary": {&APIError{StatusCode: http.StatusBadRequest}}},
+			expectedModels: []string{"primary"},
+			expectedError:  &APIError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name: "Every model failing",
+			errors: map[string][]error{
+				"primary":   {unavailable, unavailable, unavailable},
+				"secondary": {unavailable, unavailable, limited},
+			},
+			expectedModels: []string{"primary", "primary", "primary", "secondary", "secondary", "secondary"},
+			expectedDelays: []time.Duration{time.Second, 2 * time.Second, time.Second, 2 * time.Second},
+			expectedError:  limited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.errors == nil {
+				tt.errors = map[string][]error{}
+			}
+			provider := &scriptedProvider{errors: tt.errors}
+			resilient, delays := newResilient(provider, options)
+
+			response, err := resilient.Complete(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "Hola"}}})
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedModel, response.Model)
+			assert.Equal(t, tt.expectedModels, provider.models)
+			assert.Equal(t, tt.expectedDelays, *delays)
+		})
+	}
+}
+
+func TestResilientProvider_CompleteTimeout(t *testing.T) {
+	provider := &blockingProvider{}
+	resilient, _ := newResilient(provider, ResilienceOptions{CallTimeout: 10 * time.Millisecond, MaxRetries: 1})
+
+	_, err := resilient.Complete(context.Background(), Request{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, provider.calls)
+	assert.Equal(t, int64(1), resilient.Stats().Retries)
+}
+
+func TestResilientProvider_CompleteCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resilient, delays := newResilient(&blockingProvider{}, ResilienceOptions{FallbackModels: []string{"secondary"}, MaxRetries: 3})
+
+	_, err := resilient.Complete(ctx, Request{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, *delays)
+	assert.Equal(t, int64(0), resilient.Stats().Breakers[0].Calls)
+}
+
+// blockingProvider answers once its context is done.
+type blockingProvider struct {
+	calls int
+}
+
+func (p *blockingProvider) Complete(ctx context.Context, request Request) (Response, error) {
+	p.calls++
+	<-ctx.Done()
+	return Response{}, ctx.Err()
+}
+
+func (p *blockingProvider) Stream(ctx context.Context, request Request, onDelta DeltaHandler) (Response, error) {
+	return p.Complete(ctx, request)
+}
+
+func TestResilientProvider_Breaker(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusInternalServerError}
+	provider := &scriptedProvider{errors: map[string][]error{"primary": {unavailable, unavailable, unavailable}}}
+	resilient, _ := newResilient(provider, ResilienceOptions{
+		FallbackModels:   []string{"secondary"},
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resilient.now = func() time.Time { return now }
+	request := Request{Messages: []Message{{Role: RoleUser, Content: "Hola"}}}
+
+	for range 3 {
+		response, err := resilient.Complete(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, "secondary", response.Model)
+	}
+	assert.Equal(t, []string{"primary", "secondary", "primary", "secondary", "secondary"}, provider.models)
+
+	stats := resilient.Stats()
+	assert.Equal(t, int64(3), stats.Calls)
+	assert.Equal(t, int64(3), stats.Fallbacks)
+	assert.Equal(t, BreakerStats{Provider: "openai", Model: "primary", State: BreakerOpen, ConsecutiveFailures: 2,
+		OpenedAt: &now, Calls: 2, Failures: 2, Rejected: 1, Trips: 1}, stats.Breakers[0])
+	assert.Equal(t, BreakerClosed, stats.Breakers[1].State)
+
+	// The probe at the end of the cooldown fails and opens the breaker again.
+	now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, resilient.Stats().Breakers[0].State)
+	_, err := resilient.Complete(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerOpen, resilient.Stats().Breakers[0].State)
+	assert.Equal(t, int64(2), resilient.Stats().Breakers[0].Trips)
+
+	// The next probe succeeds and closes the breaker.
+	now = now.Add(time.Minute)
+	response, err := resilient.Complete(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "primary", response.Model)
+	assert.Equal(t, BreakerClosed, resilient.Stats().Breakers[0].State)
+	assert.Nil(t, resilient.Stats().Breakers[0].OpenedAt)
+}
+
+func TestResilientProvider_BreakerOpen(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusBadGateway}
+	provider := &scriptedProvider{errors: map[string][]error{"primary": {unavailable}}}
+	resilient, _ := newResilient(provider, ResilienceOptions{BreakerThreshold: 1, BreakerCooldown: time.Minute})
+
+	_, err := resilient.Complete(context.Background(), Request{})
+	assert.Equal(t, unavailable, err)
+	_, err = resilient.Complete(context.Background(), Request{})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, []string{"primary"}, provider.models)
+	assert.Equal(t, int64(2), resilient.Stats().Failed)
+}
+
+func TestResilientProvider_Stream(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	options := ResilienceOptions{FallbackModels: []string{"secondary"}, MaxRetries: 1, RetryBaseDelay: time.Second}
+
+	// Nothing was streamed, so the call is retried.
+	provider := &scriptedProvider{errors: map[string][]error{"primary": {unavailable}}}
+	resilient, _ := newResilient(provider, options)
+	_, err := resilient.Stream(context.Background(), Request{}, func(delta string) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"primary", "primary"}, provider.models)
+
+	// Part of the reply was streamed, so the call fails.
+	provider = &scriptedProvider{errors: map[string][]error{"primary": {unavailable}}, deltas: true}
+	resilient, _ = newResilient(provider, options)
+	_, err = resilient.Stream(context.Background(), Request{}, func(delta string) error { return nil })
+	assert.Equal(t, unavailable, err)
+	assert.Equal(t, []string{"primary"}, provider.models)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Retryable(&APIError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, Retryable(&APIError{StatusCode: http.StatusServiceUnavailable}))
+	assert.True(t, Retryable(&APIError{StatusCode: http.StatusRequestTimeout}))
+	assert.True(t, Retryable(context.DeadlineExceeded))
+	assert.False(t, Retryable(&APIError{StatusCode: http.StatusUnauthorized}))
+	assert.False(t, Retryable(ErrEmptyCompletion))
+	assert.False(t, Retryable(errors.New("the learner left")))
+}
+
+func TestNewResilientProviderFromConfig(t *testing.T) {
+	resilient := NewResilientProviderFromConfig(NewFakeProvider(), &configs.Config{
+		LLMProvider:         ProviderOpenAI,
+		LLMModel:            "gpt-4o-mini",
+		LLMFallbackModels:   " gpt-4o , ,gpt-3.5-turbo",
+		LLMTimeout:          time.Minute,
+		LLMBreakerThreshold: 5,
+	})
+	assert.Equal(t, []string{"gpt-4o", "gpt-3.5-turbo"}, resilient.options.FallbackModels)
+	assert.Equal(t, time.Minute, resilient.options.CallTimeout)
+
+	stats := resilient.Stats()
+	assert.Len(t, stats.Breakers, 3)
+	assert.Equal(t, BreakerStats{Provider: ProviderOpenAI, Model: "gpt-3.5-turbo", State: BreakerClosed}, stats.Breakers[0])
+
+	resilient = NewResilientProviderFromConfig(NewFakeProvider(), &configs.Config{LLMModel: "gpt-4o-mini"})
+	assert.Equal(t, []BreakerStats{{Provider: ProviderFake, Model: FakeModel, State: BreakerClosed}}, resilient.Stats().Breakers)
+}
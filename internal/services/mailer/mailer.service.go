@@ -0,0 +1,79 @@
+// Package mailer provides the email delivery used to reach users outside of the API,
+// such as organization invitations.
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/configs"
+)
+
+// Mailer is implemented by every email delivery backend.
+type Mailer interface {
+	// Send delivers a plain text email to a single recipient.
+	Send(to string, subject string, body string) error
+}
+
+// NewMailer returns the Mailer matching the provided configuration.
+// An SMTPMailer is used when an SMTP host is configured, otherwise emails are only logged,
+// which is convenient during local development.
+func NewMailer(config *configs.Config) Mailer {
+	if config.SMTPHost == "" {
+		return LogMailer{}
+	}
+	return SMTPMailer{
+		Host:     config.SMTPHost,
+		Port:     config.SMTPPort,
+		Username: config.SMTPUsername,
+		Password: config.SMTPPassword,
+		From:     config.SMTPFrom,
+	}
+}
+
+// SMTPMailer sends emails through an SMTP server using PLAIN authentication.
+type SMTPMailer struct {
+	Host     string // Host is the SMTP server address.
+	Port     string // Port is the SMTP server port.
+	Username string // Username is used to authenticate against the server.
+	Password string // Password is used to authenticate against the server.
+	From     string // From is the sender address.
+}
+
+// Send delivers the email through the configured SMTP server.
+func (m SMTPMailer) Send(to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	message := buildMessage(m.From, to, subject, body)
+	auth := smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, message); err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+	return nil
+}
+
+// LogMailer writes emails to the standard logger instead of sending them.
+type LogMailer struct{}
+
+// Send logs the email.
+func (LogMailer) Send(to string, subject string, body string) error {
+	log.Printf("email to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// buildMessage formats a RFC 5322 plain text message.
+func buildMessage(from string, to string, subject string, body string) []byte {
+	var builder strings.Builder
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + to + "\r\n")
+	builder.WriteString("Subject: " + subject + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(body)
+	return []byte(builder.String())
+}
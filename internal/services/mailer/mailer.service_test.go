@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"testing"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMailer(t *testing.T) {
+	assert.IsType(t, LogMailer{}, NewMailer(&configs.Config{}))
+	assert.IsType(t, SMTPMailer{}, NewMailer(&configs.Config{SMTPHost: "smtp.localhost"}))
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := SMTPMailer{Host: "smtp.localhost", Port: "25"}
+
+	assert.Error(t, mailer.Send("john@mail.pe\r\nBcc: eve@mail.pe", "subject", "body"))
+	assert.Error(t, mailer.Send("john@mail.pe", "subject\nBcc: eve@mail.pe", "body"))
+}
+
+func TestBuildMessage(t *testing.T) {
+	message := string(buildMessage("from@mail.pe", "to@mail.pe", "Hello", "Body"))
+
+	assert.Contains(t, message, "From: from@mail.pe\r\n")
+	assert.Contains(t, message, "To: to@mail.pe\r\n")
+	assert.Contains(t, message, "Subject: Hello\r\n")
+	assert.Contains(t, message, "\r\n\r\nBody")
+}
+
+func TestLogMailer(t *testing.T) {
+	assert.NoError(t, LogMailer{}.Send("to@mail.pe", "Hello", "Body"))
+}
@@ -0,0 +1,182 @@
+// Package metering meters the calls to the language model: it records the tokens consumed by each call with its
+// estimated cost, and enforces the daily and monthly token quotas of the plan of each user.
+package metering
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Periods of the quotas.
+const (
+	PeriodDay   = "day"   // PeriodDay is the current day, in UTC.
+	PeriodMonth = "month" // PeriodMonth is the current month, in UTC.
+)
+
+// ErrQuotaExceeded is matched by the errors returned when a user has consumed the tokens of a quota.
+var ErrQuotaExceeded = errors.New("the token quota is exhausted")
+
+// QuotaError describes the quota a user has exhausted. It is sent to the clients as is.
+type QuotaError struct {
+	Plan    string    `json:"plan"`     // Plan the quota derives from
+	Period  string    `json:"period"`   // Either day or month
+	Limit   int64     `json:"limit"`    // Number of tokens of the quota
+	Used    int64     `json:"used"`     // Number of tokens consumed during the period
+	ResetAt time.Time `json:"reset_at"` // Timestamp when the period ends and the quota is available again
+}
+
+// Error describes the exhausted quota.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("the %s token quota of the %s plan is exhausted until %s", e.Period, e.Plan, e.ResetAt.Format(time.RFC3339))
+}
+
+// Is makes errors.Is match ErrQuotaExceeded.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Quota is the number of tokens a user can consume per day and per month. 0 is unlimited.
+type Quota struct {
+	Daily   int64
+	Monthly int64
+}
+
+// Meter records the consumption of the users and enforces their quotas.
+type Meter struct {
+	quotas          map[string]Quota
+	promptPrice     float64
+	completionPrice float64
+}
+
+// NewMeter returns the Meter matching the provided configuration.
+func NewMeter(config *configs.Config) *Meter {
+	return &Meter{
+		quotas: map[string]Quota{
+			models.PlanFree:      {Daily: config.UsageFreeDailyTokens, Monthly: config.UsageFreeMonthlyTokens},
+			models.PlanPremium:   {Daily: config.UsagePremiumDailyTokens, Monthly: config.UsagePremiumMonthlyTokens},
+			models.PlanClassroom: {Daily: config.UsageClassroomDailyTokens, Monthly: config.UsageClassroomMonthlyTokens},
+		},
+		promptPrice:     config.UsagePromptPrice,
+		completionPrice: config.UsageCompletionPrice,
+	}
+}
+
+// Plan returns the plan granted to the user by the subscription code they redeemed, or the free plan.
+func Plan(db *gorm.DB, user models.User) (string, error) {
+	if !user.SubscriptionCode.Valid || user.SubscriptionCode.String == "" {
+		return models.PlanFree, nil
+	}
+	var code models.SubscriptionCode
+	if err := db.Where("code = ?", user.SubscriptionCode.String).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.PlanFree, nil
+		}
+		return "", err
+	}
+	return code.Plan, nil
+}
+
+// Check returns a *QuotaError if the user has consumed the tokens of their daily or monthly quota.
+func (m *Meter) Check(db *gorm.DB, user models.User, now time.Time) error {
+	plan, err := Plan(db, user)
+	if err != nil {
+		return err
+	}
+	quota := m.quotas[plan]
+	if quota.Daily == 0 && quota.Monthly == 0 {
+		return nil
+	}
+
+	summary, err := m.summary(db, user.ID, plan, now)
+	if err != nil {
+		return err
+	}
+	for _, period := range []struct {
+		name  string
+		usage models.UsagePeriodResponse
+	}{{PeriodDay, summary.Day}, {PeriodMonth, summary.Month}} {
+		if period.usage.Limit > 0 && period.usage.TotalTokens >= period.usage.Limit {
+			return &QuotaError{
+				Plan:    plan,
+				Period:  period.name,
+				Limit:   period.usage.Limit,
+				Used:    period.usage.TotalTokens,
+				ResetAt: period.usage.ResetAt,
+			}
+		}
+	}
+	return nil
+}
+
+// Summary returns the consumption of the user during the current day and month, with the quotas of their plan.
+func (m *Meter) Summary(db *gorm.DB, user models.User, now time.Time) (models.UsageResponse, error) {
+	plan, err := Plan(db, user)
+	if err != nil {
+		return models.UsageResponse{}, err
+	}
+	return m.summary(db, user.ID, plan, now)
+}
+
+// Cost returns the estimated cost of a call, in US dollars.
+func (m *Meter) Cost(usage llm.Usage) float64 {
+	return (float64(usage.PromptTokens)*m.promptPrice + float64(usage.CompletionTokens)*m.completionPrice) / 1_000_000
+}
+
+// NewRecord returns the record of a call answering a message of the conversation.
+func (m *Meter) NewRecord(conversation models.Conversation, completion llm.Response) models.UsageRecord {
+	record := m.NewUserRecord(conversation.UserID, completion)
+	record.ConversationID = uuid.NullUUID{UUID: conversation.ID, Valid: true}
+	return record
+}
+
+// NewUserRecord returns the record of a call made for the user outside any conversation.
+func (m *Meter) NewUserRecord(userID uuid.UUID, completion llm.Response) models.UsageRecord {
+	return models.UsageRecord{
+		UserID:           userID,
+		Model:            completion.Model,
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		Cost:             m.Cost(completion.Usage),
+	}
+}
+
+// summary returns the consumption of the user during the current day and month, with the quotas of the plan.
+func (m *Meter) summary(db *gorm.DB, userID uuid.UUID, plan string, now time.Time) (models.UsageResponse, error) {
+	now = now.UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	quota := m.quotas[plan]
+
+	day, err := consumption(db, userID, dayStart)
+	if err != nil {
+		return models.UsageResponse{}, err
+	}
+	day.Limit, day.ResetAt = quota.Daily, dayStart.AddDate(0, 0, 1)
+
+	month, err := consumption(db, userID, monthStart)
+	if err != nil {
+		return models.UsageResponse{}, err
+	}
+	month.Limit, month.ResetAt = quota.Monthly, monthStart.AddDate(0, 1, 0)
+
+	return models.UsageResponse{Plan: plan, Day: day, Month: month}, nil
+}
+
+// consumption sums the calls of the user made since the given time.
+func consumption(db *gorm.DB, userID uuid.UUID, since time.Time) (models.UsagePeriodResponse, error) {
+	var period models.UsagePeriodResponse
+	err := db.Model(&models.UsageRecord{}).
+		Select("COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(cost), 0) AS cost").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&period).Error
+	period.TotalTokens = period.PromptTokens + period.CompletionTokens
+	return period, err
+}
@@ -0,0 +1,149 @@
+package metering
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const queryConsumption = `SELECT COUNT(*) AS calls, COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, ` +
+	`COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(cost), 0) AS cost ` +
+	`FROM "usage_records" WHERE user_id = $1 AND created_at >= $2`
+
+var config = &configs.Config{
+	UsageFreeDailyTokens:    100,
+	UsageFreeMonthlyTokens:  1000,
+	UsagePremiumDailyTokens: 0,
+	UsagePromptPrice:        0.15,
+	UsageCompletionPrice:    0.6,
+}
+
+func expectConsumption(mock sqlmock.Sqlmock, promptTokens int, completionTokens int) {
+	mock.ExpectQuery(regexp.QuoteMeta(queryConsumption)).
+		WillReturnRows(sqlmock.NewRows([]string{"calls", "prompt_tokens", "completion_tokens", "cost"}).
+			AddRow(1, promptTokens, completionTokens, 0.001))
+}
+
+func TestPlan(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+
+	plan, err := Plan(database, models.User{})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanFree, plan)
+
+	user := models.User{SubscriptionCode: sql.NullString{String: "ABCD-EFGH", Valid: true}}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "subscription_codes" WHERE code = $1`)).
+		WithArgs("ABCD-EFGH", 1).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.SubscriptionCode{{ID: uuid.New(), Code: "ABCD-EFGH", Plan: models.PlanPremium}}))
+	plan, err = Plan(database, user)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanPremium, plan)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "subscription_codes" WHERE code = $1`)).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.SubscriptionCode{}))
+	plan, err = Plan(database, user)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanFree, plan)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMeter_Check(t *testing.T) {
+	now := time.Date(2026, time.March, 14, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		dayTokens      int
+		monthTokens    int
+		expectedPeriod string
+		expectedReset  time.Time
+	}{
+		{
+			name:        "within the quotas",
+			dayTokens:   40,
+			monthTokens: 400,
+		},
+		{
+			name:           "daily quota exhausted",
+			dayTokens:      100,
+			monthTokens:    400,
+			expectedPeriod: PeriodDay,
+			expectedReset:  time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "monthly quota exhausted",
+			dayTokens:      40,
+			monthTokens:    1200,
+			expectedPeriod: PeriodMonth,
+			expectedReset:  time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			expectConsumption(mock, tt.dayTokens/2, tt.dayTokens-tt.dayTokens/2)
+			expectConsumption(mock, tt.monthTokens/2, tt.monthTokens-tt.monthTokens/2)
+
+			err := NewMeter(config).Check(database, models.User{ID: uuid.New()}, now)
+			if tt.expectedPeriod == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrQuotaExceeded)
+				var quotaErr *QuotaError
+				assert.True(t, errors.As(err, &quotaErr))
+				assert.Equal(t, tt.expectedPeriod, quotaErr.Period)
+				assert.Equal(t, models.PlanFree, quotaErr.Plan)
+				assert.Equal(t, tt.expectedReset, quotaErr.ResetAt)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMeter_CheckUnlimited(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+
+	assert.NoError(t, NewMeter(&configs.Config{}).Check(database, models.User{ID: uuid.New()}, time.Now()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMeter_Summary(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	expectConsumption(mock, 20, 30)
+	expectConsumption(mock, 200, 300)
+
+	summary, err := NewMeter(config).Summary(database, models.User{ID: uuid.New()}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanFree, summary.Plan)
+	assert.Equal(t, int64(50), summary.Day.TotalTokens)
+	assert.Equal(t, int64(100), summary.Day.Limit)
+	assert.Equal(t, int64(500), summary.Month.TotalTokens)
+	assert.Equal(t, int64(1000), summary.Month.Limit)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMeter_NewRecord(t *testing.T) {
+	conversation := models.Conversation{ID: uuid.New(), UserID: uuid.New()}
+	completion := llm.Response{Model: "gpt-4o-mini", Usage: llm.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}}
+
+	record := NewMeter(config).NewRecord(conversation, completion)
+	assert.Equal(t, conversation.UserID, record.UserID)
+	assert.Equal(t, uuid.NullUUID{UUID: conversation.ID, Valid: true}, record.ConversationID)
+	assert.Equal(t, "gpt-4o-mini", record.Model)
+	assert.InDelta(t, 0.45, record.Cost, 1e-9)
+}
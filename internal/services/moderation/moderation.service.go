@@ -0,0 +1,208 @@
+// Package moderation screens the messages of the learners and the replies of the pen pals. Classifiers find the
+// harmful content of a text, with the built-in engine of keyword and regular expression rules and optionally an
+// external classifier, and a policy decides the action taken for each category: letting the content through,
+// flagging it for review, masking the offending words, or blocking it. Under-age accounts get a stricter policy
+// and stricter rules.
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/google/uuid"
+)
+
+// Classifiers available in the configuration, on top of the rules.
+const (
+	ClassifierRules  = "rules"  // ClassifierRules only uses the rules.
+	ClassifierOpenAI = "openai" // ClassifierOpenAI also calls the moderation API of OpenAI.
+)
+
+// WithheldReply replaces the replies of the pen pals blocked by the moderation.
+const WithheldReply = "Sorry, I can't answer that. Let's talk about something else!"
+
+// Finding is a harmful content found in a text.
+type Finding struct {
+	Category string // Category is one of models.ModerationCategories.
+	Start    int    // Start is the offset of the offending words, in runes.
+	End      int    // End is the offset following the offending words, in runes. It is 0 when the whole text is concerned.
+}
+
+// Classifier is implemented by every moderation backend.
+type Classifier interface {
+	// Classify returns the harmful content found in the text. Strict asks for the screening of the under-age accounts.
+	Classify(ctx context.Context, text string, strict bool) ([]Finding, error)
+}
+
+// Policy gives the action taken for each category, one of models.ModerationActions.
+// The categories it doesn't list are flagged.
+type Policy map[string]string
+
+// ParsePolicy reads a policy written as comma separated category:action pairs, e.g. "sexual:block,profanity:redact".
+func ParsePolicy(value string) (Policy, error) {
+	policy := make(Policy)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		category, action, found := strings.Cut(pair, ":")
+		category, action = strings.TrimSpace(category), strings.TrimSpace(action)
+		if !found || !slices.Contains(models.ModerationCategories, category) || !slices.Contains(models.ModerationActions, action) {
+			return nil, fmt.Errorf("invalid moderation policy entry %q", pair)
+		}
+		policy[category] = action
+	}
+	return policy, nil
+}
+
+// action returns the action taken for the category.
+func (p Policy) action(category string) string {
+	if action, found := p[category]; found {
+		return action
+	}
+	return models.ModerationActionFlag
+}
+
+// Verdict is the outcome of the moderation of a text.
+type Verdict struct {
+	Action     string   // Action is the strictest action taken for the findings, empty if nothing was found.
+	Categories []string // Categories are the categories found, sorted.
+	Original   string   // Original is the moderated text.
+	Content    string   // Content is the text to store, with the offending words masked when it is redacted.
+	Strict     bool     // Strict tells whether the policy of the under-age accounts applied.
+}
+
+// Moderated reports whether the text was flagged, redacted or blocked.
+func (v Verdict) Moderated() bool {
+	return v.Action != "" && v.Action != models.ModerationActionAllow
+}
+
+// Blocked reports whether the text was blocked.
+func (v Verdict) Blocked() bool {
+	return v.Action == models.ModerationActionBlock
+}
+
+// Record returns the record of the moderated text, pending review. messageID is null when the text isn't stored.
+func (v Verdict) Record(userID uuid.UUID, conversationID uuid.UUID, messageID uuid.NullUUID, role string) models.ModerationRecord {
+	return models.ModerationRecord{
+		UserID:         userID,
+		ConversationID: conversationID,
+		MessageID:      messageID,
+		Role:           role,
+		Content:        v.Original,
+		Action:         v.Action,
+		Categories:     v.Categories,
+		Strict:         v.Strict,
+		Status:         models.ModerationStatusPending,
+	}
+}
+
+// Moderator screens texts with its classifiers and applies the standard or the strict policy.
+type Moderator struct {
+	classifiers  []Classifier
+	policy       Policy
+	strictPolicy Policy
+	ages         agegate.Policy
+}
+
+// NewModerator returns a Moderator applying the policy, or the strict policy to the accounts the age policy restricts.
+func NewModerator(ages agegate.Policy, policy Policy, strictPolicy Policy, classifiers ...Classifier) *Moderator {
+	return &Moderator{classifiers: classifiers, policy: policy, strictPolicy: strictPolicy, ages: ages}
+}
+
+// NewModeratorFromConfig returns the Moderator matching the provided configuration, nil if the moderation is disabled.
+func NewModeratorFromConfig(config *configs.Config) (*Moderator, error) {
+	if !config.ModerationEnabled {
+		return nil, nil
+	}
+	rules, err := LoadRules(config.ModerationRulesFile)
+	if err != nil {
+		return nil, err
+	}
+	classifiers := []Classifier{NewRulesClassifier(append(DefaultRules(), rules...))}
+	switch config.ModerationClassifier {
+	case ClassifierOpenAI:
+		classifiers = append(classifiers, NewOpenAIClassifier(config))
+	case ClassifierRules, "":
+	default:
+		return nil, fmt.Errorf("unknown moderation classifier %q", config.ModerationClassifier)
+	}
+
+	policy, err := ParsePolicy(config.ModerationActions)
+	if err != nil {
+		return nil, err
+	}
+	strictPolicy, err := ParsePolicy(config.ModerationStrictActions)
+	if err != nil {
+		return nil, err
+	}
+	return NewModerator(agegate.NewPolicy(config), policy, strictPolicy, classifiers...), nil
+}
+
+// IsStrict reports whether the strict policy applies to the user at the given time.
+func (m *Moderator) IsStrict(user models.User, now time.Time) bool {
+	return m.ages.Restrictions(user.Birthday, now).StrictModeration
+}
+
+// Moderate screens the text with every classifier and returns the action the policy takes.
+// A classifier failing is logged and skipped, the others still apply.
+// Redacting content without located words blocks it, as there is nothing to mask.
+func (m *Moderator) Moderate(ctx context.Context, text string, strict bool) Verdict {
+	policy := m.policy
+	if strict {
+		policy = m.strictPolicy
+	}
+
+	verdict := Verdict{Original: text, Content: text, Strict: strict}
+	var masked []Finding
+	for _, classifier := range m.classifiers {
+		findings, err := classifier.Classify(ctx, text, strict)
+		if err != nil {
+			log.Printf("moderation classifier %T failed: %v", classifier, err)
+			continue
+		}
+		for _, finding := range findings {
+			action := policy.action(finding.Category)
+			if action == models.ModerationActionAllow {
+				continue
+			}
+			if action == models.ModerationActionRedact {
+				if finding.End <= finding.Start {
+					action = models.ModerationActionBlock
+				} else {
+					masked = append(masked, finding)
+				}
+			}
+			if !slices.Contains(verdict.Categories, finding.Category) {
+				verdict.Categories = append(verdict.Categories, finding.Category)
+			}
+			if slices.Index(models.ModerationActions, action) > slices.Index(models.ModerationActions, verdict.Action) {
+				verdict.Action = action
+			}
+		}
+	}
+	slices.Sort(verdict.Categories)
+	if verdict.Action == models.ModerationActionRedact {
+		verdict.Content = mask(text, masked)
+	}
+	return verdict
+}
+
+// mask replaces the runes of the findings with asterisks.
+func mask(text string, findings []Finding) string {
+	runes := []rune(text)
+	for _, finding := range findings {
+		for i := max(finding.Start, 0); i < min(finding.End, len(runes)); i++ {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
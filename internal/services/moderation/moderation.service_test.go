@@ -0,0 +1,122 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/agegate"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubClassifier returns the same findings or error for every text.
+type stubClassifier struct {
+	findings []Finding
+	err      error
+}
+
+func (c stubClassifier) Classify(ctx context.Context, text string, strict bool) ([]Finding, error) {
+	return c.findings, c.err
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(" sexual:block, profanity:redact,,")
+	assert.NoError(t, err)
+	assert.Equal(t, Policy{"sexual": "block", "profanity": "redact"}, policy)
+	assert.Equal(t, models.ModerationActionFlag, policy.action(models.ModerationCategoryHate))
+
+	_, err = ParsePolicy("sexual")
+	assert.Error(t, err)
+	_, err = ParsePolicy("gambling:block")
+	assert.Error(t, err)
+	_, err = ParsePolicy("sexual:delete")
+	assert.Error(t, err)
+}
+
+func TestModerator_Moderate(t *testing.T) {
+	policy := Policy{"profanity": "redact", "sexual": "block", "drugs": "allow"}
+	strictPolicy := Policy{"profanity": "block"}
+	moderator := NewModerator(agegate.Policy{}, policy, strictPolicy, NewRulesClassifier(DefaultRules()))
+	ctx := context.Background()
+
+	verdict := moderator.Moderate(ctx, "¡Hola! How are you?", false)
+	assert.False(t, verdict.Moderated())
+	assert.Equal(t, "¡Hola! How are you?", verdict.Content)
+
+	verdict = moderator.Moderate(ctx, "¡Qué shit! Fucking bus.", false)
+	assert.Equal(t, models.ModerationActionRedact, verdict.Action)
+	assert.Equal(t, []string{models.ModerationCategoryProfanity}, verdict.Categories)
+	assert.Equal(t, "¡Qué ****! ******* bus.", verdict.Content)
+	assert.Equal(t, "¡Qué shit! Fucking bus.", verdict.Original)
+
+	verdict = moderator.Moderate(ctx, "Shit, I found porn.", false)
+	assert.True(t, verdict.Blocked())
+	assert.Equal(t, []string{models.ModerationCategoryProfanity, models.ModerationCategorySexual}, verdict.Categories)
+	assert.Equal(t, "Shit, I found porn.", verdict.Content)
+
+	verdict = moderator.Moderate(ctx, "They sell cocaine.", false)
+	assert.False(t, verdict.Moderated())
+
+	verdict = moderator.Moderate(ctx, "Damn, send me a selfie.", false)
+	assert.False(t, verdict.Moderated())
+	verdict = moderator.Moderate(ctx, "Damn, send me a selfie.", true)
+	assert.True(t, verdict.Blocked())
+	assert.True(t, verdict.Strict)
+	assert.Equal(t, []string{models.ModerationCategoryContact, models.ModerationCategoryProfanity}, verdict.Categories)
+}
+
+func TestModerator_ModerateUnlocated(t *testing.T) {
+	classifier := stubClassifier{findings: []Finding{{Category: models.ModerationCategoryProfanity}}}
+	failing := stubClassifier{err: errors.New("unavailable")}
+	moderator := NewModerator(agegate.Policy{}, Policy{"profanity": "redact"}, Policy{}, failing, classifier)
+
+	verdict := moderator.Moderate(context.Background(), "Something rude", false)
+	assert.True(t, verdict.Blocked())
+	assert.Equal(t, "Something rude", verdict.Content)
+}
+
+func TestModerator_IsStrict(t *testing.T) {
+	moderator := NewModerator(agegate.Policy{MinimumAge: 13, ConsentAge: 16}, Policy{}, Policy{})
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, moderator.IsStrict(models.User{Birthday: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)}, now))
+	assert.False(t, moderator.IsStrict(models.User{Birthday: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}, now))
+}
+
+func TestVerdict_Record(t *testing.T) {
+	userID, conversationID := uuid.New(), uuid.New()
+	verdict := Verdict{Action: models.ModerationActionRedact, Categories: []string{"profanity"}, Original: "shit", Content: "****", Strict: true}
+
+	record := verdict.Record(userID, conversationID, uuid.NullUUID{}, models.MessageRoleUser)
+	assert.Equal(t, userID, record.UserID)
+	assert.Equal(t, conversationID, record.ConversationID)
+	assert.False(t, record.MessageID.Valid)
+	assert.Equal(t, "shit", record.Content)
+	assert.Equal(t, models.ModerationActionRedact, record.Action)
+	assert.True(t, record.Strict)
+	assert.Equal(t, models.ModerationStatusPending, record.Status)
+}
+
+func TestNewModeratorFromConfig(t *testing.T) {
+	moderator, err := NewModeratorFromConfig(&configs.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, moderator)
+
+	moderator, err = NewModeratorFromConfig(&configs.Config{ModerationEnabled: true, ModerationActions: "sexual:block"})
+	assert.NoError(t, err)
+	assert.Len(t, moderator.classifiers, 1)
+	assert.Equal(t, Policy{"sexual": "block"}, moderator.policy)
+
+	moderator, err = NewModeratorFromConfig(&configs.Config{ModerationEnabled: true, ModerationClassifier: ClassifierOpenAI})
+	assert.NoError(t, err)
+	assert.Len(t, moderator.classifiers, 2)
+
+	_, err = NewModeratorFromConfig(&configs.Config{ModerationEnabled: true, ModerationClassifier: "other"})
+	assert.Error(t, err)
+	_, err = NewModeratorFromConfig(&configs.Config{ModerationEnabled: true, ModerationStrictActions: "sexual"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/enzo-gbd/GBA/internal/models"
+)
+
+const (
+	// OpenAIThreshold is the lowest score of a category of the moderation API flagging the text.
+	OpenAIThreshold = 0.5
+	// OpenAIStrictThreshold is the lowest score of a category flagging the text of an under-age account.
+	OpenAIStrictThreshold = 0.2
+)
+
+// openAICategories maps the prefixes of the categories of the moderation API to the categories of the moderation.
+var openAICategories = map[string]string{
+	"sexual":     models.ModerationCategorySexual,
+	"hate":       models.ModerationCategoryHate,
+	"harassment": models.ModerationCategoryHarassment,
+	"self-harm":  models.ModerationCategorySelfHarm,
+	"violence":   models.ModerationCategoryViolence,
+	"illicit":    models.ModerationCategoryDrugs,
+}
+
+// OpenAIClassifier is a Classifier calling a moderation API compatible with the one of OpenAI.
+// It scores the whole text, so its findings are never located.
+type OpenAIClassifier struct {
+	BaseURL string       // BaseURL is the root of the API, e.g. "https://api.openai.com/v1".
+	APIKey  string       // APIKey is sent as a bearer token when not empty.
+	Client  *http.Client // Client is the HTTP client used to reach the API.
+}
+
+// moderationResponse is the body answered by the moderation API.
+type moderationResponse struct {
+	Results []struct {
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// NewOpenAIClassifier returns an OpenAIClassifier reaching the API of the language model.
+func NewOpenAIClassifier(config *configs.Config) *OpenAIClassifier {
+	return &OpenAIClassifier{
+		BaseURL: config.LLMBaseURL,
+		APIKey:  config.LLMAPIKey,
+		Client:  &http.Client{Timeout: config.LLMTimeout},
+	}
+}
+
+// Classify returns the categories whose score reaches the threshold, the strict one if strict is set.
+func (c *OpenAIClassifier) Classify(ctx context.Context, text string, strict bool) ([]Finding, error) {
+	body, err := json.Marshal(map[string]string{"input": text})
+	if err != nil {
+		return nil, err
+	}
+	url := strings.TrimRight(c.BaseURL, "/") + "/moderations"
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation API returned status %d", response.StatusCode)
+	}
+	var result moderationResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	threshold := OpenAIThreshold
+	if strict {
+		threshold = OpenAIStrictThreshold
+	}
+	var findings []Finding
+	for _, scores := range result.Results {
+		for name, score := range scores.CategoryScores {
+			prefix, _, _ := strings.Cut(name, "/")
+			if category, found := openAICategories[prefix]; found && score >= threshold {
+				findings = append(findings, Finding{Category: category})
+			}
+		}
+	}
+	return findings, nil
+}
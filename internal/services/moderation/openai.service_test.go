@@ -0,0 +1,39 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAIClassifier_Classify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if r.URL.Path != "/v1/moderations" || r.Header.Get("Authorization") != "Bearer key" ||
+			json.NewDecoder(r.Body).Decode(&body) != nil || body["input"] == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"results": [{"category_scores": {
+			"sexual": 0.01, "sexual/minors": 0.3, "hate": 0.0, "self-harm/intent": 0.7, "illicit": 0.1
+		}}]}`))
+	}))
+	defer server.Close()
+	classifier := &OpenAIClassifier{BaseURL: server.URL + "/v1/", APIKey: "key"}
+
+	findings, err := classifier.Classify(context.Background(), "text", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{{Category: models.ModerationCategorySelfHarm}}, findings)
+
+	findings, err = classifier.Classify(context.Background(), "text", true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Finding{{Category: models.ModerationCategorySelfHarm}, {Category: models.ModerationCategorySexual}}, findings)
+
+	_, err = classifier.Classify(context.Background(), "", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+)
+
+// Rule finds a category of harmful content with a regular expression.
+type Rule struct {
+	Category string         // Category is one of models.ModerationCategories.
+	Pattern  *regexp.Regexp // Pattern matches the offending words.
+	Strict   bool           // Strict restricts the rule to the screening of the under-age accounts.
+}
+
+// RulesClassifier is the built-in Classifier, finding harmful content with keyword and regular expression rules.
+type RulesClassifier struct {
+	rules []Rule
+}
+
+// NewRulesClassifier returns a RulesClassifier applying the rules.
+func NewRulesClassifier(rules []Rule) *RulesClassifier {
+	return &RulesClassifier{rules: rules}
+}
+
+// Classify returns every match of the rules in the text, skipping the strict rules unless strict is set.
+func (c *RulesClassifier) Classify(ctx context.Context, text string, strict bool) ([]Finding, error) {
+	var findings []Finding
+	for _, rule := range c.rules {
+		if rule.Strict && !strict {
+			continue
+		}
+		for _, match := range rule.Pattern.FindAllStringIndex(text, -1) {
+			findings = append(findings, Finding{
+				Category: rule.Category,
+				Start:    utf8.RuneCountInString(text[:match[0]]),
+				End:      utf8.RuneCountInString(text[:match[1]]),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// words returns a case-insensitive pattern matching any of the words, with any ending.
+func words(list ...string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(list, "|") + `)\w*`)
+}
+
+// DefaultRules returns the rules built in the moderation. The strict ones screen the under-age accounts only.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Category: models.ModerationCategoryProfanity, Pattern: words("fuck", "shit", "bitch", "cunt", "motherfuck", "asshole")},
+		{Category: models.ModerationCategoryProfanity, Pattern: words("damn", "crap", "bastard", "bloody hell"), Strict: true},
+		{Category: models.ModerationCategorySexual, Pattern: words("porn", "blowjob", "nude pic", "nudes", "sexting", "dick pic")},
+		{Category: models.ModerationCategorySexual, Pattern: words("sexy", "horny", "naked", "make out"), Strict: true},
+		{Category: models.ModerationCategoryHate, Pattern: words("nigger", "faggot", "retard", "kike", "spic", "chink")},
+		{Category: models.ModerationCategoryViolence, Pattern: regexp.MustCompile(`(?i)\b(?:i(?:'ll| will| am going to| want to) (?:kill|shoot|stab|murder)|bomb threat|mass shooting)\w*`)},
+		{Category: models.ModerationCategorySelfHarm, Pattern: regexp.MustCompile(`(?i)\b(?:kill myself|suicide|suicidal|cut myself|self[- ]harm|end my life|want to die)\w*`)},
+		{Category: models.ModerationCategoryHarassment, Pattern: regexp.MustCompile(`(?i)\b(?:kill yourself|kys|you(?: are|'re) (?:worthless|pathetic|disgusting)|nobody likes you)\b`)},
+		{Category: models.ModerationCategoryDrugs, Pattern: words("cocaine", "heroin", "meth", "buy weed", "sell weed")},
+		{Category: models.ModerationCategoryDrugs, Pattern: words("weed", "vape", "get high", "drunk"), Strict: true},
+		{Category: models.ModerationCategoryContact, Pattern: regexp.MustCompile(`(?i)\b(?:send (?:me )?(?:a )?(?:pic|photo|selfie)s?|meet (?:me )?in person|what(?:'s| is) your (?:address|phone number|snapchat|instagram)|where do you live)\b`), Strict: true},
+	}
+}
+
+// LoadRules reads the rules of the file, one per line: the category, optionally prefixed with "strict:", a space and
+// the regular expression. Empty lines and lines starting with a # are ignored. No file gives no rules.
+func LoadRules(path string) ([]Rule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		category, pattern, found := strings.Cut(line, " ")
+		category, strict := strings.CutPrefix(category, "strict:")
+		if !found || !slices.Contains(models.ModerationCategories, category) {
+			return nil, fmt.Errorf("%s:%d: invalid moderation rule", path, number)
+		}
+		expression, err := regexp.Compile(strings.TrimSpace(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, number, err)
+		}
+		rules = append(rules, Rule{Category: category, Pattern: expression, Strict: strict})
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,58 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRulesClassifier_Classify(t *testing.T) {
+	classifier := NewRulesClassifier(DefaultRules())
+	ctx := context.Background()
+
+	findings, err := classifier.Classify(ctx, "Él dijo: shit!", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{{Category: models.ModerationCategoryProfanity, Start: 9, End: 13}}, findings)
+
+	findings, err = classifier.Classify(ctx, "Where do you live?", false)
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+	findings, err = classifier.Classify(ctx, "Where do you live?", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{{Category: models.ModerationCategoryContact, Start: 0, End: 17}}, findings)
+
+	findings, err = classifier.Classify(ctx, "I'm learning Spanish in Scunthorpe.", true)
+	assert.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# custom rules\n\ndrugs (?i)\\bmarihuana\\b\nstrict:profanity (?i)\\bjoder\\b\n"), 0o600))
+
+	rules, err := LoadRules(path)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, models.ModerationCategoryDrugs, rules[0].Category)
+	assert.False(t, rules[0].Strict)
+	assert.True(t, rules[0].Pattern.MatchString("Marihuana"))
+	assert.Equal(t, models.ModerationCategoryProfanity, rules[1].Category)
+	assert.True(t, rules[1].Strict)
+
+	assert.NoError(t, os.WriteFile(path, []byte("gambling casino\n"), 0o600))
+	_, err = LoadRules(path)
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(path, []byte("drugs (unclosed\n"), 0o600))
+	_, err = LoadRules(path)
+	assert.Error(t, err)
+	_, err = LoadRules(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+
+	rules, err = LoadRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+}
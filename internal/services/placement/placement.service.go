@@ -0,0 +1,172 @@
+// Package placement assesses the CEFR level of the learners with an adaptive test: each task is written by the
+// language model at the level of the learner so far, one level up after a good answer and one level down after a
+// poor one, and graded by the language model. It also gives the level of a learner in a language, used to pitch
+// the replies of the pen pals.
+package placement
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// StartLevel is the level of the first task of a test.
+	StartLevel = "B1"
+	// PassingScore is the lowest score of an answer showing the level of the task, the next task is one level up.
+	PassingScore = 60
+	// FailingScore is the score under which an answer falls short of the level of the task, the next task is one level down.
+	FailingScore = 40
+	// QuestionTokens is the maximum length of a task, in tokens.
+	QuestionTokens = 300
+	// GradingTokens is the maximum length of a grade, in tokens.
+	GradingTokens = 300
+)
+
+var (
+	// ErrInvalidQuestion is returned when the language model doesn't answer with a task.
+	ErrInvalidQuestion = errors.New("the placement question is empty")
+	// ErrInvalidGrade is returned when the language model doesn't answer with a JSON grade between 0 and 100.
+	ErrInvalidGrade = errors.New("the placement grade is not a JSON object with a score between 0 and 100")
+)
+
+// Level returns the CEFR level of the user in the language, empty if unknown.
+func Level(db *gorm.DB, userID uuid.UUID, language string) (string, error) {
+	var languages []models.LearnerLanguage
+	if err := db.Where("user_id = ? AND language = ?", userID, language).Limit(1).Find(&languages).Error; err != nil {
+		return "", err
+	}
+	if len(languages) == 0 {
+		return "", nil
+	}
+	return languages[0].Level, nil
+}
+
+// Place sets the level of the user in the language to the result of a placement test, adding the language to
+// the languages they learn if needed.
+func Place(db *gorm.DB, userID uuid.UUID, language string, level string, now time.Time) error {
+	learnerLanguage := models.LearnerLanguage{
+		UserID:   userID,
+		Language: language,
+		Level:    level,
+		PlacedAt: sql.NullTime{Time: now, Valid: true},
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "placed_at", "updated_at"}),
+	}).Create(&learnerLanguage).Error
+}
+
+// Step returns the level of the task following a task of the level graded with the score.
+func Step(level string, score int) string {
+	index := slices.Index(models.CEFRLevels, level)
+	switch {
+	case index < 0:
+		return StartLevel
+	case score >= PassingScore && index < len(models.CEFRLevels)-1:
+		return models.CEFRLevels[index+1]
+	case score < FailingScore && index > 0:
+		return models.CEFRLevels[index-1]
+	}
+	return level
+}
+
+// Result returns the level assessed by the graded questions: the highest level whose tasks were passed more often
+// than failed, the lowest level if there is none.
+func Result(questions []models.PlacementQuestion) string {
+	balance := make(map[string]int, len(models.CEFRLevels))
+	for _, question := range questions {
+		if question.Score >= PassingScore {
+			balance[question.Level]++
+		} else {
+			balance[question.Level]--
+		}
+	}
+	for i := len(models.CEFRLevels) - 1; i > 0; i-- {
+		if balance[models.CEFRLevels[i]] > 0 {
+			return models.CEFRLevels[i]
+		}
+	}
+	return models.CEFRLevels[0]
+}
+
+// NewQuestionRequest returns the request asking the language model for a task of the level, in the language,
+// explained in the native language of the learner and different from the tasks already asked.
+func NewQuestionRequest(language string, level string, nativeLanguage string, asked []models.PlacementQuestion) llm.Request {
+	if nativeLanguage == "" {
+		nativeLanguage = models.DefaultNativeLanguage
+	}
+	instructions := fmt.Sprintf("You assess the level of a learner of the language with the code %q. Write one short "+
+		"task testing whether they reach the level %s of the CEFR: a question to answer, a sentence to translate, or a "+
+		"situation to react to in a few sentences, answered in writing in the language with the code %q. Explain the "+
+		"task in the language with the code %q. Answer only with the task.", language, level, language, nativeLanguage)
+	if len(asked) > 0 {
+		instructions += "\n\nThe learner already had these tasks, ask something different:"
+		for _, question := range asked {
+			instructions += "\n- " + question.Prompt
+		}
+	}
+	return llm.Request{
+		MaxTokens: QuestionTokens,
+		Messages:  []llm.Message{{Role: llm.RoleSystem, Content: instructions}},
+	}
+}
+
+// ParseQuestion reads the task answered by the language model.
+func ParseQuestion(answer string) (string, error) {
+	question := strings.TrimSpace(answer)
+	if question == "" {
+		return "", ErrInvalidQuestion
+	}
+	return question, nil
+}
+
+// NewGradingRequest returns the request asking the language model to grade the answer of the learner to the task,
+// with a feedback in their native language.
+func NewGradingRequest(language string, question models.PlacementQuestion, nativeLanguage string) llm.Request {
+	if nativeLanguage == "" {
+		nativeLanguage = models.DefaultNativeLanguage
+	}
+	temperature := 0.0
+	return llm.Request{
+		MaxTokens:   GradingTokens,
+		Temperature: &temperature,
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: fmt.Sprintf("You grade the answer of a learner of the language with the code "+
+				"%q to a task of the level %s of the CEFR. Score from 0 to 100 how well the answer shows that level, "+
+				"for its accuracy, its range and its relevance to the task. An answer in another language scores 0. "+
+				"Answer only with a JSON object with the fields score, an integer, and feedback, one sentence in the "+
+				"language with the code %q.\n\nTask:\n%s", language, question.Level, nativeLanguage, question.Prompt)},
+			{Role: llm.RoleUser, Content: question.Answer},
+		},
+	}
+}
+
+// ParseGrade reads the score and the feedback answered by the language model.
+func ParseGrade(answer string) (int, string, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return 0, "", ErrInvalidGrade
+	}
+	var grade struct {
+		Score    *int   `json:"score"`
+		Feedback string `json:"feedback"`
+	}
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &grade); err != nil {
+		return 0, "", errors.Join(ErrInvalidGrade, err)
+	}
+	if grade.Score == nil || *grade.Score < 0 || *grade.Score > 100 {
+		return 0, "", ErrInvalidGrade
+	}
+	return *grade.Score, strings.TrimSpace(grade.Feedback), nil
+}
@@ -0,0 +1,138 @@
+package placement
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevel(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	userID := uuid.New()
+	query := `SELECT * FROM "learner_languages" WHERE user_id = $1 AND language = $2 LIMIT $3`
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(userID, "es", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"level"}).AddRow("B2"))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(userID, "de", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"level"}))
+
+	level, err := Level(database, userID, "es")
+	assert.NoError(t, err)
+	assert.Equal(t, "B2", level)
+	level, err = Level(database, userID, "de")
+	assert.NoError(t, err)
+	assert.Empty(t, level)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPlace(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	userID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "learner_languages"`)+`.*`+
+		regexp.QuoteMeta(`ON CONFLICT ("user_id","language") DO UPDATE SET "level"="excluded"."level","placed_at"="excluded"."placed_at","updated_at"="excluded"."updated_at"`)).
+		WithArgs(sqlmock.AnyArg(), userID, "es", "B1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, Place(database, userID, "es", "B1", time.Now()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStep(t *testing.T) {
+	assert.Equal(t, "B2", Step("B1", PassingScore))
+	assert.Equal(t, "B1", Step("B1", FailingScore))
+	assert.Equal(t, "A2", Step("B1", FailingScore-1))
+	assert.Equal(t, "C2", Step("C2", 100))
+	assert.Equal(t, "A1", Step("A1", 0))
+	assert.Equal(t, StartLevel, Step("", 50))
+}
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		questions []models.PlacementQuestion
+		expected  string
+	}{
+		{
+			name:      "no question passed",
+			questions: []models.PlacementQuestion{{Level: "B1", Score: 20}, {Level: "A2", Score: 30}, {Level: "A1", Score: 10}},
+			expected:  "A1",
+		},
+		{
+			name: "highest level passed",
+			questions: []models.PlacementQuestion{
+				{Level: "B1", Score: 80}, {Level: "B2", Score: 70}, {Level: "C1", Score: 30},
+				{Level: "B2", Score: 65}, {Level: "C1", Score: 45}, {Level: "B2", Score: 90},
+			},
+			expected: "B2",
+		},
+		{
+			name: "level failed as often as passed",
+			questions: []models.PlacementQuestion{
+				{Level: "B1", Score: 80}, {Level: "B2", Score: 70}, {Level: "C1", Score: 30}, {Level: "B2", Score: 20},
+			},
+			expected: "B1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Result(tt.questions))
+		})
+	}
+}
+
+func TestNewQuestionRequest(t *testing.T) {
+	request := NewQuestionRequest("es", "B2", "fr", []models.PlacementQuestion{{Level: "B1", Prompt: "¿Qué hiciste ayer?"}})
+
+	assert.Equal(t, QuestionTokens, request.MaxTokens)
+	assert.Len(t, request.Messages, 1)
+	assert.Contains(t, request.Messages[0].Content, "level B2")
+	assert.Contains(t, request.Messages[0].Content, `"fr"`)
+	assert.Contains(t, request.Messages[0].Content, "¿Qué hiciste ayer?")
+
+	request = NewQuestionRequest("es", "B1", "", nil)
+	assert.Contains(t, request.Messages[0].Content, `"en"`)
+}
+
+func TestParseQuestion(t *testing.T) {
+	question, err := ParseQuestion("  Describe tu ciudad.\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "Describe tu ciudad.", question)
+
+	_, err = ParseQuestion(" \n")
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+}
+
+func TestNewGradingRequest(t *testing.T) {
+	question := models.PlacementQuestion{Level: "B1", Prompt: "¿Qué hiciste ayer?", Answer: "Fui al cine."}
+	request := NewGradingRequest("es", question, "fr")
+
+	assert.Equal(t, 0.0, *request.Temperature)
+	assert.Contains(t, request.Messages[0].Content, "level B1")
+	assert.Contains(t, request.Messages[0].Content, "¿Qué hiciste ayer?")
+	assert.Equal(t, llm.Message{Role: llm.RoleUser, Content: "Fui al cine."}, request.Messages[1])
+}
+
+func TestParseGrade(t *testing.T) {
+	score, feedback, err := ParseGrade("Here is the grade: {\"score\": 72, \"feedback\": \" Bien hecho. \"}")
+	assert.NoError(t, err)
+	assert.Equal(t, 72, score)
+	assert.Equal(t, "Bien hecho.", feedback)
+
+	for _, answer := range []string{"Good job!", `{"feedback": "Bien."}`, `{"score": 120}`, `{"score": "high"}`} {
+		_, _, err = ParseGrade(answer)
+		assert.ErrorIs(t, err, ErrInvalidGrade, answer)
+	}
+}
@@ -0,0 +1,134 @@
+// Package prompts renders the system prompts of the pen pals and picks the version of a prompt template served
+// to a learner: the version a persona is pinned to, or the most recent published version whose rollout includes
+// the learner.
+package prompts
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMissingVariable is returned when a variable required by a template has no value.
+	ErrMissingVariable = errors.New("missing prompt variable")
+	// ErrNoPublishedVersion is returned when a prompt template has no published version to serve.
+	ErrNoPublishedVersion = errors.New("the prompt template has no published version")
+)
+
+// Data holds the variables available in the prompt templates, e.g. {{.Persona.Name}} or {{.LearnerName}}.
+type Data struct {
+	Persona     models.Persona // Persona the learner talks to
+	LearnerName string         // First name of the learner
+	Language    string         // Language the conversation is held in
+	Level       string         // CEFR level of the learner, empty if unknown
+}
+
+// value returns the value of a variable of models.PromptVariables, empty if it has none.
+func (d Data) value(variable string) string {
+	switch variable {
+	case "LearnerName":
+		return d.LearnerName
+	case "Language":
+		return d.Language
+	case "Level":
+		return d.Level
+	case "Persona":
+		return d.Persona.Name
+	}
+	return ""
+}
+
+// Render executes a prompt template with the given data, after checking that every required variable has a value.
+func Render(text string, required []string, data Data) (string, error) {
+	for _, variable := range required {
+		if data.value(variable) == "" {
+			return "", fmt.Errorf("%w: %s", ErrMissingVariable, variable)
+		}
+	}
+
+	tmpl, err := template.New("prompt").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", err
+	}
+	return prompt.String(), nil
+}
+
+// Check renders a prompt template with sample data, reporting the variables that don't exist.
+func Check(text string) error {
+	_, err := Render(text, nil, Data{
+		Persona:     models.Persona{Name: "Lucía", NativeLanguage: "es"},
+		LearnerName: "John",
+		Language:    "es",
+		Level:       "A2",
+	})
+	return err
+}
+
+// Resolve returns the version of the prompt template of the persona served to the user.
+// A persona pinned to a version always gets it. Otherwise, the published versions are tried from the most recent
+// one, and the first whose rollout includes the user is served. The oldest published version serves the users left out.
+func Resolve(db *gorm.DB, persona models.Persona, userID uuid.UUID) (models.PromptTemplate, error) {
+	query := db.Where("name = ? AND status = ?", persona.PromptName, models.PromptStatusPublished)
+	if persona.PromptVersion != "" {
+		query = query.Where("version = ?", persona.PromptVersion)
+	}
+
+	var templates []models.PromptTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return models.PromptTemplate{}, err
+	}
+	if len(templates) == 0 {
+		return models.PromptTemplate{}, ErrNoPublishedVersion
+	}
+	if persona.PromptVersion != "" {
+		return templates[0], nil
+	}
+
+	slices.SortFunc(templates, func(a, b models.PromptTemplate) int {
+		return CompareVersions(b.Version, a.Version)
+	})
+	bucket := Bucket(userID, persona.PromptName)
+	for _, tmpl := range templates {
+		if bucket < tmpl.Rollout {
+			return tmpl, nil
+		}
+	}
+	return templates[len(templates)-1], nil
+}
+
+// Bucket places a user between 0 and 99 for the rollout of a prompt template.
+// A user keeps the same bucket across the versions of a template, so raising a rollout only adds users to it.
+func Bucket(userID uuid.UUID, name string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	hash.Write(userID[:])
+	return int(hash.Sum32() % 100)
+}
+
+// CompareVersions compares two semantic versions, returning -1 if a is older than b, 1 if it is newer and 0 if
+// they are equal.
+func CompareVersions(a string, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numberA, _ := strconv.Atoi(partsA[i])
+		numberB, _ := strconv.Atoi(partsB[i])
+		if c := cmp.Compare(numberA, numberB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package prompts
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/db"
+	"github.com/enzo-gbd/GBA/internal/models"
+	"github.com/enzo-gbd/GBA/internal/utils/testUtils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const queryPublished = `SELECT * FROM "prompt_templates" WHERE name = $1 AND status = $2`
+
+func TestRender(t *testing.T) {
+	prompt, err := Render("Write to {{.LearnerName}} as {{.Persona.Name}}.", []string{"LearnerName"}, Data{LearnerName: "John", Persona: models.Persona{Name: "Lucía"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Write to John as Lucía.", prompt)
+
+	_, err = Render("Write at level {{.Level}}.", []string{"Level"}, Data{LearnerName: "John"})
+	assert.ErrorIs(t, err, ErrMissingVariable)
+
+	_, err = Render("Write to {{.Unknown}}.", nil, Data{})
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check("Write to {{.LearnerName}} in {{.Language}} at level {{.Level}}."))
+	assert.Error(t, Check("Write to {{.Unknown}}."))
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, CompareVersions("1.2.3", "1.2.3"))
+	assert.Equal(t, -1, CompareVersions("1.2.3", "1.10.0"))
+	assert.Equal(t, 1, CompareVersions("2.0.0", "1.99.99"))
+}
+
+func TestBucket(t *testing.T) {
+	userID := uuid.New()
+	bucket := Bucket(userID, "pen-pal")
+	assert.GreaterOrEqual(t, bucket, 0)
+	assert.Less(t, bucket, 100)
+	assert.Equal(t, bucket, Bucket(userID, "pen-pal"))
+}
+
+func TestResolve(t *testing.T) {
+	userID := uuid.New()
+	bucket := Bucket(userID, "pen-pal")
+	persona := models.Persona{PromptName: "pen-pal"}
+	stable := models.PromptTemplate{ID: uuid.New(), Name: "pen-pal", Version: "1.9.0", Status: models.PromptStatusPublished, Rollout: 100}
+	included := models.PromptTemplate{ID: uuid.New(), Name: "pen-pal", Version: "1.10.0", Status: models.PromptStatusPublished, Rollout: bucket + 1}
+	excluded := models.PromptTemplate{ID: uuid.New(), Name: "pen-pal", Version: "1.10.0", Status: models.PromptStatusPublished, Rollout: bucket}
+
+	tests := []struct {
+		name      string
+		templates []models.PromptTemplate
+		expected  models.PromptTemplate
+	}{
+		{
+			name:      "user in the rollout of the new version",
+			templates: []models.PromptTemplate{stable, included},
+			expected:  included,
+		},
+		{
+			name:      "user out of the rollout of the new version",
+			templates: []models.PromptTemplate{stable, excluded},
+			expected:  stable,
+		},
+		{
+			name:      "user out of every rollout",
+			templates: []models.PromptTemplate{excluded},
+			expected:  excluded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, sqlDB, mock := db.InitMockDB()
+			defer sqlDB.Close()
+			mock.ExpectQuery(regexp.QuoteMeta(queryPublished)).
+				WithArgs("pen-pal", models.PromptStatusPublished).
+				WillReturnRows(testUtils.ConvertStructsToSQLMockRows(tt.templates))
+
+			tmpl, err := Resolve(database, persona, userID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected.ID, tmpl.ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestResolvePinned(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	pinned := models.PromptTemplate{ID: uuid.New(), Name: "pen-pal", Version: "1.0.0", Status: models.PromptStatusPublished}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "prompt_templates" WHERE (name = $1 AND status = $2) AND version = $3`)).
+		WithArgs("pen-pal", models.PromptStatusPublished, "1.0.0").
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.PromptTemplate{pinned}))
+
+	tmpl, err := Resolve(database, models.Persona{PromptName: "pen-pal", PromptVersion: "1.0.0"}, uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, pinned.ID, tmpl.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolveNoPublishedVersion(t *testing.T) {
+	database, sqlDB, mock := db.InitMockDB()
+	defer sqlDB.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(queryPublished)).
+		WillReturnRows(testUtils.ConvertStructsToSQLMockRows([]models.PromptTemplate{}))
+
+	_, err := Resolve(database, models.Persona{PromptName: "pen-pal"}, uuid.New())
+	assert.ErrorIs(t, err, ErrNoPublishedVersion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,162 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// writeWait is the time allowed to write an event to the client.
+const writeWait = 10 * time.Second
+
+var (
+	// ErrClosed is returned when sending an event on a closed connection.
+	ErrClosed = errors.New("the connection is closed")
+	// ErrSlowConsumer is returned when the client does not read its events fast enough. The connection is closed.
+	ErrSlowConsumer = errors.New("the client does not read its events fast enough")
+)
+
+// Limits bounds what a single connection can do.
+type Limits struct {
+	MaxMessageSize    int64         // MaxMessageSize is the size of the largest event accepted, in bytes.
+	PingInterval      time.Duration // PingInterval is the time between two heartbeats. Clients missing two of them are disconnected.
+	SendBuffer        int           // SendBuffer is the number of events waiting to be written before the client is considered too slow.
+	MessagesPerMinute int           // MessagesPerMinute is the number of events accepted per minute, in bursts of the same size.
+}
+
+// Conn is a WebSocket connection speaking the protocol of the chat.
+// Events are written by a single goroutine from a bounded buffer, so that a slow client can't block the server.
+type Conn struct {
+	ws      *websocket.Conn
+	limits  Limits
+	limiter *rate.Limiter
+
+	send      chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+}
+
+// NewConn wraps an open WebSocket connection.
+func NewConn(ws *websocket.Conn, limits Limits) *Conn {
+	return &Conn{
+		ws:        ws,
+		limits:    limits,
+		limiter:   rate.NewLimiter(rate.Every(time.Minute/time.Duration(max(limits.MessagesPerMinute, 1))), max(limits.MessagesPerMinute, 1)),
+		send:      make(chan Event, max(limits.SendBuffer, 1)),
+		done:      make(chan struct{}),
+		closeCode: websocket.CloseNormalClosure,
+	}
+}
+
+// Send queues the event to be written to the client. It never blocks: when the buffer is full,
+// the connection is closed and ErrSlowConsumer is returned.
+func (c *Conn) Send(event Event) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- event:
+		return nil
+	default:
+		c.close(websocket.CloseTryAgainLater)
+		return ErrSlowConsumer
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() {
+	c.close(websocket.CloseNormalClosure)
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Run writes the queued events and the heartbeats, and calls handle with every event read from the client
+// until the connection is closed. Pings, malformed events, events of another protocol version and events
+// beyond the rate limit are answered by Run itself. handle is called from a single goroutine.
+func (c *Conn) Run(handle func(Event)) {
+	go c.writePump()
+	defer c.Close()
+
+	pongWait := 2 * c.limits.PingInterval
+	c.ws.SetReadLimit(c.limits.MaxMessageSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(pongWait))
+
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil || event.Type == "" {
+			_ = c.Send(NewError(event, CodeInvalidEvent, "The event is not valid"))
+			continue
+		}
+		if event.Version != ProtocolVersion {
+			_ = c.Send(NewError(event, CodeUnsupportedVersion, "The protocol version is not supported"))
+			continue
+		}
+		if !c.limiter.Allow() {
+			_ = c.Send(NewError(event, CodeRateLimited, "Too many events, please slow down"))
+			continue
+		}
+		if event.Type == TypePing {
+			pong := NewEvent(TypePong)
+			pong.ID = event.ID
+			_ = c.Send(pong)
+			continue
+		}
+		handle(event)
+	}
+}
+
+// writePump writes the queued events and the heartbeats until the connection is closed.
+func (c *Conn) writePump() {
+	ticker := time.NewTicker(c.limits.PingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.ws.Close()
+	}()
+
+	for {
+		select {
+		case event := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.ws.WriteJSON(event); err != nil {
+				c.close(websocket.CloseAbnormalClosure)
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure)
+				return
+			}
+		case <-c.done:
+			message := websocket.FormatCloseMessage(c.closeCode, "")
+			_ = c.ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+// close marks the connection as closed with the given close code. Only the first call has an effect.
+func (c *Conn) close(code int) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		close(c.done)
+	})
+}
@@ -0,0 +1,130 @@
+package realtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoServer starts a server answering every handled event with an event of the same type and content.
+func newEchoServer(t *testing.T, limits Limits) *websocket.Conn {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := NewConn(ws, limits)
+		conn.Run(func(event Event) {
+			answer := NewEvent(event.Type)
+			answer.ID = event.ID
+			answer.Content = event.Content
+			_ = conn.Send(answer)
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func readEvent(t *testing.T, ws *websocket.Conn) Event {
+	var event Event
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	assert.NoError(t, ws.ReadJSON(&event))
+	return event
+}
+
+func TestConn_Run(t *testing.T) {
+	ws := newEchoServer(t, Limits{MaxMessageSize: 1024, PingInterval: time.Minute, SendBuffer: 8, MessagesPerMinute: 10})
+
+	assert.NoError(t, ws.WriteJSON(Event{Version: ProtocolVersion, Type: TypeSend, ID: "1", Content: "Hola"}))
+	event := readEvent(t, ws)
+	assert.Equal(t, TypeSend, event.Type)
+	assert.Equal(t, "1", event.ID)
+	assert.Equal(t, "Hola", event.Content)
+
+	assert.NoError(t, ws.WriteJSON(Event{Version: ProtocolVersion, Type: TypePing, ID: "2"}))
+	event = readEvent(t, ws)
+	assert.Equal(t, TypePong, event.Type)
+	assert.Equal(t, "2", event.ID)
+
+	assert.NoError(t, ws.WriteJSON(Event{Version: 2, Type: TypeSend, ID: "3"}))
+	event = readEvent(t, ws)
+	assert.Equal(t, TypeError, event.Type)
+	assert.Equal(t, CodeUnsupportedVersion, event.Data.(map[string]interface{})["code"])
+
+	assert.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("not json")))
+	event = readEvent(t, ws)
+	assert.Equal(t, CodeInvalidEvent, event.Data.(map[string]interface{})["code"])
+}
+
+func TestConn_RunRateLimited(t *testing.T) {
+	ws := newEchoServer(t, Limits{MaxMessageSize: 1024, PingInterval: time.Minute, SendBuffer: 8, MessagesPerMinute: 2})
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ws.WriteJSON(Event{Version: ProtocolVersion, Type: TypePing}))
+	}
+	assert.Equal(t, TypePong, readEvent(t, ws).Type)
+	assert.Equal(t, TypePong, readEvent(t, ws).Type)
+	event := readEvent(t, ws)
+	assert.Equal(t, TypeError, event.Type)
+	assert.Equal(t, CodeRateLimited, event.Data.(map[string]interface{})["code"])
+}
+
+func TestConn_RunMessageTooLarge(t *testing.T) {
+	ws := newEchoServer(t, Limits{MaxMessageSize: 64, PingInterval: time.Minute, SendBuffer: 8, MessagesPerMinute: 10})
+
+	assert.NoError(t, ws.WriteJSON(Event{Version: ProtocolVersion, Type: TypeSend, Content: strings.Repeat("a", 128)}))
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig))
+}
+
+func TestConn_RunHeartbeat(t *testing.T) {
+	ws := newEchoServer(t, Limits{MaxMessageSize: 1024, PingInterval: 20 * time.Millisecond, SendBuffer: 8, MessagesPerMinute: 10})
+
+	pings := make(chan struct{}, 10)
+	ws.SetPingHandler(func(string) error {
+		pings <- struct{}{}
+		return ws.WriteControl(websocket.PongMessage, nil, time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-pings:
+	case <-time.After(time.Second):
+		t.Error("no heartbeat received")
+	}
+}
+
+func TestConn_SendSlowConsumer(t *testing.T) {
+	conn := NewConn(nil, Limits{SendBuffer: 2})
+
+	assert.NoError(t, conn.Send(NewEvent(TypePong)))
+	assert.NoError(t, conn.Send(NewEvent(TypePong)))
+	assert.ErrorIs(t, conn.Send(NewEvent(TypePong)), ErrSlowConsumer)
+	assert.ErrorIs(t, conn.Send(NewEvent(TypePong)), ErrClosed)
+	assert.Equal(t, websocket.CloseTryAgainLater, conn.closeCode)
+
+	select {
+	case <-conn.Done():
+	default:
+		t.Error("the connection is not closed")
+	}
+}
@@ -0,0 +1,135 @@
+package realtime
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Hub opens the WebSocket connections, bounds the number of connections of each user and publishes the events
+// raised outside of a connection, such as the delivery of a letter, to the connections of their user.
+type Hub struct {
+	tickets    *TicketStore
+	limits     Limits
+	maxPerUser int
+	upgrader   websocket.Upgrader
+
+	mu          sync.Mutex
+	connections map[uuid.UUID]int
+	attached    map[uuid.UUID]map[*Conn]struct{}
+}
+
+// NewHub returns the Hub matching the provided configuration.
+// Connections are only accepted from the client origin, or without any origin, as sent by the mobile app.
+func NewHub(config *configs.Config) *Hub {
+	return &Hub{
+		tickets: NewTicketStore(config.WSTicketExpiresIn),
+		limits: Limits{
+			MaxMessageSize:    config.WSMaxMessageSize,
+			PingInterval:      config.WSPingInterval,
+			SendBuffer:        config.WSSendBuffer,
+			MessagesPerMinute: config.WSMessagesPerMinute,
+		},
+		maxPerUser: config.WSMaxConnectionsPerUser,
+		upgrader: websocket.Upgrader{
+			HandshakeTimeout: 10 * time.Second,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || origin == config.ClientOrigin
+			},
+		},
+		connections: make(map[uuid.UUID]int),
+		attached:    make(map[uuid.UUID]map[*Conn]struct{}),
+	}
+}
+
+// Tickets returns the store of the tickets authenticating the connections.
+func (h *Hub) Tickets() *TicketStore {
+	return h.tickets
+}
+
+// Limits returns the limits of each connection.
+func (h *Hub) Limits() Limits {
+	return h.limits
+}
+
+// Acquire reserves a connection for the user. It returns false if the user has too many connections open.
+// Every successful call must be followed by a call to Release.
+func (h *Hub) Acquire(userID uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.connections[userID] >= h.maxPerUser {
+		return false
+	}
+	h.connections[userID]++
+	return true
+}
+
+// Release frees a connection reserved for the user.
+func (h *Hub) Release(userID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.connections[userID] <= 1 {
+		delete(h.connections, userID)
+	} else {
+		h.connections[userID]--
+	}
+}
+
+// Upgrade switches the HTTP request to the WebSocket protocol.
+// On failure, an HTTP error has already been sent to the client.
+func (h *Hub) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(ws, h.limits), nil
+}
+
+// Attach registers an open connection of the user, so that it receives the events published to the user.
+// Every call must be followed by a call to Detach once the connection is closed.
+func (h *Hub) Attach(userID uuid.UUID, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.attached[userID] == nil {
+		h.attached[userID] = make(map[*Conn]struct{})
+	}
+	h.attached[userID][conn] = struct{}{}
+}
+
+// Detach unregisters a connection of the user.
+func (h *Hub) Detach(userID uuid.UUID, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.attached[userID], conn)
+	if len(h.attached[userID]) == 0 {
+		delete(h.attached, userID)
+	}
+}
+
+// Publish sends the event to every open connection of the user. It returns the number of connections the event was
+// queued on, 0 if the user isn't connected.
+func (h *Hub) Publish(userID uuid.UUID, event Event) int {
+	h.mu.Lock()
+	conns := make([]*Conn, 0, len(h.attached[userID]))
+	for conn := range h.attached[userID] {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	sent := 0
+	for _, conn := range conns {
+		if conn.Send(event) == nil {
+			sent++
+		}
+	}
+	return sent
+}
@@ -0,0 +1,114 @@
+package realtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHub() *Hub {
+	return NewHub(&configs.Config{
+		ClientOrigin:            "https://localhost",
+		WSTicketExpiresIn:       30 * time.Second,
+		WSMaxConnectionsPerUser: 2,
+		WSMaxMessageSize:        1024,
+		WSPingInterval:          time.Second,
+		WSSendBuffer:            8,
+		WSMessagesPerMinute:     3,
+	})
+}
+
+func TestHub_Acquire(t *testing.T) {
+	hub := newTestHub()
+	userID := uuid.New()
+
+	assert.True(t, hub.Acquire(userID))
+	assert.True(t, hub.Acquire(userID))
+	assert.False(t, hub.Acquire(userID))
+	assert.True(t, hub.Acquire(uuid.New()))
+
+	hub.Release(userID)
+	assert.True(t, hub.Acquire(userID))
+	hub.Release(userID)
+	hub.Release(userID)
+	assert.NotContains(t, hub.connections, userID)
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := newTestHub()
+	userID := uuid.New()
+	conn := NewConn(nil, hub.Limits())
+	closed := NewConn(nil, hub.Limits())
+	close(closed.done)
+
+	assert.Equal(t, 0, hub.Publish(userID, NewEvent(TypeDelivered)))
+
+	hub.Attach(userID, conn)
+	hub.Attach(userID, closed)
+	hub.Attach(uuid.New(), NewConn(nil, hub.Limits()))
+	assert.Equal(t, 1, hub.Publish(userID, NewEvent(TypeDelivered)))
+	assert.Equal(t, TypeDelivered, (<-conn.send).Type)
+
+	hub.Detach(userID, conn)
+	hub.Detach(userID, closed)
+	assert.NotContains(t, hub.attached, userID)
+	assert.Equal(t, 0, hub.Publish(userID, NewEvent(TypeDelivered)))
+}
+
+func TestHub_Upgrade(t *testing.T) {
+	hub := newTestHub()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := hub.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		name     string
+		origin   string
+		accepted bool
+	}{
+		{
+			name:     "mobile app",
+			accepted: true,
+		},
+		{
+			name:     "client origin",
+			origin:   "https://localhost",
+			accepted: true,
+		},
+		{
+			name:     "other origin",
+			origin:   "https://example.com",
+			accepted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			ws, response, err := websocket.DefaultDialer.Dial(url, header)
+			if tt.accepted {
+				assert.NoError(t, err)
+				ws.Close()
+			} else {
+				assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+				assert.Equal(t, http.StatusForbidden, response.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package realtime provides the WebSocket channel of the chat: the single-use tickets authenticating the
+// connections, the versioned JSON protocol, heartbeats, backpressure and the limits of each connection.
+package realtime
+
+import "github.com/google/uuid"
+
+// ProtocolVersion is the version of the protocol spoken by the server. Every event carries it.
+const ProtocolVersion = 1
+
+// Types of the events sent by the client.
+const (
+	TypePing   = "ping"              // TypePing asks the server for a "pong" event.
+	TypeSend   = "message.send"      // TypeSend sends a message to a conversation.
+	TypeCancel = "generation.cancel" // TypeCancel stops the reply being generated.
+)
+
+// Types of the events sent by the server.
+const (
+	TypeWelcome   = "welcome"              // TypeWelcome is sent once the connection is open, with the limits of the connection.
+	TypePong      = "pong"                 // TypePong answers a "ping" event.
+	TypeTyping    = "typing"               // TypeTyping tells whether the pen pal is writing a reply.
+	TypeDelta     = "message.delta"        // TypeDelta holds a piece of the reply being generated.
+	TypeDone      = "message.done"         // TypeDone holds the stored message and its reply.
+	TypeCancelled = "generation.cancelled" // TypeCancelled confirms that the reply was stopped and nothing was stored.
+	TypeScheduled = "letter.scheduled"     // TypeScheduled holds the stored message and the letter scheduling its reply, in letters mode.
+	TypeDelivered = "letter.delivered"     // TypeDelivered holds the message and the reply of a delivered letter.
+	TypeError     = "error"                // TypeError reports a refused event or a failed generation.
+)
+
+// Codes of the "error" events.
+const (
+	CodeUnsupportedVersion = "unsupported_version" // CodeUnsupportedVersion is sent for events of another protocol version.
+	CodeInvalidEvent       = "invalid_event"       // CodeInvalidEvent is sent for malformed or unknown events.
+	CodeRateLimited        = "rate_limited"        // CodeRateLimited is sent when the client sends too many events.
+	CodeBusy               = "busy"                // CodeBusy is sent when a message is sent while a reply is being generated.
+	CodeLetterPending      = "letter_pending"      // CodeLetterPending is sent when a letter is sent while the previous one waits for its reply.
+	CodeNotFound           = "not_found"           // CodeNotFound is sent for conversations the user can't access.
+	CodeArchived           = "archived"            // CodeArchived is sent for messages sent to an archived conversation.
+	CodePersonaUnavailable = "persona_unavailable" // CodePersonaUnavailable is sent when the pen pal of the conversation was removed or disabled.
+	CodeNoReply            = "no_reply"            // CodeNoReply is sent when the language model can't generate a reply.
+	CodeQuotaExceeded      = "quota_exceeded"      // CodeQuotaExceeded is sent when the user has consumed the tokens of their plan.
+	CodeBlocked            = "message_blocked"     // CodeBlocked is sent when the message is blocked by the moderation.
+	CodeRefused            = "message_refused"     // CodeRefused is sent when the message is refused by the injection guard.
+	CodeInternal           = "internal"            // CodeInternal is sent for unexpected server errors.
+)
+
+// Event is the JSON document exchanged in both directions.
+type Event struct {
+	Version        int         `json:"v"`                         // Version of the protocol
+	Type           string      `json:"type"`                      // Type of the event
+	ID             string      `json:"id,omitempty"`              // Identifier chosen by the client, repeated in the events answering it
+	ConversationID *uuid.UUID  `json:"conversation_id,omitempty"` // Conversation the event relates to
+	Content        string      `json:"content,omitempty"`         // Text of a message or of a piece of reply
+	Data           interface{} `json:"data,omitempty"`            // Payload of the server events
+}
+
+// ErrorData is the payload of the "error" events.
+type ErrorData struct {
+	Code    string      `json:"code"`              // Code identifies the error
+	Message string      `json:"message"`           // Message describes the error
+	Details interface{} `json:"details,omitempty"` // Details describes some errors further, e.g. the exhausted quota
+}
+
+// WelcomeData is the payload of the "welcome" event.
+type WelcomeData struct {
+	MaxMessageSize    int64 `json:"max_message_size"`    // Size of the largest event accepted, in bytes
+	MessagesPerMinute int   `json:"messages_per_minute"` // Number of events accepted per minute
+	PingInterval      int   `json:"ping_interval"`       // Seconds between two heartbeats of the server
+}
+
+// TypingData is the payload of the "typing" events.
+type TypingData struct {
+	Typing bool `json:"typing"` // Typing is true while the pen pal writes its reply
+}
+
+// NewEvent returns an event of the current protocol version.
+func NewEvent(eventType string) Event {
+	return Event{Version: ProtocolVersion, Type: eventType}
+}
+
+// NewError returns an "error" event answering the given event.
+func NewError(answered Event, code string, message string) Event {
+	return NewErrorWithDetails(answered, code, message, nil)
+}
+
+// NewErrorWithDetails returns an "error" event answering the given event, describing the error further.
+func NewErrorWithDetails(answered Event, code string, message string, details interface{}) Event {
+	event := NewEvent(TypeError)
+	event.ID = answered.ID
+	event.ConversationID = answered.ConversationID
+	event.Data = ErrorData{Code: code, Message: message, Details: details}
+	return event
+}
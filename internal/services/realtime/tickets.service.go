@@ -0,0 +1,73 @@
+package realtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/enzo-gbd/GBA/internal/utils"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTicket is returned for unknown, expired or already used tickets.
+var ErrInvalidTicket = errors.New("the ticket is invalid or expired")
+
+// ticket is a ticket waiting to be redeemed.
+type ticket struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+// TicketStore issues the short-lived tickets authenticating the WebSocket connections, as browsers can't
+// send an Authorization header when opening a WebSocket. A ticket can only be redeemed once.
+// Only hashes of the tickets are kept, in memory, so a ticket must be redeemed on the instance that issued it.
+type TicketStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	tickets map[string]ticket
+}
+
+// NewTicketStore returns a store issuing tickets valid for ttl.
+func NewTicketStore(ttl time.Duration) *TicketStore {
+	return &TicketStore{ttl: ttl, now: time.Now, tickets: make(map[string]ticket)}
+}
+
+// Issue returns a new ticket for the user and its expiry.
+func (s *TicketStore) Issue(userID uuid.UUID) (string, time.Time, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for hash, issued := range s.tickets {
+		if !now.Before(issued.expiresAt) {
+			delete(s.tickets, hash)
+		}
+	}
+	expiresAt := now.Add(s.ttl)
+	s.tickets[utils.HashToken(token)] = ticket{userID: userID, expiresAt: expiresAt}
+	return token, expiresAt, nil
+}
+
+// Redeem returns the user the ticket was issued to and invalidates it.
+func (s *TicketStore) Redeem(token string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := utils.HashToken(token)
+	issued, found := s.tickets[hash]
+	if !found {
+		return uuid.Nil, ErrInvalidTicket
+	}
+	delete(s.tickets, hash)
+	if !s.now().Before(issued.expiresAt) {
+		return uuid.Nil, ErrInvalidTicket
+	}
+	return issued.userID, nil
+}
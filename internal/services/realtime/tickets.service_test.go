@@ -0,0 +1,48 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicketStore(t *testing.T) {
+	store := NewTicketStore(30 * time.Second)
+	userID := uuid.New()
+
+	ticket, expiresAt, err := store.Issue(userID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ticket)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), expiresAt, time.Second)
+
+	redeemed, err := store.Redeem(ticket)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, redeemed)
+
+	_, err = store.Redeem(ticket)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+	_, err = store.Redeem("unknown")
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
+
+func TestTicketStore_Expired(t *testing.T) {
+	now := time.Now()
+	store := NewTicketStore(30 * time.Second)
+	store.now = func() time.Time { return now }
+
+	expired, _, err := store.Issue(uuid.New())
+	assert.NoError(t, err)
+
+	now = now.Add(30 * time.Second)
+	_, err = store.Redeem(expired)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+
+	expired, _, _ = store.Issue(uuid.New())
+	now = now.Add(time.Minute)
+	_, _, _ = store.Issue(uuid.New())
+	assert.Len(t, store.tickets, 1)
+	_, err = store.Redeem(expired)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
@@ -0,0 +1,76 @@
+package redaction
+
+import (
+	"context"
+	"strings"
+
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+)
+
+// Provider is an llm.Provider redacting the personal data of the requests before passing them to another provider,
+// and restoring them in its completions.
+type Provider struct {
+	provider llm.Provider
+	redactor *Redactor
+}
+
+// NewProvider returns the provider redacting the requests with the redactor, or the provider itself if the
+// redactor is nil.
+func NewProvider(provider llm.Provider, redactor *Redactor) llm.Provider {
+	if redactor == nil {
+		return provider
+	}
+	return &Provider{provider: provider, redactor: redactor}
+}
+
+// Complete implements llm.Provider.
+func (p *Provider) Complete(ctx context.Context, request llm.Request) (llm.Response, error) {
+	vault := NewVault()
+	response, err := p.provider.Complete(ctx, p.redact(ctx, vault, request))
+	if err != nil {
+		return response, err
+	}
+	response.Content = p.redactor.Restore(vault, response.Content)
+	return response, nil
+}
+
+// Stream implements llm.Provider. A piece of the completion which may end with the start of a placeholder is held
+// back until the placeholder is complete.
+func (p *Provider) Stream(ctx context.Context, request llm.Request, onDelta llm.DeltaHandler) (llm.Response, error) {
+	vault := NewVault()
+	var pending string
+	response, err := p.provider.Stream(ctx, p.redact(ctx, vault, request), func(delta string) error {
+		text := pending + delta
+		pending = ""
+		if start := strings.LastIndex(text, "["); start >= 0 && !strings.Contains(text[start:], "]") && len(text)-start < maxPlaceholderLength {
+			text, pending = text[:start], text[start:]
+		}
+		if text == "" {
+			return nil
+		}
+		return onDelta(p.redactor.restore(vault, text, false))
+	})
+	if err != nil {
+		return response, err
+	}
+	if pending != "" {
+		if err := onDelta(p.redactor.restore(vault, pending, false)); err != nil {
+			return llm.Response{}, err
+		}
+	}
+	response.Content = p.redactor.Restore(vault, response.Content)
+	return response, nil
+}
+
+// redact returns a copy of the request whose messages are redacted into the vault.
+func (p *Provider) redact(ctx context.Context, vault *Vault, request llm.Request) llm.Request {
+	address := AddressFromContext(ctx)
+	messages := make([]llm.Message, len(request.Messages))
+	for i, message := range request.Messages {
+		message.Content = p.redactor.Redact(vault, message.Content, address)
+		messages[i] = message
+	}
+	request.Messages = messages
+	p.redactor.countCall()
+	return request
+}
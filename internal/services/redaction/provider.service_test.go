@@ -0,0 +1,52 @@
+package redaction
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/internal/services/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider(t *testing.T) {
+	fake := llm.NewFakeProvider()
+	assert.Same(t, fake, NewProvider(fake, nil))
+}
+
+func TestProvider_Complete(t *testing.T) {
+	redactor, _ := NewRedactor(Categories, []string{CategoryEmail, CategoryAddress})
+	fake := llm.NewFakeProvider()
+	provider := NewProvider(fake, redactor)
+	ctx := WithAddress(context.Background(), "Calle Mayor 5, Madrid")
+
+	response, err := provider.Complete(ctx, llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleSystem, Content: "Reply in Spanish."},
+		{Role: llm.RoleUser, Content: "Vivo en Calle Mayor 5, mi correo es ana@mail.es y mi IBAN ES91 2100 0418 4502 0005 1332"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "You said: Vivo en Calle Mayor 5, mi correo es ana@mail.es y mi IBAN **** **** **** **** **** 1332", response.Content)
+	assert.Equal(t, "Vivo en [ADDRESS_1], mi correo es [EMAIL_1] y mi IBAN [IBAN_1]", fake.Requests()[0].Messages[1].Content)
+	assert.Equal(t, "Reply in Spanish.", fake.Requests()[0].Messages[0].Content)
+	assert.Equal(t, int64(1), redactor.Stats().Calls)
+}
+
+func TestProvider_Stream(t *testing.T) {
+	redactor, _ := NewRedactor(Categories, []string{CategoryEmail})
+	fake := llm.NewFakeProvider()
+	fake.Reply = "Te escribo a [EMAIL_1] mañana [sin falta]"
+	provider := NewProvider(fake, redactor)
+
+	var deltas []string
+	response, err := provider.Stream(context.Background(), llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleUser, Content: "Mi correo es ana@mail.es"},
+	}}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Te escribo a ana@mail.es mañana [sin falta]", response.Content)
+	assert.Equal(t, response.Content, strings.Join(deltas, ""))
+	assert.Equal(t, "Mi correo es [EMAIL_1]", fake.Requests()[0].Messages[0].Content)
+	assert.Equal(t, int64(1), redactor.Stats().Restored[CategoryEmail])
+}
@@ -0,0 +1,342 @@
+// Package redaction keeps the personal data of the learners away from the language model provider. Emails, phone
+// numbers, IBANs, card numbers and the address of the learner are replaced with placeholders, such as [EMAIL_1],
+// before any call, the same value always getting the same placeholder within a call. The placeholders of the
+// categories safe to echo back are restored in the completions, the others are masked.
+package redaction
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/enzo-gbd/GBA/configs"
+)
+
+// Categories of personal data.
+const (
+	CategoryEmail   = "email"   // CategoryEmail is an email address.
+	CategoryPhone   = "phone"   // CategoryPhone is a phone number.
+	CategoryIBAN    = "iban"    // CategoryIBAN is a bank account number.
+	CategoryCard    = "card"    // CategoryCard is a payment card number.
+	CategoryAddress = "address" // CategoryAddress is the postal address of the learner.
+)
+
+// Categories lists the categories of personal data, in the order they are detected.
+var Categories = []string{CategoryAddress, CategoryEmail, CategoryIBAN, CategoryCard, CategoryPhone}
+
+// patterns find the candidates of each category, checked by valid before being redacted.
+var patterns = map[string]*regexp.Regexp{
+	CategoryEmail: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	CategoryIBAN:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+	CategoryCard:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	CategoryPhone: regexp.MustCompile(`(?:\+|\b00)?\(?\d[\d .()-]{6,}\d\b`),
+}
+
+// placeholderPattern matches the placeholders written by a Redactor.
+var placeholderPattern = regexp.MustCompile(`\[(EMAIL|PHONE|IBAN|CARD|ADDRESS)_\d+\]`)
+
+// maxPlaceholderLength is the length of the longest placeholder a streamed completion has to wait for.
+const maxPlaceholderLength = 16
+
+// addressContextKey is the context key holding the address of the learner.
+type addressContextKey struct{}
+
+// WithAddress returns a copy of ctx redacting the address of the learner from the calls made with it.
+func WithAddress(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, addressContextKey{}, address)
+}
+
+// AddressFromContext returns the address of the learner set on the context, empty if none.
+func AddressFromContext(ctx context.Context) string {
+	address, _ := ctx.Value(addressContextKey{}).(string)
+	return address
+}
+
+// Stats counts the personal data redacted since the start of the server.
+type Stats struct {
+	Since    time.Time        `json:"since"`    // Since is the start of the count
+	Calls    int64            `json:"calls"`    // Calls is the number of calls to the provider screened
+	Redacted map[string]int64 `json:"redacted"` // Redacted is the number of values replaced with placeholders, per category
+	Restored map[string]int64 `json:"restored"` // Restored is the number of placeholders restored in completions, per category
+}
+
+// Redactor replaces the personal data of the configured categories with placeholders.
+type Redactor struct {
+	redacted []string
+	restored []string
+
+	mutex sync.Mutex
+	stats Stats
+}
+
+// NewRedactor returns a Redactor replacing the personal data of the redacted categories, and restoring those of the
+// restored categories in the completions.
+func NewRedactor(redacted []string, restored []string) (*Redactor, error) {
+	for _, category := range append(slices.Clone(redacted), restored...) {
+		if !slices.Contains(Categories, category) {
+			return nil, fmt.Errorf("unknown personal data category %q", category)
+		}
+	}
+	return &Redactor{
+		redacted: redacted,
+		restored: restored,
+		stats:    Stats{Since: time.Now(), Redacted: map[string]int64{}, Restored: map[string]int64{}},
+	}, nil
+}
+
+// NewRedactorFromConfig returns the Redactor matching the provided configuration, nil if no category is redacted.
+func NewRedactorFromConfig(config *configs.Config) (*Redactor, error) {
+	redacted, restored := split(config.PIIRedactedCategories), split(config.PIIRestoredCategories)
+	if len(redacted) == 0 {
+		return nil, nil
+	}
+	return NewRedactor(redacted, restored)
+}
+
+// split returns the values of a comma separated list.
+func split(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Stats returns the counts of the personal data redacted so far.
+func (r *Redactor) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := r.stats
+	stats.Redacted, stats.Restored = make(map[string]int64, len(Categories)), make(map[string]int64, len(Categories))
+	for _, category := range Categories {
+		stats.Redacted[category] = r.stats.Redacted[category]
+		stats.Restored[category] = r.stats.Restored[category]
+	}
+	return stats
+}
+
+// countCall counts a call screened.
+func (r *Redactor) countCall() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.Calls++
+}
+
+// countRedacted counts a value of the category replaced with a placeholder.
+func (r *Redactor) countRedacted(category string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.Redacted[category]++
+}
+
+// countRestored counts a placeholder of the category restored.
+func (r *Redactor) countRestored(category string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.stats.Restored[category]++
+}
+
+// Vault holds the values replaced during a call, so that the completion can be restored.
+type Vault struct {
+	placeholders map[string]string // placeholders maps the values to their placeholder
+	values       map[string]string // values maps the placeholders to their value
+	categories   map[string]string // categories maps the placeholders to their category
+	counts       map[string]int    // counts is the number of values of each category
+}
+
+// NewVault returns an empty Vault.
+func NewVault() *Vault {
+	return &Vault{placeholders: map[string]string{}, values: map[string]string{}, categories: map[string]string{}, counts: map[string]int{}}
+}
+
+// placeholder returns the placeholder of the value, assigning the next one of the category if it is new.
+func (v *Vault) placeholder(category string, value string) string {
+	key := category + "\x00" + value
+	if placeholder, found := v.placeholders[key]; found {
+		return placeholder
+	}
+	v.counts[category]++
+	placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(category), v.counts[category])
+	v.placeholders[key], v.values[placeholder], v.categories[placeholder] = placeholder, value, category
+	return placeholder
+}
+
+// span is a value found in a text.
+type span struct {
+	category   string
+	start, end int
+}
+
+// Redact replaces the personal data of the text with placeholders recorded in the vault. address is the address of
+// the learner, ignored if empty.
+func (r *Redactor) Redact(vault *Vault, text string, address string) string {
+	var spans []span
+	for _, category := range Categories {
+		if !slices.Contains(r.redacted, category) {
+			continue
+		}
+		for _, match := range find(category, text, address) {
+			if !slices.ContainsFunc(spans, func(s span) bool { return s.start < match[1] && match[0] < s.end }) {
+				spans = append(spans, span{category: category, start: match[0], end: match[1]})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	slices.SortFunc(spans, func(a, b span) int { return a.start - b.start })
+	var redacted strings.Builder
+	last := 0
+	for _, s := range spans {
+		redacted.WriteString(text[last:s.start])
+		redacted.WriteString(vault.placeholder(s.category, text[s.start:s.end]))
+		r.countRedacted(s.category)
+		last = s.end
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String()
+}
+
+// find returns the byte offsets of the values of the category in the text.
+func find(category string, text string, address string) [][]int {
+	if category == CategoryAddress {
+		return findAddress(text, address)
+	}
+	var found [][]int
+	for _, match := range patterns[category].FindAllStringIndex(text, -1) {
+		if valid(category, text[match[0]:match[1]]) {
+			found = append(found, match)
+		}
+	}
+	return found
+}
+
+// findAddress returns the byte offsets of the address, or of its first line, in the text, whatever their case and spacing.
+func findAddress(text string, address string) [][]int {
+	address = strings.TrimSpace(address)
+	if len([]rune(address)) < 5 {
+		return nil
+	}
+	candidates := []string{address}
+	if street, _, found := strings.Cut(address, ","); found && len([]rune(strings.TrimSpace(street))) >= 8 {
+		candidates = append(candidates, strings.TrimSpace(street))
+	}
+	for _, candidate := range candidates {
+		words := strings.Fields(candidate)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		pattern := regexp.MustCompile(`(?i)` + strings.Join(words, `[\s,]+`))
+		if found := pattern.FindAllStringIndex(text, -1); len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// valid reports whether the candidate is a value of the category: IBANs and card numbers must pass their checksum,
+// phone numbers must have between 8 and 15 digits, 9 unless they start with an international prefix.
+func valid(category string, candidate string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, candidate)
+	switch category {
+	case CategoryIBAN:
+		return validIBAN(strings.ReplaceAll(candidate, " ", ""))
+	case CategoryCard:
+		return luhn(digits)
+	case CategoryPhone:
+		international := strings.HasPrefix(candidate, "+") || strings.HasPrefix(candidate, "00")
+		return len(digits) <= 15 && (len(digits) >= 9 || (international && len(digits) >= 8))
+	}
+	return true
+}
+
+// validIBAN reports whether the IBAN passes its mod 97 checksum.
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if unicode.IsLetter(r) {
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	number, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+// luhn reports whether the digits pass the Luhn checksum of the card numbers.
+func luhn(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(digits) {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// Restore replaces the placeholders of the vault found in the text with their value if their category is restored,
+// and with their masked value otherwise. Unknown placeholders are left untouched.
+func (r *Redactor) Restore(vault *Vault, text string) string {
+	return r.restore(vault, text, true)
+}
+
+// restore restores the placeholders of the text, counting the restored ones if counted is set.
+func (r *Redactor) restore(vault *Vault, text string, counted bool) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		value, found := vault.values[placeholder]
+		if !found {
+			return placeholder
+		}
+		category := vault.categories[placeholder]
+		if !slices.Contains(r.restored, category) {
+			return mask(value)
+		}
+		if counted {
+			r.countRestored(category)
+		}
+		return value
+	})
+}
+
+// mask hides the letters and digits of the value but the last four.
+func mask(value string) string {
+	runes := []rune(value)
+	kept := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+			if kept < 4 {
+				kept++
+			} else {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,93 @@
+package redaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enzo-gbd/GBA/configs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor, err := NewRedactor(Categories, []string{CategoryEmail, CategoryPhone, CategoryAddress})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		text     string
+		address  string
+		expected string
+	}{
+		{
+			name:     "email",
+			text:     "Escríbeme a juan.perez@mail.es o a juan.perez@mail.es",
+			expected: "Escríbeme a [EMAIL_1] o a [EMAIL_1]",
+		},
+		{
+			name:     "phones",
+			text:     "Mi número es +34 612 345 678 y el de casa 0612345678.",
+			expected: "Mi número es [PHONE_1] y el de casa [PHONE_2].",
+		},
+		{
+			name:     "IBAN and card",
+			text:     "IBAN: FR76 3000 6000 0112 3456 7890 189, tarjeta 4242 4242 4242 4242.",
+			expected: "IBAN: [IBAN_1], tarjeta [CARD_1].",
+		},
+		{
+			name:     "invalid numbers",
+			text:     "Nací el 2001-05-12, tengo 3 gatos y la tarjeta 4242 4242 4242 4241 no existe.",
+			expected: "Nací el 2001-05-12, tengo 3 gatos y la tarjeta 4242 4242 4242 4241 no existe.",
+		},
+		{
+			name:     "address",
+			text:     "Vivo en 12 Rue de la  Paix, cerca del centro.",
+			address:  "12 rue de la Paix, 75002 Paris",
+			expected: "Vivo en [ADDRESS_1], cerca del centro.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redactor.Redact(NewVault(), tt.text, tt.address))
+		})
+	}
+
+	stats := redactor.Stats()
+	assert.Equal(t, int64(2), stats.Redacted[CategoryEmail])
+	assert.Equal(t, int64(2), stats.Redacted[CategoryPhone])
+	assert.Equal(t, int64(1), stats.Redacted[CategoryIBAN])
+	assert.Equal(t, int64(1), stats.Redacted[CategoryCard])
+	assert.Equal(t, int64(1), stats.Redacted[CategoryAddress])
+}
+
+func TestRedactor_Restore(t *testing.T) {
+	redactor, err := NewRedactor([]string{CategoryEmail, CategoryCard}, []string{CategoryEmail})
+	assert.NoError(t, err)
+	vault := NewVault()
+
+	redacted := redactor.Redact(vault, "Mi correo es ana@mail.es y mi tarjeta 4242 4242 4242 4242", "")
+	assert.Equal(t, "Mi correo es [EMAIL_1] y mi tarjeta [CARD_1]", redacted)
+	restored := redactor.Restore(vault, "Te escribo a [EMAIL_1]. No compartas [CARD_1] ni [PHONE_1].")
+	assert.Equal(t, "Te escribo a ana@mail.es. No compartas **** **** **** 4242 ni [PHONE_1].", restored)
+	assert.Equal(t, int64(1), redactor.Stats().Restored[CategoryEmail])
+	assert.Equal(t, int64(0), redactor.Stats().Restored[CategoryCard])
+}
+
+func TestNewRedactorFromConfig(t *testing.T) {
+	redactor, err := NewRedactorFromConfig(&configs.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, redactor)
+
+	redactor, err = NewRedactorFromConfig(&configs.Config{PIIRedactedCategories: "email, phone", PIIRestoredCategories: "email"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CategoryEmail, CategoryPhone}, redactor.redacted)
+	assert.Equal(t, []string{CategoryEmail}, redactor.restored)
+
+	_, err = NewRedactorFromConfig(&configs.Config{PIIRedactedCategories: "email,passport"})
+	assert.Error(t, err)
+}
+
+func TestWithAddress(t *testing.T) {
+	assert.Empty(t, AddressFromContext(context.Background()))
+	assert.Equal(t, "12 rue de la Paix", AddressFromContext(WithAddress(context.Background(), "12 rue de la Paix")))
+}
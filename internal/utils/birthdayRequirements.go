@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxPlausibleAge is the age above which a birthday is considered a typing mistake.
+const MaxPlausibleAge = 120
+
+// BirthdayRequirements checks that the given birthday is plausible.
+// The function takes a value of any type, but expects a time.Time for validation.
+// It rejects birthdays in the future and birthdays more than MaxPlausibleAge years ago.
+// Zero values are left to the validation.Required rule.
+func BirthdayRequirements(value interface{}) error {
+	birthday, _ := value.(time.Time)
+	if birthday.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+	if birthday.After(now) {
+		return errors.New("must not be in the future")
+	}
+	if birthday.Before(now.AddDate(-MaxPlausibleAge, 0, 0)) {
+		return errors.New("must be within the last 120 years")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBirthdayRequirements(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		birthday interface{}
+		wantErr  bool
+	}{
+		{"adult", time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC), false},
+		{"child", now.AddDate(-9, 0, 0), false},
+		{"zero value", time.Time{}, false},
+		{"in the future", now.AddDate(0, 0, 1), true},
+		{"implausibly old", now.AddDate(-MaxPlausibleAge-1, 0, 0), true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := BirthdayRequirements(tc.birthday)
+			if tc.wantErr && err == nil {
+				t.Errorf("Expected an error but got none")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("Did not expect an error but got one: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import "strings"
+
+// emailProviders lists the rules of the known providers delivering several spellings of an address
+// to the same mailbox. Addresses are not resolved through DNS: only these providers are normalized.
+var emailProviders = map[string]struct {
+	canonical string // canonical is the domain the provider's aliases are rewritten to.
+	plus      bool   // plus reports whether everything after a '+' in the local part is ignored.
+	dots      bool   // dots reports whether dots in the local part are ignored.
+}{
+	"gmail.com":      {"gmail.com", true, true},
+	"googlemail.com": {"gmail.com", true, true},
+	"outlook.com":    {"outlook.com", true, false},
+	"hotmail.com":    {"hotmail.com", true, false},
+	"live.com":       {"live.com", true, false},
+	"icloud.com":     {"icloud.com", true, false},
+	"me.com":         {"icloud.com", true, false},
+	"mac.com":        {"icloud.com", true, false},
+	"proton.me":      {"proton.me", true, false},
+	"protonmail.com": {"proton.me", true, false},
+	"fastmail.com":   {"fastmail.com", true, false},
+}
+
+// NormalizeEmail returns the canonical form of an email address, used to detect several accounts
+// registered with spellings of the same mailbox.
+// The address is lowercased and, for known providers, the plus-addressing tag and the dots
+// of the local part are removed, and the domain aliases are rewritten.
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+
+	provider, known := emailProviders[domain]
+	if !known {
+		return email
+	}
+	if provider.plus {
+		if plus := strings.Index(local, "+"); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+	if provider.dots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + provider.canonical
+}
+
+// EmailDomain returns the lowercased domain of an email address, or an empty string if it has none.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
@@ -0,0 +1,45 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{"John.Doe@mail.pe", "john.doe@mail.pe"},
+		{"john.doe+tag@mail.pe", "john.doe+tag@mail.pe"},
+		{"J.o.h.n.Doe+spam@GMail.com", "johndoe@gmail.com"},
+		{"john.doe@googlemail.com", "johndoe@gmail.com"},
+		{"john.doe+news@outlook.com", "john.doe@outlook.com"},
+		{"john.doe+news@me.com", "john.doe@icloud.com"},
+		{"not-an-email", "not-an-email"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.email, func(t *testing.T) {
+			if got := NormalizeEmail(tc.email); got != tc.expected {
+				t.Errorf("NormalizeEmail(%q) = %q, expected %q", tc.email, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{"john.doe@Mail.PE", "mail.pe"},
+		{"john@doe@school.edu", "school.edu"},
+		{"not-an-email", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.email, func(t *testing.T) {
+			if got := EmailDomain(tc.email); got != tc.expected {
+				t.Errorf("EmailDomain(%q) = %q, expected %q", tc.email, got, tc.expected)
+			}
+		})
+	}
+}
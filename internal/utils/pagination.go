@@ -0,0 +1,43 @@
+// Package utils provides utility functions that support various operations across the application.
+package utils
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20  // defaultPageSize is used when the request does not provide a page size.
+	maxPageSize     = 100 // maxPageSize bounds the page size a client can request.
+)
+
+// Pagination describes the page of results requested by a client.
+type Pagination struct {
+	Page     int `json:"page"`      // Page is the 1-based index of the requested page.
+	PageSize int `json:"page_size"` // PageSize is the number of items per page.
+}
+
+// GetPagination reads the `page` and `page_size` query parameters of the request.
+// Missing or invalid values fall back to the first page and the default page size,
+// and the page size is capped to avoid unbounded queries.
+func GetPagination(context *gin.Context) Pagination {
+	page, err := strconv.Atoi(context.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(context.Query("page_size"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return Pagination{Page: page, PageSize: pageSize}
+}
+
+// Scope returns a GORM scope applying the pagination to a query.
+func (p Pagination) Scope(db *gorm.DB) *gorm.DB {
+	return db.Offset((p.Page - 1) * p.PageSize).Limit(p.PageSize)
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPagination(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected Pagination
+	}{
+		{"defaults", "", Pagination{Page: 1, PageSize: 20}},
+		{"valid values", "?page=3&page_size=10", Pagination{Page: 3, PageSize: 10}},
+		{"invalid values", "?page=-1&page_size=abc", Pagination{Page: 1, PageSize: 20}},
+		{"page size too large", "?page_size=1000", Pagination{Page: 1, PageSize: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			context, _ := gin.CreateTestContext(httptest.NewRecorder())
+			context.Request, _ = http.NewRequest("GET", "/"+tt.query, nil)
+
+			assert.Equal(t, tt.expected, GetPagination(context))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+// Package utils provides utility functions that support various operations across the application.
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateRandomToken returns a URL-safe random token built from the given number of
+// random bytes. It is used for secrets that are sent to users, such as invitation links,
+// and must never be stored as is: see HashToken.
+func GenerateRandomToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// HashToken returns the hexadecimal SHA-256 digest of a token.
+// Only this digest is persisted, so a leaked database does not expose usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
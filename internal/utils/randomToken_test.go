@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRandomToken(t *testing.T) {
+	first, err := GenerateRandomToken(32)
+	require.NoError(t, err)
+	require.Len(t, first, 43)
+
+	second, err := GenerateRandomToken(32)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashToken("token"))
+	require.NotEqual(t, hash, HashToken("other"))
+}